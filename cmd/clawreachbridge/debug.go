@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// connectionEntry mirrors an element of GET /api/v1/connections.
+type connectionEntry struct {
+	IP    string `json:"ip"`
+	Count int    `json:"count"`
+}
+
+// fetchConnections queries the web UI API at baseURL (the health listener)
+// for the per-IP active connection breakdown.
+func fetchConnections(ctx context.Context, client *http.Client, baseURL string) ([]connectionEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(baseURL, "/")+"/api/v1/connections", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", req.URL, resp.Status)
+	}
+	var entries []connectionEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("decoding connections: %w", err)
+	}
+	return entries, nil
+}
+
+// printConnections writes entries as a table followed by the total.
+func printConnections(w io.Writer, entries []connectionEntry) error {
+	if len(entries) == 0 {
+		_, err := fmt.Fprintln(w, "No active connections.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLIENT IP\tCONNECTIONS")
+	total := 0
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%d\n", e.IP, e.Count)
+		total += e.Count
+	}
+	fmt.Fprintf(tw, "TOTAL\t%d\n", total)
+	return tw.Flush()
+}
+
+// debugConnections prints the active connections of the instance at baseURL.
+// With a positive watch interval it clears the screen and reprints on every
+// tick until ctx is cancelled; a failed poll is reported and retried rather
+// than ending the watch.
+func debugConnections(ctx context.Context, w io.Writer, baseURL string, watch time.Duration) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	show := func() error {
+		entries, err := fetchConnections(ctx, client, baseURL)
+		if err != nil {
+			return err
+		}
+		return printConnections(w, entries)
+	}
+	if watch <= 0 {
+		return show()
+	}
+
+	ticker := time.NewTicker(watch)
+	defer ticker.Stop()
+	for {
+		fmt.Fprint(w, "\033[H\033[2J")
+		fmt.Fprintf(w, "%s  %s (every %s)\n\n", time.Now().Format(time.TimeOnly), baseURL, watch)
+		if err := show(); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(w, "error: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func stubConnectionsAPI(t *testing.T, body string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/connections" || r.Method != http.MethodGet {
+			http.NotFound(w, r)
+			return
+		}
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestDebugConnectionsTable(t *testing.T) {
+	srv, _ := stubConnectionsAPI(t, `[{"ip":"100.64.0.7","count":3},{"ip":"100.64.0.12","count":1}]`)
+
+	var out bytes.Buffer
+	if err := debugConnections(context.Background(), &out, srv.URL+"/", 0); err != nil {
+		t.Fatalf("debugConnections: %v", err)
+	}
+	want := "CLIENT IP    CONNECTIONS\n" +
+		"100.64.0.7   3\n" +
+		"100.64.0.12  1\n" +
+		"TOTAL        4\n"
+	if out.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestDebugConnectionsEmpty(t *testing.T) {
+	srv, _ := stubConnectionsAPI(t, `[]`)
+
+	var out bytes.Buffer
+	if err := debugConnections(context.Background(), &out, srv.URL, 0); err != nil {
+		t.Fatalf("debugConnections: %v", err)
+	}
+	if got := out.String(); got != "No active connections.\n" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestDebugConnectionsErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	err := debugConnections(context.Background(), &bytes.Buffer{}, srv.URL, 0)
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("err = %v, want a 403 error", err)
+	}
+}
+
+func TestDebugConnectionsWatch(t *testing.T) {
+	srv, hits := stubConnectionsAPI(t, `[{"ip":"100.64.0.7","count":2}]`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	var out lockedBuffer
+	go func() { done <- debugConnections(ctx, &out, srv.URL, 10*time.Millisecond) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for hits.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("watch polled %d times, want at least 3", hits.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("watch returned %v after cancel", err)
+	}
+	if n := strings.Count(out.String(), "100.64.0.7"); n < 3 {
+		t.Errorf("table printed %d times, want at least 3:\n%s", n, out.String())
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe to read while the watch loop writes.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
@@ -9,13 +10,16 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/coreos/go-systemd/v22/daemon"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
+	"gopkg.in/natefinch/lumberjack.v2"
 
+	"github.com/cortexuvula/clawreachbridge/internal/audit"
 	"github.com/cortexuvula/clawreachbridge/internal/canvas"
 	"github.com/cortexuvula/clawreachbridge/internal/chatsync"
 	"github.com/cortexuvula/clawreachbridge/internal/config"
@@ -47,17 +51,27 @@ func main() {
 	var configPath string
 	var verbose bool
 	var foreground bool
+	var selftest string
+	var logJSONTo string
 
 	startCmd := &cobra.Command{
 		Use:   "start",
 		Short: "Start the WebSocket proxy bridge",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runBridge(configPath, verbose)
+			switch selftest {
+			case "", "warn", "strict":
+			default:
+				return fmt.Errorf("invalid --selftest value %q (must be warn or strict)", selftest)
+			}
+			return runBridge(configPath, verbose, selftest, logJSONTo)
 		},
 	}
 	startCmd.Flags().StringVarP(&configPath, "config", "c", "", "Path to config file")
 	startCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable debug logging")
 	startCmd.Flags().BoolVar(&foreground, "foreground", false, "Run in foreground (implied)")
+	startCmd.Flags().StringVar(&selftest, "selftest", "", "Dial the gateway over WebSocket before reporting ready: warn or strict (abort on failure)")
+	startCmd.Flags().Lookup("selftest").NoOptDefVal = "warn"
+	startCmd.Flags().StringVar(&logJSONTo, "log-json-to", "", "Also write logs as JSON to this file (overrides logging.json_mirror_file)")
 
 	versionCmd := &cobra.Command{
 		Use:   "version",
@@ -79,9 +93,18 @@ func main() {
 			}
 			fmt.Printf("Configuration is valid.\n")
 			fmt.Printf("  Listen: %s\n", cfg.Bridge.ListenAddress)
-			fmt.Printf("  Gateway: %s\n", cfg.Bridge.GatewayURL)
+			fmt.Printf("  Gateway: %s\n", config.RedactURL(cfg.Bridge.GatewayURL))
 			fmt.Printf("  Health: %s\n", cfg.Health.ListenAddress)
 			fmt.Printf("  Tailscale only: %v\n", cfg.Security.TailscaleOnly)
+			for _, w := range cfg.Warnings() {
+				fmt.Printf("  Warning: %s\n", w)
+			}
+			if err := security.CheckTailscaleListen(cfg); err != nil {
+				if cfg.Security.RequireTailscaleListen {
+					return fmt.Errorf("config validation failed: %w", err)
+				}
+				fmt.Printf("  Warning: %v\n", err)
+			}
 			return nil
 		},
 	}
@@ -98,16 +121,37 @@ func main() {
 	healthCmd.Flags().String("url", "http://127.0.0.1:8081/health", "Health endpoint URL")
 
 	var setupConfigPath string
+	var setupTimeout time.Duration
+	var setupServiceTimeout time.Duration
 	setupCmd := &cobra.Command{
 		Use:   "setup",
 		Short: "Interactive setup wizard",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return setup.RunWizard(os.Stdin, os.Stdout, setup.WizardOptions{
-				ConfigPath: setupConfigPath,
+				ConfigPath:          setupConfigPath,
+				NetworkTimeout:      setupTimeout,
+				ServiceStartTimeout: setupServiceTimeout,
 			})
 		},
 	}
 	setupCmd.Flags().StringVar(&setupConfigPath, "config-path", "", "Override config file path (default: /etc/clawreachbridge/config.yaml)")
+	setupCmd.Flags().DurationVar(&setupTimeout, "timeout", 3*time.Second, "Timeout for the wizard's gateway reachability check")
+	setupCmd.Flags().DurationVar(&setupServiceTimeout, "service-timeout", 30*time.Second, "How long to wait for the systemd service to become active after starting it")
+
+	generateTokenCmd := &cobra.Command{
+		Use:   "generate-token",
+		Short: "Print a random URL-safe token for security.auth_token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n, _ := cmd.Flags().GetInt("bytes")
+			token, err := security.GenerateToken(n)
+			if err != nil {
+				return err
+			}
+			fmt.Println(token)
+			return nil
+		},
+	}
+	generateTokenCmd.Flags().Int("bytes", security.DefaultTokenBytes, "Random bytes in the token (before base64url encoding)")
 
 	systemdCmd := &cobra.Command{
 		Use:   "systemd",
@@ -122,45 +166,160 @@ func main() {
 	}
 	systemdCmd.Flags().Bool("print", false, "Print systemd unit to stdout")
 
-	rootCmd.AddCommand(startCmd, versionCmd, validateCmd, healthCmd, setupCmd, systemdCmd)
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Config file utilities",
+	}
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "sample",
+		Short: "Print a sample config with every option and its default",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := config.Sample()
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		},
+	})
+
+	debugCmd := &cobra.Command{
+		Use:   "debug",
+		Short: "Inspect a running instance",
+	}
+	debugConnectionsCmd := &cobra.Command{
+		Use:   "connections",
+		Short: "Print active connections per client IP from the web UI API",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			url, _ := cmd.Flags().GetString("url")
+			watch, _ := cmd.Flags().GetDuration("watch")
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return debugConnections(ctx, os.Stdout, url, watch)
+		},
+	}
+	debugConnectionsCmd.Flags().String("url", "http://127.0.0.1:8081", "Health listener URL serving the web UI API")
+	debugConnectionsCmd.Flags().Duration("watch", 0, "Refresh at this interval until interrupted (e.g. 2s)")
+	debugConnectionsCmd.Flags().Lookup("watch").NoOptDefVal = "2s"
+	debugCmd.AddCommand(debugConnectionsCmd)
+
+	mockGatewayCmd := &cobra.Command{
+		Use:   "mockgateway",
+		Short: "Run a fake gateway for local client development (echo + synthetic chat/canvas events)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listen, _ := cmd.Flags().GetString("listen")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			canvasURL, _ := cmd.Flags().GetString("canvas-url")
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return runMockGateway(ctx, listen, &mockGateway{interval: interval, canvasURL: canvasURL})
+		},
+	}
+	mockGatewayCmd.Flags().String("listen", "127.0.0.1:18800", "Address to listen on (point bridge.gateway_url here)")
+	mockGatewayCmd.Flags().Duration("interval", 2*time.Second, "Time between synthetic events (0 = echo only)")
+	mockGatewayCmd.Flags().String("canvas-url", "/__openclaw__/a2ui/?session=mock", "url param of the synthetic canvas.present requests")
+
+	rootCmd.AddCommand(startCmd, versionCmd, validateCmd, healthCmd, setupCmd, generateTokenCmd, systemdCmd, configCmd, debugCmd, mockGatewayCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
 }
 
-func runBridge(configPath string, verbose bool) error {
+func runBridge(configPath string, verbose bool, selftest, logJSONTo string) error {
 	cfg, err := config.Load(configPath)
 	if err != nil {
 		return fmt.Errorf("loading config: %w", err)
 	}
 
+	if err := cfg.Security.CheckPrivileges(os.Geteuid()); err != nil {
+		return err
+	}
+	listenErr := security.CheckTailscaleListen(cfg)
+	if listenErr != nil && cfg.Security.RequireTailscaleListen {
+		return fmt.Errorf("%w (security.require_tailscale_listen is set)", listenErr)
+	}
+
 	if verbose {
 		cfg.Logging.Level = "debug"
 	}
+	if logJSONTo != "" {
+		cfg.Logging.JSONMirrorFile = logJSONTo
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid --log-json-to: %w", err)
+		}
+	}
 
-	// Set up logging with ring buffer for web UI log viewer
+	// Set up logging with ring buffer for web UI log viewer.
+	// reopenLogging (re)builds the log handler from cfg, closing the previous
+	// log files so rotated or deleted files are not written to.
 	ring := logring.NewRingBuffer(1000)
-	baseHandler, lj := logging.SetupHandler(
-		cfg.Logging.Level,
-		cfg.Logging.Format,
-		cfg.Logging.File,
-		cfg.Logging.MaxSizeMB,
-		cfg.Logging.MaxBackups,
-		cfg.Logging.MaxAgeDays,
-		cfg.Logging.Compress,
-	)
-	slog.SetDefault(slog.New(logring.NewTeeHandler(baseHandler, ring)))
-	if lj != nil {
-		defer lj.Close()
+	var logMu sync.Mutex
+	var lj, mirrorLJ *lumberjack.Logger
+	reopenLogging := func() {
+		logMu.Lock()
+		defer logMu.Unlock()
+		h, newLJ, err := logging.ReopenLog(lj,
+			cfg.Logging.Level,
+			cfg.Logging.Format,
+			cfg.Logging.File,
+			cfg.Logging.MaxSizeMB,
+			cfg.Logging.MaxBackups,
+			cfg.Logging.MaxAgeDays,
+			cfg.Logging.Compress,
+		)
+		if err != nil {
+			// Keep logging through the current handler and file.
+			slog.Error("failed to reopen log file, keeping the current one", "error", err)
+			return
+		}
+		lj = newLJ
+		oldMirror := mirrorLJ
+		h, mirrorLJ = logging.WithJSONMirror(h,
+			cfg.Logging.Level,
+			cfg.Logging.JSONMirrorFile,
+			cfg.Logging.MaxSizeMB,
+			cfg.Logging.MaxBackups,
+			cfg.Logging.MaxAgeDays,
+			cfg.Logging.Compress,
+		)
+		slog.SetDefault(slog.New(logring.NewTeeHandler(h, ring)))
+		if oldMirror != nil {
+			oldMirror.Close()
+		}
 	}
+	reopenLogging()
+	defer func() {
+		logMu.Lock()
+		defer logMu.Unlock()
+		if lj != nil {
+			lj.Close()
+		}
+		if mirrorLJ != nil {
+			mirrorLJ.Close()
+		}
+	}()
 
 	startTime := time.Now()
 
+	// Audit trail of admin actions (in-memory, optionally mirrored to a file)
+	auditLog, err := audit.NewLog(500, cfg.WebUI.AuditFile)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	defer auditLog.Close()
+
+	for _, w := range cfg.Warnings() {
+		slog.Warn("config warning", "warning", w)
+	}
+	if listenErr != nil {
+		slog.Warn("config warning", "warning", listenErr.Error())
+	}
+
 	slog.Info("starting ClawReach Bridge",
 		"version", Version,
 		"listen", cfg.Bridge.ListenAddress,
-		"gateway", cfg.Bridge.GatewayURL,
+		"gateway", config.RedactURL(cfg.Bridge.GatewayURL),
 		"health", cfg.Health.ListenAddress,
 	)
 
@@ -175,6 +334,7 @@ func runBridge(configPath string, verbose bool) error {
 	if cfg.Security.RateLimit.Enabled {
 		r := rate.Limit(float64(cfg.Security.RateLimit.ConnectionsPerMinute) / 60.0)
 		rl = security.NewRateLimiter(r, cfg.Security.RateLimit.ConnectionsPerMinute)
+		rl.SetCleanup(cfg.Security.RateLimit.GCInterval, cfg.Security.RateLimit.IdleTTL)
 		defer rl.Stop()
 		slog.Info("rate limiting enabled",
 			"connections_per_minute", cfg.Security.RateLimit.ConnectionsPerMinute,
@@ -184,34 +344,96 @@ func runBridge(configPath string, verbose bool) error {
 	// Create proxy handler
 	handler := proxy.NewHandler(cfg, p, rl, shutdownCtx)
 
-	// Optional Prometheus metrics
+	// Optional pool of pre-dialed gateway connections
+	if cfg.Bridge.GatewayPoolSize > 0 {
+		handler.StartGatewayPool(shutdownCtx, cfg.Bridge.GatewayPoolSize)
+	}
+
+	// Scheduled maintenance windows. The scheduler always runs so a reload
+	// can add bridge.maintenance_schedule later.
+	go handler.RunMaintenanceSchedule(shutdownCtx)
+
+	// Optional external authorization webhook
+	if cfg.Security.AuthzWebhook != "" {
+		handler.AuthzWebhook = security.NewAuthzWebhook(cfg.Security.AuthzWebhook,
+			cfg.Security.AuthzWebhookTimeout, cfg.Security.AuthzWebhookCacheTTL,
+			cfg.Security.AuthzWebhookFailMode == "open")
+		slog.Info("authz webhook enabled", "url", cfg.Security.AuthzWebhook, "fail_mode", cfg.Security.AuthzWebhookFailMode)
+	}
+
+	// Optional metrics, exposed to Prometheus and/or pushed to StatsD
 	var m *metrics.Metrics
-	if cfg.Monitoring.MetricsEnabled {
+	if cfg.Monitoring.MetricsEnabled || cfg.Monitoring.StatsDAddress != "" || cfg.Monitoring.MetricsFile != "" {
 		m = metrics.New()
 		handler.Metrics = m
+		go m.RunIPDistributionCollector(shutdownCtx, 15*time.Second, p.ActiveIPConnections)
+		m.RegisterLogRingDropped(ring.DroppedCount)
+	}
+	if cfg.Monitoring.MetricsEnabled {
 		slog.Info("prometheus metrics enabled", "endpoint", cfg.Monitoring.MetricsEndpoint)
 	}
+	if cfg.Monitoring.StatsDAddress != "" {
+		statsd, err := metrics.NewStatsDPusher(cfg.Monitoring.StatsDAddress, prometheus.DefaultGatherer)
+		if err != nil {
+			return err
+		}
+		go statsd.Run(shutdownCtx, cfg.Monitoring.StatsDInterval)
+		slog.Info("statsd push enabled", "address", cfg.Monitoring.StatsDAddress, "interval", cfg.Monitoring.StatsDInterval)
+	}
+	if cfg.Monitoring.MetricsFile != "" {
+		go metrics.NewFileWriter(cfg.Monitoring.MetricsFile, prometheus.DefaultGatherer).Run(shutdownCtx, cfg.Monitoring.MetricsFileInterval)
+		slog.Info("metrics file enabled", "path", cfg.Monitoring.MetricsFile, "interval", cfg.Monitoring.MetricsFileInterval)
+	}
+
+	// Optional per-method request counts (requires metrics)
+	if len(cfg.Monitoring.MethodMetrics) > 0 && m != nil {
+		handler.MethodInspector = proxy.NewMethodInspector(m.MessagesByMethod, cfg.Monitoring.MethodMetrics)
+		slog.Info("method metrics enabled", "methods", cfg.Monitoring.MethodMetrics)
+	}
 
 	// Optional reaction inspector (requires metrics for counting)
 	if cfg.Bridge.Reactions.Enabled && m != nil {
 		handler.ReactionInspector = proxy.NewReactionInspector(m.ReactionsTotal)
 		slog.Info("reaction inspector enabled", "mode", cfg.Bridge.Reactions.Mode)
 	}
-	if cfg.Bridge.Reactions.Enabled && !cfg.Monitoring.MetricsEnabled {
+	if cfg.Bridge.Reactions.Enabled && m == nil {
 		slog.Warn("reactions enabled but metrics disabled; reaction counting requires metrics")
 	}
 
+	// Optional external transform — off unless bridge.transform_command is set
+	if len(cfg.Bridge.TransformCommand) > 0 {
+		handler.TransformInspector = &proxy.TransformInspector{
+			Command:   cfg.Bridge.TransformCommand,
+			Timeout:   cfg.Bridge.TransformTimeout,
+			MaxOutput: max(cfg.Bridge.UpstreamMessageLimit(), cfg.Bridge.DownstreamMessageLimit()),
+		}
+		if m != nil {
+			handler.TransformInspector.Failures = m.ErrorsTotal.WithLabelValues("transform")
+		}
+		slog.Warn("transform command enabled: every text message is piped through an external process", "command", cfg.Bridge.TransformCommand, "timeout", cfg.Bridge.TransformTimeout)
+	}
+
 	// File receive inspector — saves uploaded files to agent workspace
+	var inboxDir string
 	if cfg.Bridge.Media.Enabled && cfg.Bridge.Media.Directory != "" {
-		inboxDir := filepath.Join(cfg.Bridge.Media.Directory, "inbox")
+		inboxDir = filepath.Join(cfg.Bridge.Media.Directory, "inbox")
 		if err := os.MkdirAll(inboxDir, 0755); err != nil {
 			slog.Error("failed to create inbox directory", "path", inboxDir, "error", err)
 		} else {
 			handler.FileReceiveInspector = &proxy.FileReceiveInspector{
-				InboxDir: inboxDir,
-				Logger:   slog.Default().With("component", "file-receive"),
+				InboxDir:    inboxDir,
+				MaxBytes:    cfg.Bridge.Media.InboxMaxBytes,
+				MaxFiles:    cfg.Bridge.Media.InboxMaxFiles,
+				EvictOldest: cfg.Bridge.Media.InboxPolicy == "evict_oldest",
+
+				AllowedExtensions: cfg.Bridge.Media.InboxAllowedExtensions,
+				VerifyMIME:        cfg.Bridge.Media.InboxVerifyMIME,
+				ByDate:            cfg.Bridge.Media.InboxLayout == "by_date",
 			}
-			slog.Info("file receive inspector enabled", "inbox", inboxDir)
+			if m != nil {
+				handler.FileReceiveInspector.SkippedTotal = m.FilesSkippedTotal
+			}
+			slog.Info("file receive inspector enabled", "inbox", inboxDir, "layout", cfg.Bridge.Media.InboxLayout)
 		}
 	}
 
@@ -219,7 +441,7 @@ func runBridge(configPath string, verbose bool) error {
 	if cfg.Bridge.Canvas.StateTracking {
 		tracker := canvas.NewTracker(cfg.Bridge.Canvas)
 		if m != nil {
-			tracker.SetMetrics(m.CanvasEventsTotal, m.CanvasReplaysTotal)
+			tracker.SetMetrics(m.CanvasEventsTotal, m.CanvasReplaysTotal, m.CanvasJSONLSkippedTotal)
 		}
 		handler.CanvasTracker = tracker
 		slog.Info("canvas state tracking enabled",
@@ -232,6 +454,7 @@ func runBridge(configPath string, verbose bool) error {
 	if cfg.Bridge.Sync.Enabled {
 		syncStore := chatsync.NewMessageStore(cfg.Bridge.Sync.MaxHistory)
 		syncRegistry := chatsync.NewClientRegistry()
+		syncRegistry.SetBroadcastLimits(cfg.Bridge.Sync.MaxBroadcastFanout, cfg.Bridge.Sync.BroadcastTimeout)
 		handler.SyncStore = syncStore
 		handler.SyncRegistry = syncRegistry
 		slog.Info("cross-device message sync enabled", "max_history", cfg.Bridge.Sync.MaxHistory)
@@ -239,8 +462,16 @@ func runBridge(configPath string, verbose bool) error {
 
 	// Reload config closure — shared by SIGHUP handler and web UI
 	reloadConfig := func() error {
-		newCfg, err := config.Load(configPath)
+		newCfg, err := config.Reload(configPath)
 		if err != nil {
+			reason := "invalid"
+			if errors.Is(err, config.ErrFileMissing) {
+				reason = "missing"
+				slog.Error("config file disappeared, keeping current config", "path", configPath)
+			}
+			if m != nil {
+				m.ConfigReloadFailures.WithLabelValues(reason).Inc()
+			}
 			return fmt.Errorf("config reload failed: %w", err)
 		}
 
@@ -256,48 +487,68 @@ func runBridge(configPath string, verbose bool) error {
 		if cfg.Security.RateLimit.Enabled && rl != nil {
 			r := rate.Limit(float64(cfg.Security.RateLimit.ConnectionsPerMinute) / 60.0)
 			rl.UpdateRate(r, cfg.Security.RateLimit.ConnectionsPerMinute)
+			rl.SetCleanup(cfg.Security.RateLimit.GCInterval, cfg.Security.RateLimit.IdleTTL)
 		}
 
-		// Re-setup logging with new level, re-wrap with TeeHandler
-		newHandler, _ := logging.SetupHandler(
-			cfg.Logging.Level,
-			cfg.Logging.Format,
-			cfg.Logging.File,
-			cfg.Logging.MaxSizeMB,
-			cfg.Logging.MaxBackups,
-			cfg.Logging.MaxAgeDays,
-			cfg.Logging.Compress,
-		)
-		slog.SetDefault(slog.New(logring.NewTeeHandler(newHandler, ring)))
+		// Rebuild logging with the reloaded level, format, file and rotation
+		// settings (closing the previous log files), re-wrap with TeeHandler
+		reopenLogging()
 
 		slog.Info("config reloaded successfully")
 		return nil
 	}
 
+	// Optional end-to-end gateway check (before binding listeners / sd_notify READY)
+	if selftest != "" {
+		if err := proxy.SelfTest(shutdownCtx, cfg); err != nil {
+			if selftest == "strict" {
+				return fmt.Errorf("gateway selftest failed: %w", err)
+			}
+			slog.Warn("gateway selftest failed, continuing", "error", err)
+		} else {
+			slog.Info("gateway selftest passed", "gateway", config.RedactURL(cfg.Bridge.GatewayURL))
+		}
+	}
+
 	// Bind proxy listener synchronously (detect port conflicts before sd_notify)
-	proxyListener, err := net.Listen("tcp", cfg.Bridge.ListenAddress)
+	proxyListener, err := proxy.Listen(shutdownCtx, cfg)
 	if err != nil {
 		return fmt.Errorf("failed to bind proxy listener on %s: %w", cfg.Bridge.ListenAddress, err)
 	}
+	if cfg.Bridge.TLS.Enabled {
+		slog.Info("TLS enabled on proxy listener",
+			"min_version", cfg.Bridge.TLS.MinVersion,
+			"client_auth", cfg.Bridge.TLS.ClientCAFile != "",
+		)
+	}
 	proxyServer := &http.Server{
 		Handler:           handler,
 		ReadHeaderTimeout: 10 * time.Second,
+		MaxHeaderBytes:    cfg.Bridge.MaxHeaderBytes,
 	}
 
 	// Health server (listens on 127.0.0.1:8081)
 	var healthServer *http.Server
 	var healthListener net.Listener
+	var healthHandler *health.Handler
 	if cfg.Health.Enabled {
-		healthHandler := health.NewHandler(p, cfg.Bridge.GatewayURL, Version, cfg.Health.Detailed)
+		healthHandler = health.NewHandler(p, cfg.Bridge.GatewayURL, Version, cfg.Health.Detailed)
 		if m != nil {
 			healthHandler.SetMetrics(m)
 		}
+		if inboxDir != "" {
+			healthHandler.SetWritableDirs(cfg.Bridge.Media.Directory, inboxDir)
+		}
+		healthHandler.SetMemStatsTTL(cfg.Health.MemStatsTTL)
 		healthMux := http.NewServeMux()
 		healthMux.Handle(cfg.Health.Endpoint, healthHandler)
+		if cfg.Health.ReadyEndpoint != "" {
+			healthMux.Handle(cfg.Health.ReadyEndpoint, healthHandler.ReadyHandler())
+		}
 
 		// Metrics endpoint on health listener
 		if cfg.Monitoring.MetricsEnabled {
-			healthMux.Handle(cfg.Monitoring.MetricsEndpoint, promhttp.Handler())
+			healthMux.Handle(cfg.Monitoring.MetricsEndpoint, metrics.Handler(cfg.Monitoring.MetricsAuthToken))
 		}
 
 		// Web admin UI on health listener
@@ -306,6 +557,7 @@ func runBridge(configPath string, verbose bool) error {
 			Handler:     handler,
 			RateLimiter: rl,
 			RingBuffer:  ring,
+			AuditLog:    auditLog,
 			Version:     Version,
 			BuildTime:   BuildTime,
 			GitCommit:   GitCommit,
@@ -313,6 +565,7 @@ func runBridge(configPath string, verbose bool) error {
 			StartTime:   startTime,
 			GetConfig:   func() *config.Config { return handler.GetConfig() },
 			ReloadFunc:  reloadConfig,
+			ReadOnly:    cfg.WebUI.ReadOnly,
 		})
 		healthMux.Handle("/ui/", adminUI.StaticHandler())
 		healthMux.Handle("/api/v1/", adminUI.APIHandler())
@@ -326,6 +579,7 @@ func runBridge(configPath string, verbose bool) error {
 		healthServer = &http.Server{
 			Handler:           healthMux,
 			ReadHeaderTimeout: 10 * time.Second,
+			MaxHeaderBytes:    cfg.Bridge.MaxHeaderBytes,
 			ReadTimeout:       30 * time.Second,
 			WriteTimeout:      30 * time.Second,
 		}
@@ -384,14 +638,25 @@ func runBridge(configPath string, verbose bool) error {
 
 	// Signal handling
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	signal.Notify(sigChan, append([]os.Signal{syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP}, reopenSignals...)...)
 
 	for sig := range sigChan {
+		if isReopenSignal(sig) {
+			reopenLogging()
+			slog.Info("received SIGUSR1, reopened log file", "file", cfg.Logging.File)
+			continue
+		}
 		switch sig {
 		case syscall.SIGHUP:
 			slog.Info("received SIGHUP, reloading config")
-			if err := reloadConfig(); err != nil {
+			err := reloadConfig()
+			entry := audit.Entry{Action: "reload", Source: "signal"}
+			if err != nil {
 				slog.Error("config reload failed", "error", err)
+				entry.Error = err.Error()
+			}
+			if err := auditLog.Record(entry); err != nil {
+				slog.Warn("failed to write audit entry", "action", "reload", "error", err)
 			}
 
 		case syscall.SIGTERM, syscall.SIGINT:
@@ -400,45 +665,29 @@ func runBridge(configPath string, verbose bool) error {
 				"drain_timeout", cfg.Bridge.DrainTimeout.String(),
 			)
 
-			// Stop watchdog and notify systemd
-			watchdogCancel()
-			daemon.SdNotify(false, daemon.SdNotifyStopping)
-
-			// Phase 1: Stop accepting new connections + drain active ones
-			proxyServer.Close() // immediately close listener
-
-			handler.StartDrain() // send close frames to all active connections
-
-			// Wait for active connections to finish (up to drain timeout)
-			drainDeadline := time.After(cfg.Bridge.DrainTimeout)
-			drainTick := time.NewTicker(100 * time.Millisecond)
-		drainLoop:
-			for {
-				select {
-				case <-drainDeadline:
-					remaining := p.ConnectionCount()
-					if remaining > 0 {
-						slog.Warn("drain timeout reached, force-closing remaining connections", "remaining", remaining)
-					}
-					break drainLoop
-				case <-drainTick.C:
-					if p.ConnectionCount() == 0 {
-						slog.Info("all connections drained")
-						break drainLoop
-					}
-				}
+			var readyDelay time.Duration
+			if cfg.Health.ReadyEndpoint != "" {
+				readyDelay = cfg.Health.ShutdownDelay
 			}
-			drainTick.Stop()
-
-			// Phase 2: Force-close anything remaining
-			shutdownCancel()
-
-			// Shutdown health server
-			if healthServer != nil {
-				shutdownCtx, shutdownCtxCancel := context.WithTimeout(context.Background(), 5*time.Second)
-				healthServer.Shutdown(shutdownCtx)
-				shutdownCtxCancel()
+			shutdown := &gracefulShutdown{
+				health:       healthHandler,
+				healthServer: healthServer,
+				proxyServer:  proxyServer,
+				handler:      handler,
+				proxy:        p,
+				drainTimeout: cfg.Bridge.DrainTimeout,
+				readyDelay:   readyDelay,
+				stopping: func() {
+					if err := auditLog.Record(audit.Entry{Action: "drain", Source: "signal"}); err != nil {
+						slog.Warn("failed to write audit entry", "action", "drain", "error", err)
+					}
+					// Stop watchdog and notify systemd
+					watchdogCancel()
+					daemon.SdNotify(false, daemon.SdNotifyStopping)
+				},
+				cancel: shutdownCancel,
 			}
+			shutdown.run()
 
 			slog.Info("shutdown complete")
 			return nil
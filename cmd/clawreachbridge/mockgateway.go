@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// mockGateway is a stand-in gateway for developing clients without a real
+// one. Every WebSocket message it receives is echoed back, and with a
+// positive interval it also plays a synthetic assistant run on a loop — two
+// chat deltas, a chat final, then a canvas.present — one message per tick,
+// so UIs can be exercised against the bridge end to end.
+type mockGateway struct {
+	interval  time.Duration // 0 = echo only
+	canvasURL string        // url param of the canvas.present requests
+}
+
+func (g *mockGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The bridge sends its configured Origin, which won't match this host.
+	c, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+	if err != nil {
+		return
+	}
+	defer c.CloseNow()
+	slog.Info("mock gateway: client connected", "remote", r.RemoteAddr, "path", r.URL.Path)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	if g.interval > 0 {
+		go g.emit(ctx, c)
+	}
+	for {
+		msgType, data, err := c.Read(ctx)
+		if err != nil {
+			slog.Info("mock gateway: client disconnected", "remote", r.RemoteAddr)
+			return
+		}
+		if err := c.Write(ctx, msgType, data); err != nil {
+			return
+		}
+	}
+}
+
+// emit writes the next synthetic event on every tick until ctx is done.
+func (g *mockGateway) emit(ctx context.Context, c *websocket.Conn) {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	var queue [][]byte
+	for run := 1; ; {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if len(queue) == 0 {
+			queue = g.runEvents(run)
+			run++
+		}
+		if err := c.Write(ctx, websocket.MessageText, queue[0]); err != nil {
+			return
+		}
+		queue = queue[1:]
+	}
+}
+
+// runEvents returns the messages of synthetic run n, in order.
+func (g *mockGateway) runEvents(n int) [][]byte {
+	runID := fmt.Sprintf("mock-run-%d", n)
+	text := fmt.Sprintf("Mock reply %d from the test gateway.", n)
+	return [][]byte{
+		mockChatEvent(runID, "delta", text[:len(text)/3]),
+		mockChatEvent(runID, "delta", text[:2*len(text)/3]),
+		mockChatEvent(runID, "final", text),
+		mustMarshal(map[string]any{
+			"type":   "req",
+			"id":     fmt.Sprintf("mock-canvas-%d", n),
+			"method": "canvas.present",
+			"params": map[string]any{"url": g.canvasURL},
+		}),
+	}
+}
+
+// mockChatEvent builds a gateway chat event in the shape the media injector
+// and chat sync parse.
+func mockChatEvent(runID, state, text string) []byte {
+	return mustMarshal(map[string]any{
+		"type":  "event",
+		"event": "chat",
+		"payload": map[string]any{
+			"state": state,
+			"runId": runID,
+			"message": map[string]any{
+				"role":      "assistant",
+				"content":   []map[string]any{{"type": "text", "text": text}},
+				"timestamp": time.Now().UnixMilli(),
+			},
+		},
+	})
+}
+
+func mustMarshal(v any) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// runMockGateway serves a mockGateway on addr until ctx is cancelled.
+func runMockGateway(ctx context.Context, addr string, g *mockGateway) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to bind mock gateway on %s: %w", addr, err)
+	}
+	srv := &http.Server{Handler: g, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	slog.Info("mock gateway listening", "address", ln.Addr().String(), "interval", g.interval.String())
+	if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+func dialMockGateway(t *testing.T, g *mockGateway) (*websocket.Conn, context.Context) {
+	t.Helper()
+	srv := httptest.NewServer(g)
+	t.Cleanup(srv.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { c.CloseNow() })
+	return c, ctx
+}
+
+func TestMockGatewayEchoes(t *testing.T) {
+	c, ctx := dialMockGateway(t, &mockGateway{})
+
+	if err := c.Write(ctx, websocket.MessageText, []byte(`{"type":"req","method":"ping"}`)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, got, err := c.Read(ctx)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(got) != `{"type":"req","method":"ping"}` {
+		t.Errorf("echo = %s", got)
+	}
+}
+
+func TestMockGatewayEmitsRun(t *testing.T) {
+	c, ctx := dialMockGateway(t, &mockGateway{interval: 10 * time.Millisecond, canvasURL: "/canvas"})
+
+	var got []string
+	for range 5 {
+		_, data, err := c.Read(ctx)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		var msg struct {
+			Type    string
+			Event   string
+			Method  string
+			Params  struct{ URL string }
+			Payload struct {
+				State string
+				RunID string
+			}
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("event is not JSON: %s", data)
+		}
+		switch msg.Type {
+		case "event":
+			got = append(got, msg.Event+"/"+msg.Payload.State+"/"+msg.Payload.RunID)
+		case "req":
+			got = append(got, msg.Method+"/"+msg.Params.URL)
+		}
+	}
+	want := "chat/delta/mock-run-1 chat/delta/mock-run-1 chat/final/mock-run-1 canvas.present//canvas chat/delta/mock-run-2"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("events = %s\nwant     %s", s, want)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/cortexuvula/clawreachbridge/internal/health"
+	"github.com/cortexuvula/clawreachbridge/internal/proxy"
+)
+
+// gracefulShutdown is the SIGTERM/SIGINT sequence. The order matters to load
+// balancers: readiness flips to 503 before anything else, so they stop
+// routing new clients here while the existing ones drain, and the health
+// listener keeps answering until the drain is over.
+type gracefulShutdown struct {
+	health       *health.Handler // nil when the health listener is disabled
+	healthServer *http.Server    // nil when the health listener is disabled
+	proxyServer  *http.Server
+	handler      *proxy.Handler
+	proxy        *proxy.Proxy
+	drainTimeout time.Duration
+	readyDelay   time.Duration      // keep the listener open this long after reporting unready
+	stopping     func()             // audit entry, watchdog stop, systemd STOPPING
+	cancel       context.CancelFunc // force-closes connections left after the drain
+}
+
+func (s *gracefulShutdown) run() {
+	// Phase 0: Report unready so traffic moves elsewhere first
+	if s.health != nil {
+		s.health.SetShuttingDown()
+	}
+	if s.stopping != nil {
+		s.stopping()
+	}
+	// Give load balancers time to see the 503 before new connections are refused
+	if s.health != nil && s.readyDelay > 0 {
+		slog.Info("reported unready, waiting before closing the listener", "delay", s.readyDelay)
+		time.Sleep(s.readyDelay)
+	}
+
+	// Phase 1: Stop accepting new connections + drain active ones
+	s.proxyServer.Close() // immediately close listener
+
+	s.handler.NotifyDrain(context.Background(), proxy.DrainStarted)
+	s.handler.StartDrain() // send close frames to all active connections
+
+	// Wait for active connections to finish (up to drain timeout)
+	drainDeadline := time.After(s.drainTimeout)
+	drainTick := time.NewTicker(100 * time.Millisecond)
+drainLoop:
+	for {
+		select {
+		case <-drainDeadline:
+			remaining := s.proxy.ConnectionCount()
+			if remaining > 0 {
+				slog.Warn("drain timeout reached, force-closing remaining connections", "remaining", remaining)
+			}
+			break drainLoop
+		case <-drainTick.C:
+			if s.proxy.ConnectionCount() == 0 {
+				slog.Info("all connections drained")
+				break drainLoop
+			}
+		}
+	}
+	drainTick.Stop()
+	s.handler.NotifyDrain(context.Background(), proxy.DrainCompleted)
+
+	// Phase 2: Force-close anything remaining
+	s.cancel()
+
+	// Phase 3: Shutdown health server
+	if s.healthServer != nil {
+		shutdownCtx, shutdownCtxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		s.healthServer.Shutdown(shutdownCtx)
+		shutdownCtxCancel()
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cortexuvula/clawreachbridge/internal/config"
+	"github.com/cortexuvula/clawreachbridge/internal/health"
+	"github.com/cortexuvula/clawreachbridge/internal/proxy"
+)
+
+func TestGracefulShutdownReportsUnreadyBeforeDrain(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Bridge.GatewayURL = "http://127.0.0.1:1"
+	p := proxy.New()
+	shutdownCtx, shutdownCancel := context.WithCancel(context.Background())
+	defer shutdownCancel()
+	handler := proxy.NewHandler(cfg, p, nil, shutdownCtx)
+
+	hh := health.NewHandler(p, cfg.Bridge.GatewayURL, "test", false)
+	mux := http.NewServeMux()
+	mux.Handle("/readyz", hh.ReadyHandler())
+	healthSrv := httptest.NewServer(mux)
+	defer healthSrv.Close()
+	proxySrv := httptest.NewServer(handler)
+	defer proxySrv.Close()
+
+	readyCode := func() int {
+		resp, err := healthSrv.Client().Get(healthSrv.URL + "/readyz")
+		if err != nil {
+			t.Fatalf("GET /readyz: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := readyCode(); code != http.StatusOK {
+		t.Fatalf("/readyz before shutdown = %d, want 200", code)
+	}
+
+	// A connection that outlives StartDrain keeps the drain loop waiting.
+	if reason := p.TryIncrementConnections("100.64.0.1", 10, 10); reason != "" {
+		t.Fatalf("TryIncrementConnections: %s", reason)
+	}
+
+	stopping := make(chan struct{})
+	done := make(chan struct{})
+	s := &gracefulShutdown{
+		health:       hh,
+		healthServer: healthSrv.Config,
+		proxyServer:  proxySrv.Config,
+		handler:      handler,
+		proxy:        p,
+		drainTimeout: 10 * time.Second,
+		stopping:     func() { close(stopping) },
+		cancel:       shutdownCancel,
+	}
+	go func() {
+		s.run()
+		close(done)
+	}()
+
+	<-stopping
+	if code := readyCode(); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz during drain = %d, want 503", code)
+	}
+	select {
+	case <-done:
+		t.Fatal("shutdown finished with a connection still active")
+	default:
+	}
+	if shutdownCtx.Err() != nil {
+		t.Error("shutdown context cancelled before the drain completed")
+	}
+
+	p.DecrementConnections("100.64.0.1")
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not finish after the last connection closed")
+	}
+	if shutdownCtx.Err() == nil {
+		t.Error("shutdown context not cancelled after the drain")
+	}
+}
+
+func TestGracefulShutdownKeepsListenerOpenForReadyDelay(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Bridge.GatewayURL = "http://127.0.0.1:1"
+	p := proxy.New()
+	shutdownCtx, shutdownCancel := context.WithCancel(context.Background())
+	defer shutdownCancel()
+	handler := proxy.NewHandler(cfg, p, nil, shutdownCtx)
+
+	hh := health.NewHandler(p, cfg.Bridge.GatewayURL, "test", false)
+	proxySrv := httptest.NewServer(handler)
+	defer proxySrv.Close()
+
+	const delay = 300 * time.Millisecond
+	stopping := make(chan struct{})
+	done := make(chan struct{})
+	s := &gracefulShutdown{
+		health:       hh,
+		proxyServer:  proxySrv.Config,
+		handler:      handler,
+		proxy:        p,
+		drainTimeout: time.Second,
+		readyDelay:   delay,
+		stopping:     func() { close(stopping) },
+		cancel:       shutdownCancel,
+	}
+	start := time.Now()
+	go func() {
+		s.run()
+		close(done)
+	}()
+
+	<-stopping
+	w := httptest.NewRecorder()
+	hh.ReadyHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz during the ready delay = %d, want 503", w.Code)
+	}
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get(proxySrv.URL)
+	if err != nil {
+		t.Fatalf("proxy listener closed during the ready delay: %v", err)
+	}
+	resp.Body.Close()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not finish")
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("shutdown took %v, want at least the %v ready delay", elapsed, delay)
+	}
+	if resp, err := client.Get(proxySrv.URL); err == nil {
+		resp.Body.Close()
+		t.Error("proxy listener still accepting after shutdown")
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// reopenSignals are the signals that ask the bridge to reopen its log file
+// (e.g. from a logrotate postrotate script).
+var reopenSignals = []os.Signal{syscall.SIGUSR1}
+
+// isReopenSignal reports whether sig is one of reopenSignals.
+func isReopenSignal(sig os.Signal) bool {
+	return sig == syscall.SIGUSR1
+}
@@ -0,0 +1,11 @@
+package main
+
+import "os"
+
+// reopenSignals is empty on Windows, which has no SIGUSR1.
+var reopenSignals []os.Signal
+
+// isReopenSignal always reports false on Windows.
+func isReopenSignal(os.Signal) bool {
+	return false
+}
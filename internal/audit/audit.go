@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Change records the before and after values of a single config field.
+type Change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Entry is a single admin action recorded in the audit log.
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	Action  string            `json:"action"`
+	Source  string            `json:"source"`
+	Changes map[string]Change `json:"changes,omitempty"`
+	Details map[string]any    `json:"details,omitempty"` // action parameters and outcome, e.g. a close filter and count
+	Error   string            `json:"error,omitempty"`
+}
+
+// Log is an append-only audit trail of admin actions. Entries are kept in an
+// in-memory ring for the web UI and optionally appended to a file as JSON
+// lines so they survive restarts and can be replayed.
+type Log struct {
+	mu      sync.Mutex
+	entries []Entry
+	head    int  // next write position
+	full    bool // whether we've wrapped around
+	file    *os.File
+}
+
+// NewLog creates an audit log retaining up to capacity entries in memory.
+// If path is non-empty, entries are also appended to that file.
+func NewLog(capacity int, path string) (*Log, error) {
+	l := &Log{entries: make([]Entry, capacity)}
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+		if err != nil {
+			return nil, fmt.Errorf("opening audit file %s: %w", path, err)
+		}
+		l.file = f
+	}
+	return l, nil
+}
+
+// Record appends an entry to the log. A zero Time is set to now.
+// File write errors are returned but the entry is still kept in memory.
+func (l *Log) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries[l.head] = e
+	l.head = (l.head + 1) % len(l.entries)
+	if l.head == 0 {
+		l.full = true
+	}
+
+	if l.file == nil {
+		return nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshaling audit entry: %w", err)
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing audit entry: %w", err)
+	}
+	return nil
+}
+
+// Entries returns up to limit entries, newest first. limit <= 0 returns all.
+func (l *Log) Entries(limit int) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := l.head
+	if l.full {
+		n = len(l.entries)
+	}
+	if limit > 0 && limit < n {
+		n = limit
+	}
+
+	result := make([]Entry, 0, n)
+	for i := 0; i < n; i++ {
+		idx := (l.head - 1 - i + len(l.entries)) % len(l.entries)
+		result = append(result, l.entries[idx])
+	}
+	return result
+}
+
+// Close closes the underlying audit file, if any.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogEntriesNewestFirst(t *testing.T) {
+	l, err := NewLog(10, "")
+	if err != nil {
+		t.Fatalf("NewLog: %v", err)
+	}
+
+	l.Record(Entry{Action: "reload", Source: "webui"})
+	l.Record(Entry{Action: "restart", Source: "webui"})
+
+	entries := l.Entries(0)
+	if len(entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(entries))
+	}
+	if entries[0].Action != "restart" {
+		t.Errorf("entries[0].Action = %q, want %q", entries[0].Action, "restart")
+	}
+	if entries[1].Time.IsZero() {
+		t.Error("Record should set Time when zero")
+	}
+}
+
+func TestLogWrap(t *testing.T) {
+	l, _ := NewLog(3, "")
+	for _, a := range []string{"a", "b", "c", "d", "e"} {
+		l.Record(Entry{Action: a})
+	}
+
+	entries := l.Entries(0)
+	if len(entries) != 3 {
+		t.Fatalf("entries = %d, want 3", len(entries))
+	}
+	if entries[0].Action != "e" || entries[2].Action != "c" {
+		t.Errorf("entries = %v, want [e d c]", entries)
+	}
+
+	if got := l.Entries(2); len(got) != 2 {
+		t.Errorf("Entries(2) returned %d, want 2", len(got))
+	}
+}
+
+func TestLogFileAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := NewLog(10, path)
+	if err != nil {
+		t.Fatalf("NewLog: %v", err)
+	}
+
+	l.Record(Entry{Action: "config_update", Source: "webui", Changes: map[string]Change{
+		"log_level": {Before: "info", After: "debug"},
+	}})
+	l.Record(Entry{Action: "reload", Source: "signal"})
+	l.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+
+	var lines []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("unmarshal line: %v", err)
+		}
+		lines = append(lines, e)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("file lines = %d, want 2", len(lines))
+	}
+	if lines[0].Changes["log_level"].After != "debug" {
+		t.Errorf("changes = %v, want log_level after=debug", lines[0].Changes)
+	}
+}
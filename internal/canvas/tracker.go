@@ -2,6 +2,7 @@ package canvas
 
 import (
 	"context"
+	"crypto/sha256"
 	"log/slog"
 	"sync"
 	"time"
@@ -29,24 +30,44 @@ type CanvasTracker struct {
 	updatedAt   time.Time
 	maxAge      time.Duration
 	bufferSize  int
+	maxJSONL    int // larger pushJSONL messages aren't buffered; 0 = no limit
+
+	// Hashes of messages replayed to each connection, kept for dedupWindow so
+	// the gateway re-sending the same state right after a reconnect can be
+	// dropped instead of reaching the client twice.
+	dedupWindow time.Duration
+	replayMu    sync.Mutex
+	replayed    map[*websocket.Conn]*replayRecord
 
 	// Optional metrics (nil if metrics disabled)
 	eventsTotal  *prometheus.CounterVec
 	replaysTotal prometheus.Counter
+	skipped      prometheus.Counter
 }
 
 // NewTracker creates a CanvasTracker with the given config.
 func NewTracker(cfg config.CanvasConfig) *CanvasTracker {
 	return &CanvasTracker{
-		bufferSize: cfg.JSONLBufferSize,
-		maxAge:     cfg.MaxAge,
+		bufferSize:  cfg.JSONLBufferSize,
+		maxJSONL:    cfg.MaxJSONLBytes,
+		maxAge:      cfg.MaxAge,
+		dedupWindow: cfg.ReplayDedupWindow,
+		replayed:    make(map[*websocket.Conn]*replayRecord),
 	}
 }
 
-// SetMetrics attaches Prometheus counters for canvas events and replays.
-func (t *CanvasTracker) SetMetrics(events *prometheus.CounterVec, replays prometheus.Counter) {
+// replayRecord is what was replayed to one connection, and when.
+type replayRecord struct {
+	at     time.Time
+	hashes map[[sha256.Size]byte]int // message hash → copies replayed not yet matched
+}
+
+// SetMetrics attaches Prometheus counters for canvas events, replays, and
+// pushJSONL messages too large to buffer.
+func (t *CanvasTracker) SetMetrics(events *prometheus.CounterVec, replays, skipped prometheus.Counter) {
 	t.eventsTotal = events
 	t.replaysTotal = replays
+	t.skipped = skipped
 }
 
 // HandleMessage updates the canvas state based on the method and raw payload.
@@ -69,6 +90,15 @@ func (t *CanvasTracker) HandleMessage(method string, rawPayload []byte) {
 		slog.Debug("canvas state: hide")
 
 	case "canvas.a2ui.pushJSONL":
+		if t.maxJSONL > 0 && len(rawPayload) > t.maxJSONL {
+			// Still forwarded by the caller; only the replay misses it.
+			slog.Warn("canvas state: pushJSONL too large to buffer, replay will omit it", "payload_size", len(rawPayload), "max_jsonl_bytes", t.maxJSONL)
+			if t.skipped != nil {
+				t.skipped.Inc()
+			}
+			t.updatedAt = time.Now()
+			return
+		}
 		entry := append([]byte(nil), rawPayload...)
 		if len(t.jsonlBuffer) >= t.bufferSize {
 			// Ring: drop oldest
@@ -116,6 +146,7 @@ func (t *CanvasTracker) ReplayMessages(ctx context.Context, conn *websocket.Conn
 
 	replayCount := 1 + len(jsonlCopies)
 	slog.Info("canvas replay injected", "messages", replayCount)
+	t.recordReplay(conn, append([][]byte{presentCopy}, jsonlCopies...))
 
 	if t.replaysTotal != nil {
 		t.replaysTotal.Inc()
@@ -124,6 +155,61 @@ func (t *CanvasTracker) ReplayMessages(ctx context.Context, conn *websocket.Conn
 	return nil
 }
 
+// recordReplay remembers the hashes of msgs replayed to conn for dedupWindow.
+func (t *CanvasTracker) recordReplay(conn *websocket.Conn, msgs [][]byte) {
+	if t.dedupWindow <= 0 {
+		return
+	}
+	rec := &replayRecord{at: time.Now(), hashes: make(map[[sha256.Size]byte]int, len(msgs))}
+	for _, m := range msgs {
+		rec.hashes[sha256.Sum256(m)]++
+	}
+
+	t.replayMu.Lock()
+	defer t.replayMu.Unlock()
+	t.pruneReplayedLocked()
+	t.replayed[conn] = rec
+}
+
+// SuppressReplayed reports whether a gateway canvas message headed to conn is
+// byte-identical to one replayed to it within the dedup window. Each replayed
+// message suppresses at most one copy.
+func (t *CanvasTracker) SuppressReplayed(conn *websocket.Conn, payload []byte) bool {
+	if t.dedupWindow <= 0 {
+		return false
+	}
+	t.replayMu.Lock()
+	defer t.replayMu.Unlock()
+	t.pruneReplayedLocked()
+
+	rec := t.replayed[conn]
+	if rec == nil {
+		return false
+	}
+	h := sha256.Sum256(payload)
+	if rec.hashes[h] == 0 {
+		return false
+	}
+	if rec.hashes[h]--; rec.hashes[h] == 0 {
+		delete(rec.hashes, h)
+	}
+	if len(rec.hashes) == 0 {
+		delete(t.replayed, conn)
+	}
+	return true
+}
+
+// pruneReplayedLocked drops replay records older than the dedup window.
+// Must be called with replayMu held.
+func (t *CanvasTracker) pruneReplayedLocked() {
+	cutoff := time.Now().Add(-t.dedupWindow)
+	for conn, rec := range t.replayed {
+		if rec.at.Before(cutoff) {
+			delete(t.replayed, conn)
+		}
+	}
+}
+
 // State returns a snapshot of the tracker's current state for health/debug endpoints.
 func (t *CanvasTracker) State() TrackerState {
 	t.mu.RLock()
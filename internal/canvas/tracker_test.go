@@ -4,12 +4,15 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/coder/websocket"
 	"github.com/cortexuvula/clawreachbridge/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func newTestTracker() *CanvasTracker {
@@ -79,6 +82,35 @@ func TestJSONLBufferOverflow(t *testing.T) {
 	}
 }
 
+func TestPushJSONLOverMaxNotBuffered(t *testing.T) {
+	tr := NewTracker(config.CanvasConfig{
+		StateTracking:   true,
+		JSONLBufferSize: 3,
+		MaxAge:          5 * time.Minute,
+		MaxJSONLBytes:   1024,
+	})
+	skipped := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_canvas_jsonl_skipped_total"})
+	tr.SetMetrics(nil, nil, skipped)
+	tr.HandleMessage("canvas.present", []byte(`{"type":"req","method":"canvas.present"}`))
+
+	small := []byte(`{"type":"req","method":"canvas.a2ui.pushJSONL","params":{"data":"line1"}}`)
+	big := []byte(`{"type":"req","method":"canvas.a2ui.pushJSONL","params":{"data":"` + strings.Repeat("x", 2048) + `"}}`)
+	tr.HandleMessage("canvas.a2ui.pushJSONL", small)
+	tr.HandleMessage("canvas.a2ui.pushJSONL", big)
+
+	if n := tr.State().JSONLBuffered; n != 1 {
+		t.Errorf("buffered = %d, want 1 (oversized entry skipped)", n)
+	}
+	if got := testutil.ToFloat64(skipped); got != 1 {
+		t.Errorf("skipped counter = %v, want 1", got)
+	}
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	if string(tr.jsonlBuffer[0]) != string(small) {
+		t.Errorf("buffered entry = %s, want the small one", tr.jsonlBuffer[0])
+	}
+}
+
 func TestPresentClearsBuffer(t *testing.T) {
 	tr := newTestTracker()
 	tr.HandleMessage("canvas.present", []byte(`{"type":"req","method":"canvas.present","params":{"url":"old"}}`))
@@ -281,3 +313,82 @@ func TestStateStaleFlag(t *testing.T) {
 		t.Error("expected stale after max_age")
 	}
 }
+
+func TestSuppressReplayed(t *testing.T) {
+	tr := NewTracker(config.CanvasConfig{
+		StateTracking:     true,
+		JSONLBufferSize:   3,
+		MaxAge:            5 * time.Minute,
+		ReplayDedupWindow: time.Minute,
+	})
+	presentMsg := []byte(`{"type":"req","method":"canvas.present","params":{"url":"test"}}`)
+	jsonlMsg := []byte(`{"type":"req","method":"canvas.a2ui.pushJSONL","params":{"data":"line1"}}`)
+	tr.HandleMessage("canvas.present", presentMsg)
+	tr.HandleMessage("canvas.a2ui.pushJSONL", jsonlMsg)
+
+	server, wsURL := wsEchoServer(t)
+	defer server.Close()
+	ctx := context.Background()
+	conn, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.CloseNow()
+	other, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer other.CloseNow()
+
+	if tr.SuppressReplayed(conn, presentMsg) {
+		t.Error("message suppressed before any replay")
+	}
+	if err := tr.ReplayMessages(ctx, conn); err != nil {
+		t.Fatalf("ReplayMessages: %v", err)
+	}
+
+	if tr.SuppressReplayed(other, presentMsg) {
+		t.Error("message suppressed on a connection that wasn't replayed to")
+	}
+	if tr.SuppressReplayed(conn, []byte(`{"type":"req","method":"canvas.present","params":{"url":"new"}}`)) {
+		t.Error("different present message suppressed")
+	}
+	if !tr.SuppressReplayed(conn, presentMsg) {
+		t.Error("duplicate present message not suppressed")
+	}
+	if !tr.SuppressReplayed(conn, jsonlMsg) {
+		t.Error("duplicate pushJSONL message not suppressed")
+	}
+	// Each replayed message suppresses a single copy.
+	if tr.SuppressReplayed(conn, presentMsg) {
+		t.Error("second copy of present message suppressed")
+	}
+}
+
+func TestSuppressReplayedWindowExpires(t *testing.T) {
+	tr := NewTracker(config.CanvasConfig{
+		StateTracking:     true,
+		JSONLBufferSize:   3,
+		MaxAge:            5 * time.Minute,
+		ReplayDedupWindow: 20 * time.Millisecond,
+	})
+	presentMsg := []byte(`{"type":"req","method":"canvas.present","params":{"url":"test"}}`)
+	tr.HandleMessage("canvas.present", presentMsg)
+
+	server, wsURL := wsEchoServer(t)
+	defer server.Close()
+	ctx := context.Background()
+	conn, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.CloseNow()
+
+	if err := tr.ReplayMessages(ctx, conn); err != nil {
+		t.Fatalf("ReplayMessages: %v", err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if tr.SuppressReplayed(conn, presentMsg) {
+		t.Error("message suppressed after the dedup window expired")
+	}
+}
@@ -2,8 +2,10 @@ package chatsync
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/coder/websocket"
 )
@@ -18,15 +20,37 @@ type ClientEntry struct {
 type ClientRegistry struct {
 	mu       sync.RWMutex
 	sessions map[string]map[string]*ClientEntry
+
+	maxFanout    int           // 0 = unlimited
+	writeTimeout time.Duration // 0 = bounded only by the caller's context
+
+	queueMu sync.Mutex
+	queues  map[string][]queuedBroadcast // pending ordered broadcasts per session; present while a worker runs
+}
+
+// queuedBroadcast is a broadcast waiting in a session's dispatch queue.
+type queuedBroadcast struct {
+	ctx      context.Context
+	senderID string
+	payload  []byte
 }
 
 // NewClientRegistry creates an empty registry.
 func NewClientRegistry() *ClientRegistry {
 	return &ClientRegistry{
 		sessions: make(map[string]map[string]*ClientEntry),
+		queues:   make(map[string][]queuedBroadcast),
 	}
 }
 
+// SetBroadcastLimits caps the number of recipients per broadcast and bounds
+// each recipient's write so a slow sibling cannot hold up the others.
+// Call before the registry is shared.
+func (r *ClientRegistry) SetBroadcastLimits(maxFanout int, writeTimeout time.Duration) {
+	r.maxFanout = maxFanout
+	r.writeTimeout = writeTimeout
+}
+
 // Register adds a client to a session.
 func (r *ClientRegistry) Register(sessionKey, clientID string, conn *websocket.Conn) {
 	r.mu.Lock()
@@ -55,25 +79,108 @@ func (r *ClientRegistry) Unregister(sessionKey, clientID string) {
 	slog.Debug("sync registry: unregistered", "session", sessionKey, "client", clientID)
 }
 
+// unregisterEntry removes a client only if it is still registered with
+// entry, so a client that reconnected under the same ID is left alone.
+func (r *ClientRegistry) unregisterEntry(sessionKey, clientID string, entry *ClientEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	clients := r.sessions[sessionKey]
+	if clients == nil || clients[clientID] != entry {
+		return
+	}
+	delete(clients, clientID)
+	if len(clients) == 0 {
+		delete(r.sessions, sessionKey)
+	}
+	slog.Debug("sync registry: unregistered dead client", "session", sessionKey, "client", clientID)
+}
+
 // Broadcast sends a payload to all clients on a session EXCEPT the sender.
-// Takes a snapshot of entries under RLock, then writes without holding the lock.
-// coder/websocket Write() serializes internally via mutex, so concurrent
-// calls from broadcast + forwarder goroutines are safe.
+// Takes a snapshot of entries under RLock, then writes to each recipient
+// concurrently without holding the lock, returning once every write has
+// finished or hit its deadline. coder/websocket Write() serializes internally
+// via mutex, so concurrent calls from broadcast + forwarder goroutines are safe.
+// A write that times out closes that recipient's connection, which its own
+// forwarder then tears down. Any recipient whose write fails is unregistered
+// right away so later broadcasts don't keep writing to a dead connection.
 func (r *ClientRegistry) Broadcast(ctx context.Context, sessionKey, senderID string, payload []byte) {
 	r.mu.RLock()
 	clients := r.sessions[sessionKey]
+	targetIDs := make([]string, 0, len(clients))
 	targets := make([]*ClientEntry, 0, len(clients))
 	for id, entry := range clients {
 		if id != senderID {
+			targetIDs = append(targetIDs, id)
 			targets = append(targets, entry)
 		}
 	}
 	r.mu.RUnlock()
 
-	for _, entry := range targets {
-		if err := entry.Conn.Write(ctx, websocket.MessageText, payload); err != nil {
-			slog.Debug("sync broadcast: write failed", "error", err)
+	if r.maxFanout > 0 && len(targets) > r.maxFanout {
+		slog.Warn("sync broadcast: fanout capped", "session", sessionKey, "recipients", len(targets), "max", r.maxFanout)
+		targetIDs = targetIDs[:r.maxFanout]
+		targets = targets[:r.maxFanout]
+	}
+
+	var wg sync.WaitGroup
+	for i, entry := range targets {
+		wg.Add(1)
+		go func(id string, entry *ClientEntry) {
+			defer wg.Done()
+			writeCtx, cancel := ctx, context.CancelFunc(func() {})
+			if r.writeTimeout > 0 {
+				writeCtx, cancel = context.WithTimeout(ctx, r.writeTimeout)
+			}
+			defer cancel()
+
+			if err := entry.Conn.Write(writeCtx, websocket.MessageText, payload); err != nil {
+				if ctx.Err() != nil {
+					return // the broadcast itself was cancelled; not the recipient's fault
+				}
+				if errors.Is(writeCtx.Err(), context.DeadlineExceeded) {
+					slog.Warn("sync broadcast: dropped slow recipient", "session", sessionKey, "client", id, "timeout", r.writeTimeout)
+				} else {
+					slog.Debug("sync broadcast: write failed", "client", id, "error", err)
+				}
+				r.unregisterEntry(sessionKey, id, entry)
+			}
+		}(targetIDs[i], entry)
+	}
+	wg.Wait()
+}
+
+// BroadcastOrdered queues a broadcast and returns without waiting for it.
+// Broadcasts for the same session are delivered one after another in the
+// order they were queued, so two messages sent back to back reach siblings
+// in send order; different sessions don't wait on each other. A worker
+// goroutine runs per session only while its queue is non-empty.
+func (r *ClientRegistry) BroadcastOrdered(ctx context.Context, sessionKey, senderID string, payload []byte) {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	pending, running := r.queues[sessionKey]
+	r.queues[sessionKey] = append(pending, queuedBroadcast{ctx: ctx, senderID: senderID, payload: payload})
+	if !running {
+		go r.drainQueue(sessionKey)
+	}
+}
+
+// drainQueue delivers a session's queued broadcasts in order until the queue
+// is empty, then removes it so the next BroadcastOrdered starts a new worker.
+func (r *ClientRegistry) drainQueue(sessionKey string) {
+	for {
+		r.queueMu.Lock()
+		pending := r.queues[sessionKey]
+		if len(pending) == 0 {
+			delete(r.queues, sessionKey)
+			r.queueMu.Unlock()
+			return
 		}
+		next := pending[0]
+		r.queues[sessionKey] = pending[1:]
+		r.queueMu.Unlock()
+
+		r.Broadcast(next.ctx, sessionKey, next.senderID, next.payload)
 	}
 }
 
@@ -2,6 +2,7 @@ package chatsync
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -143,3 +144,213 @@ func TestRegistryBroadcast(t *testing.T) {
 		t.Error("c1 (sender) should not have received broadcast")
 	}
 }
+
+// dialPairs starts a WebSocket server and returns n client/server connection
+// pairs with ids c0..c(n-1). Connections are closed at test cleanup.
+func dialPairs(t *testing.T, ctx context.Context, n int) []connPair {
+	t.Helper()
+	serverConns := make(chan *websocket.Conn, n)
+	done := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := websocket.Accept(w, req, nil)
+		if err != nil {
+			return
+		}
+		serverConns <- conn
+		<-done
+		conn.CloseNow()
+	}))
+	t.Cleanup(s.Close)
+	t.Cleanup(func() { close(done) })
+
+	pairs := make([]connPair, n)
+	for i := range pairs {
+		c, _, err := websocket.Dial(ctx, "ws"+s.URL[4:], nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		t.Cleanup(func() { c.CloseNow() })
+		c.SetReadLimit(64 << 20)
+		pairs[i] = connPair{id: fmt.Sprintf("c%d", i), serverConn: <-serverConns, clientConn: c}
+	}
+	return pairs
+}
+
+func TestRegistryBroadcastSlowSiblingDoesNotBlockOthers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pairs := dialPairs(t, ctx, 3) // c0 sender, c1 slow (never reads), c2 fast
+	r := NewClientRegistry()
+	r.SetBroadcastLimits(0, 300*time.Millisecond)
+	for _, p := range pairs {
+		r.Register("sess", p.id, p.serverConn)
+	}
+
+	// Large enough to fill the slow peer's socket buffers so its write blocks.
+	payload := make([]byte, 32<<20)
+
+	received := make(chan error, 1)
+	go func() {
+		_, _, err := pairs[2].clientConn.Read(ctx)
+		received <- err
+	}()
+
+	start := time.Now()
+	r.Broadcast(ctx, "sess", "c0", payload)
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Broadcast took %v, want bounded by per-recipient timeout", elapsed)
+	}
+
+	select {
+	case err := <-received:
+		if err != nil {
+			t.Fatalf("fast sibling read: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("fast sibling did not receive broadcast while slow sibling was stalled")
+	}
+}
+
+func TestRegistryBroadcastFanoutCap(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pairs := dialPairs(t, ctx, 4) // c0 sender, three siblings
+	r := NewClientRegistry()
+	r.SetBroadcastLimits(2, time.Second)
+	for _, p := range pairs {
+		r.Register("sess", p.id, p.serverConn)
+	}
+
+	r.Broadcast(ctx, "sess", "c0", []byte(`{"test":"fanout"}`))
+
+	got := 0
+	for _, p := range pairs[1:] {
+		readCtx, readCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		if _, _, err := p.clientConn.Read(readCtx); err == nil {
+			got++
+		}
+		readCancel()
+	}
+	if got != 2 {
+		t.Errorf("recipients = %d, want 2 (max_broadcast_fanout)", got)
+	}
+}
+
+func TestRegistryBroadcastUnregistersDeadClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pairs := dialPairs(t, ctx, 3) // c0 sender, c1 dead, c2 live
+	r := NewClientRegistry()
+	for _, p := range pairs {
+		r.Register("sess", p.id, p.serverConn)
+	}
+
+	pairs[1].serverConn.CloseNow()
+	r.Broadcast(ctx, "sess", "c0", []byte(`{"test":"dead"}`))
+
+	if n := r.ClientCount("sess"); n != 2 {
+		t.Errorf("client count = %d, want 2 (dead sibling unregistered)", n)
+	}
+	if _, msg, err := pairs[2].clientConn.Read(ctx); err != nil || string(msg) != `{"test":"dead"}` {
+		t.Errorf("live sibling read = %q, %v", msg, err)
+	}
+}
+
+func TestRegistryBroadcastKeepsReregisteredClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pairs := dialPairs(t, ctx, 2)
+	r := NewClientRegistry()
+	r.Register("sess", "c1", pairs[1].serverConn)
+	dead := r.sessions["sess"]["c1"]
+
+	// c1 reconnects under the same ID before the failed write is noticed.
+	pairs[1].serverConn.CloseNow()
+	r.Register("sess", "c1", pairs[0].serverConn)
+	r.unregisterEntry("sess", "c1", dead)
+
+	if n := r.ClientCount("sess"); n != 1 {
+		t.Errorf("client count = %d, want 1 (new registration kept)", n)
+	}
+}
+
+func TestRegistryBroadcastCancelledKeepsClients(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pairs := dialPairs(t, ctx, 2)
+	r := NewClientRegistry()
+	for _, p := range pairs {
+		r.Register("sess", p.id, p.serverConn)
+	}
+
+	cancelled, cancelNow := context.WithCancel(ctx)
+	cancelNow()
+	r.Broadcast(cancelled, "sess", "c0", []byte(`{}`))
+
+	if n := r.ClientCount("sess"); n != 2 {
+		t.Errorf("client count = %d, want 2 (cancelled broadcast must not unregister)", n)
+	}
+}
+
+func TestRegistryBroadcastOrderedPreservesSendOrder(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pairs := dialPairs(t, ctx, 3) // c0 sender, c1 and c2 siblings
+	r := NewClientRegistry()
+	for _, p := range pairs {
+		r.Register("sess", p.id, p.serverConn)
+	}
+
+	const n = 50
+	for i := 0; i < n; i++ {
+		r.BroadcastOrdered(ctx, "sess", "c0", []byte(fmt.Sprintf("msg-%d", i)))
+	}
+
+	for _, p := range pairs[1:] {
+		for i := 0; i < n; i++ {
+			_, data, err := p.clientConn.Read(ctx)
+			if err != nil {
+				t.Fatalf("%s read %d: %v", p.id, i, err)
+			}
+			if want := fmt.Sprintf("msg-%d", i); string(data) != want {
+				t.Fatalf("%s message %d = %q, want %q", p.id, i, data, want)
+			}
+		}
+	}
+}
+
+func TestRegistryBroadcastOrderedWorkerExits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pairs := dialPairs(t, ctx, 2)
+	r := NewClientRegistry()
+	for _, p := range pairs {
+		r.Register("sess", p.id, p.serverConn)
+	}
+
+	r.BroadcastOrdered(ctx, "sess", "c0", []byte("hello"))
+	if _, _, err := pairs[1].clientConn.Read(ctx); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		r.queueMu.Lock()
+		_, running := r.queues["sess"]
+		r.queueMu.Unlock()
+		if !running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("session queue still present after its broadcasts were delivered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
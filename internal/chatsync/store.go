@@ -18,6 +18,32 @@ type StoredMessage struct {
 	Timestamp int64         `json:"timestamp"`
 }
 
+// Store is the backend for synced chat history. MessageStore is the default
+// in-memory implementation; other backends (e.g. Redis, so devices on
+// different bridge instances share history) plug in via Handler.SyncStore.
+// Implementations must be safe for concurrent use and handle their own
+// errors (sync is best-effort; a failed Append must not break forwarding).
+type Store interface {
+	// Append adds a message to a session's history.
+	Append(sessionKey string, msg StoredMessage)
+	// GetHistory returns up to limit of the most recent messages, oldest first.
+	GetHistory(sessionKey string, limit int) []StoredMessage
+	// GetHistoryBefore returns up to limit messages older than the cursor,
+	// oldest first, and whether older messages remain. The cursor is the
+	// oldest message of the previous page: beforeID when it is still
+	// retained, otherwise its timestamp before (ms; <= 0 means no bound).
+	GetHistoryBefore(sessionKey string, before int64, beforeID string, limit int) (msgs []StoredMessage, more bool)
+	// GetHistoryAfter returns up to limit of the most recent messages newer
+	// than the one with ID afterID, oldest first, and whether older messages
+	// newer than afterID remain. If afterID is no longer retained it behaves
+	// like GetHistoryBefore with no bound.
+	GetHistoryAfter(sessionKey, afterID string, limit int) (msgs []StoredMessage, more bool)
+	// Count returns the number of messages retained for a session.
+	Count(sessionKey string) int
+}
+
+var _ Store = (*MessageStore)(nil)
+
 // MessageStore is a per-session in-memory ring buffer for chat messages.
 // Thread-safe via sync.RWMutex.
 type MessageStore struct {
@@ -79,6 +105,103 @@ func (s *MessageStore) GetHistory(sessionKey string, limit int) []StoredMessage
 	return result
 }
 
+// GetHistoryBefore returns up to limit messages stored before the message
+// with ID beforeID, in chronological order. Several messages can share a
+// millisecond, so the ID is what keeps a page boundary from skipping any; if
+// beforeID is empty or no longer retained, messages with a timestamp strictly
+// older than before are returned instead (before <= 0 means no upper bound).
+// more reports whether older messages remain beyond the returned page.
+func (s *MessageStore) GetHistoryBefore(sessionKey string, before int64, beforeID string, limit int) (msgs []StoredMessage, more bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ss, ok := s.sessions[sessionKey]
+	if !ok {
+		return nil, false
+	}
+
+	// Messages are appended in arrival order, so find the first one at or
+	// after the cursor and page backward from there.
+	end := -1
+	if beforeID != "" {
+		for i := len(ss.messages) - 1; i >= 0; i-- {
+			if ss.messages[i].ID == beforeID {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		end = len(ss.messages)
+		if before > 0 {
+			for end > 0 && ss.messages[end-1].Timestamp >= before {
+				end--
+			}
+		}
+	}
+	start := 0
+	if limit > 0 && end-limit > 0 {
+		start = end - limit
+	}
+
+	result := make([]StoredMessage, end-start)
+	copy(result, ss.messages[start:end])
+	return result, start > 0
+}
+
+// GetHistoryAfter returns up to limit of the most recent messages stored after
+// the one with ID afterID, in chronological order. more reports whether older
+// messages after afterID remain beyond the returned page. If afterID is not in
+// the buffer (never seen or already evicted) the client may be missing
+// anything, so the page is taken from the whole history instead.
+func (s *MessageStore) GetHistoryAfter(sessionKey, afterID string, limit int) (msgs []StoredMessage, more bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ss, ok := s.sessions[sessionKey]
+	if !ok {
+		return nil, false
+	}
+
+	first := 0
+	for i := len(ss.messages) - 1; i >= 0; i-- {
+		if ss.messages[i].ID == afterID {
+			first = i + 1
+			break
+		}
+	}
+	start := first
+	if limit > 0 && len(ss.messages)-limit > first {
+		start = len(ss.messages) - limit
+	}
+
+	result := make([]StoredMessage, len(ss.messages)-start)
+	copy(result, ss.messages[start:])
+	return result, start > first
+}
+
+// GetHistoryBetween pages backward from the cursor like GetHistoryBefore,
+// but stops at the message with ID afterID, so a client that already has
+// afterID is never sent it or anything older. It works on any Store. If
+// afterID isn't in the requested range it is no bound, as in GetHistoryAfter.
+func GetHistoryBetween(store Store, sessionKey, afterID string, before int64, beforeID string, limit int) (msgs []StoredMessage, more bool) {
+	if limit <= 0 {
+		msgs, more = store.GetHistoryBefore(sessionKey, before, beforeID, 0)
+	} else {
+		// One extra message shows whether the page ends right after afterID.
+		msgs, more = store.GetHistoryBefore(sessionKey, before, beforeID, limit+1)
+	}
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].ID == afterID {
+			return msgs[i+1:], false
+		}
+	}
+	if limit > 0 && len(msgs) > limit {
+		return msgs[1:], true
+	}
+	return msgs, more
+}
+
 // Count returns the number of stored messages for a session.
 func (s *MessageStore) Count(sessionKey string) int {
 	s.mu.RLock()
@@ -90,3 +213,50 @@ func (s *MessageStore) Count(sessionKey string) int {
 	}
 	return len(ss.messages)
 }
+
+// Exporter is implemented by stores whose whole contents can be exported and
+// loaded back, e.g. to move history between bridge instances or snapshot it
+// for debugging. MessageStore implements it.
+type Exporter interface {
+	// Export returns a copy of every session's history, oldest first.
+	Export() map[string][]StoredMessage
+	// Import replaces the history of each session in sessions and returns
+	// the number of messages kept. Sessions not in the import are untouched.
+	Import(sessions map[string][]StoredMessage) int
+}
+
+var _ Exporter = (*MessageStore)(nil)
+
+// Export returns a copy of every session's history, oldest first.
+func (s *MessageStore) Export() map[string][]StoredMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make(map[string][]StoredMessage, len(s.sessions))
+	for key, ss := range s.sessions {
+		msgs := make([]StoredMessage, len(ss.messages))
+		copy(msgs, ss.messages)
+		out[key] = msgs
+	}
+	return out
+}
+
+// Import replaces the history of each given session. A session with more
+// than maxSize messages keeps only the newest maxSize, as if they had been
+// appended in order.
+func (s *MessageStore) Import(sessions map[string][]StoredMessage) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := 0
+	for key, msgs := range sessions {
+		if len(msgs) > s.maxSize {
+			msgs = msgs[len(msgs)-s.maxSize:]
+		}
+		ss := &sessionStore{messages: make([]StoredMessage, len(msgs))}
+		copy(ss.messages, msgs)
+		s.sessions[key] = ss
+		kept += len(msgs)
+	}
+	return kept
+}
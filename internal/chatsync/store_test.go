@@ -1,6 +1,8 @@
 package chatsync
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -130,3 +132,186 @@ func TestMessageStoreReturnsCopy(t *testing.T) {
 		t.Errorf("store was modified through returned slice: got ID %q", msgs2[0].ID)
 	}
 }
+
+func TestMessageStoreGetHistoryBefore(t *testing.T) {
+	store := NewMessageStore(100)
+	for i := 1; i <= 10; i++ {
+		store.Append("s", StoredMessage{ID: fmt.Sprintf("m%d", i), Role: "user", Timestamp: int64(i * 100)})
+	}
+
+	// Page backward three at a time from the newest message.
+	var pages [][]StoredMessage
+	var before int64
+	for {
+		msgs, more := store.GetHistoryBefore("s", before, "", 3)
+		pages = append(pages, msgs)
+		if !more {
+			break
+		}
+		before = msgs[0].Timestamp
+	}
+
+	if len(pages) != 4 {
+		t.Fatalf("pages = %d, want 4", len(pages))
+	}
+	if pages[0][0].ID != "m8" || pages[0][2].ID != "m10" {
+		t.Errorf("first page = %v, want m8..m10", pages[0])
+	}
+	if pages[2][0].ID != "m2" || pages[2][2].ID != "m4" {
+		t.Errorf("third page = %v, want m2..m4", pages[2])
+	}
+	if len(pages[3]) != 1 || pages[3][0].ID != "m1" {
+		t.Errorf("last page = %v, want [m1]", pages[3])
+	}
+}
+
+func TestMessageStoreGetHistoryBeforeSameTimestamp(t *testing.T) {
+	store := NewMessageStore(100)
+	// m2..m5 arrive within the same millisecond.
+	for i, ts := range []int64{100, 200, 200, 200, 200, 300} {
+		store.Append("s", StoredMessage{ID: fmt.Sprintf("m%d", i+1), Timestamp: ts})
+	}
+
+	var ids []string
+	var before int64
+	var beforeID string
+	for pages := 0; ; pages++ {
+		if pages > 6 {
+			t.Fatal("pagination did not terminate")
+		}
+		msgs, more := store.GetHistoryBefore("s", before, beforeID, 2)
+		for i := len(msgs) - 1; i >= 0; i-- {
+			ids = append(ids, msgs[i].ID)
+		}
+		if !more {
+			break
+		}
+		before, beforeID = msgs[0].Timestamp, msgs[0].ID
+	}
+
+	if got, want := strings.Join(ids, ","), "m6,m5,m4,m3,m2,m1"; got != want {
+		t.Errorf("paged IDs = %s, want %s", got, want)
+	}
+
+	// A cursor whose message was evicted falls back to the timestamp.
+	msgs, _ := store.GetHistoryBefore("s", 300, "gone", 10)
+	if len(msgs) != 5 || msgs[4].ID != "m5" {
+		t.Errorf("fallback page = %v, want m1..m5", msgs)
+	}
+}
+
+func TestMessageStoreGetHistoryAfter(t *testing.T) {
+	store := NewMessageStore(5)
+	for i := 1; i <= 8; i++ {
+		store.Append("s", StoredMessage{ID: fmt.Sprintf("m%d", i), Timestamp: int64(i * 100)})
+	}
+	tests := []struct {
+		afterID  string
+		limit    int
+		want     string
+		wantMore bool
+	}{
+		{"m6", 10, "m7,m8", false},
+		{"m8", 10, "", false},
+		{"m4", 2, "m7,m8", true},            // newest page; m5 and m6 still missing
+		{"m2", 10, "m4,m5,m6,m7,m8", false}, // evicted: whole buffer
+		{"unknown", 3, "m6,m7,m8", true},
+	}
+	for _, tt := range tests {
+		msgs, more := store.GetHistoryAfter("s", tt.afterID, tt.limit)
+		if got := joinIDs(msgs); got != tt.want || more != tt.wantMore {
+			t.Errorf("GetHistoryAfter(%q, %d) = %q more=%v, want %q more=%v", tt.afterID, tt.limit, got, more, tt.want, tt.wantMore)
+		}
+	}
+
+	if msgs, more := store.GetHistoryAfter("missing", "m1", 10); msgs != nil || more {
+		t.Errorf("nonexistent session: got %v more=%v, want nil", msgs, more)
+	}
+}
+
+func TestGetHistoryBetween(t *testing.T) {
+	store := NewMessageStore(100)
+	for i := 1; i <= 6; i++ {
+		store.Append("s", StoredMessage{ID: fmt.Sprintf("m%d", i), Timestamp: int64(i * 100)})
+	}
+
+	tests := []struct {
+		afterID  string
+		beforeID string
+		limit    int
+		want     string
+		wantMore bool
+	}{
+		{"m2", "m5", 2, "m3,m4", false}, // page ends right at afterID
+		{"m1", "m5", 2, "m3,m4", true},  // m2 is still newer than afterID
+		{"m3", "m5", 2, "m4", false},    // afterID inside the page
+		{"m4", "m5", 2, "", false},      // nothing between afterID and the cursor
+		{"gone", "m5", 2, "m3,m4", true},
+		{"m2", "m5", 0, "m3,m4", false},
+	}
+	for _, tt := range tests {
+		msgs, more := GetHistoryBetween(store, "s", tt.afterID, 0, tt.beforeID, tt.limit)
+		if got := joinIDs(msgs); got != tt.want || more != tt.wantMore {
+			t.Errorf("GetHistoryBetween(after %q, before %q, %d) = %q more=%v, want %q more=%v",
+				tt.afterID, tt.beforeID, tt.limit, got, more, tt.want, tt.wantMore)
+		}
+	}
+}
+
+func TestMessageStoreGetHistoryBeforeOldest(t *testing.T) {
+	store := NewMessageStore(100)
+	store.Append("s", StoredMessage{ID: "m1", Timestamp: 100})
+
+	msgs, more := store.GetHistoryBefore("s", 100, "", 10)
+	if len(msgs) != 0 || more {
+		t.Errorf("got %v more=%v, want empty page with no more", msgs, more)
+	}
+	if msgs, more := store.GetHistoryBefore("s", 100, "m1", 10); len(msgs) != 0 || more {
+		t.Errorf("before m1: got %v more=%v, want empty page with no more", msgs, more)
+	}
+
+	if msgs, more := store.GetHistoryBefore("missing", 0, "", 10); msgs != nil || more {
+		t.Errorf("nonexistent session: got %v more=%v, want nil", msgs, more)
+	}
+}
+
+func TestMessageStoreExportImport(t *testing.T) {
+	src := NewMessageStore(10)
+	for i := 1; i <= 3; i++ {
+		src.Append("session-a", StoredMessage{ID: fmt.Sprintf("a-%d", i), Role: "user", Timestamp: int64(i)})
+	}
+	src.Append("session-b", StoredMessage{ID: "b-1", Role: "assistant", Content: []ContentItem{{Type: "text", Text: "hi"}}, Timestamp: 5})
+
+	exported := src.Export()
+	exported["session-a"][0].ID = "mutated" // the export is a copy
+	if got := src.GetHistory("session-a", 0)[0].ID; got != "a-1" {
+		t.Fatalf("export aliases the store: first ID = %q", got)
+	}
+
+	dst := NewMessageStore(2)
+	dst.Append("session-a", StoredMessage{ID: "old", Timestamp: 99})
+	dst.Append("session-c", StoredMessage{ID: "c-1", Timestamp: 1})
+	if kept := dst.Import(src.Export()); kept != 3 {
+		t.Errorf("Import kept %d messages, want 3 (session-a trimmed to 2)", kept)
+	}
+
+	got := dst.GetHistory("session-a", 0)
+	if len(got) != 2 || got[0].ID != "a-2" || got[1].ID != "a-3" {
+		t.Errorf("session-a = %+v, want a-2, a-3 replacing the old history", got)
+	}
+	if got := dst.GetHistory("session-b", 0); len(got) != 1 || got[0].Content[0].Text != "hi" {
+		t.Errorf("session-b = %+v", got)
+	}
+	if dst.Count("session-c") != 1 {
+		t.Error("session absent from the import was modified")
+	}
+}
+
+// joinIDs returns the messages' IDs, comma-separated.
+func joinIDs(msgs []StoredMessage) string {
+	var out []string
+	for _, m := range msgs {
+		out = append(out, m.ID)
+	}
+	return strings.Join(out, ",")
+}
@@ -1,11 +1,19 @@
 package config
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net"
 	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,26 +27,271 @@ type Config struct {
 	Logging    LoggingConfig    `yaml:"logging"`
 	Health     HealthConfig     `yaml:"health"`
 	Monitoring MonitoringConfig `yaml:"monitoring"`
+	WebUI      WebUIConfig      `yaml:"webui"`
 }
 
 // BridgeConfig contains the core proxy settings.
 type BridgeConfig struct {
-	ListenAddress       string         `yaml:"listen_address"`
-	GatewayURL          string         `yaml:"gateway_url"`
-	Origin              string         `yaml:"origin"`
-	DrainTimeout        time.Duration  `yaml:"drain_timeout"`
-	MaxMessageSize      int64          `yaml:"max_message_size"`
-	PingInterval        time.Duration  `yaml:"ping_interval"`
-	PongTimeout         time.Duration  `yaml:"pong_timeout"`
-	WriteTimeout        time.Duration  `yaml:"write_timeout"`
-	ReadTimeout         time.Duration  `yaml:"read_timeout"`
-	DialTimeout         time.Duration  `yaml:"dial_timeout"`
-	AllowedSubprotocols []string       `yaml:"allowed_subprotocols"`
-	TLS                 TLSConfig      `yaml:"tls"`
-	Media               MediaConfig    `yaml:"media"`
-	Reactions           ReactionConfig `yaml:"reactions"`
-	Canvas              CanvasConfig   `yaml:"canvas"`
-	Sync                SyncConfig     `yaml:"sync"`
+	ListenAddress            string                   `yaml:"listen_address"`
+	ListenBacklog            int                      `yaml:"listen_backlog"`   // accept queue length for the proxy listener; 0 = OS default (Linux only)
+	ReusePort                bool                     `yaml:"reuse_port"`       // set SO_REUSEPORT so overlapping processes can share the port (Linux only)
+	MaxHeaderBytes           int                      `yaml:"max_header_bytes"` // request header size limit on the proxy and health listeners; larger requests get 431
+	GatewayURL               string                   `yaml:"gateway_url"`
+	HistoryGatewayURL        string                   `yaml:"history_gateway_url"` // read replica for sessions.history requests; empty = primary gateway
+	Origin                   OriginConfig             `yaml:"origin"`
+	DrainTimeout             time.Duration            `yaml:"drain_timeout"`
+	DrainOrder               string                   `yaml:"drain_order"`          // all_at_once, oldest_first, newest_first
+	DrainWebhook             string                   `yaml:"drain_webhook"`        // URL POSTed drain_started/drain_completed events; empty disables
+	MaintenanceSchedule      []string                 `yaml:"maintenance_schedule"` // recurring "[Mon] HH:MM-HH:MM" windows (local time) during which connections are drained and refused
+	MaxMessageSize           int64                    `yaml:"max_message_size"`
+	MaxMessageSizeUpstream   int64                    `yaml:"max_message_size_upstream"`   // client→gateway limit; 0 = max_message_size
+	MaxMessageSizeDownstream int64                    `yaml:"max_message_size_downstream"` // gateway→client limit; 0 = max_message_size
+	ClientBufferHighWater    int64                    `yaml:"client_buffer_high_water"`    // bytes queued for a client before it is closed as a slow consumer; 0 = unbuffered
+	PingInterval             time.Duration            `yaml:"ping_interval"`
+	PongTimeout              time.Duration            `yaml:"pong_timeout"`
+	WriteTimeout             time.Duration            `yaml:"write_timeout"`
+	WriteTimeoutByPath       map[string]time.Duration `yaml:"write_timeout_by_path"` // path prefix → write_timeout override; longest prefix wins
+	CloseCodeMap             map[int]int              `yaml:"close_code_map"`        // gateway close code → code sent to the client
+	ReadTimeout              time.Duration            `yaml:"read_timeout"`
+	MaxConcurrentDials       int                      `yaml:"max_concurrent_dials"` // gateway dials in flight at once; others wait up to dial_timeout, then are closed with 1013 (0 = unlimited)
+	DialTimeout              time.Duration            `yaml:"dial_timeout"`
+	GatewayPoolSize          int                      `yaml:"gateway_pool_size"`        // pre-dialed gateway connections kept idle; 0 disables
+	GatewayResolveTTL        time.Duration            `yaml:"gateway_resolve_ttl"`      // cache gateway DNS lookups this long, serving stale entries while refreshing; 0 disables
+	GatewayResolveOverride   string                   `yaml:"gateway_resolve_override"` // static IP used for the gateway_url host instead of DNS; empty disables
+	AllowedSubprotocols      []string                 `yaml:"allowed_subprotocols"`
+	AllowedExtensions        []string                 `yaml:"allowed_extensions"`     // WebSocket extensions clients may negotiate; other offers are stripped (empty = none)
+	GatewaySubprotocols      []string                 `yaml:"gateway_subprotocols"`   // always offered to the gateway after the client's own
+	DefaultSubprotocol       string                   `yaml:"default_subprotocol"`    // offered to the gateway when the client offers none; empty disables
+	HTTPProxyEnabled         bool                     `yaml:"http_proxy_enabled"`     // proxy non-WebSocket requests to the gateway
+	StripPathPrefix          string                   `yaml:"strip_path_prefix"`      // removed from request paths before routing and proxying, e.g. "/bridge"; empty disables
+	FlushInterval            time.Duration            `yaml:"flush_interval"`         // HTTP proxy flush period for responses with a length; negative = every write (streams always flush)
+	RequestIDHeader          string                   `yaml:"request_id_header"`      // correlation ID header sent to the gateway; empty disables
+	ClientIPHeader           string                   `yaml:"client_ip_header"`       // header carrying the client IP to the gateway, e.g. X-Forwarded-For; empty disables
+	BadGatewayPage           string                   `yaml:"bad_gateway_page"`       // file served as the body of HTTP proxy 502s; empty = plain text
+	StripResponseHeaders     []string                 `yaml:"strip_response_headers"` // removed from HTTP proxy responses
+	TransformCommand         []string                 `yaml:"transform_command"`      // argv of a command each text message is piped through (stdin → stdout); empty disables
+	TransformTimeout         time.Duration            `yaml:"transform_timeout"`      // per-message limit for transform_command; on timeout the message passes unchanged
+	TLS                      TLSConfig                `yaml:"tls"`
+	HTTPTransport            HTTPTransportConfig      `yaml:"http_transport"`
+	Compression              CompressionConfig        `yaml:"compression"`
+	Media                    MediaConfig              `yaml:"media"`
+	Reactions                ReactionConfig           `yaml:"reactions"`
+	Canvas                   CanvasConfig             `yaml:"canvas"`
+	Sync                     SyncConfig               `yaml:"sync"`
+	Resume                   ResumeConfig             `yaml:"resume"`
+}
+
+// SupportedExtensions lists the WebSocket extensions the bridge can
+// negotiate with clients, and so may appear in bridge.allowed_extensions.
+var SupportedExtensions = []string{"permessage-deflate"}
+
+// OriginConfig is the Origin header injected on gateway requests. In YAML it
+// is either a single string used for every route, or a map of path prefix to
+// origin where the longest matching prefix wins and "/" is the fallback.
+type OriginConfig struct {
+	Default string            // used when no route prefix matches ("/" in map form)
+	Routes  map[string]string // path prefix → origin, excluding "/"
+}
+
+// UnmarshalYAML accepts either a scalar origin or a prefix → origin mapping.
+func (o *OriginConfig) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		o.Routes = nil
+		return node.Decode(&o.Default)
+	case yaml.MappingNode:
+		var m map[string]string
+		if err := node.Decode(&m); err != nil {
+			return err
+		}
+		o.Default = m["/"]
+		delete(m, "/")
+		o.Routes = m
+		return nil
+	default:
+		return fmt.Errorf("bridge.origin must be a string or a map of path prefix to origin")
+	}
+}
+
+// MarshalYAML writes the scalar form when no per-route origins are set.
+func (o OriginConfig) MarshalYAML() (any, error) {
+	if len(o.Routes) == 0 {
+		return o.Default, nil
+	}
+	m := make(map[string]string, len(o.Routes)+1)
+	for k, v := range o.Routes {
+		m[k] = v
+	}
+	if o.Default != "" {
+		m["/"] = o.Default
+	}
+	return m, nil
+}
+
+// For returns the origin for a request path: the longest matching route
+// prefix, or Default if none match.
+func (o OriginConfig) For(path string) string {
+	best, bestLen := o.Default, 0
+	for prefix, origin := range o.Routes {
+		if len(prefix) > bestLen && strings.HasPrefix(path, prefix) {
+			best, bestLen = origin, len(prefix)
+		}
+	}
+	return best
+}
+
+// WriteTimeoutFor returns the write timeout for connections on a request
+// path: the longest matching WriteTimeoutByPath prefix, or WriteTimeout.
+func (b BridgeConfig) WriteTimeoutFor(path string) time.Duration {
+	best, bestLen := b.WriteTimeout, 0
+	for prefix, d := range b.WriteTimeoutByPath {
+		if len(prefix) > bestLen && strings.HasPrefix(path, prefix) {
+			best, bestLen = d, len(prefix)
+		}
+	}
+	return best
+}
+
+// CloseCodeFor returns the close code to send the client when the gateway
+// closes with code: its CloseCodeMap entry, or code itself.
+func (b BridgeConfig) CloseCodeFor(code int) int {
+	if mapped, ok := b.CloseCodeMap[code]; ok {
+		return mapped
+	}
+	return code
+}
+
+// ValidCloseCode reports whether code may be sent in a close frame
+// (RFC 6455 §7.4): 1005, 1006 and 1015 are reserved for local use.
+func ValidCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014, code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// MaintenanceWindow is one recurring bridge.maintenance_schedule entry.
+type MaintenanceWindow struct {
+	AnyDay  bool         // recurs daily
+	Weekday time.Weekday // day the window starts on, unless AnyDay
+	Start   int          // minutes after local midnight
+	End     int          // minutes after local midnight; before Start if the window spans midnight
+	spec    string
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseMaintenanceWindow parses "HH:MM-HH:MM" (daily) or "Day HH:MM-HH:MM"
+// (weekly, Day being Mon through Sun). A window whose end is before its start
+// runs past midnight into the next day.
+func ParseMaintenanceWindow(spec string) (MaintenanceWindow, error) {
+	w := MaintenanceWindow{AnyDay: true, spec: spec}
+	bad := fmt.Errorf("bridge.maintenance_schedule: invalid window %q (want \"HH:MM-HH:MM\" or \"Mon HH:MM-HH:MM\")", spec)
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 1:
+	case 2:
+		day, ok := weekdays[strings.ToLower(fields[0])]
+		if !ok {
+			return w, bad
+		}
+		w.AnyDay, w.Weekday = false, day
+		fields = fields[1:]
+	default:
+		return w, bad
+	}
+	from, to, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return w, bad
+	}
+	start, err1 := time.Parse("15:04", from)
+	end, err2 := time.Parse("15:04", to)
+	if err1 != nil || err2 != nil {
+		return w, bad
+	}
+	w.Start = start.Hour()*60 + start.Minute()
+	w.End = end.Hour()*60 + end.Minute()
+	if w.Start == w.End {
+		return w, fmt.Errorf("bridge.maintenance_schedule: window %q is empty", spec)
+	}
+	return w, nil
+}
+
+// Contains reports whether t, in its own location, falls inside the window.
+func (w MaintenanceWindow) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	onDay := func(d time.Weekday) bool { return w.AnyDay || d == w.Weekday }
+	if w.Start < w.End {
+		return onDay(t.Weekday()) && minute >= w.Start && minute < w.End
+	}
+	return (onDay(t.Weekday()) && minute >= w.Start) || (onDay((t.Weekday()+6)%7) && minute < w.End)
+}
+
+func (w MaintenanceWindow) String() string { return w.spec }
+
+// MaintenanceWindows parses MaintenanceSchedule.
+func (b BridgeConfig) MaintenanceWindows() ([]MaintenanceWindow, error) {
+	windows := make([]MaintenanceWindow, 0, len(b.MaintenanceSchedule))
+	for _, spec := range b.MaintenanceSchedule {
+		w, err := ParseMaintenanceWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// RedactURL returns raw with its whole userinfo masked, for logging URLs
+// such as a gateway_url carrying basic-auth credentials. The username is
+// masked too, since some gateways take a bare token in that position.
+// Strings that don't parse as URLs are returned unchanged.
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	u.User = url.User("xxxxx")
+	return u.String()
+}
+
+// UpstreamMessageLimit returns the read limit for client→gateway messages:
+// MaxMessageSizeUpstream, or MaxMessageSize when unset.
+func (b BridgeConfig) UpstreamMessageLimit() int64 {
+	if b.MaxMessageSizeUpstream > 0 {
+		return b.MaxMessageSizeUpstream
+	}
+	return b.MaxMessageSize
+}
+
+// DownstreamMessageLimit returns the read limit for gateway→client messages:
+// MaxMessageSizeDownstream, or MaxMessageSize when unset.
+func (b BridgeConfig) DownstreamMessageLimit() int64 {
+	if b.MaxMessageSizeDownstream > 0 {
+		return b.MaxMessageSizeDownstream
+	}
+	return b.MaxMessageSize
+}
+
+// CompressionConfig controls permessage-deflate negotiation with clients.
+// MaxMessageSize always bounds the decompressed message size, so a small
+// compressed frame cannot inflate past the read limit.
+type CompressionConfig struct {
+	Mode      string `yaml:"mode"`      // disabled, context_takeover, no_context_takeover
+	Threshold int    `yaml:"threshold"` // min message size in bytes to compress outgoing messages (0 = library default)
+}
+
+// HTTPTransportConfig tunes the connections the HTTP reverse proxy keeps to
+// the gateway. The defaults match Go's default transport, except that all
+// idle connections may go to the gateway host (the default allows two).
+type HTTPTransportConfig struct {
+	MaxIdleConns        int           `yaml:"max_idle_conns"`        // idle keep-alive connections kept to the gateway (0 = unlimited)
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout"`     // idle connections are closed after this long
+	TLSHandshakeTimeout time.Duration `yaml:"tls_handshake_timeout"` // limit on the TLS handshake with an https:// gateway
 }
 
 // ReactionConfig controls reaction message inspection.
@@ -49,16 +302,30 @@ type ReactionConfig struct {
 
 // SyncConfig controls cross-device message sync via the bridge.
 type SyncConfig struct {
-	Enabled    bool `yaml:"enabled"`
-	MaxHistory int  `yaml:"max_history"`
+	Enabled                bool          `yaml:"enabled"`
+	MaxHistory             int           `yaml:"max_history"`
+	MaxBroadcastFanout     int           `yaml:"max_broadcast_fanout"`      // max sibling recipients per broadcast (0 = unlimited)
+	BroadcastTimeout       time.Duration `yaml:"broadcast_timeout"`         // per-recipient write deadline for broadcasts
+	MaxHistoryResponseSize int64         `yaml:"max_history_response_size"` // byte cap on a sessions.history response (0 = bridge.max_message_size)
+}
+
+// ResumeConfig lets a client that drops reconnect to its gateway session.
+// Clients opt in by sending a resume token; the gateway connection of a
+// dropped client is kept open and its messages buffered for Window.
+type ResumeConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Window         time.Duration `yaml:"window"`           // how long a dropped client's gateway session is kept
+	MaxBufferBytes int64         `yaml:"max_buffer_bytes"` // gateway messages buffered per parked session; more ends it
 }
 
 // CanvasConfig controls canvas state tracking for reconnect replay.
 type CanvasConfig struct {
-	StateTracking   bool          `yaml:"state_tracking"`
-	JSONLBufferSize int           `yaml:"jsonl_buffer_size"`
-	MaxAge          time.Duration `yaml:"max_age"`
-	A2UIURL         string        `yaml:"a2ui_url"`
+	StateTracking     bool          `yaml:"state_tracking"`
+	JSONLBufferSize   int           `yaml:"jsonl_buffer_size"`
+	MaxAge            time.Duration `yaml:"max_age"`
+	A2UIURL           string        `yaml:"a2ui_url"`
+	ReplayDedupWindow time.Duration `yaml:"replay_dedup_window"` // drop gateway canvas messages identical to a replay this recent (0 disables)
+	MaxJSONLBytes     int           `yaml:"max_jsonl_bytes"`     // pushJSONL messages larger than this are forwarded but not buffered for replay (0 = no limit)
 }
 
 // MediaConfig controls image injection from the gateway's media directory.
@@ -70,71 +337,225 @@ type MediaConfig struct {
 	Extensions  []string      `yaml:"extensions"`
 	InjectPaths []string      `yaml:"inject_paths"`
 	AllowedDirs []string      `yaml:"allowed_dirs"` // restrict MEDIA: paths to these directories
+
+	MaxConcurrentInjections int `yaml:"max_concurrent_injections"` // finals enriched at once across all connections (0 = unlimited)
+
+	Recompress          bool  `yaml:"recompress"`           // re-encode large opaque images as JPEG
+	RecompressThreshold int64 `yaml:"recompress_threshold"` // only recompress images larger than this (bytes)
+	RecompressQuality   int   `yaml:"recompress_quality"`   // JPEG quality 1-100
+
+	InboxMaxBytes int64  `yaml:"inbox_max_bytes"` // total size cap for received files (0 = unlimited)
+	InboxMaxFiles int    `yaml:"inbox_max_files"` // file count cap for received files (0 = unlimited)
+	InboxPolicy   string `yaml:"inbox_policy"`    // "reject" or "evict_oldest" when a cap would be exceeded
+
+	InboxAllowedExtensions []string `yaml:"inbox_allowed_extensions"` // empty = accept any extension
+	InboxVerifyMIME        bool     `yaml:"inbox_verify_mime"`        // reject files whose content doesn't match the declared MIME family
+	InboxLayout            string   `yaml:"inbox_layout"`             // "flat" or "by_date" (inbox/YYYY-MM-DD/)
 }
 
 // TLSConfig contains optional TLS settings.
 type TLSConfig struct {
-	Enabled  bool   `yaml:"enabled"`
-	CertFile string `yaml:"cert_file"`
-	KeyFile  string `yaml:"key_file"`
+	Enabled      bool     `yaml:"enabled"`
+	CertFile     string   `yaml:"cert_file"`
+	KeyFile      string   `yaml:"key_file"`
+	ClientCAFile string   `yaml:"client_ca_file"` // require client certs signed by these CAs (mTLS); empty disables
+	MinVersion   string   `yaml:"min_version"`    // 1.2 or 1.3
+	CipherSuites []string `yaml:"cipher_suites"`  // TLS 1.2 suite names (crypto/tls constants); empty = Go defaults
+}
+
+// MinTLSVersion returns the crypto/tls constant for MinVersion.
+func (t TLSConfig) MinTLSVersion() (uint16, error) {
+	switch t.MinVersion {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("bridge.tls.min_version must be 1.2 or 1.3")
+	}
+}
+
+// CipherSuiteIDs resolves CipherSuites to crypto/tls IDs. Only secure TLS 1.2
+// suites are accepted; TLS 1.3 suites are not configurable in Go.
+func (t TLSConfig) CipherSuiteIDs() ([]uint16, error) {
+	if len(t.CipherSuites) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]*tls.CipherSuite)
+	for _, cs := range tls.CipherSuites() {
+		known[cs.Name] = cs
+	}
+	ids := make([]uint16, 0, len(t.CipherSuites))
+	for _, name := range t.CipherSuites {
+		cs, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("bridge.tls.cipher_suites: unknown or insecure cipher suite %q", name)
+		}
+		if !slices.Contains(cs.SupportedVersions, tls.VersionTLS12) {
+			return nil, fmt.Errorf("bridge.tls.cipher_suites: %q is a TLS 1.3 suite and cannot be configured", name)
+		}
+		ids = append(ids, cs.ID)
+	}
+	return ids, nil
 }
 
 // SecurityConfig contains security-related settings.
 type SecurityConfig struct {
-	TailscaleOnly       bool            `yaml:"tailscale_only"`
-	AuthToken           string          `yaml:"auth_token"`
-	PublicPaths         []string        `yaml:"public_paths"`
-	RateLimit           RateLimitConfig `yaml:"rate_limit"`
-	MaxConnections      int             `yaml:"max_connections"`
-	MaxConnectionsPerIP int             `yaml:"max_connections_per_ip"`
+	TailscaleOnly          bool            `yaml:"tailscale_only"`
+	AuthToken              string          `yaml:"auth_token"`
+	AuthTokenCredential    string          `yaml:"auth_token_credential"`    // systemd credential name; token read from $CREDENTIALS_DIRECTORY/<name>
+	AllowQueryToken        bool            `yaml:"allow_query_token"`        // accept ?token= as a fallback to the Authorization header
+	JSONRejections         bool            `yaml:"json_rejections"`          // JSON reason bodies for rejected upgrades when the client Accepts application/json
+	ForbidRoot             bool            `yaml:"forbid_root"`              // refuse to start when running as root (effective UID 0)
+	RequireTailscaleListen bool            `yaml:"require_tailscale_listen"` // with tailscale_only, refuse to start unless listen_address is a Tailscale IP (otherwise warn)
+	PublicPaths            []string        `yaml:"public_paths"`
+	AllowedHosts           []string        `yaml:"allowed_hosts"` // Host header values the proxy answers to; others get 403 (empty = any)
+	RateLimit              RateLimitConfig `yaml:"rate_limit"`
+	MaxConnections         int             `yaml:"max_connections"`
+	MaxConnectionsPerIP    int             `yaml:"max_connections_per_ip"`
+	MaxConnectionsPerToken int             `yaml:"max_connections_per_token"` // 0 = unlimited
+
+	// MaxConnectionsBySubprotocol replaces max_connections and
+	// max_connections_per_ip for clients whose negotiated subprotocol is the
+	// key ("" = no subprotocol). Those clients are counted per class.
+	MaxConnectionsBySubprotocol map[string]ConnectionLimits `yaml:"max_connections_by_subprotocol"`
+
+	AuthzWebhook         string        `yaml:"authz_webhook"`           // URL POSTed on each WebSocket upgrade; empty disables
+	AuthzWebhookFailMode string        `yaml:"authz_webhook_fail_mode"` // closed (deny) or open (allow) when the webhook errors
+	AuthzWebhookTimeout  time.Duration `yaml:"authz_webhook_timeout"`
+	AuthzWebhookCacheTTL time.Duration `yaml:"authz_webhook_cache_ttl"` // decision cache per token+path; 0 disables
+}
+
+// CheckPrivileges returns an error if ForbidRoot is set and euid, the
+// process's effective user ID, is root.
+func (s SecurityConfig) CheckPrivileges(euid int) error {
+	if s.ForbidRoot && euid == 0 {
+		return fmt.Errorf("security.forbid_root is set and the bridge is running as root; run it as an unprivileged user")
+	}
+	return nil
+}
+
+// ConnectionLimits overrides the global connection limits for one class of
+// connections. Zero fields fall back to the global value.
+type ConnectionLimits struct {
+	MaxConnections      int `yaml:"max_connections"`
+	MaxConnectionsPerIP int `yaml:"max_connections_per_ip"`
+}
+
+// ConnectionLimitsFor returns the connection limits that apply to clients
+// negotiating subprotocol, and whether they come from an override.
+func (s SecurityConfig) ConnectionLimitsFor(subprotocol string) (maxConns, maxPerIP int, override bool) {
+	l, ok := s.MaxConnectionsBySubprotocol[subprotocol]
+	if !ok {
+		return s.MaxConnections, s.MaxConnectionsPerIP, false
+	}
+	maxConns, maxPerIP = l.MaxConnections, l.MaxConnectionsPerIP
+	if maxConns == 0 {
+		maxConns = s.MaxConnections
+	}
+	if maxPerIP == 0 {
+		maxPerIP = s.MaxConnectionsPerIP
+	}
+	return maxConns, maxPerIP, true
 }
 
 // RateLimitConfig contains rate limiting settings.
 type RateLimitConfig struct {
-	Enabled              bool `yaml:"enabled"`
-	ConnectionsPerMinute int  `yaml:"connections_per_minute"`
-	MessagesPerSecond    int  `yaml:"messages_per_second"`
+	Enabled              bool          `yaml:"enabled"`
+	ConnectionsPerMinute int           `yaml:"connections_per_minute"`
+	MessagesPerSecond    int           `yaml:"messages_per_second"`
+	Key                  string        `yaml:"key"`         // ip or cert (client certificate subject, requires mTLS)
+	GCInterval           time.Duration `yaml:"gc_interval"` // how often idle per-key limiters are evicted
+	IdleTTL              time.Duration `yaml:"idle_ttl"`    // evict a key's limiter once unseen this long
 }
 
 // LoggingConfig contains logging settings.
 type LoggingConfig struct {
-	Level      string `yaml:"level"`
-	Format     string `yaml:"format"`
-	File       string `yaml:"file"`
-	MaxSizeMB  int    `yaml:"max_size_mb"`
-	MaxBackups int    `yaml:"max_backups"`
-	MaxAgeDays int    `yaml:"max_age_days"`
-	Compress   bool   `yaml:"compress"`
+	Level          string `yaml:"level"`
+	Format         string `yaml:"format"`
+	File           string `yaml:"file"`
+	JSONMirrorFile string `yaml:"json_mirror_file"` // also write every record as JSON here; empty disables
+	MaxSizeMB      int    `yaml:"max_size_mb"`
+	MaxBackups     int    `yaml:"max_backups"`
+	MaxAgeDays     int    `yaml:"max_age_days"`
+	Compress       bool   `yaml:"compress"`
+
+	DebugSampleRate int `yaml:"debug_sample_rate"` // log 1 in N per-message debug lines (1 = all)
 }
 
 // HealthConfig contains health check endpoint settings.
 type HealthConfig struct {
-	Enabled       bool   `yaml:"enabled"`
-	Endpoint      string `yaml:"endpoint"`
-	ListenAddress string `yaml:"listen_address"`
-	Detailed      bool   `yaml:"detailed"`
+	Enabled       bool          `yaml:"enabled"`
+	Endpoint      string        `yaml:"endpoint"`
+	ReadyEndpoint string        `yaml:"ready_endpoint"` // 200 while serving, 503 from the start of shutdown; empty disables
+	ShutdownDelay time.Duration `yaml:"shutdown_delay"` // keep accepting this long after ready_endpoint turns 503, before the listener closes
+	ListenAddress string        `yaml:"listen_address"`
+	Detailed      bool          `yaml:"detailed"`
+	MemStatsTTL   time.Duration `yaml:"memstats_ttl"` // reuse the detailed memory figure for this long (0 = read on every request)
 }
 
 // MonitoringConfig contains metrics settings.
 type MonitoringConfig struct {
-	MetricsEnabled  bool   `yaml:"metrics_enabled"`
-	MetricsEndpoint string `yaml:"metrics_endpoint"`
+	MetricsEnabled   bool     `yaml:"metrics_enabled"`
+	MetricsEndpoint  string   `yaml:"metrics_endpoint"`
+	MetricsAuthToken string   `yaml:"metrics_auth_token"` // bearer token required on metrics_endpoint; empty = open
+	MethodMetrics    []string `yaml:"method_metrics"`     // req.method names counted individually; others count as "other"
+
+	StatsDAddress  string        `yaml:"statsd_address"`  // host:port; empty disables the StatsD push
+	StatsDInterval time.Duration `yaml:"statsd_interval"` // flush interval
+
+	MetricsFile         string        `yaml:"metrics_file"`          // path rewritten with all metrics in Prometheus text format; empty disables
+	MetricsFileInterval time.Duration `yaml:"metrics_file_interval"` // how often metrics_file is rewritten
+
+	ConnectionWarnThreshold     int `yaml:"connection_warn_threshold"`     // log a warning event when active connections reach this; 0 = off
+	ConnectionCriticalThreshold int `yaml:"connection_critical_threshold"` // log a critical event when active connections reach this; 0 = off
+}
+
+// WebUIConfig contains admin web UI settings.
+type WebUIConfig struct {
+	AuditFile    string   `yaml:"audit_file"`    // empty = in-memory audit log only
+	ReadOnly     bool     `yaml:"read_only"`     // reject mutating API requests (config changes, reloads, restarts)
+	LockedFields []string `yaml:"locked_fields"` // config PUT fields (e.g. max_connections) the web UI may not change
+}
+
+// webUILockableFields are the PUT /api/v1/config fields webui.locked_fields
+// may name.
+var webUILockableFields = []string{
+	"log_level", "max_connections", "max_connections_per_ip", "max_message_size",
+	"rate_limit_enabled", "connections_per_minute", "messages_per_second",
 }
 
 // DefaultConfig returns a Config with sensible defaults.
 func DefaultConfig() *Config {
 	return &Config{
 		Bridge: BridgeConfig{
-			ListenAddress:  "100.64.0.1:8080",
-			GatewayURL:     "http://localhost:18800",
-			Origin:         "https://gateway.local",
-			DrainTimeout:   30 * time.Second,
-			MaxMessageSize: 262144, // 256KB
-			PingInterval:   30 * time.Second,
-			PongTimeout:    10 * time.Second,
-			WriteTimeout:   30 * time.Second,
-			ReadTimeout:    60 * time.Second,
-			DialTimeout:    10 * time.Second,
+			ListenAddress:    "100.64.0.1:8080",
+			MaxHeaderBytes:   65536, // 64KB
+			GatewayURL:       "http://localhost:18800",
+			Origin:           OriginConfig{Default: "https://gateway.local"},
+			DrainTimeout:     30 * time.Second,
+			DrainOrder:       "all_at_once",
+			TransformTimeout: time.Second,
+			MaxMessageSize:   262144, // 256KB
+			PingInterval:     30 * time.Second,
+			PongTimeout:      10 * time.Second,
+			WriteTimeout:     30 * time.Second,
+			ReadTimeout:      60 * time.Second,
+			DialTimeout:      10 * time.Second,
+			HTTPProxyEnabled: true,
+			RequestIDHeader:  "X-Request-Id",
+			TLS: TLSConfig{
+				MinVersion: "1.2",
+			},
+			Compression: CompressionConfig{
+				Mode: "disabled",
+			},
+			AllowedExtensions: []string{"permessage-deflate"},
+			HTTPTransport: HTTPTransportConfig{
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: 10 * time.Second,
+			},
 			Media: MediaConfig{
 				Enabled:     false,
 				Directory:   "",
@@ -143,6 +564,13 @@ func DefaultConfig() *Config {
 				Extensions:  []string{".png", ".jpg", ".jpeg", ".webp", ".gif"},
 				InjectPaths: nil,
 				AllowedDirs: nil, // defaults to [Directory] if empty
+
+				Recompress:          false,
+				RecompressThreshold: 512 * 1024, // 512KB
+				RecompressQuality:   75,
+
+				InboxPolicy: "reject",
+				InboxLayout: "flat",
 			},
 			Reactions: ReactionConfig{
 				Enabled: false,
@@ -150,23 +578,39 @@ func DefaultConfig() *Config {
 			},
 			Canvas: CanvasConfig{
 				StateTracking:   false,
-				JSONLBufferSize: 5,
-				MaxAge:          5 * time.Minute,
+				JSONLBufferSize:   5,
+				MaxAge:            5 * time.Minute,
+				ReplayDedupWindow: 2 * time.Second,
 			},
 			Sync: SyncConfig{
-				Enabled:    false,
-				MaxHistory: 200,
+				Enabled:            false,
+				MaxHistory:         200,
+				MaxBroadcastFanout: 32,
+				BroadcastTimeout:   5 * time.Second,
+			},
+			Resume: ResumeConfig{
+				Enabled:        false,
+				Window:         10 * time.Second,
+				MaxBufferBytes: 1048576,
 			},
 		},
 		Security: SecurityConfig{
 			TailscaleOnly:       true,
+			AllowQueryToken:     true,
 			PublicPaths:         []string{"/__openclaw__/a2ui/"},
 			MaxConnections:      1000,
 			MaxConnectionsPerIP: 10,
+
+			AuthzWebhookFailMode: "closed",
+			AuthzWebhookTimeout:  2 * time.Second,
+			AuthzWebhookCacheTTL: 30 * time.Second,
 			RateLimit: RateLimitConfig{
 				Enabled:              true,
 				ConnectionsPerMinute: 60,
 				MessagesPerSecond:    100,
+				Key:                  "ip",
+				GCInterval:           time.Minute,
+				IdleTTL:              10 * time.Minute,
 			},
 		},
 		Logging: LoggingConfig{
@@ -176,16 +620,23 @@ func DefaultConfig() *Config {
 			MaxBackups: 3,
 			MaxAgeDays: 28,
 			Compress:   true,
+
+			DebugSampleRate: 1,
 		},
 		Health: HealthConfig{
 			Enabled:       true,
 			Endpoint:      "/health",
+			ReadyEndpoint: "/readyz",
+			ShutdownDelay: 5 * time.Second,
 			ListenAddress: "127.0.0.1:8081",
 			Detailed:      true,
+			MemStatsTTL:   time.Second,
 		},
 		Monitoring: MonitoringConfig{
-			MetricsEnabled:  false,
-			MetricsEndpoint: "/metrics",
+			MetricsEnabled:      false,
+			MetricsEndpoint:     "/metrics",
+			StatsDInterval:      10 * time.Second,
+			MetricsFileInterval: 15 * time.Second,
 		},
 	}
 }
@@ -205,6 +656,9 @@ func Load(path string) (*Config, error) {
 			}
 			return nil, fmt.Errorf("reading config file: %w", err)
 		}
+		if data, err = maybeGunzip(path, data); err != nil {
+			return nil, fmt.Errorf("decompressing config file %s: %w", path, err)
+		}
 		if err := yaml.Unmarshal(data, cfg); err != nil {
 			return nil, fmt.Errorf("parsing config file %s: %w (check YAML indentation)", path, err)
 		}
@@ -212,6 +666,10 @@ func Load(path string) (*Config, error) {
 
 	applyEnvOverrides(cfg)
 
+	if err := loadCredentials(cfg); err != nil {
+		return nil, err
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("validating config: %w", err)
 	}
@@ -219,6 +677,65 @@ func Load(path string) (*Config, error) {
 	return cfg, nil
 }
 
+// loadCredentials fills in secrets passed with systemd's LoadCredential=,
+// which exposes each one as a file under $CREDENTIALS_DIRECTORY. It runs on
+// every Load, so a reload picks up a rotated credential.
+func loadCredentials(cfg *Config) error {
+	name := cfg.Security.AuthTokenCredential
+	if name == "" {
+		return nil
+	}
+	if cfg.Security.AuthToken != "" {
+		return fmt.Errorf("security.auth_token and security.auth_token_credential are mutually exclusive")
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("security.auth_token_credential must be a credential name, not a path")
+	}
+	dir := os.Getenv("CREDENTIALS_DIRECTORY")
+	if dir == "" {
+		return fmt.Errorf("security.auth_token_credential is set but $CREDENTIALS_DIRECTORY is not (add LoadCredential=%s:<file> to the systemd unit)", name)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return fmt.Errorf("reading credential %s: %w", name, err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return fmt.Errorf("credential %s is empty", name)
+	}
+	cfg.Security.AuthToken = token
+	return nil
+}
+
+// ErrFileMissing is returned by Reload when the config file no longer exists.
+var ErrFileMissing = errors.New("config file disappeared")
+
+// Reload is Load for a running bridge. A config file that has been deleted
+// since startup is reported as ErrFileMissing instead of the setup hint Load
+// gives on first run.
+func Reload(path string) (*Config, error) {
+	if path != "" {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrFileMissing, path)
+		}
+	}
+	return Load(path)
+}
+
+// maybeGunzip decompresses data if it starts with the gzip magic bytes or the
+// path ends in .gz; plain YAML is returned unchanged.
+func maybeGunzip(path string, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) && !strings.HasSuffix(path, ".gz") {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
 // Validate checks the configuration for errors.
 func (c *Config) Validate() error {
 	// Bridge validation
@@ -228,6 +745,12 @@ func (c *Config) Validate() error {
 	if _, _, err := net.SplitHostPort(c.Bridge.ListenAddress); err != nil {
 		return fmt.Errorf("bridge.listen_address is invalid: %w", err)
 	}
+	if c.Bridge.ListenBacklog < 0 || c.Bridge.ListenBacklog > 65535 {
+		return fmt.Errorf("bridge.listen_backlog must be between 0 and 65535")
+	}
+	if c.Bridge.MaxHeaderBytes < 4096 || c.Bridge.MaxHeaderBytes > 1048576 {
+		return fmt.Errorf("bridge.max_header_bytes must be between 4096 and 1048576 (1MB)")
+	}
 	if c.Bridge.GatewayURL == "" {
 		return fmt.Errorf("bridge.gateway_url is required")
 	}
@@ -240,8 +763,39 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("bridge.gateway_url should point to localhost or a private IP, got %s", host)
 		}
 	}
-	if c.Bridge.Origin == "" {
-		return fmt.Errorf("bridge.origin is required")
+	for _, sp := range c.Bridge.GatewaySubprotocols {
+		if sp == "" || strings.ContainsAny(sp, " ,") {
+			return fmt.Errorf("bridge.gateway_subprotocols entry %q must be a non-empty token without spaces or commas", sp)
+		}
+	}
+	for _, ext := range c.Bridge.AllowedExtensions {
+		if !slices.Contains(SupportedExtensions, ext) {
+			return fmt.Errorf("bridge.allowed_extensions entry %q is not supported (supported: %s)", ext, strings.Join(SupportedExtensions, ", "))
+		}
+	}
+	if sp := c.Bridge.DefaultSubprotocol; sp != "" {
+		if strings.ContainsAny(sp, " ,") {
+			return fmt.Errorf("bridge.default_subprotocol %q must be a token without spaces or commas", sp)
+		}
+		if len(c.Bridge.AllowedSubprotocols) > 0 && !slices.Contains(c.Bridge.AllowedSubprotocols, sp) {
+			return fmt.Errorf("bridge.default_subprotocol %q must be one of bridge.allowed_subprotocols", sp)
+		}
+	}
+	if c.Bridge.HistoryGatewayURL != "" {
+		if u, err := url.Parse(c.Bridge.HistoryGatewayURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("bridge.history_gateway_url must be an http:// or https:// URL")
+		}
+	}
+	if c.Bridge.Origin.Default == "" {
+		return fmt.Errorf("bridge.origin is required (map form needs a \"/\" entry)")
+	}
+	for prefix, origin := range c.Bridge.Origin.Routes {
+		if !strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("bridge.origin route %q must start with /", prefix)
+		}
+		if origin == "" {
+			return fmt.Errorf("bridge.origin route %q must not be empty", prefix)
+		}
 	}
 	if c.Bridge.MaxMessageSize <= 0 {
 		return fmt.Errorf("bridge.max_message_size must be positive")
@@ -255,6 +809,9 @@ func (c *Config) Validate() error {
 	if c.Bridge.ReadTimeout <= 0 {
 		return fmt.Errorf("bridge.read_timeout must be positive")
 	}
+	if c.Bridge.MaxConcurrentDials < 0 {
+		return fmt.Errorf("bridge.max_concurrent_dials must not be negative")
+	}
 	if c.Bridge.DialTimeout <= 0 {
 		return fmt.Errorf("bridge.dial_timeout must be positive")
 	}
@@ -263,18 +820,104 @@ func (c *Config) Validate() error {
 	if c.Bridge.MaxMessageSize > 67108864 {
 		return fmt.Errorf("bridge.max_message_size must not exceed 67108864 (64MB)")
 	}
+	if c.Bridge.MaxMessageSizeUpstream < 0 || c.Bridge.MaxMessageSizeUpstream > 67108864 {
+		return fmt.Errorf("bridge.max_message_size_upstream must be between 0 and 67108864 (64MB)")
+	}
+	if c.Bridge.MaxMessageSizeDownstream < 0 || c.Bridge.MaxMessageSizeDownstream > 67108864 {
+		return fmt.Errorf("bridge.max_message_size_downstream must be between 0 and 67108864 (64MB)")
+	}
+	if hw := c.Bridge.ClientBufferHighWater; hw != 0 && (hw < c.Bridge.DownstreamMessageLimit() || hw > 1<<30) {
+		return fmt.Errorf("bridge.client_buffer_high_water must be 0 or between the downstream message size limit (%d) and 1073741824 (1GB)", c.Bridge.DownstreamMessageLimit())
+	}
 	if c.Bridge.DrainTimeout > 5*time.Minute {
 		return fmt.Errorf("bridge.drain_timeout must not exceed 5m")
 	}
+	switch c.Bridge.DrainOrder {
+	case "all_at_once", "oldest_first", "newest_first":
+	default:
+		return fmt.Errorf("bridge.drain_order must be all_at_once, oldest_first, or newest_first")
+	}
+	if p := c.Bridge.StripPathPrefix; p != "" && (!strings.HasPrefix(p, "/") || strings.HasSuffix(p, "/")) {
+		return fmt.Errorf("bridge.strip_path_prefix must start with / and not end with / (e.g. /bridge)")
+	}
+	if c.Bridge.FlushInterval > 10*time.Second {
+		return fmt.Errorf("bridge.flush_interval must be at most 10s (negative flushes after every write)")
+	}
+	if len(c.Bridge.TransformCommand) > 0 {
+		if !filepath.IsAbs(c.Bridge.TransformCommand[0]) {
+			return fmt.Errorf("bridge.transform_command must start with an absolute path to the executable")
+		}
+		if c.Bridge.TransformTimeout < 10*time.Millisecond || c.Bridge.TransformTimeout > 30*time.Second {
+			return fmt.Errorf("bridge.transform_timeout must be between 10ms and 30s")
+		}
+	}
+	if t := c.Bridge.HTTPTransport; t.MaxIdleConns < 0 || t.MaxIdleConns > 10000 {
+		return fmt.Errorf("bridge.http_transport.max_idle_conns must be between 0 and 10000")
+	}
+	if t := c.Bridge.HTTPTransport.IdleConnTimeout; t < time.Second || t > time.Hour {
+		return fmt.Errorf("bridge.http_transport.idle_conn_timeout must be between 1s and 1h")
+	}
+	if t := c.Bridge.HTTPTransport.TLSHandshakeTimeout; t < time.Second || t > 5*time.Minute {
+		return fmt.Errorf("bridge.http_transport.tls_handshake_timeout must be between 1s and 5m")
+	}
+	if c.Bridge.GatewayResolveTTL < 0 || c.Bridge.GatewayResolveTTL > time.Hour {
+		return fmt.Errorf("bridge.gateway_resolve_ttl must be between 0 and 1h")
+	}
+	if o := c.Bridge.GatewayResolveOverride; o != "" && net.ParseIP(o) == nil {
+		return fmt.Errorf("bridge.gateway_resolve_override must be an IP address")
+	}
+	if c.Bridge.DrainWebhook != "" {
+		if u, err := url.Parse(c.Bridge.DrainWebhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("bridge.drain_webhook must be an http:// or https:// URL")
+		}
+	}
+	if _, err := c.Bridge.MaintenanceWindows(); err != nil {
+		return err
+	}
+	switch c.Bridge.Compression.Mode {
+	case "disabled", "context_takeover", "no_context_takeover":
+	default:
+		return fmt.Errorf("bridge.compression.mode must be disabled, context_takeover, or no_context_takeover")
+	}
+	if c.Bridge.Compression.Threshold < 0 {
+		return fmt.Errorf("bridge.compression.threshold must not be negative")
+	}
+	if int64(c.Bridge.Compression.Threshold) > c.Bridge.MaxMessageSize {
+		return fmt.Errorf("bridge.compression.threshold must not exceed bridge.max_message_size")
+	}
 	if c.Bridge.WriteTimeout > 5*time.Minute {
 		return fmt.Errorf("bridge.write_timeout must not exceed 5m")
 	}
+	for prefix, d := range c.Bridge.WriteTimeoutByPath {
+		if !strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("bridge.write_timeout_by_path key %q must start with /", prefix)
+		}
+		if d <= 0 || d > 5*time.Minute {
+			return fmt.Errorf("bridge.write_timeout_by_path[%q] must be positive and not exceed 5m", prefix)
+		}
+	}
+	for from, to := range c.Bridge.CloseCodeMap {
+		if !ValidCloseCode(from) || !ValidCloseCode(to) {
+			return fmt.Errorf("bridge.close_code_map[%d] = %d: both codes must be valid close frame codes (1000-1003, 1007-1014 or 3000-4999)", from, to)
+		}
+	}
 	if c.Bridge.ReadTimeout > 5*time.Minute {
 		return fmt.Errorf("bridge.read_timeout must not exceed 5m")
 	}
 	if c.Bridge.DialTimeout > 5*time.Minute {
 		return fmt.Errorf("bridge.dial_timeout must not exceed 5m")
 	}
+	if c.Bridge.GatewayPoolSize < 0 || c.Bridge.GatewayPoolSize > 64 {
+		return fmt.Errorf("bridge.gateway_pool_size must be between 0 and 64")
+	}
+	if h := c.Bridge.ClientIPHeader; h != "" {
+		if strings.ContainsAny(h, " \t\r\n:") {
+			return fmt.Errorf("bridge.client_ip_header must be a header name, e.g. X-Forwarded-For")
+		}
+		if c.Bridge.GatewayPoolSize > 0 {
+			return fmt.Errorf("bridge.client_ip_header cannot be combined with bridge.gateway_pool_size (pooled connections are dialed before the client is known)")
+		}
+	}
 
 	// Listen address safety check
 	if c.Security.TailscaleOnly {
@@ -293,6 +936,20 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("bridge.tls.key_file is required when TLS is enabled")
 		}
 	}
+	if c.Bridge.TLS.Enabled {
+		if _, err := c.Bridge.TLS.MinTLSVersion(); err != nil {
+			return err
+		}
+		if _, err := c.Bridge.TLS.CipherSuiteIDs(); err != nil {
+			return err
+		}
+		if c.Bridge.TLS.MinVersion == "1.3" && len(c.Bridge.TLS.CipherSuites) > 0 {
+			return fmt.Errorf("bridge.tls.cipher_suites has no effect with min_version 1.3")
+		}
+	}
+	if c.Bridge.TLS.ClientCAFile != "" && !c.Bridge.TLS.Enabled {
+		return fmt.Errorf("bridge.tls.client_ca_file requires bridge.tls.enabled")
+	}
 
 	// Security validation
 	if c.Security.MaxConnections <= 0 {
@@ -307,10 +964,60 @@ func (c *Config) Validate() error {
 	if c.Security.MaxConnectionsPerIP > c.Security.MaxConnections {
 		return fmt.Errorf("security.max_connections_per_ip must not exceed security.max_connections")
 	}
+	if c.Security.MaxConnectionsPerToken < 0 {
+		return fmt.Errorf("security.max_connections_per_token must not be negative")
+	}
+	for sp, l := range c.Security.MaxConnectionsBySubprotocol {
+		if l.MaxConnections < 0 || l.MaxConnections > 65535 {
+			return fmt.Errorf("security.max_connections_by_subprotocol[%q].max_connections must be between 0 and 65535", sp)
+		}
+		if l.MaxConnectionsPerIP < 0 {
+			return fmt.Errorf("security.max_connections_by_subprotocol[%q].max_connections_per_ip must not be negative", sp)
+		}
+	}
+	for _, h := range c.Security.AllowedHosts {
+		if strings.TrimSpace(h) == "" || strings.ContainsAny(h, "/ \t") {
+			return fmt.Errorf("security.allowed_hosts entries must be hostnames or IPs, optionally with :port (got %q)", h)
+		}
+	}
+	if c.Security.AuthzWebhook != "" {
+		u, err := url.Parse(c.Security.AuthzWebhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("security.authz_webhook must be an http:// or https:// URL")
+		}
+		switch c.Security.AuthzWebhookFailMode {
+		case "closed", "open":
+		default:
+			return fmt.Errorf("security.authz_webhook_fail_mode must be closed or open")
+		}
+		if c.Security.AuthzWebhookTimeout <= 0 || c.Security.AuthzWebhookTimeout > 30*time.Second {
+			return fmt.Errorf("security.authz_webhook_timeout must be between 0 and 30s")
+		}
+		if c.Security.AuthzWebhookCacheTTL < 0 {
+			return fmt.Errorf("security.authz_webhook_cache_ttl must not be negative")
+		}
+	}
 	if c.Security.RateLimit.Enabled {
 		if c.Security.RateLimit.ConnectionsPerMinute <= 0 {
 			return fmt.Errorf("security.rate_limit.connections_per_minute must be positive")
 		}
+		// A limiter evicted before its bucket refills (one minute at most)
+		// would let that key start over with a full burst.
+		if c.Security.RateLimit.IdleTTL < time.Minute {
+			return fmt.Errorf("security.rate_limit.idle_ttl must be at least 1m")
+		}
+		if c.Security.RateLimit.GCInterval < time.Second || c.Security.RateLimit.GCInterval > c.Security.RateLimit.IdleTTL {
+			return fmt.Errorf("security.rate_limit.gc_interval must be between 1s and idle_ttl")
+		}
+	}
+	switch c.Security.RateLimit.Key {
+	case "ip":
+	case "cert":
+		if c.Bridge.TLS.ClientCAFile == "" {
+			return fmt.Errorf("security.rate_limit.key cert requires bridge.tls.client_ca_file (mTLS)")
+		}
+	default:
+		return fmt.Errorf("security.rate_limit.key must be ip or cert")
 	}
 
 	// Logging validation
@@ -326,6 +1033,66 @@ func (c *Config) Validate() error {
 	default:
 		return fmt.Errorf("logging.format must be one of: json, text")
 	}
+	if c.Logging.JSONMirrorFile != "" && c.Logging.JSONMirrorFile == c.Logging.File {
+		return fmt.Errorf("logging.json_mirror_file must differ from logging.file")
+	}
+	if c.Logging.DebugSampleRate < 1 {
+		return fmt.Errorf("logging.debug_sample_rate must be at least 1")
+	}
+
+	// Media validation
+	if c.Bridge.Media.Enabled && c.Bridge.Media.Recompress {
+		if c.Bridge.Media.RecompressQuality < 1 || c.Bridge.Media.RecompressQuality > 100 {
+			return fmt.Errorf("bridge.media.recompress_quality must be between 1 and 100")
+		}
+		if c.Bridge.Media.RecompressThreshold < 0 {
+			return fmt.Errorf("bridge.media.recompress_threshold must not be negative")
+		}
+	}
+	if c.Bridge.Media.Enabled {
+		if c.Bridge.Media.MaxConcurrentInjections < 0 {
+			return fmt.Errorf("bridge.media.max_concurrent_injections must not be negative")
+		}
+		if c.Bridge.Media.InboxMaxBytes < 0 {
+			return fmt.Errorf("bridge.media.inbox_max_bytes must not be negative")
+		}
+		if c.Bridge.Media.InboxMaxFiles < 0 {
+			return fmt.Errorf("bridge.media.inbox_max_files must not be negative")
+		}
+		if c.Bridge.Media.InboxPolicy != "reject" && c.Bridge.Media.InboxPolicy != "evict_oldest" {
+			return fmt.Errorf("bridge.media.inbox_policy must be \"reject\" or \"evict_oldest\"")
+		}
+		if c.Bridge.Media.InboxLayout != "flat" && c.Bridge.Media.InboxLayout != "by_date" {
+			return fmt.Errorf("bridge.media.inbox_layout must be \"flat\" or \"by_date\"")
+		}
+	}
+
+	if c.Monitoring.StatsDAddress != "" {
+		if _, _, err := net.SplitHostPort(c.Monitoring.StatsDAddress); err != nil {
+			return fmt.Errorf("monitoring.statsd_address must be host:port: %w", err)
+		}
+		if c.Monitoring.StatsDInterval < time.Second {
+			return fmt.Errorf("monitoring.statsd_interval must be at least 1s")
+		}
+	}
+	if c.Monitoring.MetricsFile != "" && c.Monitoring.MetricsFileInterval < time.Second {
+		return fmt.Errorf("monitoring.metrics_file_interval must be at least 1s")
+	}
+	for _, m := range c.Monitoring.MethodMetrics {
+		if strings.TrimSpace(m) == "" || m == "other" {
+			return fmt.Errorf("monitoring.method_metrics entries must be non-empty method names other than \"other\"")
+		}
+	}
+	if c.Monitoring.ConnectionWarnThreshold < 0 {
+		return fmt.Errorf("monitoring.connection_warn_threshold must be non-negative")
+	}
+	if c.Monitoring.ConnectionCriticalThreshold < 0 {
+		return fmt.Errorf("monitoring.connection_critical_threshold must be non-negative")
+	}
+	if c.Monitoring.ConnectionWarnThreshold > 0 && c.Monitoring.ConnectionCriticalThreshold > 0 &&
+		c.Monitoring.ConnectionCriticalThreshold <= c.Monitoring.ConnectionWarnThreshold {
+		return fmt.Errorf("monitoring.connection_critical_threshold must be greater than monitoring.connection_warn_threshold")
+	}
 
 	// Reactions validation
 	if c.Bridge.Reactions.Enabled {
@@ -347,6 +1114,12 @@ func (c *Config) Validate() error {
 		if c.Bridge.Canvas.MaxAge < time.Second || c.Bridge.Canvas.MaxAge > 30*time.Minute {
 			return fmt.Errorf("bridge.canvas.max_age must be between 1s and 30m")
 		}
+		if c.Bridge.Canvas.ReplayDedupWindow < 0 || c.Bridge.Canvas.ReplayDedupWindow > time.Minute {
+			return fmt.Errorf("bridge.canvas.replay_dedup_window must be between 0 and 1m")
+		}
+		if c.Bridge.Canvas.MaxJSONLBytes != 0 && (c.Bridge.Canvas.MaxJSONLBytes < 1024 || c.Bridge.Canvas.MaxJSONLBytes > 64<<20) {
+			return fmt.Errorf("bridge.canvas.max_jsonl_bytes must be 0 (no limit) or between 1024 and 67108864 (64MB)")
+		}
 	}
 
 	// Sync validation
@@ -354,6 +1127,24 @@ func (c *Config) Validate() error {
 		if c.Bridge.Sync.MaxHistory < 10 || c.Bridge.Sync.MaxHistory > 10000 {
 			return fmt.Errorf("bridge.sync.max_history must be between 10 and 10000")
 		}
+		if c.Bridge.Sync.MaxBroadcastFanout < 0 {
+			return fmt.Errorf("bridge.sync.max_broadcast_fanout must not be negative")
+		}
+		if c.Bridge.Sync.BroadcastTimeout <= 0 {
+			return fmt.Errorf("bridge.sync.broadcast_timeout must be positive")
+		}
+		if c.Bridge.Sync.MaxHistoryResponseSize < 0 {
+			return fmt.Errorf("bridge.sync.max_history_response_size must not be negative")
+		}
+	}
+
+	if c.Bridge.Resume.Enabled {
+		if c.Bridge.Resume.Window < time.Second || c.Bridge.Resume.Window > 5*time.Minute {
+			return fmt.Errorf("bridge.resume.window must be between 1s and 5m")
+		}
+		if c.Bridge.Resume.MaxBufferBytes < 1024 || c.Bridge.Resume.MaxBufferBytes > 67108864 {
+			return fmt.Errorf("bridge.resume.max_buffer_bytes must be between 1024 and 67108864 (64MB)")
+		}
 	}
 
 	// Health validation
@@ -372,51 +1163,215 @@ func (c *Config) Validate() error {
 		if c.Bridge.ListenAddress == c.Health.ListenAddress {
 			return fmt.Errorf("bridge.listen_address and health.listen_address must be different")
 		}
+		if c.Health.MemStatsTTL < 0 || c.Health.MemStatsTTL > time.Minute {
+			return fmt.Errorf("health.memstats_ttl must be between 0 and 1m")
+		}
+		if c.Health.ShutdownDelay < 0 || c.Health.ShutdownDelay > time.Minute {
+			return fmt.Errorf("health.shutdown_delay must be between 0 and 1m")
+		}
+		if r := c.Health.ReadyEndpoint; r != "" {
+			if !strings.HasPrefix(r, "/") {
+				return fmt.Errorf("health.ready_endpoint must start with /")
+			}
+			if r == c.Health.Endpoint || (c.Monitoring.MetricsEnabled && r == c.Monitoring.MetricsEndpoint) {
+				return fmt.Errorf("health.ready_endpoint must differ from health.endpoint and monitoring.metrics_endpoint")
+			}
+		}
+	}
+
+	for _, f := range c.WebUI.LockedFields {
+		if !slices.Contains(webUILockableFields, f) {
+			return fmt.Errorf("webui.locked_fields: unknown field %q (must be one of %s)", f, strings.Join(webUILockableFields, ", "))
+		}
 	}
 
 	return nil
 }
 
+// Warnings returns non-fatal configuration issues worth logging at startup.
+func (c *Config) Warnings() []string {
+	var warnings []string
+	if !c.Bridge.HTTPProxyEnabled && c.Bridge.Canvas.A2UIURL != "" {
+		a2uiPath := "/"
+		if u, err := url.Parse(c.Bridge.Canvas.A2UIURL); err == nil && u.Path != "" {
+			a2uiPath = u.Path
+		}
+		public := false
+		for _, prefix := range c.Security.PublicPaths {
+			if strings.HasPrefix(a2uiPath, prefix) {
+				public = true
+				break
+			}
+		}
+		if !public {
+			warnings = append(warnings, "bridge.canvas.a2ui_url is served through the HTTP reverse proxy, which is disabled (bridge.http_proxy_enabled: false) and its path is not in security.public_paths")
+		}
+	}
+	if len(c.Bridge.TransformCommand) > 0 {
+		warnings = append(warnings, fmt.Sprintf("bridge.transform_command runs %s on every text message; it sees all traffic (including auth tokens) and its output is forwarded as-is", c.Bridge.TransformCommand[0]))
+	}
+	return warnings
+}
+
 // applyEnvOverrides applies CLAWREACH_ prefixed environment variables.
 // Convention: CLAWREACH_ + uppercase + underscores for nesting.
 func applyEnvOverrides(cfg *Config) {
 	envMap := map[string]func(string){
 		"CLAWREACH_BRIDGE_LISTEN_ADDRESS":           func(v string) { cfg.Bridge.ListenAddress = v },
 		"CLAWREACH_BRIDGE_GATEWAY_URL":              func(v string) { cfg.Bridge.GatewayURL = v },
-		"CLAWREACH_BRIDGE_ORIGIN":                   func(v string) { cfg.Bridge.Origin = v },
+		"CLAWREACH_BRIDGE_MAX_CONCURRENT_DIALS": func(v string) {
+			cfg.Bridge.MaxConcurrentDials = parseInt(v, cfg.Bridge.MaxConcurrentDials)
+		},
+		"CLAWREACH_BRIDGE_DRAIN_WEBHOOK":            func(v string) { cfg.Bridge.DrainWebhook = v },
+		"CLAWREACH_BRIDGE_MAINTENANCE_SCHEDULE": func(v string) {
+			cfg.Bridge.MaintenanceSchedule = parseList(v)
+		},
+		"CLAWREACH_BRIDGE_HISTORY_GATEWAY_URL":      func(v string) { cfg.Bridge.HistoryGatewayURL = v },
+		"CLAWREACH_BRIDGE_ORIGIN":                   func(v string) { cfg.Bridge.Origin.Default = v },
+		"CLAWREACH_BRIDGE_DEFAULT_SUBPROTOCOL":      func(v string) { cfg.Bridge.DefaultSubprotocol = v },
 		"CLAWREACH_BRIDGE_DRAIN_TIMEOUT":            func(v string) { cfg.Bridge.DrainTimeout = parseDuration(v, cfg.Bridge.DrainTimeout) },
+		"CLAWREACH_BRIDGE_DRAIN_ORDER":              func(v string) { cfg.Bridge.DrainOrder = v },
+		"CLAWREACH_BRIDGE_BAD_GATEWAY_PAGE":         func(v string) { cfg.Bridge.BadGatewayPage = v },
+		"CLAWREACH_BRIDGE_STRIP_PATH_PREFIX":        func(v string) { cfg.Bridge.StripPathPrefix = v },
+		"CLAWREACH_BRIDGE_FLUSH_INTERVAL":           func(v string) { cfg.Bridge.FlushInterval = parseDuration(v, cfg.Bridge.FlushInterval) },
+		"CLAWREACH_BRIDGE_GATEWAY_RESOLVE_TTL": func(v string) {
+			cfg.Bridge.GatewayResolveTTL = parseDuration(v, cfg.Bridge.GatewayResolveTTL)
+		},
+		"CLAWREACH_BRIDGE_GATEWAY_RESOLVE_OVERRIDE": func(v string) { cfg.Bridge.GatewayResolveOverride = v },
+		"CLAWREACH_BRIDGE_HTTP_TRANSPORT_MAX_IDLE_CONNS": func(v string) {
+			cfg.Bridge.HTTPTransport.MaxIdleConns = parseInt(v, cfg.Bridge.HTTPTransport.MaxIdleConns)
+		},
+		"CLAWREACH_BRIDGE_HTTP_TRANSPORT_IDLE_CONN_TIMEOUT": func(v string) {
+			cfg.Bridge.HTTPTransport.IdleConnTimeout = parseDuration(v, cfg.Bridge.HTTPTransport.IdleConnTimeout)
+		},
+		"CLAWREACH_BRIDGE_HTTP_TRANSPORT_TLS_HANDSHAKE_TIMEOUT": func(v string) {
+			cfg.Bridge.HTTPTransport.TLSHandshakeTimeout = parseDuration(v, cfg.Bridge.HTTPTransport.TLSHandshakeTimeout)
+		},
+		"CLAWREACH_BRIDGE_TRANSFORM_COMMAND": func(v string) {
+			cfg.Bridge.TransformCommand = strings.Fields(v)
+		},
+		"CLAWREACH_BRIDGE_TRANSFORM_TIMEOUT": func(v string) {
+			cfg.Bridge.TransformTimeout = parseDuration(v, cfg.Bridge.TransformTimeout)
+		},
+		"CLAWREACH_BRIDGE_ALLOWED_EXTENSIONS": func(v string) {
+			cfg.Bridge.AllowedExtensions = parseList(v)
+		},
+		"CLAWREACH_BRIDGE_STRIP_RESPONSE_HEADERS": func(v string) {
+			cfg.Bridge.StripResponseHeaders = parseList(v)
+		},
+		"CLAWREACH_BRIDGE_COMPRESSION_MODE":         func(v string) { cfg.Bridge.Compression.Mode = v },
+		"CLAWREACH_BRIDGE_COMPRESSION_THRESHOLD":    func(v string) { cfg.Bridge.Compression.Threshold = parseInt(v, cfg.Bridge.Compression.Threshold) },
+		"CLAWREACH_BRIDGE_LISTEN_BACKLOG": func(v string) { cfg.Bridge.ListenBacklog = parseInt(v, cfg.Bridge.ListenBacklog) },
+		"CLAWREACH_BRIDGE_REUSE_PORT":     func(v string) { cfg.Bridge.ReusePort = parseBool(v, cfg.Bridge.ReusePort) },
+		"CLAWREACH_BRIDGE_MAX_HEADER_BYTES": func(v string) { cfg.Bridge.MaxHeaderBytes = parseInt(v, cfg.Bridge.MaxHeaderBytes) },
 		"CLAWREACH_BRIDGE_MAX_MESSAGE_SIZE":         func(v string) { cfg.Bridge.MaxMessageSize = parseInt64(v, cfg.Bridge.MaxMessageSize) },
+		"CLAWREACH_BRIDGE_MAX_MESSAGE_SIZE_UPSTREAM": func(v string) {
+			cfg.Bridge.MaxMessageSizeUpstream = parseInt64(v, cfg.Bridge.MaxMessageSizeUpstream)
+		},
+		"CLAWREACH_BRIDGE_CLIENT_BUFFER_HIGH_WATER": func(v string) {
+			cfg.Bridge.ClientBufferHighWater = parseInt64(v, cfg.Bridge.ClientBufferHighWater)
+		},
+		"CLAWREACH_BRIDGE_MAX_MESSAGE_SIZE_DOWNSTREAM": func(v string) {
+			cfg.Bridge.MaxMessageSizeDownstream = parseInt64(v, cfg.Bridge.MaxMessageSizeDownstream)
+		},
 		"CLAWREACH_BRIDGE_PING_INTERVAL":            func(v string) { cfg.Bridge.PingInterval = parseDuration(v, cfg.Bridge.PingInterval) },
 		"CLAWREACH_BRIDGE_PONG_TIMEOUT":             func(v string) { cfg.Bridge.PongTimeout = parseDuration(v, cfg.Bridge.PongTimeout) },
 		"CLAWREACH_BRIDGE_WRITE_TIMEOUT":            func(v string) { cfg.Bridge.WriteTimeout = parseDuration(v, cfg.Bridge.WriteTimeout) },
 		"CLAWREACH_BRIDGE_READ_TIMEOUT":             func(v string) { cfg.Bridge.ReadTimeout = parseDuration(v, cfg.Bridge.ReadTimeout) },
+		"CLAWREACH_BRIDGE_CLIENT_IP_HEADER":         func(v string) { cfg.Bridge.ClientIPHeader = v },
+		"CLAWREACH_BRIDGE_GATEWAY_POOL_SIZE":        func(v string) { cfg.Bridge.GatewayPoolSize = parseInt(v, cfg.Bridge.GatewayPoolSize) },
 		"CLAWREACH_BRIDGE_DIAL_TIMEOUT":             func(v string) { cfg.Bridge.DialTimeout = parseDuration(v, cfg.Bridge.DialTimeout) },
 		"CLAWREACH_SECURITY_TAILSCALE_ONLY":         func(v string) { cfg.Security.TailscaleOnly = parseBool(v, cfg.Security.TailscaleOnly) },
 		"CLAWREACH_SECURITY_AUTH_TOKEN":             func(v string) { cfg.Security.AuthToken = v },
+		"CLAWREACH_SECURITY_AUTH_TOKEN_CREDENTIAL": func(v string) { cfg.Security.AuthTokenCredential = v },
+		"CLAWREACH_SECURITY_ALLOW_QUERY_TOKEN":      func(v string) { cfg.Security.AllowQueryToken = parseBool(v, cfg.Security.AllowQueryToken) },
+		"CLAWREACH_SECURITY_JSON_REJECTIONS":        func(v string) { cfg.Security.JSONRejections = parseBool(v, cfg.Security.JSONRejections) },
+		"CLAWREACH_SECURITY_FORBID_ROOT":            func(v string) { cfg.Security.ForbidRoot = parseBool(v, cfg.Security.ForbidRoot) },
+		"CLAWREACH_SECURITY_REQUIRE_TAILSCALE_LISTEN": func(v string) {
+			cfg.Security.RequireTailscaleListen = parseBool(v, cfg.Security.RequireTailscaleListen)
+		},
 		"CLAWREACH_SECURITY_PUBLIC_PATHS": func(v string) {
-			cfg.Security.PublicPaths = strings.Split(v, ",")
+			cfg.Security.PublicPaths = parseList(v)
+		},
+		"CLAWREACH_SECURITY_ALLOWED_HOSTS": func(v string) {
+			cfg.Security.AllowedHosts = parseList(v)
 		},
 		"CLAWREACH_SECURITY_MAX_CONNECTIONS":        func(v string) { cfg.Security.MaxConnections = parseInt(v, cfg.Security.MaxConnections) },
 		"CLAWREACH_SECURITY_MAX_CONNECTIONS_PER_IP": func(v string) { cfg.Security.MaxConnectionsPerIP = parseInt(v, cfg.Security.MaxConnectionsPerIP) },
+		"CLAWREACH_SECURITY_MAX_CONNECTIONS_PER_TOKEN": func(v string) {
+			cfg.Security.MaxConnectionsPerToken = parseInt(v, cfg.Security.MaxConnectionsPerToken)
+		},
+		"CLAWREACH_SECURITY_AUTHZ_WEBHOOK":          func(v string) { cfg.Security.AuthzWebhook = v },
+		"CLAWREACH_SECURITY_AUTHZ_WEBHOOK_FAIL_MODE": func(v string) { cfg.Security.AuthzWebhookFailMode = v },
 		"CLAWREACH_SECURITY_RATE_LIMIT_ENABLED":     func(v string) { cfg.Security.RateLimit.Enabled = parseBool(v, cfg.Security.RateLimit.Enabled) },
 		"CLAWREACH_SECURITY_RATE_LIMIT_CONNECTIONS_PER_MINUTE": func(v string) {
 			cfg.Security.RateLimit.ConnectionsPerMinute = parseInt(v, cfg.Security.RateLimit.ConnectionsPerMinute)
 		},
+		"CLAWREACH_SECURITY_RATE_LIMIT_KEY":         func(v string) { cfg.Security.RateLimit.Key = v },
+		"CLAWREACH_SECURITY_RATE_LIMIT_GC_INTERVAL": func(v string) {
+			cfg.Security.RateLimit.GCInterval = parseDuration(v, cfg.Security.RateLimit.GCInterval)
+		},
+		"CLAWREACH_SECURITY_RATE_LIMIT_IDLE_TTL": func(v string) {
+			cfg.Security.RateLimit.IdleTTL = parseDuration(v, cfg.Security.RateLimit.IdleTTL)
+		},
 		"CLAWREACH_LOGGING_LEVEL":         func(v string) { cfg.Logging.Level = v },
 		"CLAWREACH_LOGGING_FORMAT":        func(v string) { cfg.Logging.Format = v },
 		"CLAWREACH_LOGGING_FILE":          func(v string) { cfg.Logging.File = v },
+		"CLAWREACH_LOGGING_JSON_MIRROR_FILE": func(v string) { cfg.Logging.JSONMirrorFile = v },
+		"CLAWREACH_LOGGING_DEBUG_SAMPLE_RATE": func(v string) { cfg.Logging.DebugSampleRate = parseInt(v, cfg.Logging.DebugSampleRate) },
 		"CLAWREACH_HEALTH_ENABLED":        func(v string) { cfg.Health.Enabled = parseBool(v, cfg.Health.Enabled) },
 		"CLAWREACH_HEALTH_LISTEN_ADDRESS": func(v string) { cfg.Health.ListenAddress = v },
+		"CLAWREACH_HEALTH_MEMSTATS_TTL":   func(v string) { cfg.Health.MemStatsTTL = parseDuration(v, cfg.Health.MemStatsTTL) },
+		"CLAWREACH_HEALTH_READY_ENDPOINT": func(v string) { cfg.Health.ReadyEndpoint = v },
+		"CLAWREACH_HEALTH_SHUTDOWN_DELAY": func(v string) { cfg.Health.ShutdownDelay = parseDuration(v, cfg.Health.ShutdownDelay) },
+		"CLAWREACH_MONITORING_METRICS_AUTH_TOKEN": func(v string) { cfg.Monitoring.MetricsAuthToken = v },
+		"CLAWREACH_MONITORING_STATSD_ADDRESS":  func(v string) { cfg.Monitoring.StatsDAddress = v },
+		"CLAWREACH_MONITORING_STATSD_INTERVAL": func(v string) { cfg.Monitoring.StatsDInterval = parseDuration(v, cfg.Monitoring.StatsDInterval) },
+		"CLAWREACH_MONITORING_METRICS_FILE":    func(v string) { cfg.Monitoring.MetricsFile = v },
+		"CLAWREACH_MONITORING_METRICS_FILE_INTERVAL": func(v string) {
+			cfg.Monitoring.MetricsFileInterval = parseDuration(v, cfg.Monitoring.MetricsFileInterval)
+		},
+		"CLAWREACH_MONITORING_METHOD_METRICS": func(v string) {
+			cfg.Monitoring.MethodMetrics = parseList(v)
+		},
+		"CLAWREACH_MONITORING_CONNECTION_WARN_THRESHOLD": func(v string) {
+			cfg.Monitoring.ConnectionWarnThreshold = parseInt(v, cfg.Monitoring.ConnectionWarnThreshold)
+		},
+		"CLAWREACH_MONITORING_CONNECTION_CRITICAL_THRESHOLD": func(v string) {
+			cfg.Monitoring.ConnectionCriticalThreshold = parseInt(v, cfg.Monitoring.ConnectionCriticalThreshold)
+		},
+		"CLAWREACH_WEBUI_READ_ONLY":       func(v string) { cfg.WebUI.ReadOnly = parseBool(v, cfg.WebUI.ReadOnly) },
+		"CLAWREACH_WEBUI_AUDIT_FILE":      func(v string) { cfg.WebUI.AuditFile = v },
+		"CLAWREACH_WEBUI_LOCKED_FIELDS": func(v string) {
+			cfg.WebUI.LockedFields = parseList(v)
+		},
 		"CLAWREACH_BRIDGE_MEDIA_ENABLED":      func(v string) { cfg.Bridge.Media.Enabled = parseBool(v, cfg.Bridge.Media.Enabled) },
 		"CLAWREACH_BRIDGE_MEDIA_DIRECTORY":    func(v string) { cfg.Bridge.Media.Directory = v },
+		"CLAWREACH_BRIDGE_MEDIA_INBOX_LAYOUT": func(v string) { cfg.Bridge.Media.InboxLayout = v },
 		"CLAWREACH_BRIDGE_REACTIONS_ENABLED":  func(v string) { cfg.Bridge.Reactions.Enabled = parseBool(v, cfg.Bridge.Reactions.Enabled) },
 		"CLAWREACH_BRIDGE_REACTIONS_MODE":     func(v string) { cfg.Bridge.Reactions.Mode = v },
 		"CLAWREACH_BRIDGE_CANVAS_STATE_TRACKING":   func(v string) { cfg.Bridge.Canvas.StateTracking = parseBool(v, cfg.Bridge.Canvas.StateTracking) },
 		"CLAWREACH_BRIDGE_CANVAS_JSONL_BUFFER_SIZE": func(v string) { cfg.Bridge.Canvas.JSONLBufferSize = parseInt(v, cfg.Bridge.Canvas.JSONLBufferSize) },
 		"CLAWREACH_BRIDGE_CANVAS_MAX_AGE":           func(v string) { cfg.Bridge.Canvas.MaxAge = parseDuration(v, cfg.Bridge.Canvas.MaxAge) },
 		"CLAWREACH_BRIDGE_CANVAS_A2UI_URL":          func(v string) { cfg.Bridge.Canvas.A2UIURL = v },
+		"CLAWREACH_BRIDGE_CANVAS_MAX_JSONL_BYTES": func(v string) {
+			cfg.Bridge.Canvas.MaxJSONLBytes = parseInt(v, cfg.Bridge.Canvas.MaxJSONLBytes)
+		},
+		"CLAWREACH_BRIDGE_CANVAS_REPLAY_DEDUP_WINDOW": func(v string) {
+			cfg.Bridge.Canvas.ReplayDedupWindow = parseDuration(v, cfg.Bridge.Canvas.ReplayDedupWindow)
+		},
+		"CLAWREACH_BRIDGE_RESUME_ENABLED": func(v string) { cfg.Bridge.Resume.Enabled = parseBool(v, cfg.Bridge.Resume.Enabled) },
+		"CLAWREACH_BRIDGE_RESUME_WINDOW":  func(v string) { cfg.Bridge.Resume.Window = parseDuration(v, cfg.Bridge.Resume.Window) },
+		"CLAWREACH_BRIDGE_RESUME_MAX_BUFFER_BYTES": func(v string) {
+			cfg.Bridge.Resume.MaxBufferBytes = parseInt64(v, cfg.Bridge.Resume.MaxBufferBytes)
+		},
 		"CLAWREACH_BRIDGE_SYNC_ENABLED":             func(v string) { cfg.Bridge.Sync.Enabled = parseBool(v, cfg.Bridge.Sync.Enabled) },
 		"CLAWREACH_BRIDGE_SYNC_MAX_HISTORY":         func(v string) { cfg.Bridge.Sync.MaxHistory = parseInt(v, cfg.Bridge.Sync.MaxHistory) },
+		"CLAWREACH_BRIDGE_SYNC_MAX_BROADCAST_FANOUT": func(v string) { cfg.Bridge.Sync.MaxBroadcastFanout = parseInt(v, cfg.Bridge.Sync.MaxBroadcastFanout) },
+		"CLAWREACH_BRIDGE_SYNC_BROADCAST_TIMEOUT":    func(v string) { cfg.Bridge.Sync.BroadcastTimeout = parseDuration(v, cfg.Bridge.Sync.BroadcastTimeout) },
+		"CLAWREACH_BRIDGE_SYNC_MAX_HISTORY_RESPONSE_SIZE": func(v string) {
+			cfg.Bridge.Sync.MaxHistoryResponseSize = parseInt64(v, cfg.Bridge.Sync.MaxHistoryResponseSize)
+		},
 	}
 
 	for env, setter := range envMap {
@@ -432,30 +1387,165 @@ func (c *Config) ApplyReloadableFields(newCfg *Config) *Config {
 	updated := *c
 	updated.Security.RateLimit = newCfg.Security.RateLimit
 	updated.Security.AuthToken = newCfg.Security.AuthToken
+	updated.Security.AuthTokenCredential = newCfg.Security.AuthTokenCredential
+	updated.Security.AllowQueryToken = newCfg.Security.AllowQueryToken
+	updated.Security.JSONRejections = newCfg.Security.JSONRejections
 	updated.Security.PublicPaths = newCfg.Security.PublicPaths
+	updated.Security.AllowedHosts = newCfg.Security.AllowedHosts
+	updated.WebUI.LockedFields = newCfg.WebUI.LockedFields
 	updated.Security.MaxConnections = newCfg.Security.MaxConnections
 	updated.Security.MaxConnectionsPerIP = newCfg.Security.MaxConnectionsPerIP
+	updated.Security.MaxConnectionsPerToken = newCfg.Security.MaxConnectionsPerToken
+	updated.Security.MaxConnectionsBySubprotocol = newCfg.Security.MaxConnectionsBySubprotocol
 	updated.Logging.Level = newCfg.Logging.Level
+	updated.Logging.Format = newCfg.Logging.Format
+	updated.Logging.File = newCfg.Logging.File
+	updated.Logging.MaxSizeMB = newCfg.Logging.MaxSizeMB
+	updated.Logging.MaxBackups = newCfg.Logging.MaxBackups
+	updated.Logging.MaxAgeDays = newCfg.Logging.MaxAgeDays
+	updated.Logging.Compress = newCfg.Logging.Compress
+	updated.Logging.DebugSampleRate = newCfg.Logging.DebugSampleRate
+	updated.Monitoring.ConnectionWarnThreshold = newCfg.Monitoring.ConnectionWarnThreshold
+	updated.Monitoring.ConnectionCriticalThreshold = newCfg.Monitoring.ConnectionCriticalThreshold
 	updated.Bridge.MaxMessageSize = newCfg.Bridge.MaxMessageSize
+	updated.Bridge.MaxMessageSizeUpstream = newCfg.Bridge.MaxMessageSizeUpstream
+	updated.Bridge.MaxMessageSizeDownstream = newCfg.Bridge.MaxMessageSizeDownstream
+	updated.Bridge.ClientBufferHighWater = newCfg.Bridge.ClientBufferHighWater
+	updated.Bridge.Sync.MaxHistoryResponseSize = newCfg.Bridge.Sync.MaxHistoryResponseSize
 	updated.Bridge.Canvas.A2UIURL = newCfg.Bridge.Canvas.A2UIURL
+	updated.Bridge.HTTPProxyEnabled = newCfg.Bridge.HTTPProxyEnabled
+	updated.Bridge.StripPathPrefix = newCfg.Bridge.StripPathPrefix
+	updated.Bridge.BadGatewayPage = newCfg.Bridge.BadGatewayPage
+	updated.Bridge.StripResponseHeaders = newCfg.Bridge.StripResponseHeaders
+	updated.Bridge.AllowedExtensions = newCfg.Bridge.AllowedExtensions
+	updated.Bridge.HistoryGatewayURL = newCfg.Bridge.HistoryGatewayURL
+	updated.Bridge.DrainWebhook = newCfg.Bridge.DrainWebhook
+	updated.Bridge.MaintenanceSchedule = newCfg.Bridge.MaintenanceSchedule
+	updated.Bridge.CloseCodeMap = newCfg.Bridge.CloseCodeMap
+	updated.Bridge.Resume = newCfg.Bridge.Resume
 	return &updated
 }
 
+// Clone returns a deep copy of c. Slices and maps are copied too, so the
+// clone can be published to concurrent readers while c keeps being edited.
+func (c *Config) Clone() *Config {
+	cp := *c
+	b := &cp.Bridge
+	b.Origin.Routes = maps.Clone(b.Origin.Routes)
+	b.WriteTimeoutByPath = maps.Clone(b.WriteTimeoutByPath)
+	b.CloseCodeMap = maps.Clone(b.CloseCodeMap)
+	b.MaintenanceSchedule = slices.Clone(b.MaintenanceSchedule)
+	b.AllowedSubprotocols = slices.Clone(b.AllowedSubprotocols)
+	b.AllowedExtensions = slices.Clone(b.AllowedExtensions)
+	b.GatewaySubprotocols = slices.Clone(b.GatewaySubprotocols)
+	b.StripResponseHeaders = slices.Clone(b.StripResponseHeaders)
+	b.TransformCommand = slices.Clone(b.TransformCommand)
+	b.TLS.CipherSuites = slices.Clone(b.TLS.CipherSuites)
+	b.Media.Extensions = slices.Clone(b.Media.Extensions)
+	b.Media.InjectPaths = slices.Clone(b.Media.InjectPaths)
+	b.Media.AllowedDirs = slices.Clone(b.Media.AllowedDirs)
+	b.Media.InboxAllowedExtensions = slices.Clone(b.Media.InboxAllowedExtensions)
+	cp.Security.PublicPaths = slices.Clone(cp.Security.PublicPaths)
+	cp.Security.AllowedHosts = slices.Clone(cp.Security.AllowedHosts)
+	cp.WebUI.LockedFields = slices.Clone(cp.WebUI.LockedFields)
+	cp.Security.MaxConnectionsBySubprotocol = maps.Clone(cp.Security.MaxConnectionsBySubprotocol)
+	cp.Monitoring.MethodMetrics = slices.Clone(cp.Monitoring.MethodMetrics)
+	return &cp
+}
+
 // IsReloadSafe checks if only reloadable fields changed between configs.
 func IsReloadSafe(old, new *Config) []string {
 	var warnings []string
 	if old.Bridge.ListenAddress != new.Bridge.ListenAddress {
 		warnings = append(warnings, "bridge.listen_address requires restart")
 	}
+	if old.Bridge.ListenBacklog != new.Bridge.ListenBacklog || old.Bridge.ReusePort != new.Bridge.ReusePort {
+		warnings = append(warnings, "bridge.listen_backlog and reuse_port require restart")
+	}
+	if old.Bridge.MaxHeaderBytes != new.Bridge.MaxHeaderBytes {
+		warnings = append(warnings, "bridge.max_header_bytes requires restart")
+	}
 	if old.Bridge.GatewayURL != new.Bridge.GatewayURL {
 		warnings = append(warnings, "bridge.gateway_url requires restart")
 	}
+	if old.Bridge.MaxConcurrentDials != new.Bridge.MaxConcurrentDials {
+		warnings = append(warnings, "bridge.max_concurrent_dials requires restart")
+	}
+	if old.Bridge.DefaultSubprotocol != new.Bridge.DefaultSubprotocol {
+		warnings = append(warnings, "bridge.default_subprotocol requires restart")
+	}
+	if old.Bridge.ClientIPHeader != new.Bridge.ClientIPHeader {
+		warnings = append(warnings, "bridge.client_ip_header requires restart")
+	}
+	if old.Bridge.GatewayPoolSize != new.Bridge.GatewayPoolSize {
+		warnings = append(warnings, "bridge.gateway_pool_size requires restart")
+	}
 	if !reflect.DeepEqual(old.Bridge.TLS, new.Bridge.TLS) {
 		warnings = append(warnings, "bridge.tls requires restart")
 	}
 	if old.Health.ListenAddress != new.Health.ListenAddress {
 		warnings = append(warnings, "health.listen_address requires restart")
 	}
+	if old.Health.ReadyEndpoint != new.Health.ReadyEndpoint {
+		warnings = append(warnings, "health.ready_endpoint requires restart")
+	}
+	if old.Health.ShutdownDelay != new.Health.ShutdownDelay {
+		warnings = append(warnings, "health.shutdown_delay requires restart")
+	}
+	if old.Health.MemStatsTTL != new.Health.MemStatsTTL {
+		warnings = append(warnings, "health.memstats_ttl requires restart")
+	}
+	if old.Bridge.FlushInterval != new.Bridge.FlushInterval {
+		warnings = append(warnings, "bridge.flush_interval requires restart")
+	}
+	if old.Bridge.HTTPTransport != new.Bridge.HTTPTransport {
+		warnings = append(warnings, "bridge.http_transport requires restart")
+	}
+	if !slices.Equal(old.Bridge.TransformCommand, new.Bridge.TransformCommand) {
+		warnings = append(warnings, "bridge.transform_command requires restart")
+	}
+	if old.Bridge.TransformTimeout != new.Bridge.TransformTimeout {
+		warnings = append(warnings, "bridge.transform_timeout requires restart")
+	}
+	if old.Bridge.Canvas.MaxJSONLBytes != new.Bridge.Canvas.MaxJSONLBytes {
+		warnings = append(warnings, "bridge.canvas.max_jsonl_bytes requires restart")
+	}
+	if old.Bridge.GatewayResolveTTL != new.Bridge.GatewayResolveTTL {
+		warnings = append(warnings, "bridge.gateway_resolve_ttl requires restart")
+	}
+	if old.Bridge.GatewayResolveOverride != new.Bridge.GatewayResolveOverride {
+		warnings = append(warnings, "bridge.gateway_resolve_override requires restart")
+	}
+	if old.Security.ForbidRoot != new.Security.ForbidRoot {
+		warnings = append(warnings, "security.forbid_root requires restart")
+	}
+	if old.Security.RequireTailscaleListen != new.Security.RequireTailscaleListen {
+		warnings = append(warnings, "security.require_tailscale_listen requires restart")
+	}
+	if old.Security.AuthzWebhook != new.Security.AuthzWebhook ||
+		old.Security.AuthzWebhookFailMode != new.Security.AuthzWebhookFailMode ||
+		old.Security.AuthzWebhookTimeout != new.Security.AuthzWebhookTimeout ||
+		old.Security.AuthzWebhookCacheTTL != new.Security.AuthzWebhookCacheTTL {
+		warnings = append(warnings, "security.authz_webhook settings require restart")
+	}
+	if old.Monitoring.StatsDAddress != new.Monitoring.StatsDAddress || old.Monitoring.StatsDInterval != new.Monitoring.StatsDInterval {
+		warnings = append(warnings, "monitoring.statsd_address and statsd_interval require restart")
+	}
+	if old.Monitoring.MetricsFile != new.Monitoring.MetricsFile || old.Monitoring.MetricsFileInterval != new.Monitoring.MetricsFileInterval {
+		warnings = append(warnings, "monitoring.metrics_file and metrics_file_interval require restart")
+	}
+	if old.Monitoring.MetricsAuthToken != new.Monitoring.MetricsAuthToken {
+		warnings = append(warnings, "monitoring.metrics_auth_token requires restart")
+	}
+	if !slices.Equal(old.Monitoring.MethodMetrics, new.Monitoring.MethodMetrics) {
+		warnings = append(warnings, "monitoring.method_metrics requires restart")
+	}
+	if old.WebUI.AuditFile != new.WebUI.AuditFile {
+		warnings = append(warnings, "webui.audit_file requires restart")
+	}
+	if old.WebUI.ReadOnly != new.WebUI.ReadOnly {
+		warnings = append(warnings, "webui.read_only requires restart")
+	}
 	return warnings
 }
 
@@ -483,6 +1573,18 @@ func parseInt(s string, fallback int) int {
 	return v
 }
 
+// parseList splits a comma-separated env value, trimming whitespace around
+// each entry and dropping empty ones, so "a, b" yields ["a" "b"].
+func parseList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func parseBool(s string, fallback bool) bool {
 	s = strings.ToLower(s)
 	switch s {
@@ -1,10 +1,17 @@
 package config
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -119,6 +126,130 @@ health:
 	}
 }
 
+func TestLoadGzipConfig(t *testing.T) {
+	content := []byte(`
+bridge:
+  listen_address: "100.101.102.103:8080"
+  gateway_url: "http://localhost:18800"
+  origin: "https://gateway.local"
+  max_message_size: 2097152
+security:
+  auth_token: "test-token"
+logging:
+  level: "debug"
+`)
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(plainPath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(content)
+	zw.Close()
+
+	want, err := Load(plainPath)
+	if err != nil {
+		t.Fatalf("Load(plain) error: %v", err)
+	}
+
+	// Detected by extension and by magic header alone.
+	for _, name := range []string{"config.yaml.gz", "config-gzipped.yaml"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load(%s) error: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Load(%s) = %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestLoadGzipCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml.gz")
+	if err := os.WriteFile(path, []byte("not gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("Load() with corrupt .gz should fail")
+	}
+}
+
+func TestReloadMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("logging:\n  level: \"debug\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Reload(path); err != nil {
+		t.Fatalf("Reload() with file present: %v", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Reload(path)
+	if !errors.Is(err, ErrFileMissing) {
+		t.Fatalf("Reload() after removing file = %v, want ErrFileMissing", err)
+	}
+
+	// Other failures are not reported as a missing file.
+	if err := os.WriteFile(path, []byte("logging:\n  level: \"loud\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Reload(path); err == nil || errors.Is(err, ErrFileMissing) {
+		t.Errorf("Reload() with invalid config = %v, want a validation error", err)
+	}
+}
+
+func TestLoadAuthTokenCredential(t *testing.T) {
+	credDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(credDir, "bridge_token"), []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("security:\n  auth_token_credential: \"bridge_token\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.Security.AuthToken != "s3cret" {
+		t.Errorf("auth_token = %q, want s3cret (trailing newline trimmed)", cfg.Security.AuthToken)
+	}
+
+	// Rotating the credential takes effect on the next load.
+	if err := os.WriteFile(filepath.Join(credDir, "bridge_token"), []byte("rotated"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = Reload(path); err != nil || cfg.Security.AuthToken != "rotated" {
+		t.Errorf("Reload() = %v, %v; want auth_token rotated", cfg, err)
+	}
+
+	t.Setenv("CLAWREACH_SECURITY_AUTH_TOKEN", "inline")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Errorf("Load() with auth_token also set = %v, want mutually exclusive error", err)
+	}
+	t.Setenv("CLAWREACH_SECURITY_AUTH_TOKEN", "")
+
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "CREDENTIALS_DIRECTORY") {
+		t.Errorf("Load() outside systemd = %v, want CREDENTIALS_DIRECTORY error", err)
+	}
+
+	t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+	t.Setenv("CLAWREACH_SECURITY_AUTH_TOKEN_CREDENTIAL", "../bridge_token")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "not a path") {
+		t.Errorf("Load() with a path = %v, want not a path error", err)
+	}
+}
+
 func TestLoadDefaults(t *testing.T) {
 	// Load with empty path uses defaults
 	cfg, err := Load("")
@@ -130,6 +261,193 @@ func TestLoadDefaults(t *testing.T) {
 	}
 }
 
+func TestLoadOriginMap(t *testing.T) {
+	content := `
+bridge:
+  listen_address: "100.101.102.103:8080"
+  gateway_url: "http://localhost:18800"
+  origin:
+    "/": "https://gateway.local"
+    "/ws/operator": "https://operator.local"
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got := cfg.Bridge.Origin.For("/ws/operator/123"); got != "https://operator.local" {
+		t.Errorf("origin for /ws/operator/123 = %q, want %q", got, "https://operator.local")
+	}
+	if got := cfg.Bridge.Origin.For("/ws/node"); got != "https://gateway.local" {
+		t.Errorf("origin for /ws/node = %q, want %q", got, "https://gateway.local")
+	}
+}
+
+func TestOriginConfigFor(t *testing.T) {
+	o := OriginConfig{
+		Default: "https://default.local",
+		Routes: map[string]string{
+			"/ws":          "https://ws.local",
+			"/ws/operator": "https://operator.local",
+		},
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/", "https://default.local"},
+		{"/health", "https://default.local"},
+		{"/ws/node", "https://ws.local"},
+		{"/ws/operator", "https://operator.local"},
+		{"/ws/operator/x", "https://operator.local"},
+	}
+	for _, tt := range tests {
+		if got := o.For(tt.path); got != tt.want {
+			t.Errorf("For(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWriteTimeoutFor(t *testing.T) {
+	b := BridgeConfig{
+		WriteTimeout: 30 * time.Second,
+		WriteTimeoutByPath: map[string]time.Duration{
+			"/ws":          10 * time.Second,
+			"/ws/operator": 2 * time.Minute,
+		},
+	}
+	tests := []struct {
+		path string
+		want time.Duration
+	}{
+		{"/", 30 * time.Second},
+		{"/canvas", 30 * time.Second},
+		{"/ws/node", 10 * time.Second},
+		{"/ws/operator", 2 * time.Minute},
+		{"/ws/operator/x", 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := b.WriteTimeoutFor(tt.path); got != tt.want {
+			t.Errorf("WriteTimeoutFor(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	// 2026-03-01 is a Sunday.
+	at := func(day int, hhmm string) time.Time {
+		tm, _ := time.Parse("15:04", hhmm)
+		return time.Date(2026, 3, day, tm.Hour(), tm.Minute(), 0, 0, time.Local)
+	}
+	tests := []struct {
+		spec string
+		t    time.Time
+		want bool
+	}{
+		{"03:00-04:00", at(1, "03:00"), true},
+		{"03:00-04:00", at(4, "03:59"), true},
+		{"03:00-04:00", at(1, "04:00"), false},
+		{"03:00-04:00", at(1, "02:59"), false},
+		{"sun 03:00-04:00", at(1, "03:30"), true},
+		{"Sun 03:00-04:00", at(2, "03:30"), false},
+		{"23:30-00:30", at(1, "23:45"), true},
+		{"23:30-00:30", at(2, "00:15"), true},
+		{"23:30-00:30", at(2, "00:30"), false},
+		{"Sat 23:30-00:30", at(1, "00:15"), true},  // Saturday night into Sunday
+		{"Sat 23:30-00:30", at(1, "23:45"), false}, // Sunday night
+	}
+	for _, tt := range tests {
+		w, err := ParseMaintenanceWindow(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseMaintenanceWindow(%q): %v", tt.spec, err)
+		}
+		if got := w.Contains(tt.t); got != tt.want {
+			t.Errorf("%q.Contains(%s) = %v, want %v", tt.spec, tt.t.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "03:00", "3am-4am", "Mon", "Mon Tue 03:00-04:00", "25:00-26:00"} {
+		if _, err := ParseMaintenanceWindow(spec); err == nil {
+			t.Errorf("ParseMaintenanceWindow(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestCheckPrivileges(t *testing.T) {
+	tests := []struct {
+		forbid  bool
+		euid    int
+		wantErr bool
+	}{
+		{false, 0, false},
+		{false, 1000, false},
+		{true, 1000, false},
+		{true, 0, true},
+	}
+	for _, tt := range tests {
+		s := SecurityConfig{ForbidRoot: tt.forbid}
+		if err := s.CheckPrivileges(tt.euid); (err != nil) != tt.wantErr {
+			t.Errorf("forbid_root=%v euid=%d: err = %v, wantErr %v", tt.forbid, tt.euid, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"http://localhost:18800", "http://localhost:18800"},
+		{"http://alice:s3cret@gw:18800/x", "http://xxxxx@gw:18800/x"},
+		{"http://s3cret-token@gw:18800/x", "http://xxxxx@gw:18800/x"},
+		{"::not a url", "::not a url"},
+	}
+	for _, tt := range tests {
+		if got := RedactURL(tt.in); got != tt.want {
+			t.Errorf("RedactURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDirectionalMessageLimits(t *testing.T) {
+	b := BridgeConfig{MaxMessageSize: 1000}
+	if got := b.UpstreamMessageLimit(); got != 1000 {
+		t.Errorf("unset UpstreamMessageLimit() = %d, want 1000", got)
+	}
+	if got := b.DownstreamMessageLimit(); got != 1000 {
+		t.Errorf("unset DownstreamMessageLimit() = %d, want 1000", got)
+	}
+
+	b.MaxMessageSizeUpstream = 10
+	b.MaxMessageSizeDownstream = 5000
+	if got := b.UpstreamMessageLimit(); got != 10 {
+		t.Errorf("UpstreamMessageLimit() = %d, want 10", got)
+	}
+	if got := b.DownstreamMessageLimit(); got != 5000 {
+		t.Errorf("DownstreamMessageLimit() = %d, want 5000", got)
+	}
+}
+
+func TestOriginConfigMarshalRoundTrip(t *testing.T) {
+	for _, o := range []OriginConfig{
+		{Default: "https://gateway.local"},
+		{Default: "https://gateway.local", Routes: map[string]string{"/ws/operator": "https://operator.local"}},
+	} {
+		data, err := yaml.Marshal(o)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		var got OriginConfig
+		if err := yaml.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%q): %v", data, err)
+		}
+		if !reflect.DeepEqual(got, o) {
+			t.Errorf("round trip = %+v, want %+v", got, o)
+		}
+	}
+}
+
 func TestEnvOverrides(t *testing.T) {
 	t.Setenv("CLAWREACH_BRIDGE_GATEWAY_URL", "http://10.0.0.1:18800")
 	t.Setenv("CLAWREACH_SECURITY_AUTH_TOKEN", "env-token")
@@ -159,6 +477,26 @@ func TestEnvOverrides(t *testing.T) {
 	}
 }
 
+func TestEnvListOverridesTrimSpace(t *testing.T) {
+	t.Setenv("CLAWREACH_MONITORING_METHOD_METRICS", "chat.send, chat.history ,")
+	t.Setenv("CLAWREACH_SECURITY_ALLOWED_HOSTS", " bridge.example.ts.net, 100.64.0.1")
+	t.Setenv("CLAWREACH_BRIDGE_ALLOWED_EXTENSIONS", " permessage-deflate ,")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if want := []string{"chat.send", "chat.history"}; !reflect.DeepEqual(cfg.Monitoring.MethodMetrics, want) {
+		t.Errorf("method_metrics = %q, want %q", cfg.Monitoring.MethodMetrics, want)
+	}
+	if want := []string{"bridge.example.ts.net", "100.64.0.1"}; !reflect.DeepEqual(cfg.Security.AllowedHosts, want) {
+		t.Errorf("allowed_hosts = %q, want %q", cfg.Security.AllowedHosts, want)
+	}
+	if want := []string{"permessage-deflate"}; !reflect.DeepEqual(cfg.Bridge.AllowedExtensions, want) {
+		t.Errorf("allowed_extensions = %q, want %q", cfg.Bridge.AllowedExtensions, want)
+	}
+}
+
 func TestValidation(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -192,14 +530,99 @@ func TestValidation(t *testing.T) {
 		},
 		{
 			name:    "empty origin",
-			modify:  func(c *Config) { c.Bridge.Origin = "" },
+			modify:  func(c *Config) { c.Bridge.Origin = OriginConfig{} },
 			wantErr: "bridge.origin is required",
 		},
+		{
+			name: "origin route without leading slash",
+			modify: func(c *Config) {
+				c.Bridge.Origin.Routes = map[string]string{"ws/operator": "https://operator.local"}
+			},
+			wantErr: "must start with /",
+		},
+		{
+			name:    "invalid drain_order",
+			modify:  func(c *Config) { c.Bridge.DrainOrder = "random" },
+			wantErr: "bridge.drain_order must be",
+		},
+		{
+			name:    "invalid compression mode",
+			modify:  func(c *Config) { c.Bridge.Compression.Mode = "gzip" },
+			wantErr: "bridge.compression.mode must be",
+		},
+		{
+			name:    "negative compression threshold",
+			modify:  func(c *Config) { c.Bridge.Compression.Threshold = -1 },
+			wantErr: "bridge.compression.threshold must not be negative",
+		},
+		{
+			name: "compression threshold above max_message_size",
+			modify: func(c *Config) {
+				c.Bridge.Compression.Mode = "context_takeover"
+				c.Bridge.Compression.Threshold = int(c.Bridge.MaxMessageSize) + 1
+			},
+			wantErr: "bridge.compression.threshold must not exceed bridge.max_message_size",
+		},
 		{
 			name:    "zero max_message_size",
 			modify:  func(c *Config) { c.Bridge.MaxMessageSize = 0 },
 			wantErr: "bridge.max_message_size must be positive",
 		},
+		{
+			name: "json mirror same as log file",
+			modify: func(c *Config) {
+				c.Logging.File = "/var/log/clawreachbridge.log"
+				c.Logging.JSONMirrorFile = "/var/log/clawreachbridge.log"
+			},
+			wantErr: "logging.json_mirror_file must differ from logging.file",
+		},
+		{
+			name:    "rate_limit idle_ttl below refill window",
+			modify:  func(c *Config) { c.Security.RateLimit.IdleTTL = 30 * time.Second },
+			wantErr: "security.rate_limit.idle_ttl must be at least 1m",
+		},
+		{
+			name:    "rate_limit gc_interval above idle_ttl",
+			modify:  func(c *Config) { c.Security.RateLimit.GCInterval = time.Hour },
+			wantErr: "security.rate_limit.gc_interval must be between 1s and idle_ttl",
+		},
+		{
+			name:    "invalid rate_limit key",
+			modify:  func(c *Config) { c.Security.RateLimit.Key = "token" },
+			wantErr: "security.rate_limit.key must be ip or cert",
+		},
+		{
+			name:    "cert rate_limit key without mTLS",
+			modify:  func(c *Config) { c.Security.RateLimit.Key = "cert" },
+			wantErr: "requires bridge.tls.client_ca_file",
+		},
+		{
+			name: "invalid tls min_version",
+			modify: func(c *Config) {
+				c.Bridge.TLS = TLSConfig{Enabled: true, CertFile: "c.pem", KeyFile: "k.pem", MinVersion: "1.1"}
+			},
+			wantErr: "bridge.tls.min_version must be 1.2 or 1.3",
+		},
+		{
+			name: "unknown tls cipher suite",
+			modify: func(c *Config) {
+				c.Bridge.TLS = TLSConfig{Enabled: true, CertFile: "c.pem", KeyFile: "k.pem", MinVersion: "1.2", CipherSuites: []string{"TLS_NOPE"}}
+			},
+			wantErr: "unknown or insecure cipher suite",
+		},
+		{
+			name: "tls cipher suites with min_version 1.3",
+			modify: func(c *Config) {
+				c.Bridge.TLS = TLSConfig{Enabled: true, CertFile: "c.pem", KeyFile: "k.pem", MinVersion: "1.3",
+					CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}}
+			},
+			wantErr: "no effect with min_version 1.3",
+		},
+		{
+			name:    "client_ca_file without TLS",
+			modify:  func(c *Config) { c.Bridge.TLS.ClientCAFile = "/etc/clawreachbridge/clients.pem" },
+			wantErr: "bridge.tls.client_ca_file requires bridge.tls.enabled",
+		},
 		{
 			name:    "invalid log level",
 			modify:  func(c *Config) { c.Logging.Level = "verbose" },
@@ -261,6 +684,200 @@ func TestValidation(t *testing.T) {
 			modify:  func(c *Config) { c.Bridge.WriteTimeout = 6 * time.Minute },
 			wantErr: "bridge.write_timeout must not exceed 5m",
 		},
+		{
+			name:    "negative max_concurrent_dials",
+			modify:  func(c *Config) { c.Bridge.MaxConcurrentDials = -1 },
+			wantErr: "bridge.max_concurrent_dials must not be negative",
+		},
+		{
+			name:    "drain_webhook without scheme",
+			modify:  func(c *Config) { c.Bridge.DrainWebhook = "lb.local/drain" },
+			wantErr: "bridge.drain_webhook must be an http:// or https:// URL",
+		},
+		{
+			name:    "strip_path_prefix with trailing slash",
+			modify:  func(c *Config) { c.Bridge.StripPathPrefix = "/bridge/" },
+			wantErr: "bridge.strip_path_prefix must start with / and not end with /",
+		},
+		{
+			name: "metrics_file_interval too short",
+			modify: func(c *Config) {
+				c.Monitoring.MetricsFile = "/var/lib/clawreachbridge/metrics.prom"
+				c.Monitoring.MetricsFileInterval = 100 * time.Millisecond
+			},
+			wantErr: "monitoring.metrics_file_interval must be at least 1s",
+		},
+		{
+			name:    "transform_command relative path",
+			modify:  func(c *Config) { c.Bridge.TransformCommand = []string{"jq", "."} },
+			wantErr: "bridge.transform_command must start with an absolute path",
+		},
+		{
+			name: "transform_timeout too long",
+			modify: func(c *Config) {
+				c.Bridge.TransformCommand = []string{"/usr/bin/jq", "."}
+				c.Bridge.TransformTimeout = time.Minute
+			},
+			wantErr: "bridge.transform_timeout must be between 10ms and 30s",
+		},
+		{
+			name:    "allowed_extensions unsupported",
+			modify:  func(c *Config) { c.Bridge.AllowedExtensions = []string{"permessage-deflate", "x-webkit-deflate-frame"} },
+			wantErr: `bridge.allowed_extensions entry "x-webkit-deflate-frame" is not supported`,
+		},
+		{
+			name:    "gateway_resolve_ttl too long",
+			modify:  func(c *Config) { c.Bridge.GatewayResolveTTL = 2 * time.Hour },
+			wantErr: "bridge.gateway_resolve_ttl must be between 0 and 1h",
+		},
+		{
+			name:    "negative http_transport.max_idle_conns",
+			modify:  func(c *Config) { c.Bridge.HTTPTransport.MaxIdleConns = -1 },
+			wantErr: "bridge.http_transport.max_idle_conns must be between 0 and 10000",
+		},
+		{
+			name:   "unlimited http_transport.max_idle_conns is valid",
+			modify: func(c *Config) { c.Bridge.HTTPTransport.MaxIdleConns = 0 },
+		},
+		{
+			name:    "http_transport.idle_conn_timeout too short",
+			modify:  func(c *Config) { c.Bridge.HTTPTransport.IdleConnTimeout = 500 * time.Millisecond },
+			wantErr: "bridge.http_transport.idle_conn_timeout must be between 1s and 1h",
+		},
+		{
+			name:    "http_transport.idle_conn_timeout too long",
+			modify:  func(c *Config) { c.Bridge.HTTPTransport.IdleConnTimeout = 2 * time.Hour },
+			wantErr: "bridge.http_transport.idle_conn_timeout must be between 1s and 1h",
+		},
+		{
+			name:    "zero http_transport.tls_handshake_timeout",
+			modify:  func(c *Config) { c.Bridge.HTTPTransport.TLSHandshakeTimeout = 0 },
+			wantErr: "bridge.http_transport.tls_handshake_timeout must be between 1s and 5m",
+		},
+		{
+			name:    "gateway_resolve_override not an IP",
+			modify:  func(c *Config) { c.Bridge.GatewayResolveOverride = "gateway.internal" },
+			wantErr: "bridge.gateway_resolve_override must be an IP address",
+		},
+		{
+			name:    "flush_interval too long",
+			modify:  func(c *Config) { c.Bridge.FlushInterval = time.Minute },
+			wantErr: "bridge.flush_interval must be at most 10s",
+		},
+		{
+			name:    "maintenance_schedule bad day",
+			modify:  func(c *Config) { c.Bridge.MaintenanceSchedule = []string{"Someday 03:00-04:00"} },
+			wantErr: "bridge.maintenance_schedule: invalid window",
+		},
+		{
+			name:    "maintenance_schedule empty window",
+			modify:  func(c *Config) { c.Bridge.MaintenanceSchedule = []string{"03:00-03:00"} },
+			wantErr: "bridge.maintenance_schedule: window \"03:00-03:00\" is empty",
+		},
+		{
+			name:    "health memstats_ttl too long",
+			modify:  func(c *Config) { c.Health.MemStatsTTL = time.Hour },
+			wantErr: "health.memstats_ttl must be between 0 and 1m",
+		},
+		{
+			name:    "health shutdown_delay negative",
+			modify:  func(c *Config) { c.Health.ShutdownDelay = -time.Second },
+			wantErr: "health.shutdown_delay must be between 0 and 1m",
+		},
+		{
+			name:    "health ready_endpoint without leading slash",
+			modify:  func(c *Config) { c.Health.ReadyEndpoint = "readyz" },
+			wantErr: "health.ready_endpoint must start with /",
+		},
+		{
+			name:    "health ready_endpoint same as health endpoint",
+			modify:  func(c *Config) { c.Health.ReadyEndpoint = c.Health.Endpoint },
+			wantErr: "health.ready_endpoint must differ from health.endpoint and monitoring.metrics_endpoint",
+		},
+		{
+			name:    "max_header_bytes below minimum",
+			modify:  func(c *Config) { c.Bridge.MaxHeaderBytes = 1024 },
+			wantErr: "bridge.max_header_bytes must be between 4096 and 1048576",
+		},
+		{
+			name:    "listen_backlog too large",
+			modify:  func(c *Config) { c.Bridge.ListenBacklog = 70000 },
+			wantErr: "bridge.listen_backlog must be between 0 and 65535",
+		},
+		{
+			name:    "negative max_message_size_upstream",
+			modify:  func(c *Config) { c.Bridge.MaxMessageSizeUpstream = -1 },
+			wantErr: "bridge.max_message_size_upstream must be between 0 and 67108864",
+		},
+		{
+			name:    "client_buffer_high_water below the message size",
+			modify:  func(c *Config) { c.Bridge.ClientBufferHighWater = 1024 },
+			wantErr: "bridge.client_buffer_high_water must be 0 or between",
+		},
+		{
+			name:    "max_message_size_downstream over 64MB",
+			modify:  func(c *Config) { c.Bridge.MaxMessageSizeDownstream = 67108865 },
+			wantErr: "bridge.max_message_size_downstream must be between 0 and 67108864",
+		},
+		{
+			name:    "empty gateway_subprotocols entry",
+			modify:  func(c *Config) { c.Bridge.GatewaySubprotocols = []string{"openclaw.v2", ""} },
+			wantErr: "bridge.gateway_subprotocols entry",
+		},
+		{
+			name:    "default_subprotocol with a comma",
+			modify:  func(c *Config) { c.Bridge.DefaultSubprotocol = "a,b" },
+			wantErr: "bridge.default_subprotocol \"a,b\" must be a token",
+		},
+		{
+			name: "default_subprotocol outside allowed_subprotocols",
+			modify: func(c *Config) {
+				c.Bridge.AllowedSubprotocols = []string{"openclaw.v1"}
+				c.Bridge.DefaultSubprotocol = "openclaw.v2"
+			},
+			wantErr: "must be one of bridge.allowed_subprotocols",
+		},
+		{
+			name:    "invalid history_gateway_url scheme",
+			modify:  func(c *Config) { c.Bridge.HistoryGatewayURL = "ftp://localhost:18801" },
+			wantErr: "bridge.history_gateway_url must be an http:// or https:// URL",
+		},
+		{
+			name:    "history_gateway_url without host",
+			modify:  func(c *Config) { c.Bridge.HistoryGatewayURL = "http://" },
+			wantErr: "bridge.history_gateway_url must be an http:// or https:// URL",
+		},
+		{
+			name:    "write_timeout_by_path key without leading slash",
+			modify:  func(c *Config) { c.Bridge.WriteTimeoutByPath = map[string]time.Duration{"ws": time.Second} },
+			wantErr: "must start with /",
+		},
+		{
+			name:    "write_timeout_by_path exceeds 5m",
+			modify:  func(c *Config) { c.Bridge.WriteTimeoutByPath = map[string]time.Duration{"/ws": 6 * time.Minute} },
+			wantErr: "must be positive and not exceed 5m",
+		},
+		{
+			name: "resume window too short",
+			modify: func(c *Config) {
+				c.Bridge.Resume.Enabled = true
+				c.Bridge.Resume.Window = 500 * time.Millisecond
+			},
+			wantErr: "bridge.resume.window must be between 1s and 5m",
+		},
+		{
+			name: "resume buffer too small",
+			modify: func(c *Config) {
+				c.Bridge.Resume.Enabled = true
+				c.Bridge.Resume.MaxBufferBytes = 100
+			},
+			wantErr: "bridge.resume.max_buffer_bytes must be between 1024",
+		},
+		{
+			name:    "close_code_map to a reserved code",
+			modify:  func(c *Config) { c.Bridge.CloseCodeMap = map[int]int{4000: 1006} },
+			wantErr: "bridge.close_code_map[4000] = 1006",
+		},
 		{
 			name:    "read_timeout exceeds 5m",
 			modify:  func(c *Config) { c.Bridge.ReadTimeout = 6 * time.Minute },
@@ -394,6 +1011,184 @@ func TestValidation(t *testing.T) {
 				c.Bridge.Canvas.JSONLBufferSize = 0 // would fail if validated
 			},
 		},
+		{
+			name: "media recompress quality out of range",
+			modify: func(c *Config) {
+				c.Bridge.Media.Enabled = true
+				c.Bridge.Media.Recompress = true
+				c.Bridge.Media.RecompressQuality = 0
+			},
+			wantErr: "bridge.media.recompress_quality must be between 1 and 100",
+		},
+		{
+			name: "invalid media inbox_policy",
+			modify: func(c *Config) {
+				c.Bridge.Media.Enabled = true
+				c.Bridge.Media.InboxPolicy = "delete_all"
+			},
+			wantErr: "bridge.media.inbox_policy must be",
+		},
+		{
+			name: "negative sync max_history_response_size",
+			modify: func(c *Config) {
+				c.Bridge.Sync.Enabled = true
+				c.Bridge.Sync.MaxHistoryResponseSize = -1
+			},
+			wantErr: "bridge.sync.max_history_response_size must not be negative",
+		},
+		{
+			name:    "zero debug_sample_rate",
+			modify:  func(c *Config) { c.Logging.DebugSampleRate = 0 },
+			wantErr: "logging.debug_sample_rate must be at least 1",
+		},
+		{
+			name: "negative max_connections_by_subprotocol",
+			modify: func(c *Config) {
+				c.Security.MaxConnectionsBySubprotocol = map[string]ConnectionLimits{"node": {MaxConnectionsPerIP: -1}}
+			},
+			wantErr: `security.max_connections_by_subprotocol["node"].max_connections_per_ip must not be negative`,
+		},
+		{
+			name: "negative media max_concurrent_injections",
+			modify: func(c *Config) {
+				c.Bridge.Media.Enabled = true
+				c.Bridge.Media.MaxConcurrentInjections = -1
+			},
+			wantErr: "bridge.media.max_concurrent_injections must not be negative",
+		},
+		{
+			name: "canvas max_jsonl_bytes too small",
+			modify: func(c *Config) {
+				c.Bridge.Canvas.StateTracking = true
+				c.Bridge.Canvas.MaxJSONLBytes = 100
+			},
+			wantErr: "bridge.canvas.max_jsonl_bytes must be 0 (no limit) or between 1024",
+		},
+		{
+			name: "canvas replay_dedup_window exceeds 1m",
+			modify: func(c *Config) {
+				c.Bridge.Canvas.StateTracking = true
+				c.Bridge.Canvas.ReplayDedupWindow = 2 * time.Minute
+			},
+			wantErr: "bridge.canvas.replay_dedup_window must be between 0 and 1m",
+		},
+		{
+			name: "invalid media inbox_layout",
+			modify: func(c *Config) {
+				c.Bridge.Media.Enabled = true
+				c.Bridge.Media.InboxLayout = "by_month"
+			},
+			wantErr: "bridge.media.inbox_layout must be",
+		},
+		{
+			name: "negative sync max_broadcast_fanout",
+			modify: func(c *Config) {
+				c.Bridge.Sync.Enabled = true
+				c.Bridge.Sync.MaxBroadcastFanout = -1
+			},
+			wantErr: "bridge.sync.max_broadcast_fanout must not be negative",
+		},
+		{
+			name:    "authz_webhook without scheme",
+			modify:  func(c *Config) { c.Security.AuthzWebhook = "127.0.0.1:9000/authorize" },
+			wantErr: "security.authz_webhook must be an http:// or https:// URL",
+		},
+		{
+			name: "invalid authz_webhook_fail_mode",
+			modify: func(c *Config) {
+				c.Security.AuthzWebhook = "http://127.0.0.1:9000/authorize"
+				c.Security.AuthzWebhookFailMode = "maybe"
+			},
+			wantErr: "security.authz_webhook_fail_mode must be closed or open",
+		},
+		{
+			name: "zero authz_webhook_timeout",
+			modify: func(c *Config) {
+				c.Security.AuthzWebhook = "http://127.0.0.1:9000/authorize"
+				c.Security.AuthzWebhookTimeout = 0
+			},
+			wantErr: "security.authz_webhook_timeout must be between",
+		},
+		{
+			name: "authz_webhook fail open is valid",
+			modify: func(c *Config) {
+				c.Security.AuthzWebhook = "https://authz.internal/check"
+				c.Security.AuthzWebhookFailMode = "open"
+			},
+		},
+		{
+			name:    "negative gateway_pool_size",
+			modify:  func(c *Config) { c.Bridge.GatewayPoolSize = -1 },
+			wantErr: "bridge.gateway_pool_size must be between 0 and 64",
+		},
+		{
+			name:    "client_ip_header with a colon",
+			modify:  func(c *Config) { c.Bridge.ClientIPHeader = "X-Forwarded-For:" },
+			wantErr: "bridge.client_ip_header must be a header name",
+		},
+		{
+			name: "client_ip_header with gateway_pool_size",
+			modify: func(c *Config) {
+				c.Bridge.ClientIPHeader = "X-Forwarded-For"
+				c.Bridge.GatewayPoolSize = 2
+			},
+			wantErr: "bridge.client_ip_header cannot be combined with bridge.gateway_pool_size",
+		},
+		{
+			name:    "statsd_address without port",
+			modify:  func(c *Config) { c.Monitoring.StatsDAddress = "127.0.0.1" },
+			wantErr: "monitoring.statsd_address must be host:port",
+		},
+		{
+			name: "statsd_interval too short",
+			modify: func(c *Config) {
+				c.Monitoring.StatsDAddress = "127.0.0.1:8125"
+				c.Monitoring.StatsDInterval = 100 * time.Millisecond
+			},
+			wantErr: "monitoring.statsd_interval must be at least 1s",
+		},
+		{
+			name:    "empty method_metrics entry",
+			modify:  func(c *Config) { c.Monitoring.MethodMetrics = []string{"chat.send", ""} },
+			wantErr: "monitoring.method_metrics entries must be non-empty",
+		},
+		{
+			name:    "allowed_hosts entry with scheme",
+			modify:  func(c *Config) { c.Security.AllowedHosts = []string{"https://bridge.example.ts.net"} },
+			wantErr: "security.allowed_hosts entries must be hostnames or IPs",
+		},
+		{
+			name: "allowed_hosts with ports and IPs is valid",
+			modify: func(c *Config) {
+				c.Security.AllowedHosts = []string{"bridge.example.ts.net", "100.64.0.1", "localhost:8080"}
+			},
+		},
+		{
+			name:    "webui locked_fields unknown field",
+			modify:  func(c *Config) { c.WebUI.LockedFields = []string{"max_connections", "auth_token"} },
+			wantErr: `webui.locked_fields: unknown field "auth_token"`,
+		},
+		{
+			name:   "webui locked_fields valid",
+			modify: func(c *Config) { c.WebUI.LockedFields = []string{"max_connections", "rate_limit_enabled"} },
+		},
+		{
+			name:    "negative connection_warn_threshold",
+			modify:  func(c *Config) { c.Monitoring.ConnectionWarnThreshold = -1 },
+			wantErr: "monitoring.connection_warn_threshold must be non-negative",
+		},
+		{
+			name: "connection_critical_threshold not above warn",
+			modify: func(c *Config) {
+				c.Monitoring.ConnectionWarnThreshold = 100
+				c.Monitoring.ConnectionCriticalThreshold = 100
+			},
+			wantErr: "monitoring.connection_critical_threshold must be greater than monitoring.connection_warn_threshold",
+		},
+		{
+			name:   "connection_critical_threshold alone is valid",
+			modify: func(c *Config) { c.Monitoring.ConnectionCriticalThreshold = 500 },
+		},
 		{
 			name:   "empty public_paths is valid",
 			modify: func(c *Config) { c.Security.PublicPaths = nil },
@@ -420,6 +1215,37 @@ func TestValidation(t *testing.T) {
 	}
 }
 
+func TestWarningsHTTPProxyDisabledWithA2UI(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Bridge.HTTPProxyEnabled = false
+	cfg.Bridge.Canvas.A2UIURL = "http://100.64.0.1:8080/__openclaw__/a2ui/"
+	if w := cfg.Warnings(); len(w) != 0 {
+		t.Errorf("a2ui under public_paths: warnings = %v, want none", w)
+	}
+
+	cfg.Security.PublicPaths = nil
+	if w := cfg.Warnings(); len(w) != 1 {
+		t.Errorf("a2ui not public: warnings = %v, want 1", w)
+	}
+
+	cfg.Bridge.HTTPProxyEnabled = true
+	if w := cfg.Warnings(); len(w) != 0 {
+		t.Errorf("http proxy enabled: warnings = %v, want none", w)
+	}
+}
+
+func TestWarningsTransformCommand(t *testing.T) {
+	cfg := DefaultConfig()
+	if w := cfg.Warnings(); len(w) != 0 {
+		t.Fatalf("default config: warnings = %v, want none", w)
+	}
+	cfg.Bridge.TransformCommand = []string{"/usr/local/bin/redact"}
+	w := cfg.Warnings()
+	if len(w) != 1 || !strings.Contains(w[0], "/usr/local/bin/redact") {
+		t.Errorf("transform_command set: warnings = %v, want one naming the command", w)
+	}
+}
+
 func TestIsReloadSafe(t *testing.T) {
 	old := DefaultConfig()
 	new := DefaultConfig()
@@ -452,6 +1278,9 @@ func TestApplyReloadableFields(t *testing.T) {
 	newCfg.Logging.Level = "debug"
 	newCfg.Bridge.MaxMessageSize = 2097152
 	newCfg.Bridge.Canvas.A2UIURL = "http://100.64.0.1:8080/__openclaw__/a2ui/"
+	newCfg.Logging.Format = "text"
+	newCfg.Logging.File = "/var/log/clawreachbridge/bridge.log"
+	newCfg.Logging.MaxBackups = 9
 
 	updated := old.ApplyReloadableFields(newCfg)
 
@@ -476,6 +1305,53 @@ func TestApplyReloadableFields(t *testing.T) {
 	if updated.Bridge.Canvas.A2UIURL != "http://100.64.0.1:8080/__openclaw__/a2ui/" {
 		t.Errorf("a2ui_url not reloaded, got %q", updated.Bridge.Canvas.A2UIURL)
 	}
+	if updated.Logging.Format != "text" || updated.Logging.File != newCfg.Logging.File || updated.Logging.MaxBackups != 9 {
+		t.Errorf("logging format/file/rotation not reloaded, got %+v", updated.Logging)
+	}
+}
+
+func TestCloneIsDeep(t *testing.T) {
+	cfg := DefaultConfig()
+	// Give every slice and map one element so sharing would be observable.
+	walkRefFields(reflect.ValueOf(cfg).Elem(), "", func(_ string, v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Slice:
+			v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		case reflect.Map:
+			m := reflect.MakeMap(v.Type())
+			m.SetMapIndex(reflect.Zero(v.Type().Key()), reflect.Zero(v.Type().Elem()))
+			v.Set(m)
+		}
+	})
+
+	clone := cfg.Clone()
+	if !reflect.DeepEqual(cfg, clone) {
+		t.Fatal("clone differs from the original")
+	}
+	shared := map[string]uintptr{}
+	walkRefFields(reflect.ValueOf(cfg).Elem(), "", func(path string, v reflect.Value) {
+		shared[path] = v.Pointer()
+	})
+	walkRefFields(reflect.ValueOf(clone).Elem(), "", func(path string, v reflect.Value) {
+		if shared[path] == v.Pointer() {
+			t.Errorf("%s is shared between the config and its clone", path)
+		}
+	})
+}
+
+// walkRefFields calls fn for every slice and map field reachable from the
+// struct v through nested structs.
+func walkRefFields(v reflect.Value, prefix string, fn func(path string, v reflect.Value)) {
+	for i := range v.NumField() {
+		f := v.Field(i)
+		path := prefix + v.Type().Field(i).Name
+		switch f.Kind() {
+		case reflect.Struct:
+			walkRefFields(f, path+".", fn)
+		case reflect.Slice, reflect.Map:
+			fn(path, f)
+		}
+	}
 }
 
 func contains(s, substr string) bool {
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sampleHeader is written at the top of the output of Sample.
+const sampleHeader = `ClawReach Bridge configuration (generated by 'clawreachbridge config sample').
+
+Every option is listed with its default value. Delete the ones you don't
+change; anything left out keeps its default. Environment variables with the
+CLAWREACH_ prefix override file settings.`
+
+// sectionDocs describes config sections in the sample config.
+var sectionDocs = map[string]string{
+	"bridge":                "Core proxy settings.",
+	"bridge.tls":            "TLS for the client listener (usually unnecessary with Tailscale).",
+	"bridge.compression":    "permessage-deflate negotiation with clients.",
+	"bridge.http_transport": "Keep-alive connections the HTTP proxy holds to the Gateway.",
+	"bridge.media":          "Image injection from the gateway's media directory, and the file-receive inbox.",
+	"bridge.reactions":      "Reaction message observation.",
+	"bridge.canvas":         "Canvas state tracking for reconnect replay.",
+	"bridge.sync":           "Cross-device message sync.",
+	"bridge.resume":         "Keep a dropped client's gateway session open so it can reconnect and resume.",
+	"security":              "Access control and connection limits.",
+	"security.rate_limit":   "Connection and message rate limits.",
+	"logging":               "Log output and rotation.",
+	"health":                "Health endpoint, served on a separate localhost listener.",
+	"monitoring":            "Prometheus metrics and StatsD push.",
+	"webui":                 "Admin web UI on the health listener.",
+}
+
+// fieldDocs is a one-line description of every config field, keyed by its
+// dotted YAML path. TestSampleDocumentsEveryField fails when a field is added
+// without an entry here.
+var fieldDocs = map[string]string{
+	"bridge.listen_backlog":              "Accept queue length for the proxy listener (0 = OS default; Linux only).",
+	"bridge.max_header_bytes":            "Request header size limit in bytes on the proxy and health listeners; larger requests get 431.",
+	"bridge.reuse_port":                  "Set SO_REUSEPORT on the proxy listener so a new process can bind while the old one drains (Linux only).",
+	"bridge.listen_address":              "Address to listen on; must be a Tailscale IP unless security.tailscale_only is false.",
+	"bridge.gateway_url":                 "OpenClaw Gateway upstream (http:// or https://; dialed as ws:// or wss://).",
+	"bridge.history_gateway_url":         "Read replica that sessions.history requests are sent to when sync doesn't answer them; empty = primary gateway.",
+	"bridge.origin":                      "Origin header injected on gateway requests; a string, or a map of path prefix to origin with \"/\" as the fallback.",
+	"bridge.drain_timeout":               "How long to wait for active connections to finish on shutdown.",
+	"bridge.drain_order":                 "Order connections are closed in while draining: all_at_once, oldest_first or newest_first.",
+	"bridge.drain_webhook":               "URL that drain_started and drain_completed events are POSTed to as JSON, with the remaining connection count; empty disables.",
+	"bridge.maintenance_schedule":        "Recurring maintenance windows in local time, \"HH:MM-HH:MM\" daily or \"Mon HH:MM-HH:MM\" weekly; inside one, connections are closed and new ones get 503.",
+	"bridge.max_message_size":            "Maximum WebSocket message size in bytes (after decompression).",
+	"bridge.max_message_size_upstream":   "Client-to-gateway message size limit in bytes (0 = max_message_size).",
+	"bridge.max_message_size_downstream": "Gateway-to-client message size limit in bytes (0 = max_message_size).",
+	"bridge.client_buffer_high_water":    "Bytes queued for a slow client before it is closed as a slow consumer (0 = no queue; writes block the gateway read instead).",
+	"bridge.ping_interval":               "Interval between keepalive pings (0 disables).",
+	"bridge.pong_timeout":                "Close the connection if a pong doesn't arrive within this window.",
+	"bridge.write_timeout":               "Deadline for writing a single message.",
+	"bridge.write_timeout_by_path":       "Per-path write timeouts keyed by path prefix (longest match wins), e.g. {\"/ws/operator\": 2m}.",
+	"bridge.close_code_map":              "Remap gateway close codes before passing them to the client, e.g. {4000: 1011}; the reason text is kept.",
+	"bridge.read_timeout":                "Unused by the proxy loop; keepalive pings detect dead connections.",
+	"bridge.dial_timeout":                "Timeout for dialing the gateway.",
+	"bridge.max_concurrent_dials":        "Gateway dials in flight at once; new clients wait up to dial_timeout for a slot, then are closed with 1013 (0 = unlimited).",
+	"bridge.gateway_pool_size":           "Gateway connections to keep pre-dialed (0 = dial per client; gateway must accept anonymous connections).",
+	"bridge.allowed_extensions":          "WebSocket extensions clients may negotiate (only permessage-deflate is supported); other offers are stripped before negotiation. Empty = none.",
+	"bridge.allowed_subprotocols":        "Subprotocols clients may negotiate; empty = forward whatever the client offers.",
+	"bridge.gateway_subprotocols":        "Subprotocols always offered to the gateway after the client's own, e.g. a version token; the client still gets the one it negotiated.",
+	"bridge.default_subprotocol":         "Subprotocol offered to the gateway for clients that offer none; must be in allowed_subprotocols when that is set.",
+	"bridge.http_proxy_enabled":          "Proxy plain HTTP requests to the gateway; when false only WebSocket upgrades and public_paths are served.",
+	"bridge.strip_path_prefix":           "Prefix removed from request paths before routing and proxying, e.g. /bridge when the bridge is mounted under a path; empty disables.",
+	"bridge.gateway_resolve_ttl":         "How long gateway DNS lookups are cached (0 disables). Expired entries keep being used while a background lookup refreshes them, so slow or failing DNS doesn't fail dials.",
+	"bridge.gateway_resolve_override":    "Static IP used for the gateway_url host instead of DNS; empty disables.",
+	"bridge.flush_interval":              "How often proxied HTTP responses with a Content-Length are flushed to the client (0 = when done, negative = after every write); event streams and chunked responses are always flushed immediately.",
+	"bridge.request_id_header":           "Correlation ID header sent to the gateway; empty disables.",
+	"bridge.client_ip_header":            "Header carrying the client's IP to the gateway on WebSocket dials and proxied HTTP requests (X-Forwarded-For appends to the client's own list); empty disables.",
+	"bridge.bad_gateway_page":            "HTML or JSON file served as the body of HTTP proxy 502 responses; empty = plain text.",
+	"bridge.transform_command":           "Argv of a command (absolute path, no shell) each text message is piped through in both directions: the message on stdin, the replacement on stdout. Failures, timeouts and empty output forward the original. Sees all traffic; empty disables.",
+	"bridge.transform_timeout":           "Per-message time limit for transform_command; on timeout the command is killed and the message passes unchanged.",
+	"bridge.strip_response_headers":      "Headers removed from proxied HTTP responses, e.g. [\"Server\"].",
+
+	"bridge.tls.enabled":        "Serve the client listener over TLS.",
+	"bridge.tls.cert_file":      "PEM certificate; reloaded on change.",
+	"bridge.tls.key_file":       "PEM private key; reloaded on change.",
+	"bridge.tls.client_ca_file": "PEM CA bundle; when set, clients must present a certificate signed by it (mTLS).",
+	"bridge.tls.min_version":    "Minimum TLS version: 1.2 or 1.3.",
+	"bridge.tls.cipher_suites":  "TLS 1.2 cipher suites by Go name; empty = Go defaults.",
+
+	"bridge.compression.mode":                     "disabled, context_takeover or no_context_takeover.",
+	"bridge.http_transport.max_idle_conns":        "Idle connections kept open to the Gateway for reuse (0 = unlimited).",
+	"bridge.http_transport.idle_conn_timeout":     "Close idle Gateway connections after this long (1s-1h).",
+	"bridge.http_transport.tls_handshake_timeout": "Limit on the TLS handshake with an https:// Gateway (1s-5m).",
+	"bridge.compression.threshold":                "Only compress outgoing messages at least this many bytes (0 = library default).",
+
+	"bridge.media.enabled":                   "Inject images generated during a chat run and save files uploaded by clients.",
+	"bridge.media.directory":                 "Gateway's outbound media directory; received files go to <directory>/inbox.",
+	"bridge.media.max_file_size":             "Maximum bytes per injected image.",
+	"bridge.media.max_age":                   "Only inject images created within this window.",
+	"bridge.media.extensions":                "Image extensions eligible for injection.",
+	"bridge.media.inject_paths":              "Only inject on connections whose path has one of these prefixes; empty = all.",
+	"bridge.media.allowed_dirs":              "Directories images may be read from; empty = [directory].",
+	"bridge.media.max_concurrent_injections": "Final messages enriched with images at once across all connections; others wait briefly, then pass through unmodified (0 = unlimited).",
+	"bridge.media.recompress":                "Re-encode large opaque images as JPEG before injection.",
+	"bridge.media.recompress_threshold":      "Only recompress images larger than this many bytes.",
+	"bridge.media.recompress_quality":        "JPEG quality for recompressed images (1-100).",
+	"bridge.media.inbox_max_bytes":           "Total size cap for received files (0 = unlimited).",
+	"bridge.media.inbox_max_files":           "File count cap for received files (0 = unlimited).",
+	"bridge.media.inbox_policy":              "When a cap is hit: reject (forward the attachment unsaved) or evict_oldest.",
+	"bridge.media.inbox_allowed_extensions":  "Only save received files with these extensions; empty = any.",
+	"bridge.media.inbox_verify_mime":         "Don't save files whose content doesn't match the declared MIME family.",
+	"bridge.media.inbox_layout":              "flat (inbox/<file>) or by_date (inbox/YYYY-MM-DD/<file>).",
+
+	"bridge.reactions.enabled": "Count chat.react messages (requires metrics).",
+	"bridge.reactions.mode":    "passthrough (metrics only).",
+
+	"bridge.canvas.state_tracking":      "Shadow canvas state and replay it to reconnecting clients.",
+	"bridge.canvas.jsonl_buffer_size":   "Recent JSONL payloads to retain (1-100).",
+	"bridge.canvas.max_age":             "Discard canvas state older than this (1s-30m).",
+	"bridge.canvas.max_jsonl_bytes":     "pushJSONL messages larger than this are still forwarded but not buffered, so replays omit them (0 = no limit).",
+	"bridge.canvas.replay_dedup_window": "After a replay, drop gateway canvas messages byte-identical to a replayed one for this long (0 disables).",
+	"bridge.canvas.a2ui_url":            "A2UI URL injected into canvas.present params; empty = none.",
+
+	"bridge.sync.enabled":                   "Echo user messages to sibling clients and serve sessions.history from memory.",
+	"bridge.sync.max_history":               "Messages retained per session (10-10000).",
+	"bridge.sync.max_broadcast_fanout":      "Maximum sibling clients an echo is sent to (0 = unlimited).",
+	"bridge.sync.broadcast_timeout":         "Per-recipient write deadline for echoes.",
+	"bridge.sync.max_history_response_size": "Byte cap on sessions.history responses (0 = the downstream message size limit).",
+
+	"bridge.resume.enabled":          "Park the gateway session of clients that drop after connecting with a resume token (X-Resume-Token header or resume_token query, at least 16 characters).",
+	"bridge.resume.window":           "How long a parked session waits for the client to reconnect with the same token.",
+	"bridge.resume.max_buffer_bytes": "Gateway messages buffered per parked session; exceeding it ends the session.",
+
+	"security.tailscale_only":           "Only accept clients with Tailscale IPs.",
+	"security.auth_token":               "Token clients must present (Authorization: Bearer); empty disables.",
+	"security.auth_token_credential":    "systemd credential holding the token (LoadCredential=<name>:<file>); read from $CREDENTIALS_DIRECTORY instead of auth_token.",
+	"security.allow_query_token":        "Also accept the token as a ?token= query parameter.",
+	"security.json_rejections":          "Answer rejected WebSocket upgrades with a JSON reason code when the client accepts application/json.",
+	"security.forbid_root":              "Refuse to start when running as root.",
+	"security.require_tailscale_listen": "With tailscale_only, refuse to start when listen_address is an IP outside the Tailscale ranges instead of only warning.",
+	"security.public_paths":             "Path prefixes served without the auth token.",
+	"security.allowed_hosts":            "Host header values (hostname or IP, optionally with :port) the proxy accepts; others get 403. Guards against DNS rebinding. Empty allows any.",
+
+	"security.rate_limit.enabled":                "Enforce the rate limits below.",
+	"security.rate_limit.connections_per_minute": "New connections per minute per key.",
+	"security.rate_limit.messages_per_second":    "Messages per second per connection.",
+	"security.rate_limit.key":                    "ip, or cert to key by client certificate CN (requires mTLS).",
+	"security.rate_limit.gc_interval":            "How often idle per-IP limiters are evicted (1s to idle_ttl).",
+	"security.rate_limit.idle_ttl":               "Evict a per-IP limiter once unseen this long (at least 1m, so limits can't be reset early).",
+
+	"security.max_connections":                "Global connection limit.",
+	"security.max_connections_per_ip":         "Per-IP connection limit.",
+	"security.max_connections_per_token":      "Per-auth-token connection limit (0 = unlimited).",
+	"security.max_connections_by_subprotocol": "Per-subprotocol max_connections/max_connections_per_ip replacing the global limits.",
+	"security.authz_webhook":                  "URL POSTed on each WebSocket upgrade; only a 200 lets the connection proceed.",
+	"security.authz_webhook_fail_mode":        "Decision when the webhook is unreachable: closed (deny) or open (allow).",
+	"security.authz_webhook_timeout":          "Timeout for each webhook request.",
+	"security.authz_webhook_cache_ttl":        "How long decisions are cached per token and path (0 disables).",
+
+	"logging.level":             "debug, info, warn or error.",
+	"logging.format":            "json or text.",
+	"logging.file":              "Log file path; empty = stdout.",
+	"logging.json_mirror_file":  "Also write every record as JSON to this file; empty disables.",
+	"logging.max_size_mb":       "Rotate the log file at this size.",
+	"logging.max_backups":       "Rotated log files to keep.",
+	"logging.max_age_days":      "Days to keep rotated log files.",
+	"logging.compress":          "Gzip rotated log files.",
+	"logging.debug_sample_rate": "At debug level, log 1 in N per-message lines (1 = all).",
+
+	"health.enabled":        "Serve the health endpoint.",
+	"health.endpoint":       "Health endpoint path.",
+	"health.ready_endpoint": "Readiness endpoint path for load balancers: 200 while serving, 503 as soon as shutdown begins. Empty disables.",
+	"health.shutdown_delay": "After ready_endpoint turns 503 on shutdown, keep accepting connections this long before closing the listener and draining, so load balancers see the change first (0 = close at once).",
+	"health.listen_address": "Health listener address (also serves metrics and the web UI).",
+	"health.detailed":       "Include version and extended details in health responses.",
+	"health.memstats_ttl":   "Reuse the detailed memory figure for this long so frequent polls don't each call ReadMemStats (0 = every request).",
+
+	"monitoring.metrics_enabled":               "Serve Prometheus metrics on the health listener.",
+	"monitoring.metrics_endpoint":              "Metrics endpoint path.",
+	"monitoring.metrics_auth_token":            "Bearer token required to scrape metrics_endpoint; leave empty on loopback-only listeners.",
+	"monitoring.method_metrics":                "JSON-RPC methods counted individually; others count as \"other\".",
+	"monitoring.statsd_address":                "StatsD server (host:port) to push metrics to; empty disables.",
+	"monitoring.statsd_interval":               "StatsD push interval.",
+	"monitoring.metrics_file":                  "File rewritten with all metrics in Prometheus text format, for hosts without a scraper; empty disables.",
+	"monitoring.metrics_file_interval":         "How often monitoring.metrics_file is rewritten.",
+	"monitoring.connection_warn_threshold":     "Log a connection_threshold warning event when active connections reach this; it clears once they fall about 10% below. 0 disables.",
+	"monitoring.connection_critical_threshold": "Like connection_warn_threshold but logs level \"critical\"; must be greater than the warn threshold. 0 disables.",
+
+	"webui.audit_file":    "Append the web UI audit log to this file; empty = in memory only.",
+	"webui.read_only":     "Reject mutating web UI API requests.",
+	"webui.locked_fields": "Config fields the web UI may not change (403), e.g. [max_connections, rate_limit_enabled]. Reloadable.",
+}
+
+// Sample returns DefaultConfig as YAML with every field preceded by a
+// one-line description from fieldDocs.
+func Sample() ([]byte, error) {
+	var root yaml.Node
+	if err := root.Encode(DefaultConfig()); err != nil {
+		return nil, fmt.Errorf("encoding defaults: %w", err)
+	}
+	annotateSample("", &root)
+
+	var buf bytes.Buffer
+	for _, line := range strings.Split(sampleHeader, "\n") {
+		buf.WriteString(strings.TrimSpace("# " + line))
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// annotateSample sets the head comment of each key in a mapping node from
+// sectionDocs or fieldDocs and descends into non-empty nested mappings.
+func annotateSample(prefix string, n *yaml.Node) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		path := prefix + key.Value
+		if isSampleSection(val) {
+			key.HeadComment = sectionDocs[path]
+			annotateSample(path+".", val)
+			continue
+		}
+		key.HeadComment = fieldDocs[path]
+	}
+}
+
+// isSampleSection reports whether val is a nested struct rather than a leaf
+// value. Map-valued fields are leaves but encode as empty mappings by default.
+func isSampleSection(val *yaml.Node) bool {
+	return val.Kind == yaml.MappingNode && len(val.Content) > 0
+}
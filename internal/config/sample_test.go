@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSampleLoadsAndValidates(t *testing.T) {
+	data, err := Sample()
+	if err != nil {
+		t.Fatalf("Sample: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("sample config does not load: %v\n%s", err, data)
+	}
+	// Compare encoded forms: empty lists in the sample decode as empty
+	// slices where the defaults have nil.
+	got, _ := yaml.Marshal(cfg)
+	want, _ := yaml.Marshal(DefaultConfig())
+	if string(got) != string(want) {
+		t.Errorf("sample config differs from defaults:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSampleDocumentsEveryField(t *testing.T) {
+	var root yaml.Node
+	if err := root.Encode(DefaultConfig()); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	var walk func(prefix string, n *yaml.Node)
+	walk = func(prefix string, n *yaml.Node) {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			path := prefix + n.Content[i].Value
+			if isSampleSection(n.Content[i+1]) {
+				walk(path+".", n.Content[i+1])
+				continue
+			}
+			seen[path] = true
+			if fieldDocs[path] == "" {
+				t.Errorf("config field %s has no description in fieldDocs", path)
+			}
+		}
+	}
+	walk("", &root)
+
+	for path := range fieldDocs {
+		if !seen[path] {
+			t.Errorf("fieldDocs entry %s does not match a config field", path)
+		}
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"os"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/cortexuvula/clawreachbridge/internal/config"
 	"github.com/cortexuvula/clawreachbridge/internal/metrics"
 	"github.com/cortexuvula/clawreachbridge/internal/proxy"
 )
@@ -28,6 +32,15 @@ type Details struct {
 	TotalConnections int64   `json:"total_connections"`
 	TotalMessages    int64   `json:"total_messages"`
 	MemoryMB         float64 `json:"memory_mb"`
+
+	Directories []DirectoryStatus `json:"directories,omitempty"`
+}
+
+// DirectoryStatus reports whether a directory the bridge writes to is usable.
+type DirectoryStatus struct {
+	Path     string `json:"path"`
+	Writable bool   `json:"writable"`
+	Error    string `json:"error,omitempty"`
 }
 
 // Handler serves the health check endpoint.
@@ -38,6 +51,17 @@ type Handler struct {
 	gatewayURL string
 	version    string
 	detailed   bool
+	dirs       []string // checked for writability on each request
+
+	shuttingDown atomic.Bool
+
+	// runtime.ReadMemStats stops the world briefly, so the detailed memory
+	// figure is cached for memTTL instead of read on every poll.
+	readMemStats func(*runtime.MemStats)
+	memTTL       time.Duration
+	memMu        sync.Mutex
+	memAlloc     uint64
+	memReadAt    time.Time
 }
 
 // NewHandler creates a new health check handler.
@@ -48,6 +72,8 @@ func NewHandler(p *proxy.Proxy, gatewayURL, version string, detailed bool) *Hand
 		gatewayURL: gatewayURL,
 		version:    version,
 		detailed:   detailed,
+
+		readMemStats: runtime.ReadMemStats,
 	}
 }
 
@@ -56,6 +82,41 @@ func (h *Handler) SetMetrics(m *metrics.Metrics) {
 	h.metrics = m
 }
 
+// SetWritableDirs sets directories that must exist and be writable, such as
+// the media directory and its file-receive inbox. Any failing directory marks the bridge degraded.
+func (h *Handler) SetWritableDirs(dirs ...string) {
+	h.dirs = dirs
+}
+
+// SetMemStatsTTL sets how long the detailed memory figure is reused before
+// ReadMemStats is called again (0 = every request).
+func (h *Handler) SetMemStatsTTL(ttl time.Duration) {
+	h.memTTL = ttl
+}
+
+// SetShuttingDown marks the bridge as shutting down. From then on the
+// readiness endpoint and the health endpoint both answer 503, so load
+// balancers stop routing new clients here while existing ones drain.
+func (h *Handler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// ReadyHandler returns the readiness endpoint: 200 {"status":"ready"} while
+// the bridge accepts connections, 503 {"status":"shutting_down"} once
+// SetShuttingDown has been called. Unlike the health endpoint it never
+// probes the gateway, so it is cheap enough for tight load balancer polling.
+func (h *Handler) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, httpCode := "ready", http.StatusOK
+		if h.shuttingDown.Load() {
+			status, httpCode = "shutting_down", http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(httpCode)
+		json.NewEncoder(w).Encode(map[string]string{"status": status})
+	})
+}
+
 // ServeHTTP handles health check requests.
 // Health listener runs on 127.0.0.1:8081 (separate from proxy listener).
 // This allows local monitoring tools (systemd, Prometheus, Nagios) to check
@@ -66,17 +127,28 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if h.metrics != nil {
 		if gatewayOK {
 			h.metrics.GatewayReachable.Set(1)
+			h.metrics.GatewaySeen()
 		} else {
 			h.metrics.GatewayReachable.Set(0)
 		}
 	}
 
+	dirs := h.checkDirs()
+	dirsOK := true
+	for _, d := range dirs {
+		dirsOK = dirsOK && d.Writable
+	}
+
 	status := "ok"
 	httpCode := http.StatusOK
-	if !gatewayOK {
+	if !gatewayOK || !dirsOK {
 		status = "degraded"
 		httpCode = http.StatusServiceUnavailable
 	}
+	if h.shuttingDown.Load() {
+		status = "shutting_down"
+		httpCode = http.StatusServiceUnavailable
+	}
 
 	resp := Response{
 		Status:            status,
@@ -87,13 +159,12 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if h.detailed {
-		var memStats runtime.MemStats
-		runtime.ReadMemStats(&memStats)
 		resp.Version = h.version
 		resp.Details = &Details{
 			TotalConnections: h.proxy.TotalConnections(),
 			TotalMessages:    h.proxy.TotalMessages(),
-			MemoryMB:         float64(memStats.Alloc) / 1024 / 1024,
+			MemoryMB:         float64(h.memAllocBytes()) / 1024 / 1024,
+			Directories:      dirs,
 		}
 	}
 
@@ -102,6 +173,19 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// memAllocBytes returns the heap allocation from the last ReadMemStats,
+// refreshing it lazily once it is older than memTTL.
+func (h *Handler) memAllocBytes() uint64 {
+	h.memMu.Lock()
+	defer h.memMu.Unlock()
+	if h.memReadAt.IsZero() || time.Since(h.memReadAt) >= h.memTTL {
+		var memStats runtime.MemStats
+		h.readMemStats(&memStats)
+		h.memAlloc, h.memReadAt = memStats.Alloc, time.Now()
+	}
+	return h.memAlloc
+}
+
 // checkGateway verifies the upstream Gateway is reachable.
 // Uses a plain HTTP request (not WebSocket dial) to avoid creating real
 // connections and polluting Gateway logs on every health poll.
@@ -124,9 +208,31 @@ func (h *Handler) checkGateway() bool {
 
 	resp, err := noRedirectClient.Do(req)
 	if err != nil {
-		slog.Debug("gateway unreachable", "url", h.gatewayURL, "error", err)
+		slog.Debug("gateway unreachable", "url", config.RedactURL(h.gatewayURL), "error", err)
 		return false
 	}
 	resp.Body.Close()
 	return true // any response (even 4xx/3xx) means Gateway is alive
 }
+
+// checkDirs probes each configured directory by creating and removing a
+// temporary file, which catches both a missing directory and lost permissions.
+func (h *Handler) checkDirs() []DirectoryStatus {
+	var statuses []DirectoryStatus
+	for _, dir := range h.dirs {
+		st := DirectoryStatus{Path: dir}
+		f, err := os.CreateTemp(dir, ".health-*")
+		if err == nil {
+			f.Close()
+			err = os.Remove(f.Name())
+		}
+		if err != nil {
+			slog.Debug("health directory check failed", "dir", dir, "error", err)
+			st.Error = err.Error()
+		} else {
+			st.Writable = true
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses
+}
@@ -4,7 +4,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/cortexuvula/clawreachbridge/internal/proxy"
 )
@@ -131,3 +135,191 @@ func TestHealthHandler_Gateway4xx(t *testing.T) {
 		t.Errorf("status = %q, want %q", resp.Status, "ok")
 	}
 }
+
+func TestHealthHandler_WritableDirs(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+
+	writable := t.TempDir()
+	readOnly := t.TempDir()
+	if err := os.Chmod(readOnly, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(readOnly, 0755) })
+	permsEnforced := true
+	if f, err := os.CreateTemp(readOnly, "probe"); err == nil {
+		f.Close()
+		os.Remove(f.Name())
+		permsEnforced = false
+	}
+
+	tests := []struct {
+		name       string
+		dir        string
+		wantCode   int
+		wantStatus string
+	}{
+		{"writable", writable, http.StatusOK, "ok"},
+		{"read-only", readOnly, http.StatusServiceUnavailable, "degraded"},
+		{"missing", filepath.Join(writable, "missing"), http.StatusServiceUnavailable, "degraded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.dir == readOnly && !permsEnforced {
+				t.Skip("directory permissions are not enforced (running as root?)")
+			}
+			h := NewHandler(proxy.New(), gateway.URL, "test-version", true)
+			h.SetWritableDirs(tt.dir)
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d", rec.Code, tt.wantCode)
+			}
+			var resp Response
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", resp.Status, tt.wantStatus)
+			}
+			if len(resp.Details.Directories) != 1 {
+				t.Fatalf("directories = %+v, want one entry", resp.Details.Directories)
+			}
+			d := resp.Details.Directories[0]
+			if d.Path != tt.dir || d.Writable != (tt.wantStatus == "ok") {
+				t.Errorf("directory status = %+v", d)
+			}
+			if entries, _ := os.ReadDir(writable); len(entries) != 0 {
+				t.Errorf("probe file left behind: %v", entries)
+			}
+		})
+	}
+}
+
+func TestHealthHandler_ChecksEveryWritableDir(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+
+	// The inbox is fine but the media directory it lives under is gone.
+	mediaDir := filepath.Join(t.TempDir(), "media")
+	inboxDir := t.TempDir()
+	h := NewHandler(proxy.New(), gateway.URL, "test-version", true)
+	h.SetWritableDirs(mediaDir, inboxDir)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status code = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Details.Directories) != 2 {
+		t.Fatalf("directories = %+v, want two entries", resp.Details.Directories)
+	}
+	if d := resp.Details.Directories[0]; d.Path != mediaDir || d.Writable {
+		t.Errorf("media directory status = %+v, want unwritable", d)
+	}
+	if d := resp.Details.Directories[1]; d.Path != inboxDir || !d.Writable {
+		t.Errorf("inbox directory status = %+v, want writable", d)
+	}
+}
+
+func TestHealthHandler_MemStatsCached(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer gateway.Close()
+
+	h := NewHandler(proxy.New(), gateway.URL, "test-version", true)
+	reads := 0
+	h.readMemStats = func(m *runtime.MemStats) {
+		reads++
+		m.Alloc = uint64(reads) * 1024 * 1024
+	}
+	h.SetMemStatsTTL(time.Hour)
+
+	poll := func() float64 {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		var resp Response
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp.Details.MemoryMB
+	}
+
+	for i := 0; i < 5; i++ {
+		if got := poll(); got != 1 {
+			t.Errorf("poll %d memory_mb = %v, want 1 (cached)", i, got)
+		}
+	}
+	if reads != 1 {
+		t.Errorf("ReadMemStats called %d times within the TTL, want 1", reads)
+	}
+
+	// Once the TTL has passed the next poll refreshes.
+	h.memReadAt = time.Now().Add(-2 * time.Hour)
+	if got := poll(); got != 2 {
+		t.Errorf("memory_mb after TTL = %v, want 2", got)
+	}
+	if reads != 2 {
+		t.Errorf("ReadMemStats called %d times, want 2", reads)
+	}
+}
+
+func TestHealthHandler_MemStatsTTLZeroReadsEveryTime(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer gateway.Close()
+
+	h := NewHandler(proxy.New(), gateway.URL, "test-version", true)
+	reads := 0
+	h.readMemStats = func(m *runtime.MemStats) { reads++ }
+
+	for i := 0; i < 3; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	}
+	if reads != 3 {
+		t.Errorf("ReadMemStats called %d times with no TTL, want 3", reads)
+	}
+}
+
+func TestHealthHandler_ShuttingDown(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer gateway.Close()
+	h := NewHandler(proxy.New(), gateway.URL, "test-version", false)
+	ready := h.ReadyHandler()
+
+	get := func(handler http.Handler) (int, string) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		var body struct{ Status string }
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return rec.Code, body.Status
+	}
+
+	if code, status := get(ready); code != http.StatusOK || status != "ready" {
+		t.Errorf("ready before shutdown = %d %q, want 200 \"ready\"", code, status)
+	}
+	if code, status := get(h); code != http.StatusOK || status != "ok" {
+		t.Errorf("health before shutdown = %d %q, want 200 \"ok\"", code, status)
+	}
+
+	h.SetShuttingDown()
+
+	if code, status := get(ready); code != http.StatusServiceUnavailable || status != "shutting_down" {
+		t.Errorf("ready after shutdown = %d %q, want 503 \"shutting_down\"", code, status)
+	}
+	if code, status := get(h); code != http.StatusServiceUnavailable || status != "shutting_down" {
+		t.Errorf("health after shutdown = %d %q, want 503 \"shutting_down\"", code, status)
+	}
+}
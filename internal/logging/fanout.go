@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// FanoutHandler forwards each log record to several handlers, e.g. a text
+// handler for the console and a JSON handler for a mirror file.
+type FanoutHandler struct {
+	handlers []slog.Handler
+}
+
+// NewFanoutHandler creates a handler that writes to every handler in handlers.
+func NewFanoutHandler(handlers ...slog.Handler) *FanoutHandler {
+	return &FanoutHandler{handlers: handlers}
+}
+
+// Enabled reports whether any of the handlers handles records at level.
+func (h *FanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, inner := range h.handlers {
+		if inner.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle forwards a copy of the record to each handler enabled for its level.
+func (h *FanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, inner := range h.handlers {
+		if !inner.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := inner.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a new handler with the given attributes pre-set on every handler.
+func (h *FanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, inner := range h.handlers {
+		handlers[i] = inner.WithAttrs(attrs)
+	}
+	return &FanoutHandler{handlers: handlers}
+}
+
+// WithGroup returns a new handler with the given group name on every handler.
+func (h *FanoutHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, inner := range h.handlers {
+		handlers[i] = inner.WithGroup(name)
+	}
+	return &FanoutHandler{handlers: handlers}
+}
@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
@@ -48,6 +49,17 @@ func SetupHandler(level, format, file string, maxSizeMB, maxBackups, maxAgeDays
 	return handler, lj
 }
 
+// WithJSONMirror wraps handler so every record is also written as JSON to
+// file (with the same rotation settings as the primary log). If file is
+// empty, handler is returned unchanged with a nil lumberjack logger.
+func WithJSONMirror(handler slog.Handler, level, file string, maxSizeMB, maxBackups, maxAgeDays int, compress bool) (slog.Handler, *lumberjack.Logger) {
+	if file == "" {
+		return handler, nil
+	}
+	mirror, lj := SetupHandler(level, "json", file, maxSizeMB, maxBackups, maxAgeDays, compress)
+	return NewFanoutHandler(handler, mirror), lj
+}
+
 func parseLevel(level string) slog.Level {
 	switch level {
 	case "debug":
@@ -60,3 +72,22 @@ func parseLevel(level string) slog.Level {
 		return slog.LevelInfo
 	}
 }
+
+// ReopenLog builds a fresh handler via SetupHandler and, once its log file is
+// open, closes the current lumberjack logger (if any). Used after external log
+// rotation (SIGUSR1) so the process stops writing to a renamed or deleted file
+// handle. If the new file can't be opened, old is left open and an error is
+// returned so the caller can keep logging through the existing handler.
+func ReopenLog(old *lumberjack.Logger, level, format, file string, maxSizeMB, maxBackups, maxAgeDays int, compress bool) (slog.Handler, *lumberjack.Logger, error) {
+	handler, lj := SetupHandler(level, format, file, maxSizeMB, maxBackups, maxAgeDays, compress)
+	if lj != nil {
+		// lumberjack opens lazily; an empty write opens the file now.
+		if _, err := lj.Write(nil); err != nil {
+			return nil, nil, fmt.Errorf("opening log file %s: %w", file, err)
+		}
+	}
+	if old != nil {
+		old.Close()
+	}
+	return handler, lj, nil
+}
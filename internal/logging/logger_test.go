@@ -1,9 +1,13 @@
 package logging
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -82,3 +86,206 @@ func TestParseLevel(t *testing.T) {
 		})
 	}
 }
+
+func TestReopenLogAfterRotation(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "bridge.log")
+
+	handler, lj := SetupHandler("info", "json", logFile, 10, 1, 7, false)
+	slog.New(handler).Info("before rotation")
+
+	// Simulate logrotate moving the file out of the way.
+	rotated := logFile + ".1"
+	if err := os.Rename(logFile, rotated); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+
+	newHandler, newLJ, err := ReopenLog(lj, "info", "json", logFile, 10, 1, 7, false)
+	if err != nil {
+		t.Fatalf("ReopenLog: %v", err)
+	}
+	defer newLJ.Close()
+	if newHandler == handler || newLJ == lj {
+		t.Fatal("expected ReopenLog to create a new handler and lumberjack logger")
+	}
+	slog.New(newHandler).Info("after rotation")
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("log file not recreated: %v", err)
+	}
+	if !strings.Contains(string(data), "after rotation") {
+		t.Errorf("new log file = %q, want post-rotation entry", data)
+	}
+	old, _ := os.ReadFile(rotated)
+	if strings.Contains(string(old), "after rotation") {
+		t.Error("post-rotation entry written to rotated file")
+	}
+}
+
+func TestReopenLogStdout(t *testing.T) {
+	handler, lj, err := ReopenLog(nil, "info", "text", "", 100, 3, 28, true)
+	if err != nil {
+		t.Fatalf("ReopenLog: %v", err)
+	}
+	if handler == nil {
+		t.Fatal("expected handler")
+	}
+	if lj != nil {
+		t.Error("expected nil lumberjack logger for stdout")
+	}
+}
+
+func TestReopenLogAppliesReloadedFormatAndFile(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "bridge.log")
+
+	handler, lj := SetupHandler("info", "json", logFile, 10, 1, 7, false)
+	slog.New(handler).Info("as json")
+
+	// Reload switches the format; the same file is reopened and the
+	// previous logger's handle closed, leaving exactly one open.
+	handler, lj, _ = ReopenLog(lj, "info", "text", logFile, 10, 1, 7, false)
+	slog.New(handler).Info("as text")
+	if n := openHandles(t, logFile); n != 1 {
+		t.Errorf("open handles on %s = %d, want 1 (previous logger closed)", logFile, n)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log has %d lines, want 2: %q", len(lines), data)
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil || rec["msg"] != "as json" {
+		t.Errorf("first line = %q, want a JSON record", lines[0])
+	}
+	if !strings.Contains(lines[1], `msg="as text"`) || json.Valid([]byte(lines[1])) {
+		t.Errorf("second line = %q, want a text record", lines[1])
+	}
+
+	// Reload moves the log to another file; the old one gets nothing more.
+	newFile := filepath.Join(dir, "moved.log")
+	handler, lj, _ = ReopenLog(lj, "info", "text", newFile, 10, 1, 7, false)
+	defer lj.Close()
+	slog.New(handler).Info("after move")
+
+	if moved, _ := os.ReadFile(newFile); !strings.Contains(string(moved), "after move") {
+		t.Errorf("new log file = %q, want the post-reload entry", moved)
+	}
+	if old, _ := os.ReadFile(logFile); strings.Contains(string(old), "after move") {
+		t.Error("post-reload entry written to the previous log file")
+	}
+	if n := openHandles(t, logFile); n != 0 {
+		t.Errorf("open handles on %s = %d after moving the log, want 0", logFile, n)
+	}
+}
+
+// openHandles counts this process's open file descriptors on path. It
+// skips the test where /proc/self/fd isn't available.
+func openHandles(t *testing.T, path string) int {
+	t.Helper()
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skipf("cannot list open file descriptors: %v", err)
+	}
+	n := 0
+	for _, fd := range fds {
+		if target, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name())); err == nil && target == path {
+			n++
+		}
+	}
+	return n
+}
+
+func TestReopenLogKeepsOldLoggerWhenOpenFails(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "bridge.log")
+	handler, lj := SetupHandler("info", "json", logFile, 10, 1, 7, false)
+	defer lj.Close()
+	slog.New(handler).Info("before reopen")
+
+	// A path under a regular file can't be created.
+	bad := filepath.Join(logFile, "nested.log")
+	if h, newLJ, err := ReopenLog(lj, "info", "json", bad, 10, 1, 7, false); err == nil || h != nil || newLJ != nil {
+		t.Fatalf("ReopenLog(%s) = %v, %v, %v; want an error", bad, h, newLJ, err)
+	}
+
+	// The previous logger is still open and writable.
+	slog.New(handler).Info("still logging")
+	if data, _ := os.ReadFile(logFile); !strings.Contains(string(data), "still logging") {
+		t.Errorf("log file = %q, want entry written after the failed reopen", data)
+	}
+}
+
+func TestWithJSONMirrorWritesBothFormats(t *testing.T) {
+	dir := t.TempDir()
+	textFile := filepath.Join(dir, "bridge.log")
+	jsonFile := filepath.Join(dir, "bridge.json")
+
+	primary, lj := SetupHandler("info", "text", textFile, 10, 1, 7, false)
+	defer lj.Close()
+	handler, mirrorLJ := WithJSONMirror(primary, "info", jsonFile, 10, 1, 7, false)
+	if mirrorLJ == nil {
+		t.Fatal("expected lumberjack logger for mirror file")
+	}
+	defer mirrorLJ.Close()
+
+	slog.New(handler).With("component", "test").Info("mirrored", "key", "value")
+
+	text, err := os.ReadFile(textFile)
+	if err != nil {
+		t.Fatalf("read text log: %v", err)
+	}
+	if !strings.Contains(string(text), "msg=mirrored") || !strings.Contains(string(text), "component=test") {
+		t.Errorf("text log = %q, want text-format record", text)
+	}
+
+	raw, err := os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatalf("read json mirror: %v", err)
+	}
+	var rec map[string]any
+	if err := json.Unmarshal(bytes.TrimSpace(raw), &rec); err != nil {
+		t.Fatalf("mirror is not JSON: %v (%q)", err, raw)
+	}
+	if rec["msg"] != "mirrored" || rec["key"] != "value" || rec["component"] != "test" {
+		t.Errorf("json record = %v, want msg/key/component", rec)
+	}
+}
+
+func TestWithJSONMirrorDisabled(t *testing.T) {
+	primary, _ := SetupHandler("info", "text", "", 100, 3, 28, false)
+	handler, lj := WithJSONMirror(primary, "info", "", 100, 3, 28, false)
+	if handler != primary {
+		t.Error("expected handler unchanged when mirror file is empty")
+	}
+	if lj != nil {
+		t.Error("expected nil lumberjack logger when mirror file is empty")
+	}
+}
+
+func TestFanoutHandlerRespectsLevels(t *testing.T) {
+	var debugBuf, warnBuf bytes.Buffer
+	h := NewFanoutHandler(
+		slog.NewTextHandler(&debugBuf, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.NewTextHandler(&warnBuf, &slog.HandlerOptions{Level: slog.LevelWarn}),
+	)
+	if !h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("should be enabled for Debug when any handler is")
+	}
+
+	logger := slog.New(h)
+	logger.Debug("quiet")
+	logger.Warn("loud")
+
+	if !strings.Contains(debugBuf.String(), "quiet") || !strings.Contains(debugBuf.String(), "loud") {
+		t.Errorf("debug sink = %q, want both records", debugBuf.String())
+	}
+	if strings.Contains(warnBuf.String(), "quiet") || !strings.Contains(warnBuf.String(), "loud") {
+		t.Errorf("warn sink = %q, want only the warn record", warnBuf.String())
+	}
+}
@@ -21,6 +21,7 @@ type RingBuffer struct {
 	head    int  // next write position
 	full    bool // whether we've wrapped around
 	cap     int
+	dropped uint64 // entries overwritten before being read out
 }
 
 // NewRingBuffer creates a new ring buffer with the given capacity.
@@ -34,6 +35,9 @@ func NewRingBuffer(capacity int) *RingBuffer {
 // Add appends a log entry to the buffer, overwriting the oldest if full.
 func (rb *RingBuffer) Add(entry LogEntry) {
 	rb.mu.Lock()
+	if rb.full {
+		rb.dropped++
+	}
 	rb.entries[rb.head] = entry
 	rb.head = (rb.head + 1) % rb.cap
 	if rb.head == 0 || (rb.head > 0 && rb.full) {
@@ -82,3 +86,12 @@ func (rb *RingBuffer) Len() int {
 func (rb *RingBuffer) Cap() int {
 	return rb.cap
 }
+
+// DroppedCount returns how many entries have been overwritten since the
+// buffer was created. A steadily climbing count means the buffer is too small
+// to hold the log volume between reads.
+func (rb *RingBuffer) DroppedCount() uint64 {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+	return rb.dropped
+}
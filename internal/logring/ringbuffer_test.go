@@ -150,3 +150,24 @@ func TestRingBufferConcurrent(t *testing.T) {
 		t.Errorf("Len() = %d exceeds Cap() = %d", rb.Len(), rb.Cap())
 	}
 }
+
+func TestRingBufferDroppedCount(t *testing.T) {
+	rb := NewRingBuffer(3)
+
+	for i := 0; i < 3; i++ {
+		rb.Add(LogEntry{Message: "fill", Level: slog.LevelInfo, Time: time.Now()})
+	}
+	if n := rb.DroppedCount(); n != 0 {
+		t.Fatalf("DroppedCount() = %d after filling to capacity, want 0", n)
+	}
+
+	for i := 0; i < 5; i++ {
+		rb.Add(LogEntry{Message: "overflow", Level: slog.LevelInfo, Time: time.Now()})
+	}
+	if n := rb.DroppedCount(); n != 5 {
+		t.Errorf("DroppedCount() = %d, want 5", n)
+	}
+	if rb.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", rb.Len())
+	}
+}
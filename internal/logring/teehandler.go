@@ -3,14 +3,16 @@ package logring
 import (
 	"context"
 	"log/slog"
+	"maps"
 )
 
 // TeeHandler wraps an inner slog.Handler and also writes log records
-// to a RingBuffer for the web UI log viewer.
+// to a RingBuffer for the web UI log viewer. Attributes are flattened into
+// LogEntry.Attrs with dotted keys for groups, e.g. "req.method".
 type TeeHandler struct {
 	inner  slog.Handler
 	ring   *RingBuffer
-	attrs  []slog.Attr
+	preset map[string]any // flattened WithAttrs attributes, keys already qualified
 	groups []string
 }
 
@@ -35,17 +37,13 @@ func (h *TeeHandler) Handle(ctx context.Context, r slog.Record) error {
 	}
 
 	// Collect attributes: pre-set attrs from WithAttrs + record attrs
-	attrs := make(map[string]any)
-
-	// Add pre-set attrs (from WithAttrs calls)
-	prefix := groupPrefix(h.groups)
-	for _, a := range h.attrs {
-		attrs[prefix+a.Key] = a.Value.Any()
+	attrs := maps.Clone(h.preset)
+	if attrs == nil {
+		attrs = make(map[string]any)
 	}
-
-	// Add record attrs
+	prefix := groupPrefix(h.groups)
 	r.Attrs(func(a slog.Attr) bool {
-		attrs[prefix+a.Key] = a.Value.Any()
+		flattenAttr(attrs, prefix, a)
 		return true
 	})
 
@@ -59,12 +57,21 @@ func (h *TeeHandler) Handle(ctx context.Context, r slog.Record) error {
 	return h.inner.Handle(ctx, r)
 }
 
-// WithAttrs returns a new handler with the given attributes pre-set.
+// WithAttrs returns a new handler with the given attributes pre-set. They are
+// qualified with the groups open now, not any opened later.
 func (h *TeeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	preset := maps.Clone(h.preset)
+	if preset == nil {
+		preset = make(map[string]any, len(attrs))
+	}
+	prefix := groupPrefix(h.groups)
+	for _, a := range attrs {
+		flattenAttr(preset, prefix, a)
+	}
 	return &TeeHandler{
 		inner:  h.inner.WithAttrs(attrs),
 		ring:   h.ring,
-		attrs:  append(cloneAttrs(h.attrs), attrs...),
+		preset: preset,
 		groups: h.groups,
 	}
 }
@@ -77,18 +84,43 @@ func (h *TeeHandler) WithGroup(name string) slog.Handler {
 	return &TeeHandler{
 		inner:  h.inner.WithGroup(name),
 		ring:   h.ring,
-		attrs:  cloneAttrs(h.attrs),
+		preset: h.preset, // never mutated after construction
 		groups: append(append([]string{}, h.groups...), name),
 	}
 }
 
-func cloneAttrs(attrs []slog.Attr) []slog.Attr {
-	if attrs == nil {
-		return nil
+// flattenAttr stores a into dst under prefix+key, descending into groups with
+// dotted keys. Following slog's rules, LogValuers are resolved, empty attrs
+// and empty groups are dropped, and a group with an empty key is inlined.
+func flattenAttr(dst map[string]any, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			flattenAttr(dst, prefix, ga)
+		}
+		return
+	}
+	dst[prefix+a.Key] = attrValue(a.Value)
+}
+
+// attrValue converts v to a form that reads well once JSON-encoded for the
+// web UI: durations and errors become strings instead of nanoseconds and {}.
+func attrValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
 	}
-	c := make([]slog.Attr, len(attrs))
-	copy(c, attrs)
-	return c
+	return v.Any()
 }
 
 func groupPrefix(groups []string) string {
@@ -3,6 +3,7 @@ package logring
 import (
 	"bytes"
 	"context"
+	"errors"
 	"log/slog"
 	"strings"
 	"testing"
@@ -87,3 +88,74 @@ func TestTeeHandlerWithGroup(t *testing.T) {
 		t.Errorf("attrs[req.method] = %v, want %q", v, "GET")
 	}
 }
+
+func TestTeeHandlerFlattensGroups(t *testing.T) {
+	ring := NewRingBuffer(100)
+	handler := NewTeeHandler(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelDebug}), ring)
+
+	logger := slog.New(handler).With("component", "proxy").WithGroup("conn").With("id", "c-1")
+	logger.Info("test",
+		slog.Group("client", slog.String("ip", "100.64.0.1"), slog.Group("tls", slog.Bool("mtls", true))),
+		slog.Group("", slog.String("inlined", "yes")),
+		slog.Group("empty"),
+	)
+
+	entries := ring.Entries(0, slog.LevelDebug, time.Time{})
+	if len(entries) != 1 {
+		t.Fatalf("ring has %d entries, want 1", len(entries))
+	}
+	want := map[string]any{
+		"component":            "proxy", // added before the group was opened
+		"conn.id":              "c-1",
+		"conn.client.ip":       "100.64.0.1",
+		"conn.client.tls.mtls": true,
+		"conn.inlined":         "yes",
+	}
+	got := entries[0].Attrs
+	if len(got) != len(want) {
+		t.Errorf("attrs = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("attrs[%s] = %v, want %v", k, got[k], v)
+		}
+	}
+}
+
+type lazyValue string
+
+func (l lazyValue) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("resolved", string(l)))
+}
+
+func TestTeeHandlerTypedAttrs(t *testing.T) {
+	ring := NewRingBuffer(100)
+	handler := NewTeeHandler(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelDebug}), ring)
+
+	ts := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	slog.New(handler).Info("test",
+		"count", 3,
+		"ratio", 0.5,
+		"ok", false,
+		"at", ts,
+		"took", 1500*time.Millisecond,
+		"error", errors.New("boom"),
+		"lazy", lazyValue("x"),
+	)
+
+	got := ring.Entries(0, slog.LevelDebug, time.Time{})[0].Attrs
+	want := map[string]any{
+		"count":         int64(3),
+		"ratio":         0.5,
+		"ok":            false,
+		"at":            ts,
+		"took":          "1.5s",
+		"error":         "boom",
+		"lazy.resolved": "x",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("attrs[%s] = %#v, want %#v", k, got[k], v)
+		}
+	}
+}
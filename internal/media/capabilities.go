@@ -0,0 +1,64 @@
+package media
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ClientSupportsHeader is the request header a client uses to list the image
+// MIME types it can render, e.g. "X-Client-Supports: image/png, image/jpeg".
+const ClientSupportsHeader = "X-Client-Supports"
+
+// ClientCapabilities is the set of image MIME types a client renders, parsed
+// from ClientSupportsHeader. A nil set (header absent) accepts everything, so
+// clients that don't send the header keep getting every injected image.
+// Only image/* types are filtered; other files are always injected.
+type ClientCapabilities map[string]bool
+
+// ParseClientCapabilities parses a comma-separated ClientSupportsHeader
+// value. Entries may be exact types or wildcards ("image/*"); parameters
+// after ";" are ignored. An empty header yields nil.
+func ParseClientCapabilities(header string) ClientCapabilities {
+	if strings.TrimSpace(header) == "" {
+		return nil
+	}
+	caps := make(ClientCapabilities)
+	for _, part := range strings.Split(header, ",") {
+		mt, _, _ := strings.Cut(part, ";")
+		if mt = strings.ToLower(strings.TrimSpace(mt)); mt != "" {
+			caps[mt] = true
+		}
+	}
+	return caps
+}
+
+// Supports reports whether the client renders mimeType.
+func (c ClientCapabilities) Supports(mimeType string) bool {
+	if c == nil || !strings.HasPrefix(mimeType, "image/") {
+		return true
+	}
+	return c[mimeType] || c["image/*"] || c["*/*"]
+}
+
+// pathFor returns the file to inject for path given the client's
+// capabilities: path itself when the client renders it, otherwise a sibling
+// with the same name and a supported, configured extension (chart.webp →
+// chart.png), tried in bridge.media.extensions order. ok is false when the
+// client can render neither.
+func (inj *Injector) pathFor(path string, caps ClientCapabilities) (string, bool) {
+	if caps.Supports(mimeFromExt(strings.ToLower(filepath.Ext(path)))) {
+		return path, true
+	}
+	stem := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, ext := range inj.cfg.Extensions {
+		ext = strings.ToLower(ext)
+		if !caps.Supports(mimeFromExt(ext)) {
+			continue
+		}
+		if info, err := os.Stat(stem + ext); err == nil && info.Mode().IsRegular() {
+			return stem + ext, true
+		}
+	}
+	return "", false
+}
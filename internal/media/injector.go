@@ -18,6 +18,10 @@ import (
 // mediaPathRe matches "MEDIA: /path/to/file.ext" lines in message text.
 var mediaPathRe = regexp.MustCompile(`(?m)^MEDIA:\s*(/\S+)$`)
 
+// injectionQueueWait is how long a final message waits for an injection slot
+// when max_concurrent_injections is reached before it is forwarded unmodified.
+const injectionQueueWait = 250 * time.Millisecond
+
 // Injector tracks chat runs and injects images from the gateway's media
 // directory into final chat messages before they reach the client.
 type Injector struct {
@@ -26,6 +30,9 @@ type Injector struct {
 	mu          sync.Mutex
 	runStarts   map[string]time.Time // runId → first delta timestamp
 	sentFiles   map[string]time.Time // filepath → time sent (directory-scan dedup)
+
+	slots     chan struct{} // bounds concurrent enrichFinal calls; nil = unlimited
+	queueWait time.Duration
 }
 
 // NewInjector creates a media injector with the given config.
@@ -51,11 +58,44 @@ func NewInjector(cfg config.MediaConfig) *Injector {
 	if len(resolved) > 0 {
 		slog.Info("media: path allowlist configured", "allowed_dirs", resolved)
 	}
-	return &Injector{
+	inj := &Injector{
 		cfg:         cfg,
 		allowedDirs: resolved,
 		runStarts:   make(map[string]time.Time),
 		sentFiles:   make(map[string]time.Time),
+		queueWait:   injectionQueueWait,
+	}
+	if cfg.MaxConcurrentInjections > 0 {
+		inj.slots = make(chan struct{}, cfg.MaxConcurrentInjections)
+	}
+	return inj
+}
+
+// acquireSlot reserves an injection slot, waiting up to queueWait for one to
+// free up. It returns false if none did; the caller must not call releaseSlot.
+func (inj *Injector) acquireSlot() bool {
+	if inj.slots == nil {
+		return true
+	}
+	select {
+	case inj.slots <- struct{}{}:
+		return true
+	default:
+	}
+	timer := time.NewTimer(inj.queueWait)
+	defer timer.Stop()
+	select {
+	case inj.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// releaseSlot returns a slot reserved by acquireSlot.
+func (inj *Injector) releaseSlot() {
+	if inj.slots != nil {
+		<-inj.slots
 	}
 }
 
@@ -90,12 +130,21 @@ type contentItem struct {
 	Content  string `json:"content,omitempty"`
 	FileName string `json:"fileName,omitempty"`
 	FileSize int64  `json:"fileSize,omitempty"`
+
+	sourcePath string // file the item was read from (dedup key; not serialized)
 }
 
 // ProcessMessage inspects a gateway→client WebSocket message and enriches
 // chat final messages with images from the media directory. Non-chat messages
 // and delta messages are returned unchanged.
 func (inj *Injector) ProcessMessage(payload []byte) []byte {
+	return inj.ProcessMessageFor(payload, nil)
+}
+
+// ProcessMessageFor is ProcessMessage for a client with the given
+// capabilities: images it can't render are swapped for a supported sibling
+// file or left out.
+func (inj *Injector) ProcessMessageFor(payload []byte, caps ClientCapabilities) []byte {
 	var outer outerMessage
 	if err := json.Unmarshal(payload, &outer); err != nil {
 		return payload
@@ -117,7 +166,13 @@ func (inj *Injector) ProcessMessage(payload []byte) []byte {
 		inj.trackDelta(chat.RunID)
 		return inj.stripMediaFromDelta(payload, &outer, &chat)
 	case "final":
-		enriched, err := inj.enrichFinal(&outer, &chat)
+		if !inj.acquireSlot() {
+			slog.Warn("media: injection limit reached, forwarding final unmodified",
+				"runId", chat.RunID, "max_concurrent_injections", inj.cfg.MaxConcurrentInjections)
+			return payload
+		}
+		defer inj.releaseSlot()
+		enriched, err := inj.enrichFinal(&outer, &chat, caps)
 		if err != nil {
 			slog.Warn("media: failed to enrich final message", "error", err)
 			return payload
@@ -241,7 +296,7 @@ func (inj *Injector) isPathAllowed(filePath string) bool {
 // enrichFinal extracts images from the message and injects them as content items.
 // It first looks for explicit MEDIA: /path markers in the message text, then
 // falls back to scanning the configured media directory for recent images.
-func (inj *Injector) enrichFinal(outer *outerMessage, chat *chatPayload) ([]byte, error) {
+func (inj *Injector) enrichFinal(outer *outerMessage, chat *chatPayload, caps ClientCapabilities) ([]byte, error) {
 	// Look up when this run started
 	// Always use MaxAge window for directory scan instead of tracked runStart.
 	// This ensures files created before the current run (e.g. in a prior tool-use
@@ -256,13 +311,13 @@ func (inj *Injector) enrichFinal(outer *outerMessage, chat *chatPayload) ([]byte
 	}
 
 	// Strategy 1: Extract MEDIA: paths from message text
-	images := inj.extractMediaPaths(&msg)
+	images := inj.extractMediaPaths(&msg, caps)
 	mediaPathCount := len(images)
 
 	// Strategy 2: Fall back to directory scanning (files within MaxAge window)
 	var dirScanCount int
 	if len(images) == 0 {
-		images = inj.scanImages()
+		images = inj.scanImages(caps)
 		dirScanCount = len(images)
 	}
 
@@ -285,8 +340,7 @@ func (inj *Injector) enrichFinal(outer *outerMessage, chat *chatPayload) ([]byte
 		inj.mu.Lock()
 		now := time.Now()
 		for _, img := range images {
-			fullPath := filepath.Join(inj.cfg.Directory, img.FileName)
-			inj.sentFiles[fullPath] = now
+			inj.sentFiles[img.sourcePath] = now
 		}
 		inj.mu.Unlock()
 	}
@@ -315,8 +369,9 @@ func (inj *Injector) enrichFinal(outer *outerMessage, chat *chatPayload) ([]byte
 }
 
 // extractMediaPaths looks for "MEDIA: /path/to/file" lines in the message text
-// content items and reads matching image files.
-func (inj *Injector) extractMediaPaths(msg *chatMessage) []contentItem {
+// content items and reads matching image files, substituting a sibling the
+// client can render where needed.
+func (inj *Injector) extractMediaPaths(msg *chatMessage, caps ClientCapabilities) []contentItem {
 	extSet := make(map[string]bool, len(inj.cfg.Extensions))
 	for _, ext := range inj.cfg.Extensions {
 		extSet[strings.ToLower(ext)] = true
@@ -334,7 +389,7 @@ func (inj *Injector) extractMediaPaths(msg *chatMessage) []contentItem {
 	}
 
 	var items []contentItem
-	var totalMarkers, skippedExt, skippedPath, skippedAccess, skippedSize, skippedRead, skippedBudget int
+	var totalMarkers, skippedExt, skippedUnsupported, skippedPath, skippedAccess, skippedSize, skippedRead, skippedBudget int
 	for i, ci := range msg.Content {
 		if ci.Type != "text" {
 			continue
@@ -358,6 +413,19 @@ func (inj *Injector) extractMediaPaths(msg *chatMessage) []contentItem {
 				continue
 			}
 
+			// Client capabilities: swap in a format the client renders.
+			supported, ok := inj.pathFor(filePath, caps)
+			if !ok {
+				slog.Debug("media: MEDIA path format not supported by client", "path", filePath, "mimeType", mimeFromExt(ext))
+				skippedUnsupported++
+				continue
+			}
+			if supported != filePath {
+				slog.Debug("media: substituting client-supported format", "path", filePath, "substitute", supported)
+				filePath = supported
+				ext = strings.ToLower(filepath.Ext(filePath))
+			}
+
 			// Path allowlist check.
 			if !inj.isPathAllowed(filePath) {
 				slog.Warn("media: MEDIA path outside allowed directories", "path", filePath, "allowed_dirs", inj.allowedDirs)
@@ -393,7 +461,7 @@ func (inj *Injector) extractMediaPaths(msg *chatMessage) []contentItem {
 				continue
 			}
 
-			mimeType := mimeFromExt(ext)
+			data, mimeType, fileName := inj.recompressImage(data, mimeFromExt(ext), filepath.Base(filePath), caps)
 			encoded := base64.StdEncoding.EncodeToString(data)
 			contentType := "image"
 			if !strings.HasPrefix(mimeType, "image/") {
@@ -404,12 +472,12 @@ func (inj *Injector) extractMediaPaths(msg *chatMessage) []contentItem {
 				Type:     contentType,
 				MimeType: mimeType,
 				Content:  encoded,
-				FileName: filepath.Base(filePath),
-				FileSize: info.Size(),
+				FileName: fileName,
+				FileSize: int64(len(data)),
 			})
 			slog.Debug("media: extracted media from MEDIA path",
 				"path", filePath,
-				"size", info.Size(),
+				"size", len(data),
 				"mimeType", mimeType,
 				"contentType", contentType,
 			)
@@ -420,6 +488,7 @@ func (inj *Injector) extractMediaPaths(msg *chatMessage) []contentItem {
 		"totalMarkers", totalMarkers,
 		"extracted", len(items),
 		"skippedExt", skippedExt,
+		"skippedUnsupported", skippedUnsupported,
 		"skippedPath", skippedPath,
 		"skippedAccess", skippedAccess,
 		"skippedSize", skippedSize,
@@ -431,8 +500,9 @@ func (inj *Injector) extractMediaPaths(msg *chatMessage) []contentItem {
 }
 
 // scanImages looks for files in the media directory that were modified
-// within the MaxAge window and match the configured extensions.
-func (inj *Injector) scanImages() []contentItem {
+// within the MaxAge window and match the configured extensions, skipping
+// images the client can't render.
+func (inj *Injector) scanImages(caps ClientCapabilities) []contentItem {
 	if inj.cfg.Directory == "" {
 		slog.Debug("media: directory scan skipped, no directory configured")
 		return nil
@@ -450,7 +520,7 @@ func (inj *Injector) scanImages() []contentItem {
 	}
 
 	var items []contentItem
-	var totalFiles, wrongExt, tooOld, tooLarge, alreadySent int
+	var totalFiles, wrongExt, unsupported, tooOld, tooLarge, alreadySent int
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
@@ -462,6 +532,10 @@ func (inj *Injector) scanImages() []contentItem {
 			wrongExt++
 			continue
 		}
+		if !caps.Supports(mimeFromExt(ext)) {
+			unsupported++
+			continue
+		}
 
 		info, err := entry.Info()
 		if err != nil {
@@ -499,7 +573,7 @@ func (inj *Injector) scanImages() []contentItem {
 			continue
 		}
 
-		mimeType := mimeFromExt(ext)
+		data, mimeType, fileName := inj.recompressImage(data, mimeFromExt(ext), entry.Name(), caps)
 		encoded := base64.StdEncoding.EncodeToString(data)
 		contentType := "image"
 		if !strings.HasPrefix(mimeType, "image/") {
@@ -510,13 +584,15 @@ func (inj *Injector) scanImages() []contentItem {
 			Type:     contentType,
 			MimeType: mimeType,
 			Content:  encoded,
-			FileName: entry.Name(),
-			FileSize: info.Size(),
+			FileName: fileName,
+			FileSize: int64(len(data)),
+
+			sourcePath: fullPath,
 		})
 
 		slog.Debug("media: found media for injection",
 			"file", entry.Name(),
-			"size", info.Size(),
+			"size", len(data),
 			"mimeType", mimeType,
 			"contentType", contentType,
 		)
@@ -526,6 +602,7 @@ func (inj *Injector) scanImages() []contentItem {
 		"dir", inj.cfg.Directory,
 		"totalFiles", totalFiles,
 		"wrongExt", wrongExt,
+		"unsupported", unsupported,
 		"tooOld", tooOld,
 		"tooLarge", tooLarge,
 		"alreadySent", alreadySent,
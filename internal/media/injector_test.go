@@ -1,10 +1,17 @@
 package media
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -399,3 +406,306 @@ func TestProcessMessage_InvalidJSON_PassThrough(t *testing.T) {
 		t.Error("invalid JSON should be returned unchanged")
 	}
 }
+
+// writeSyntheticPNG writes a noisy w×h PNG (large when encoded) and returns its path.
+func writeSyntheticPNG(t *testing.T, dir, name string, w, h int, alpha uint8) string {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	rng := rand.New(rand.NewSource(1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: uint8(rng.Intn(256)), A: alpha})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// injectedItem runs a final message with a MEDIA: marker through inj and
+// returns the single injected content item.
+func injectedItem(t *testing.T, inj *Injector, path string) contentItem {
+	t.Helper()
+	result := inj.ProcessMessage(makeChatMessage("final", "run-rc", "MEDIA: "+path))
+
+	var outer outerMessage
+	var chat chatPayload
+	var msg chatMessage
+	if err := json.Unmarshal(result, &outer); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(outer.Payload, &chat); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(chat.Message, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(msg.Content) != 2 {
+		t.Fatalf("expected 2 content items, got %d", len(msg.Content))
+	}
+	return msg.Content[1]
+}
+
+func TestProcessMessage_Recompress_ShrinksLargeImage(t *testing.T) {
+	dir := t.TempDir()
+	path := writeSyntheticPNG(t, dir, "big.png", 512, 512, 255)
+
+	cfg := testConfig(dir)
+	off := injectedItem(t, NewInjector(cfg), path)
+
+	cfg.Recompress = true
+	cfg.RecompressThreshold = 64 * 1024
+	cfg.RecompressQuality = 60
+	on := injectedItem(t, NewInjector(cfg), path)
+
+	if off.MimeType != "image/png" {
+		t.Errorf("recompress off: mime = %q, want image/png", off.MimeType)
+	}
+	if on.MimeType != "image/jpeg" || on.FileName != "big.jpg" {
+		t.Errorf("recompress on: mime=%q file=%q, want image/jpeg big.jpg", on.MimeType, on.FileName)
+	}
+	if len(on.Content) >= len(off.Content) {
+		t.Errorf("recompressed size %d should be smaller than original %d", len(on.Content), len(off.Content))
+	}
+	t.Logf("base64 size: original=%d recompressed=%d", len(off.Content), len(on.Content))
+}
+
+func TestProcessMessage_Recompress_KeepsTransparentPNG(t *testing.T) {
+	dir := t.TempDir()
+	path := writeSyntheticPNG(t, dir, "alpha.png", 256, 256, 128)
+
+	cfg := testConfig(dir)
+	cfg.Recompress = true
+	cfg.RecompressThreshold = 1024
+	cfg.RecompressQuality = 60
+	item := injectedItem(t, NewInjector(cfg), path)
+
+	if item.MimeType != "image/png" || item.FileName != "alpha.png" {
+		t.Errorf("transparent image: mime=%q file=%q, want unchanged PNG", item.MimeType, item.FileName)
+	}
+}
+
+func TestProcessMessage_Recompress_BelowThreshold(t *testing.T) {
+	dir := t.TempDir()
+	path := writeSyntheticPNG(t, dir, "small.png", 32, 32, 255)
+
+	cfg := testConfig(dir)
+	cfg.Recompress = true
+	cfg.RecompressThreshold = 1024 * 1024
+	cfg.RecompressQuality = 60
+	item := injectedItem(t, NewInjector(cfg), path)
+
+	if item.MimeType != "image/png" {
+		t.Errorf("below threshold: mime = %q, want image/png", item.MimeType)
+	}
+}
+
+func TestInjector_MaxConcurrentInjections(t *testing.T) {
+	cfg := testConfig("")
+	cfg.MaxConcurrentInjections = 2
+	inj := NewInjector(cfg)
+	inj.queueWait = 5 * time.Second
+
+	var inFlight, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !inj.acquireSlot() {
+				t.Error("acquireSlot timed out")
+				return
+			}
+			defer inj.releaseSlot()
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			inFlight.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf("peak concurrent injections = %d, want 2", got)
+	}
+}
+
+func TestProcessMessage_InjectionLimitFailsOpen(t *testing.T) {
+	dir := t.TempDir()
+	path := writeSyntheticPNG(t, dir, "queued.png", 16, 16, 255)
+
+	cfg := testConfig(dir)
+	cfg.MaxConcurrentInjections = 1
+	inj := NewInjector(cfg)
+	inj.queueWait = 10 * time.Millisecond
+
+	// Hold the only slot so the final has to queue and then give up.
+	if !inj.acquireSlot() {
+		t.Fatal("acquireSlot failed on an idle injector")
+	}
+	final := makeChatMessage("final", "run-busy", "MEDIA: "+path)
+	if result := inj.ProcessMessage(final); !bytes.Equal(result, final) {
+		t.Errorf("final should pass through unmodified when no slot frees up, got %s", result)
+	}
+
+	inj.releaseSlot()
+	if item := injectedItem(t, inj, path); item.Type != "image" {
+		t.Errorf("injected item type = %q after slot freed, want image", item.Type)
+	}
+}
+
+func TestInjector_UnlimitedInjections(t *testing.T) {
+	inj := NewInjector(testConfig(""))
+	if inj.slots != nil {
+		t.Fatal("slots should be nil when max_concurrent_injections is 0")
+	}
+	for i := 0; i < 100; i++ {
+		if !inj.acquireSlot() {
+			t.Fatal("acquireSlot should always succeed when unlimited")
+		}
+	}
+}
+
+func TestParseClientCapabilities(t *testing.T) {
+	if caps := ParseClientCapabilities("  "); caps != nil {
+		t.Errorf("empty header = %v, want nil", caps)
+	}
+	caps := ParseClientCapabilities("image/PNG, image/jpeg;q=0.9 ,,application/pdf")
+	for mt, want := range map[string]bool{
+		"image/png":       true,
+		"image/jpeg":      true,
+		"image/webp":      false,
+		"image/gif":       false,
+		"application/pdf": true,
+		"video/mp4":       true, // only images are filtered
+	} {
+		if got := caps.Supports(mt); got != want {
+			t.Errorf("Supports(%q) = %v, want %v", mt, got, want)
+		}
+	}
+	if !ParseClientCapabilities("image/*").Supports("image/webp") {
+		t.Error("image/* should support image/webp")
+	}
+	if !ClientCapabilities(nil).Supports("image/webp") {
+		t.Error("nil capabilities should support everything")
+	}
+}
+
+// injectedItemsFor runs a final message with text through inj for a client
+// with caps and returns the injected items (possibly none) after the text.
+func injectedItemsFor(t *testing.T, inj *Injector, text string, caps ClientCapabilities) []contentItem {
+	t.Helper()
+	result := inj.ProcessMessageFor(makeChatMessage("final", "run-caps", text), caps)
+	var outer outerMessage
+	var chat chatPayload
+	var msg chatMessage
+	if err := json.Unmarshal(result, &outer); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(outer.Payload, &chat); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(chat.Message, &msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg.Content[1:]
+}
+
+func TestProcessMessageFor_SubstitutesSupportedFormat(t *testing.T) {
+	dir := t.TempDir()
+	webpPath := filepath.Join(dir, "chart.webp")
+	if err := os.WriteFile(webpPath, []byte("webp-bytes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "chart.png"), []byte("png-bytes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t.TempDir())
+	cfg.AllowedDirs = []string{dir}
+	inj := NewInjector(cfg)
+
+	tests := []struct {
+		name     string
+		caps     ClientCapabilities
+		wantMime string
+		wantFile string
+		wantData string
+	}{
+		{"no header", nil, "image/webp", "chart.webp", "webp-bytes"},
+		{"webp capable", ParseClientCapabilities("image/webp, image/png"), "image/webp", "chart.webp", "webp-bytes"},
+		{"no webp", ParseClientCapabilities("image/png, image/jpeg"), "image/png", "chart.png", "png-bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := injectedItemsFor(t, inj, "MEDIA: "+webpPath, tt.caps)
+			if len(items) != 1 {
+				t.Fatalf("injected %d items, want 1", len(items))
+			}
+			data, _ := base64.StdEncoding.DecodeString(items[0].Content)
+			if items[0].MimeType != tt.wantMime || items[0].FileName != tt.wantFile || string(data) != tt.wantData {
+				t.Errorf("injected %s %s %q, want %s %s %q", items[0].MimeType, items[0].FileName, data, tt.wantMime, tt.wantFile, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestProcessMessageFor_SkipsUnsupportedWithoutSibling(t *testing.T) {
+	dir := t.TempDir()
+	webpPath := filepath.Join(dir, "only.webp")
+	if err := os.WriteFile(webpPath, []byte("webp-bytes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t.TempDir())
+	cfg.AllowedDirs = []string{dir}
+	inj := NewInjector(cfg)
+
+	if items := injectedItemsFor(t, inj, "MEDIA: "+webpPath, ParseClientCapabilities("image/png")); len(items) != 0 {
+		t.Errorf("injected %d items for a client that can't render them, want 0", len(items))
+	}
+}
+
+func TestProcessMessageFor_DirectoryScanFiltersUnsupported(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{"a.webp": "webp-bytes", "b.png": "png-bytes"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	inj := NewInjector(testConfig(dir))
+
+	items := injectedItemsFor(t, inj, "Here you go", ParseClientCapabilities("image/png"))
+	if len(items) != 1 || items[0].FileName != "b.png" {
+		t.Fatalf("injected %+v, want only b.png", items)
+	}
+	// The skipped webp isn't marked as sent, so a capable client still gets it.
+	items = injectedItemsFor(t, inj, "Here you go", nil)
+	if len(items) != 1 || items[0].FileName != "a.webp" {
+		t.Errorf("capable client got %+v, want a.webp", items)
+	}
+}
+
+func TestProcessMessageFor_NoRecompressWithoutJPEGSupport(t *testing.T) {
+	dir := t.TempDir()
+	path := writeSyntheticPNG(t, dir, "big.png", 512, 512, 255)
+	cfg := testConfig(dir)
+	cfg.Recompress = true
+	cfg.RecompressThreshold = 64 * 1024
+	cfg.RecompressQuality = 60
+	inj := NewInjector(cfg)
+
+	items := injectedItemsFor(t, inj, "MEDIA: "+path, ParseClientCapabilities("image/png"))
+	if len(items) != 1 || items[0].MimeType != "image/png" {
+		t.Fatalf("injected %+v, want the original PNG for a client without JPEG", items)
+	}
+}
@@ -0,0 +1,67 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	// Register decoders for the formats we can recompress.
+	_ "image/gif"
+	_ "image/png"
+)
+
+// recompressImage re-encodes an image as JPEG at the configured quality when
+// it exceeds RecompressThreshold. Images with transparency are left as-is so
+// PNG alpha is preserved. Returns the original data, mime type, and file name
+// unchanged if recompression is disabled, not applicable, does not shrink
+// the image, or the client doesn't render JPEG.
+func (inj *Injector) recompressImage(data []byte, mimeType, fileName string, caps ClientCapabilities) ([]byte, string, string) {
+	if !inj.cfg.Recompress || int64(len(data)) <= inj.cfg.RecompressThreshold {
+		return data, mimeType, fileName
+	}
+	if !caps.Supports("image/jpeg") {
+		return data, mimeType, fileName
+	}
+	if mimeType != "image/png" && mimeType != "image/jpeg" && mimeType != "image/gif" {
+		return data, mimeType, fileName
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		slog.Debug("media: recompress skipped, decode failed", "file", fileName, "error", err)
+		return data, mimeType, fileName
+	}
+	if hasAlpha(img) {
+		slog.Debug("media: recompress skipped, image has transparency", "file", fileName)
+		return data, mimeType, fileName
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: inj.cfg.RecompressQuality}); err != nil {
+		slog.Warn("media: recompress failed", "file", fileName, "error", err)
+		return data, mimeType, fileName
+	}
+	if buf.Len() >= len(data) {
+		return data, mimeType, fileName
+	}
+
+	newName := strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ".jpg"
+	slog.Debug("media: recompressed image",
+		"file", fileName,
+		"originalSize", len(data),
+		"recompressedSize", buf.Len(),
+		"quality", inj.cfg.RecompressQuality,
+	)
+	return buf.Bytes(), "image/jpeg", newName
+}
+
+// hasAlpha reports whether the image contains any non-opaque pixels.
+func hasAlpha(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return !o.Opaque()
+	}
+	return true // unknown image type: assume transparency to be safe
+}
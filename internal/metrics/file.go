@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// FileWriter periodically writes all gathered metrics to a file in the
+// Prometheus text exposition format, for hosts with no scraper (e.g. for the
+// node_exporter textfile collector or a log shipper). Each write replaces the
+// file atomically, so readers never see a partial snapshot.
+type FileWriter struct {
+	path     string
+	gatherer prometheus.Gatherer
+}
+
+// NewFileWriter creates a writer that snapshots gatherer to path.
+func NewFileWriter(path string, gatherer prometheus.Gatherer) *FileWriter {
+	return &FileWriter{path: path, gatherer: gatherer}
+}
+
+// Run writes the file every interval until ctx is cancelled, and once more
+// on the way out so the last snapshot reflects shutdown.
+func (f *FileWriter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := f.Write(); err != nil {
+				slog.Warn("metrics file write failed", "path", f.path, "error", err)
+			}
+			return
+		case <-ticker.C:
+			if err := f.Write(); err != nil {
+				slog.Warn("metrics file write failed", "path", f.path, "error", err)
+			}
+		}
+	}
+}
+
+// Write gathers the current metrics and replaces the file with them.
+func (f *FileWriter) Write() error {
+	families, err := f.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, mf := range families {
+		if _, err := expfmt.MetricFamilyToText(&buf, mf); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), "."+filepath.Base(f.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o640); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
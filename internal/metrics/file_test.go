@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestFileWriterWrite(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	active := prometheus.NewGauge(prometheus.GaugeOpts{Name: "clawreachbridge_active_connections", Help: "Current active connections"})
+	messages := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "clawreachbridge_messages_total", Help: "Total messages proxied"}, []string{"direction"})
+	reg.MustRegister(active, messages)
+	active.Set(3)
+	messages.WithLabelValues("upstream").Add(5)
+
+	path := filepath.Join(t.TempDir(), "metrics.prom")
+	f := NewFileWriter(path, reg)
+	if err := f.Write(); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# TYPE clawreachbridge_active_connections gauge",
+		"clawreachbridge_active_connections 3",
+		`clawreachbridge_messages_total{direction="upstream"} 5`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("metrics file missing %q:\n%s", want, data)
+		}
+	}
+
+	// Rewrites replace the snapshot and leave no temp files behind.
+	active.Set(7)
+	if err := f.Write(); err != nil {
+		t.Fatalf("second Write: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), "clawreachbridge_active_connections 7") {
+		t.Errorf("metrics file not updated:\n%s", data)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the metrics file", len(entries))
+	}
+}
+
+func TestFileWriterRun(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "clawreachbridge_gateway_reachable", Help: "Gateway reachability"}))
+	path := filepath.Join(t.TempDir(), "metrics.prom")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewFileWriter(path, reg).Run(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, err := os.ReadFile(path)
+		if err == nil && strings.Contains(string(data), "clawreachbridge_gateway_reachable 0") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("metrics file not written: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
@@ -1,8 +1,15 @@
 package metrics
 
 import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/cortexuvula/clawreachbridge/internal/security"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Metrics holds all Prometheus metrics for ClawReach Bridge.
@@ -15,11 +22,41 @@ type Metrics struct {
 	ReactionsTotal    *prometheus.CounterVec
 	CanvasEventsTotal *prometheus.CounterVec
 	CanvasReplaysTotal prometheus.Counter
+	CanvasJSONLSkippedTotal prometheus.Counter
+	FilesSkippedTotal  *prometheus.CounterVec
+	ConnectionsPerIPMax prometheus.Gauge
+	DistinctActiveIPs   prometheus.Gauge
+	MessagesByMethod    *prometheus.CounterVec
+	ConfigReloadFailures *prometheus.CounterVec
+	SlowConsumersShed    prometheus.Counter
+
+	// GatewayLastSeenSeconds reports the age of the last successful gateway
+	// contact recorded by GatewaySeen.
+	GatewayLastSeenSeconds prometheus.GaugeFunc
+	gatewayLastSeen        atomic.Int64 // unix nanoseconds
+}
+
+// Handler serves the default registry in the Prometheus exposition format.
+// When token is non-empty, requests must carry "Authorization: Bearer <token>"
+// or get a 401.
+func Handler(token string) http.Handler {
+	h := promhttp.Handler()
+	if token == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !security.TokenMatch(security.ExtractBearerToken(r.Header.Get("Authorization")), token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
 }
 
 // New creates and registers all Prometheus metrics.
 func New() *Metrics {
-	return &Metrics{
+	m := &Metrics{
 		ConnectionsTotal: promauto.NewCounter(prometheus.CounterOpts{
 			Name: "clawreachbridge_connections_total",
 			Help: "Total connections handled",
@@ -52,5 +89,94 @@ func New() *Metrics {
 			Name: "clawreachbridge_canvas_replays_total",
 			Help: "Total canvas state replays on reconnect",
 		}),
+		CanvasJSONLSkippedTotal: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "clawreachbridge_canvas_jsonl_skipped_total",
+			Help: "Canvas pushJSONL messages forwarded but not buffered for replay because they exceeded bridge.canvas.max_jsonl_bytes",
+		}),
+		FilesSkippedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "clawreachbridge_files_skipped_total",
+			Help: "Received file attachments not saved to the inbox",
+		}, []string{"reason"}),
+		ConnectionsPerIPMax: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "clawreachbridge_connections_per_ip_max",
+			Help: "Highest number of active connections held by a single client IP",
+		}),
+		DistinctActiveIPs: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "clawreachbridge_distinct_active_ips",
+			Help: "Number of distinct client IPs with at least one active connection",
+		}),
+		MessagesByMethod: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "clawreachbridge_messages_by_method_total",
+			Help: "Client to gateway requests by JSON-RPC method (methods outside monitoring.method_metrics count as other)",
+		}, []string{"method"}),
+		ConfigReloadFailures: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "clawreachbridge_config_reload_failures_total",
+			Help: "Config reloads that failed and kept the running config, by reason (missing, invalid)",
+		}, []string{"reason"}),
+		SlowConsumersShed: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "clawreachbridge_slow_consumers_shed_total",
+			Help: "Connections closed because bytes pending to the client exceeded bridge.client_buffer_high_water",
+		}),
+	}
+	// Until the gateway is first reached, the age counts from startup.
+	m.GatewaySeen()
+	m.GatewayLastSeenSeconds = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "clawreachbridge_gateway_last_seen_seconds",
+		Help: "Seconds since the last successful gateway dial or health check",
+	}, func() float64 { return m.GatewayLastSeenAge().Seconds() })
+	return m
+}
+
+// GatewaySeen records a successful gateway dial or health check.
+func (m *Metrics) GatewaySeen() {
+	m.gatewayLastSeen.Store(time.Now().UnixNano())
+}
+
+// GatewayLastSeenAge returns the time since GatewaySeen was last called.
+func (m *Metrics) GatewayLastSeenAge() time.Duration {
+	return time.Since(time.Unix(0, m.gatewayLastSeen.Load()))
+}
+
+// RegisterLogRingDropped exposes the log ring buffer's overwrite count as
+// clawreachbridge_log_entries_dropped_total, read from dropped on each
+// scrape. The count only grows, so it is a counter and rate() applies.
+func (m *Metrics) RegisterLogRingDropped(dropped func() uint64) {
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "clawreachbridge_log_entries_dropped_total",
+		Help: "Log entries overwritten in the in-memory ring buffer before they were read",
+	}, func() float64 { return float64(dropped()) })
+}
+
+// UpdateIPDistribution sets the per-IP distribution gauges from a snapshot of
+// active connection counts keyed by client IP.
+func (m *Metrics) UpdateIPDistribution(counts map[string]int) {
+	maxConns, distinct := 0, 0
+	for _, n := range counts {
+		if n <= 0 {
+			continue
+		}
+		distinct++
+		if n > maxConns {
+			maxConns = n
+		}
+	}
+	m.ConnectionsPerIPMax.Set(float64(maxConns))
+	m.DistinctActiveIPs.Set(float64(distinct))
+}
+
+// RunIPDistributionCollector refreshes the per-IP distribution gauges from
+// snapshot every interval until ctx is cancelled.
+func (m *Metrics) RunIPDistributionCollector(ctx context.Context, interval time.Duration, snapshot func() map[string]int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m.UpdateIPDistribution(snapshot())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.UpdateIPDistribution(snapshot())
+		}
 	}
 }
@@ -1,9 +1,16 @@
 package metrics
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 )
 
 func TestNew(t *testing.T) {
@@ -53,6 +60,8 @@ func TestNew(t *testing.T) {
 	m.CanvasEventsTotal.WithLabelValues("hide").Inc()
 	m.CanvasEventsTotal.WithLabelValues("pushJSONL").Inc()
 	m.CanvasReplaysTotal.Inc()
+	m.ConfigReloadFailures.WithLabelValues("missing").Inc()
+	m.SlowConsumersShed.Inc()
 
 	// Verify metrics are gathered
 	families, err := reg.Gather()
@@ -74,6 +83,9 @@ func TestNew(t *testing.T) {
 		"clawreachbridge_reactions_total",
 		"clawreachbridge_canvas_events_total",
 		"clawreachbridge_canvas_replays_total",
+		"clawreachbridge_config_reload_failures_total",
+		"clawreachbridge_slow_consumers_shed_total",
+		"clawreachbridge_gateway_last_seen_seconds",
 	}
 	for _, name := range expected {
 		if !names[name] {
@@ -81,3 +93,133 @@ func TestNew(t *testing.T) {
 		}
 	}
 }
+
+func TestIPDistributionCollector(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	prometheus.DefaultRegisterer = reg
+	prometheus.DefaultGatherer = reg
+
+	m := New()
+
+	var mu sync.Mutex
+	counts := map[string]int{
+		"100.64.0.1": 1,
+		"100.64.0.2": 7,
+		"100.64.0.3": 2,
+	}
+	snapshot := func() map[string]int {
+		mu.Lock()
+		defer mu.Unlock()
+		out := make(map[string]int, len(counts))
+		for k, v := range counts {
+			out[k] = v
+		}
+		return out
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.RunIPDistributionCollector(ctx, 10*time.Millisecond, snapshot)
+		close(done)
+	}()
+
+	waitFor := func(wantMax, wantDistinct float64) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if testutil.ToFloat64(m.ConnectionsPerIPMax) == wantMax &&
+				testutil.ToFloat64(m.DistinctActiveIPs) == wantDistinct {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("gauges = max %v distinct %v, want %v %v",
+			testutil.ToFloat64(m.ConnectionsPerIPMax), testutil.ToFloat64(m.DistinctActiveIPs),
+			wantMax, wantDistinct)
+	}
+
+	waitFor(7, 3)
+
+	mu.Lock()
+	delete(counts, "100.64.0.2")
+	mu.Unlock()
+	waitFor(2, 2)
+
+	cancel()
+	<-done
+}
+
+func TestHandlerAuthToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{"no token configured", "", "", http.StatusOK},
+		{"missing header", "s3cret", "", http.StatusUnauthorized},
+		{"wrong token", "s3cret", "Bearer nope", http.StatusUnauthorized},
+		{"correct token", "s3cret", "Bearer s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/metrics", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			Handler(tt.token).ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestRegisterLogRingDropped(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	prometheus.DefaultRegisterer = reg
+	prometheus.DefaultGatherer = reg
+
+	m := New()
+	var dropped uint64 = 7
+	m.RegisterLogRingDropped(func() uint64 { return dropped })
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range families {
+		if mf.GetName() != "clawreachbridge_log_entries_dropped_total" {
+			continue
+		}
+		if mf.GetType() != dto.MetricType_COUNTER {
+			t.Errorf("type = %v, want COUNTER", mf.GetType())
+		}
+		if v := mf.GetMetric()[0].GetCounter().GetValue(); v != 7 {
+			t.Errorf("value = %v, want 7", v)
+		}
+		return
+	}
+	t.Error("clawreachbridge_log_entries_dropped_total not registered")
+}
+
+func TestGatewayLastSeen(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	prometheus.DefaultRegisterer = reg
+	prometheus.DefaultGatherer = reg
+
+	m := New()
+	m.gatewayLastSeen.Store(time.Now().Add(-time.Minute).UnixNano())
+	if got := testutil.ToFloat64(m.GatewayLastSeenSeconds); got < 60 {
+		t.Errorf("gateway_last_seen_seconds = %v, want >= 60", got)
+	}
+	m.GatewaySeen()
+	if got := testutil.ToFloat64(m.GatewayLastSeenSeconds); got > 1 {
+		t.Errorf("gateway_last_seen_seconds = %v after GatewaySeen, want ~0", got)
+	}
+}
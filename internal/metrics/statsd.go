@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// statsdMaxPacket keeps each UDP datagram under a typical Ethernet MTU.
+const statsdMaxPacket = 1432
+
+// StatsDPusher periodically sends bridge metrics to a StatsD server over UDP.
+// Gauges are sent as-is; counters are sent as the increase since the last
+// flush, which is what StatsD expects. Histograms are sent as two counters,
+// <name>_sum and <name>_count, so averages can be derived downstream; their
+// buckets are not sent. Labels are appended to the metric name
+// as dot-separated values, e.g. clawreachbridge_messages_total.upstream.
+type StatsDPusher struct {
+	conn     net.Conn
+	gatherer prometheus.Gatherer
+	prefix   string             // only metric families with this name prefix are sent
+	last     map[string]float64 // previous counter values, keyed by StatsD name
+}
+
+// NewStatsDPusher creates a pusher that reads metrics from gatherer and sends
+// them to the StatsD server at addr (host:port).
+func NewStatsDPusher(addr string, gatherer prometheus.Gatherer) (*StatsDPusher, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dialing statsd %s: %w", addr, err)
+	}
+	return &StatsDPusher{
+		conn:     conn,
+		gatherer: gatherer,
+		prefix:   "clawreachbridge_",
+		last:     make(map[string]float64),
+	}, nil
+}
+
+// Run flushes every interval until ctx is cancelled, then closes the socket.
+func (s *StatsDPusher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer s.conn.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				slog.Debug("statsd flush failed", "error", err)
+			}
+		}
+	}
+}
+
+// Flush gathers the current metric values and sends them.
+func (s *StatsDPusher) Flush() error {
+	families, err := s.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, mf := range families {
+		if !strings.HasPrefix(mf.GetName(), s.prefix) {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			name := statsdName(mf.GetName(), m.GetLabel())
+			switch mf.GetType() {
+			case dto.MetricType_GAUGE:
+				lines = append(lines, name+":"+formatStatsDValue(m.GetGauge().GetValue())+"|g")
+			case dto.MetricType_COUNTER:
+				lines = s.appendCounter(lines, name, m.GetCounter().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				lines = s.appendCounter(lines, statsdName(mf.GetName()+"_sum", m.GetLabel()), h.GetSampleSum())
+				lines = s.appendCounter(lines, statsdName(mf.GetName()+"_count", m.GetLabel()), float64(h.GetSampleCount()))
+			}
+		}
+	}
+	return s.send(lines)
+}
+
+// appendCounter appends a counter line carrying the increase since the last
+// flush, skipping it when the value hasn't grown.
+func (s *StatsDPusher) appendCounter(lines []string, name string, v float64) []string {
+	delta := v - s.last[name]
+	s.last[name] = v
+	if delta > 0 {
+		lines = append(lines, name+":"+formatStatsDValue(delta)+"|c")
+	}
+	return lines
+}
+
+// send writes lines newline-separated, packing as many as fit per datagram.
+func (s *StatsDPusher) send(lines []string) error {
+	var buf strings.Builder
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(line) > statsdMaxPacket {
+			if _, err := s.conn.Write([]byte(buf.String())); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+	if buf.Len() > 0 {
+		if _, err := s.conn.Write([]byte(buf.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statsdName appends label values to the metric name, replacing characters
+// that are significant in the StatsD line protocol.
+func statsdName(name string, labels []*dto.LabelPair) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, l := range labels {
+		b.WriteByte('.')
+		b.WriteString(statsdSanitizer.Replace(l.GetValue()))
+	}
+	return b.String()
+}
+
+var statsdSanitizer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "\n", "_", " ", "_")
+
+func formatStatsDValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// readStatsD returns the lines of the next datagram received on conn.
+func readStatsD(t *testing.T, conn net.PacketConn) []string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 2048)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("reading statsd packet: %v", err)
+	}
+	lines := strings.Split(string(buf[:n]), "\n")
+	sort.Strings(lines)
+	return lines
+}
+
+func TestStatsDPusherFlush(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	reg := prometheus.NewRegistry()
+	active := prometheus.NewGauge(prometheus.GaugeOpts{Name: "clawreachbridge_active_connections"})
+	messages := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "clawreachbridge_messages_total"}, []string{"direction"})
+	other := prometheus.NewGauge(prometheus.GaugeOpts{Name: "go_goroutines"})
+	reg.MustRegister(active, messages, other)
+
+	s, err := NewStatsDPusher(listener.LocalAddr().String(), reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.conn.Close()
+
+	active.Set(3)
+	messages.WithLabelValues("upstream").Add(5)
+	messages.WithLabelValues("downstream").Add(2)
+	other.Set(42)
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	got := readStatsD(t, listener)
+	want := []string{
+		"clawreachbridge_active_connections:3|g",
+		"clawreachbridge_messages_total.downstream:2|c",
+		"clawreachbridge_messages_total.upstream:5|c",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("first flush = %q, want %q", got, want)
+	}
+
+	// Counters are sent as the increase since the previous flush; unchanged
+	// counters are skipped.
+	messages.WithLabelValues("upstream").Add(4)
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	got = readStatsD(t, listener)
+	want = []string{
+		"clawreachbridge_active_connections:3|g",
+		"clawreachbridge_messages_total.upstream:4|c",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("second flush = %q, want %q", got, want)
+	}
+}
+
+func TestStatsDPusherFlushHistogram(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	reg := prometheus.NewRegistry()
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "clawreachbridge_dial_seconds",
+		Buckets: []float64{0.1, 1},
+	}, []string{"result"})
+	reg.MustRegister(latency)
+
+	s, err := NewStatsDPusher(listener.LocalAddr().String(), reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.conn.Close()
+
+	latency.WithLabelValues("ok").Observe(0.25)
+	latency.WithLabelValues("ok").Observe(0.5)
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	got := readStatsD(t, listener)
+	want := []string{
+		"clawreachbridge_dial_seconds_count.ok:2|c",
+		"clawreachbridge_dial_seconds_sum.ok:0.75|c",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("first flush = %q, want %q", got, want)
+	}
+
+	latency.WithLabelValues("ok").Observe(1.5)
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	got = readStatsD(t, listener)
+	want = []string{
+		"clawreachbridge_dial_seconds_count.ok:1|c",
+		"clawreachbridge_dial_seconds_sum.ok:1.5|c",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("second flush = %q, want %q", got, want)
+	}
+}
+
+func TestStatsDNameSanitizesLabels(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "clawreachbridge_errors_total"}, []string{"type"})
+	c.WithLabelValues("a:b|c d").Inc()
+	reg.MustRegister(c)
+
+	families, _ := reg.Gather()
+	got := statsdName(families[0].GetName(), families[0].GetMetric()[0].GetLabel())
+	if got != "clawreachbridge_errors_total.a_b_c_d" {
+		t.Errorf("statsdName = %q", got)
+	}
+}
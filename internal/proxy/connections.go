@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// ConnFilter selects tracked connections for CloseConnections. Every set
+// field must match; zero-valued fields are ignored.
+type ConnFilter struct {
+	IP         string        // exact client IP
+	PathPrefix string        // request path prefix
+	OlderThan  time.Duration // connected longer ago than this
+}
+
+// IsEmpty reports whether no filter field is set (which would match every connection).
+func (f ConnFilter) IsEmpty() bool {
+	return f.IP == "" && f.PathPrefix == "" && f.OlderThan <= 0
+}
+
+func (f ConnFilter) matches(c *activeConn, now time.Time) bool {
+	if f.IP != "" && c.ip != f.IP {
+		return false
+	}
+	if f.PathPrefix != "" && !strings.HasPrefix(c.path, f.PathPrefix) {
+		return false
+	}
+	if f.OlderThan > 0 && now.Sub(c.connectedAt) <= f.OlderThan {
+		return false
+	}
+	return true
+}
+
+// CloseConnections sends a policy-violation close frame with reason to every
+// tracked connection matching f and returns how many were closed. Close
+// handshakes run in the background; the connections untrack themselves as
+// they tear down.
+func (h *Handler) CloseConnections(f ConnFilter, reason string) int {
+	now := time.Now()
+	var matched []*activeConn
+	h.connsMu.Lock()
+	for _, c := range h.conns {
+		if f.matches(c, now) {
+			matched = append(matched, c)
+		}
+	}
+	h.connsMu.Unlock()
+
+	for _, c := range matched {
+		slog.Info("closing connection by admin request", "conn_id", c.id, "client_ip", c.ip, "path", c.path, "reason", reason)
+		go c.close(websocket.StatusPolicyViolation, reason)
+	}
+	return len(matched)
+}
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+func TestCloseConnectionsByFilter(t *testing.T) {
+	seed := []struct {
+		id, ip, path string
+		age          time.Duration
+	}{
+		{"a", "100.64.0.1", "/ws/chat", 30 * time.Minute},
+		{"b", "100.64.0.1", "/ws/operator", time.Minute},
+		{"c", "100.64.0.2", "/ws/chat", 2 * time.Hour},
+		{"d", "100.64.0.3", "/ws/operator", 10 * time.Second},
+	}
+
+	tests := []struct {
+		name   string
+		filter ConnFilter
+		want   []string
+	}{
+		{"by ip", ConnFilter{IP: "100.64.0.1"}, []string{"a", "b"}},
+		{"by path prefix", ConnFilter{PathPrefix: "/ws/op"}, []string{"b", "d"}},
+		{"older than", ConnFilter{OlderThan: 20 * time.Minute}, []string{"a", "c"}},
+		{"ip and path", ConnFilter{IP: "100.64.0.1", PathPrefix: "/ws/chat"}, []string{"a"}},
+		{"no match", ConnFilter{IP: "100.64.0.9"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(testConfig(), New(), nil, context.Background())
+
+			var mu sync.Mutex
+			var closed []string
+			var reasons []string
+			var wg sync.WaitGroup
+			now := time.Now()
+			for _, s := range seed {
+				id := s.id
+				h.trackConn(&activeConn{
+					id:          id,
+					ip:          s.ip,
+					path:        s.path,
+					connectedAt: now.Add(-s.age),
+					close: func(code websocket.StatusCode, reason string) {
+						defer wg.Done()
+						mu.Lock()
+						closed = append(closed, id)
+						reasons = append(reasons, reason)
+						mu.Unlock()
+						if code != websocket.StatusPolicyViolation {
+							t.Errorf("close code = %v, want %v", code, websocket.StatusPolicyViolation)
+						}
+					},
+				})
+			}
+
+			wg.Add(len(tt.want))
+			n := h.CloseConnections(tt.filter, "evicted for testing")
+			if n != len(tt.want) {
+				t.Fatalf("CloseConnections() = %d, want %d", n, len(tt.want))
+			}
+			wg.Wait()
+
+			mu.Lock()
+			defer mu.Unlock()
+			sort.Strings(closed)
+			if strings.Join(closed, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("closed = %v, want %v", closed, tt.want)
+			}
+			for _, r := range reasons {
+				if r != "evicted for testing" {
+					t.Errorf("close reason = %q, want %q", r, "evicted for testing")
+				}
+			}
+		})
+	}
+}
+
+func TestConnFilterIsEmpty(t *testing.T) {
+	if !(ConnFilter{}).IsEmpty() {
+		t.Error("zero filter should be empty")
+	}
+	if (ConnFilter{OlderThan: time.Second}).IsEmpty() {
+		t.Error("filter with older_than should not be empty")
+	}
+}
+
+func TestCloseConnectionsClosesLiveConnection(t *testing.T) {
+	bridge, handler, _ := setupBridgeWithGateway(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(bridge.URL, "http")+"/ws/chat", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.CloseNow()
+
+	// The connection is tracked once forwarding is set up.
+	deadline := time.Now().Add(2 * time.Second)
+	for len(handler.drainSequence("oldest_first")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if n := handler.CloseConnections(ConnFilter{PathPrefix: "/ws/"}, "bye"); n != 1 {
+		t.Fatalf("CloseConnections() = %d, want 1", n)
+	}
+	_, _, err = c.Read(ctx)
+	if got := websocket.CloseStatus(err); got != websocket.StatusPolicyViolation {
+		t.Errorf("close status = %v (err %v), want %v", got, err, websocket.StatusPolicyViolation)
+	}
+}
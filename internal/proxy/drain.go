@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// defaultDrainInterval is the pause between close frames when
+// bridge.drain_order sends them one connection at a time.
+const defaultDrainInterval = 10 * time.Millisecond
+
+// drainWebhookTimeout bounds each bridge.drain_webhook POST so an
+// unresponsive receiver can't hold up shutdown.
+const drainWebhookTimeout = 5 * time.Second
+
+// Drain events posted to bridge.drain_webhook.
+const (
+	DrainStarted   = "drain_started"
+	DrainCompleted = "drain_completed"
+)
+
+// drainEvent is the JSON body posted to bridge.drain_webhook.
+type drainEvent struct {
+	Event                string `json:"event"`
+	RemainingConnections int    `json:"remaining_connections"`
+	Timestamp            string `json:"timestamp"`
+}
+
+var drainWebhookClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// activeConn is a live proxied connection tracked for ordered draining and
+// admin-initiated closes.
+type activeConn struct {
+	id          string
+	ip          string
+	path        string
+	connectedAt time.Time
+	close       func(code websocket.StatusCode, reason string)
+}
+
+// trackConn registers a connection so StartDrain can close it in order.
+func (h *Handler) trackConn(c *activeConn) {
+	h.connsMu.Lock()
+	h.conns[c.id] = c
+	h.connsMu.Unlock()
+}
+
+// untrackConn removes a connection once it has been torn down.
+func (h *Handler) untrackConn(id string) {
+	h.connsMu.Lock()
+	delete(h.conns, id)
+	h.connsMu.Unlock()
+}
+
+// drainSequence returns a snapshot of the tracked connections sorted for the
+// given drain order: oldest_first or newest_first by connect time.
+func (h *Handler) drainSequence(order string) []*activeConn {
+	h.connsMu.Lock()
+	conns := make([]*activeConn, 0, len(h.conns))
+	for _, c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.connsMu.Unlock()
+
+	sort.Slice(conns, func(i, j int) bool {
+		if order == "newest_first" {
+			return conns[i].connectedAt.After(conns[j].connectedAt)
+		}
+		return conns[i].connectedAt.Before(conns[j].connectedAt)
+	})
+	return conns
+}
+
+// StartDrain signals all active connections to begin graceful shutdown.
+// With bridge.drain_order all_at_once (the default) every connection's drain
+// watcher sends its close frame immediately. With oldest_first or newest_first
+// close frames are sent one connection at a time, drainInterval apart, and the
+// drain context is cancelled afterwards to catch any stragglers. Gateway
+// sessions parked for bridge.resume are closed right away.
+func (h *Handler) StartDrain() {
+	h.resumes.closeAll("server shutting down")
+	order := h.GetConfig().Bridge.DrainOrder
+	if order == "" || order == "all_at_once" {
+		h.drainCancel()
+		return
+	}
+
+	conns := h.drainSequence(order)
+	slog.Info("draining connections in order", "order", order, "connections", len(conns))
+	go func() {
+		for i, c := range conns {
+			if i > 0 {
+				time.Sleep(h.drainInterval)
+			}
+			slog.Debug("drain: closing connection", "conn_id", c.id, "connected_at", c.connectedAt)
+			// Don't wait for the close handshake: a client that never
+			// answers would hold up every connection behind it.
+			go c.close(websocket.StatusGoingAway, "server shutting down")
+		}
+		h.drainCancel()
+	}()
+}
+
+// NotifyDrain POSTs a drain event (DrainStarted or DrainCompleted) with the
+// current connection count to bridge.drain_webhook, if one is configured, so
+// an orchestrator such as a load balancer controller can follow the drain.
+// It waits at most drainWebhookTimeout; failures are logged, never fatal.
+func (h *Handler) NotifyDrain(ctx context.Context, event string) {
+	url := h.GetConfig().Bridge.DrainWebhook
+	if url == "" {
+		return
+	}
+	remaining := h.Proxy.ConnectionCount()
+	if err := postDrainEvent(ctx, url, drainEvent{
+		Event:                event,
+		RemainingConnections: remaining,
+		Timestamp:            time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		slog.Warn("drain webhook failed", "event", event, "url", url, "error", err)
+		return
+	}
+	slog.Debug("drain webhook notified", "event", event, "remaining", remaining)
+}
+
+func postDrainEvent(ctx context.Context, url string, ev drainEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, drainWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := drainWebhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// trackFakeConns registers connections a, b, c connected one second apart
+// (a oldest) whose close funcs append their ID to the returned slice.
+func trackFakeConns(h *Handler) (*[]string, *sync.Mutex) {
+	var mu sync.Mutex
+	var closed []string
+	base := time.Now().Add(-time.Hour)
+	// Register out of order to make sure sorting, not insertion, decides.
+	for _, i := range []int{1, 2, 0} {
+		id := string(rune('a' + i))
+		h.trackConn(&activeConn{
+			id:          id,
+			connectedAt: base.Add(time.Duration(i) * time.Second),
+			close: func(websocket.StatusCode, string) {
+				mu.Lock()
+				closed = append(closed, id)
+				mu.Unlock()
+			},
+		})
+	}
+	return &closed, &mu
+}
+
+func TestStartDrainOrder(t *testing.T) {
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{"oldest_first", []string{"a", "b", "c"}},
+		{"newest_first", []string{"c", "b", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Bridge.DrainOrder = tt.order
+			h := NewHandler(cfg, New(), nil, context.Background())
+			h.drainInterval = 20 * time.Millisecond
+			closed, mu := trackFakeConns(h)
+
+			h.StartDrain()
+			select {
+			case <-h.drainCtx.Done():
+			case <-time.After(2 * time.Second):
+				t.Fatal("drain context not cancelled after ordered closes")
+			}
+			waitFor(t, "all connections closed", func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(*closed) == len(tt.want)
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(*closed, tt.want) {
+				t.Errorf("close order = %v, want %v", *closed, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartDrainOrderedSkipsUnresponsiveClient(t *testing.T) {
+	bridge, handler, _ := setupBridgeWithGateway(t)
+	cfg := handler.GetConfig()
+	cfg.Bridge.DrainOrder = "oldest_first"
+	handler.UpdateConfig(cfg)
+	handler.drainInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	url := "ws" + bridge.URL[4:]
+
+	// The oldest client never reads, so it never answers the close frame.
+	stale, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("dial stale: %v", err)
+	}
+	defer stale.CloseNow()
+	waitFor(t, "stale connection tracked", func() bool { return len(handler.drainSequence("oldest_first")) == 1 })
+
+	live, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("dial live: %v", err)
+	}
+	defer live.CloseNow()
+	waitFor(t, "live connection tracked", func() bool { return len(handler.drainSequence("oldest_first")) == 2 })
+
+	start := time.Now()
+	handler.StartDrain()
+	_, _, err = live.Read(ctx)
+	if got := websocket.CloseStatus(err); got != websocket.StatusGoingAway {
+		t.Fatalf("live close status = %v (err %v), want %v", got, err, websocket.StatusGoingAway)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("live connection closed after %v; the drain waited on the unresponsive client", elapsed)
+	}
+}
+
+func TestStartDrainAllAtOnce(t *testing.T) {
+	h := NewHandler(testConfig(), New(), nil, context.Background())
+	closed, mu := trackFakeConns(h)
+
+	h.StartDrain()
+	if h.drainCtx.Err() == nil {
+		t.Fatal("all_at_once should cancel the drain context immediately")
+	}
+
+	// Connections close themselves via their drain watchers; StartDrain
+	// does not call close funcs directly.
+	mu.Lock()
+	defer mu.Unlock()
+	if len(*closed) != 0 {
+		t.Errorf("closed = %v, want none", *closed)
+	}
+}
+
+func TestDrainUntracksClosedConnections(t *testing.T) {
+	bridge, handler, p := setupBridgeWithGateway(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, _, err := websocket.Dial(ctx, "ws"+bridge.URL[4:], nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	for len(handler.drainSequence("oldest_first")) == 0 && ctx.Err() == nil {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := len(handler.drainSequence("oldest_first")); got != 1 {
+		t.Fatalf("tracked connections = %d, want 1", got)
+	}
+
+	c.Close(websocket.StatusNormalClosure, "")
+	for p.ConnectionCount() != 0 && ctx.Err() == nil {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := len(handler.drainSequence("oldest_first")); got != 0 {
+		t.Errorf("tracked connections after close = %d, want 0", got)
+	}
+}
+
+func TestNotifyDrainPostsEvents(t *testing.T) {
+	var mu sync.Mutex
+	var events []drainEvent
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with Content-Type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		var ev drainEvent
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		mu.Lock()
+		events = append(events, ev)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	cfg := testConfig()
+	cfg.Bridge.DrainWebhook = hook.URL
+	p := New()
+	h := NewHandler(cfg, p, nil, context.Background())
+	p.TryIncrementConnections("100.64.0.1", 10, 10)
+	p.TryIncrementConnections("100.64.0.2", 10, 10)
+
+	h.NotifyDrain(context.Background(), DrainStarted)
+	p.DecrementConnections("100.64.0.1")
+	p.DecrementConnections("100.64.0.2")
+	h.NotifyDrain(context.Background(), DrainCompleted)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 {
+		t.Fatalf("webhook received %d events, want 2", len(events))
+	}
+	if events[0].Event != "drain_started" || events[0].RemainingConnections != 2 {
+		t.Errorf("first event = %+v, want drain_started with 2 remaining", events[0])
+	}
+	if events[1].Event != "drain_completed" || events[1].RemainingConnections != 0 {
+		t.Errorf("second event = %+v, want drain_completed with 0 remaining", events[1])
+	}
+	if events[0].Timestamp == "" {
+		t.Error("event has no timestamp")
+	}
+}
+
+func TestNotifyDrainWebhookFailureIsNotFatal(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer hook.Close()
+
+	cfg := testConfig()
+	cfg.Bridge.DrainWebhook = hook.URL
+	h := NewHandler(cfg, New(), nil, context.Background())
+	h.NotifyDrain(context.Background(), DrainStarted) // logs a warning, doesn't panic or block
+
+	if err := postDrainEvent(context.Background(), hook.URL, drainEvent{Event: DrainStarted}); err == nil {
+		t.Error("postDrainEvent with a 500 response succeeded, want an error")
+	}
+}
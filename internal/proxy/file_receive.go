@@ -3,14 +3,20 @@ package proxy
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coder/websocket"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // FileReceiveInspector intercepts client→gateway chat.send messages that
@@ -23,9 +29,48 @@ import (
 //
 // Fail-open: any error during processing logs a warning and returns the
 // original payload unchanged.
+//
+// MaxBytes and MaxFiles optionally cap total inbox usage. When a file would
+// exceed a cap it is either not saved (the attachment is forwarded intact
+// with a FILE_NOT_SAVED note) or, with EvictOldest, the oldest inbox files
+// are removed to make room.
+//
+// AllowedExtensions, if non-empty, restricts which files are saved; other
+// attachments are forwarded untouched. With VerifyMIME, files whose sniffed
+// content doesn't match the declared MIME family are likewise not saved.
+//
+// With ByDate, files are written to InboxDir/YYYY-MM-DD/ (local time) instead
+// of InboxDir itself; caps and eviction then cover all dated directories.
 type FileReceiveInspector struct {
-	InboxDir string
-	Logger   *slog.Logger
+	InboxDir          string
+	Logger            *slog.Logger // nil = slog.Default(), resolved on every call so logging reloads apply
+	MaxBytes          int64        // 0 = unlimited
+	MaxFiles          int          // 0 = unlimited
+	EvictOldest       bool
+	AllowedExtensions []string // e.g. [".pdf", ".txt"]; empty = any
+	VerifyMIME        bool
+	ByDate            bool
+	SkippedTotal      *prometheus.CounterVec // optional, labelled by reason
+
+	// Cached inbox usage, re-scanned every inboxUsageRefresh.
+	mu         sync.Mutex
+	usageBytes int64
+	usageFiles int
+	usageAt    time.Time
+}
+
+// inboxUsageRefresh is how long a computed inbox usage is trusted before the
+// directory is scanned again (files may be removed by the agent meanwhile).
+const inboxUsageRefresh = 30 * time.Second
+
+// logger returns f.Logger, or the current default logger tagged with the
+// component. The default is looked up per call: a logger captured at startup
+// would keep writing to the log file a reload has since closed.
+func (f *FileReceiveInspector) logger() *slog.Logger {
+	if f.Logger != nil {
+		return f.Logger
+	}
+	return slog.Default().With("component", "file-receive")
 }
 
 func (f *FileReceiveInspector) InspectMessage(payload []byte, msgType websocket.MessageType) []byte {
@@ -48,7 +93,7 @@ func (f *FileReceiveInspector) InspectMessage(payload []byte, msgType websocket.
 	// Parse the full message preserving unknown fields.
 	var msg map[string]json.RawMessage
 	if err := json.Unmarshal(payload, &msg); err != nil {
-		f.Logger.Warn("file receive: failed to parse message", "error", err)
+		f.logger().Warn("file receive: failed to parse message", "error", err)
 		return payload
 	}
 
@@ -59,7 +104,7 @@ func (f *FileReceiveInspector) InspectMessage(payload []byte, msgType websocket.
 
 	var params map[string]json.RawMessage
 	if err := json.Unmarshal(paramsRaw, &params); err != nil {
-		f.Logger.Warn("file receive: failed to parse params", "error", err)
+		f.logger().Warn("file receive: failed to parse params", "error", err)
 		return payload
 	}
 
@@ -70,7 +115,7 @@ func (f *FileReceiveInspector) InspectMessage(payload []byte, msgType websocket.
 
 	var attachments []map[string]interface{}
 	if err := json.Unmarshal(attachmentsRaw, &attachments); err != nil {
-		f.Logger.Warn("file receive: failed to parse attachments", "error", err)
+		f.logger().Warn("file receive: failed to parse attachments", "error", err)
 		return payload
 	}
 
@@ -99,59 +144,57 @@ func (f *FileReceiveInspector) InspectMessage(payload []byte, msgType websocket.
 			mimeType = "application/octet-stream"
 		}
 
-		// Decode base64 content.
-		data, err := base64.StdEncoding.DecodeString(contentStr)
-		if err != nil {
-			f.Logger.Warn("file receive: bad base64", "file", fileName, "error", err)
+		if !f.extensionAllowed(fileName) {
+			f.logger().Warn("file receive: extension not allowed, leaving attachment in message", "file", fileName)
+			f.countSkipped("extension")
 			continue
 		}
 
-		// Sanitize filename: strip path components.
-		safeName := filepath.Base(fileName)
-		safeName = strings.ReplaceAll(safeName, string(os.PathSeparator), "_")
-		if safeName == "." || safeName == ".." {
-			safeName = "unnamed_file"
+		if f.VerifyMIME {
+			head, err := base64Prefix(contentStr, sniffLen)
+			if err != nil {
+				f.logger().Warn("file receive: bad base64", "file", fileName, "error", err)
+				continue
+			}
+			if sniffed := http.DetectContentType(head); !mimeFamilyMatches(mimeType, sniffed) {
+				f.logger().Warn("file receive: content does not match declared MIME type, leaving attachment in message",
+					"file", fileName, "declared", mimeType, "sniffed", sniffed)
+				f.countSkipped("mime_mismatch")
+				continue
+			}
 		}
 
-		// Handle filename collisions.
-		destPath := filepath.Join(f.InboxDir, safeName)
-		if _, err := os.Stat(destPath); err == nil {
-			ext := filepath.Ext(safeName)
-			base := strings.TrimSuffix(safeName, ext)
-			safeName = fmt.Sprintf("%s_%d%s", base, time.Now().UnixMilli(), ext)
-			destPath = filepath.Join(f.InboxDir, safeName)
-		}
-
-		// Atomic write: temp file then rename.
-		tmpFile, err := os.CreateTemp(f.InboxDir, ".recv-*")
-		if err != nil {
-			f.Logger.Warn("file receive: failed to create temp file", "error", err)
+		// Decode into a temp file before touching the caps, so malformed
+		// base64 never reserves space or evicts older files.
+		tmpPath, written, ok := f.decodeToTemp(fileName, contentStr)
+		if !ok {
 			continue
 		}
-		tmpPath := tmpFile.Name()
 
-		if _, err := tmpFile.Write(data); err != nil {
-			tmpFile.Close()
+		if !f.reserveSpace(written) {
 			os.Remove(tmpPath)
-			f.Logger.Warn("file receive: failed to write file", "file", safeName, "error", err)
+			f.logger().Warn("file receive: inbox limit reached, not saving file",
+				"file", fileName, "size", written, "max_bytes", f.MaxBytes, "max_files", f.MaxFiles)
+			markers = append(markers, fmt.Sprintf("FILE_NOT_SAVED: %s (inbox storage limit reached)", filepath.Base(fileName)))
+			f.countSkipped("inbox_full")
+			modified = true
 			continue
 		}
-		tmpFile.Close()
 
-		if err := os.Rename(tmpPath, destPath); err != nil {
-			os.Remove(tmpPath)
-			f.Logger.Warn("file receive: failed to rename file", "file", safeName, "error", err)
+		destPath, ok := f.placeFile(tmpPath, fileName)
+		if !ok {
+			f.releaseSpace(written)
 			continue
 		}
 
-		marker := fmt.Sprintf("FILE_RECEIVED: %s (%s, %d bytes)", destPath, mimeType, len(data))
+		marker := fmt.Sprintf("FILE_RECEIVED: %s (%s, %d bytes)", destPath, mimeType, written)
 		markers = append(markers, marker)
 
 		// Strip base64 content from attachment to reduce payload size.
 		delete(attachments[i], "content")
 		modified = true
 
-		f.Logger.Info("file saved", "path", destPath, "size", len(data), "mime", mimeType)
+		f.logger().Info("file saved", "path", destPath, "size", written, "mime", mimeType)
 	}
 
 	if !modified {
@@ -170,7 +213,7 @@ func (f *FileReceiveInspector) InspectMessage(payload []byte, msgType websocket.
 
 	messageBytes, err := json.Marshal(messageText)
 	if err != nil {
-		f.Logger.Warn("file receive: failed to marshal message", "error", err)
+		f.logger().Warn("file receive: failed to marshal message", "error", err)
 		return payload
 	}
 	params["message"] = messageBytes
@@ -178,7 +221,7 @@ func (f *FileReceiveInspector) InspectMessage(payload []byte, msgType websocket.
 	// Re-marshal attachments back into params.
 	attBytes, err := json.Marshal(attachments)
 	if err != nil {
-		f.Logger.Warn("file receive: failed to marshal attachments", "error", err)
+		f.logger().Warn("file receive: failed to marshal attachments", "error", err)
 		return payload
 	}
 	params["attachments"] = attBytes
@@ -186,7 +229,7 @@ func (f *FileReceiveInspector) InspectMessage(payload []byte, msgType websocket.
 	// Re-marshal params back into msg.
 	paramsBytes, err := json.Marshal(params)
 	if err != nil {
-		f.Logger.Warn("file receive: failed to marshal params", "error", err)
+		f.logger().Warn("file receive: failed to marshal params", "error", err)
 		return payload
 	}
 	msg["params"] = paramsBytes
@@ -194,9 +237,269 @@ func (f *FileReceiveInspector) InspectMessage(payload []byte, msgType websocket.
 	// Re-marshal the full message.
 	result, err := json.Marshal(msg)
 	if err != nil {
-		f.Logger.Warn("file receive: failed to marshal message", "error", err)
+		f.logger().Warn("file receive: failed to marshal message", "error", err)
 		return payload
 	}
 
 	return result
 }
+
+// decodeToTemp streams the base64 content into a temp file in the
+// destination directory and returns its path and decoded size. The temp
+// file is dot-prefixed, so inbox accounting ignores it until placeFile
+// renames it. Failures are logged and leave no file behind.
+func (f *FileReceiveInspector) decodeToTemp(fileName, contentStr string) (tmpPath string, written int64, ok bool) {
+	destDir, err := f.destDir(time.Now())
+	if err != nil {
+		f.logger().Warn("file receive: failed to create inbox subdirectory", "dir", destDir, "error", err)
+		return "", 0, false
+	}
+
+	// Atomic write: temp file then rename, both in destDir so the rename
+	// never crosses directories.
+	tmpFile, err := os.CreateTemp(destDir, ".recv-*")
+	if err != nil {
+		f.logger().Warn("file receive: failed to create temp file", "error", err)
+		return "", 0, false
+	}
+	tmpPath = tmpFile.Name()
+
+	written, err = io.Copy(tmpFile, base64.NewDecoder(base64.StdEncoding, strings.NewReader(contentStr)))
+	tmpFile.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		var corrupt base64.CorruptInputError
+		if errors.As(err, &corrupt) {
+			f.logger().Warn("file receive: bad base64", "file", fileName, "error", err)
+		} else {
+			f.logger().Warn("file receive: failed to write file", "file", fileName, "error", err)
+		}
+		return "", 0, false
+	}
+	return tmpPath, written, true
+}
+
+// placeFile renames a temp file from decodeToTemp to a sanitized version of
+// fileName in the same directory. On failure the temp file is removed.
+func (f *FileReceiveInspector) placeFile(tmpPath, fileName string) (destPath string, ok bool) {
+	// Sanitize filename: strip path components.
+	safeName := filepath.Base(fileName)
+	safeName = strings.ReplaceAll(safeName, string(os.PathSeparator), "_")
+	if safeName == "." || safeName == ".." {
+		safeName = "unnamed_file"
+	}
+
+	// Handle filename collisions.
+	destDir := filepath.Dir(tmpPath)
+	destPath = filepath.Join(destDir, safeName)
+	if _, err := os.Stat(destPath); err == nil {
+		ext := filepath.Ext(safeName)
+		base := strings.TrimSuffix(safeName, ext)
+		safeName = fmt.Sprintf("%s_%d%s", base, time.Now().UnixMilli(), ext)
+		destPath = filepath.Join(destDir, safeName)
+	}
+
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		os.Remove(tmpPath)
+		f.logger().Warn("file receive: failed to rename file", "file", safeName, "error", err)
+		return "", false
+	}
+	return destPath, true
+}
+
+// destDir returns the directory a file received at t is written to,
+// creating the dated subdirectory when ByDate is set.
+func (f *FileReceiveInspector) destDir(t time.Time) (string, error) {
+	if !f.ByDate {
+		return f.InboxDir, nil
+	}
+	dir := filepath.Join(f.InboxDir, t.Format("2006-01-02"))
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+// reserveSpace reports whether a file of size n fits within the inbox caps,
+// evicting the oldest files first when EvictOldest is set. On success the
+// bytes and file slot are counted as used right away, under the same lock as
+// the check, so concurrent uploads can't all pass it and together exceed the
+// caps; the caller must releaseSpace if the file is then not saved.
+func (f *FileReceiveInspector) reserveSpace(n int64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.MaxBytes <= 0 && f.MaxFiles <= 0 {
+		f.usageBytes += n
+		f.usageFiles++
+		return true
+	}
+
+	if time.Since(f.usageAt) > inboxUsageRefresh {
+		f.usageBytes, f.usageFiles = 0, 0
+		for _, e := range f.inboxFiles() {
+			f.usageBytes += e.size
+			f.usageFiles++
+		}
+		f.usageAt = time.Now()
+	}
+	if !f.fits(n) && (!f.EvictOldest || (f.MaxBytes > 0 && n > f.MaxBytes)) {
+		return false
+	}
+
+	for _, e := range f.inboxFiles() {
+		if f.fits(n) {
+			break
+		}
+		if err := os.Remove(e.path); err != nil {
+			f.logger().Warn("file receive: failed to evict file", "path", e.path, "error", err)
+			continue
+		}
+		f.usageBytes -= e.size
+		f.usageFiles--
+		f.logger().Info("file receive: evicted oldest inbox file", "path", e.path, "size", e.size)
+		if dir := filepath.Dir(e.path); dir != f.InboxDir {
+			os.Remove(dir) // drop the dated directory once empty; fails harmlessly otherwise
+		}
+	}
+	if !f.fits(n) {
+		return false
+	}
+	f.usageBytes += n
+	f.usageFiles++
+	return true
+}
+
+// fits reports whether one more file of size n stays within the caps.
+// Caller must hold f.mu.
+func (f *FileReceiveInspector) fits(n int64) bool {
+	if f.MaxBytes > 0 && f.usageBytes+n > f.MaxBytes {
+		return false
+	}
+	if f.MaxFiles > 0 && f.usageFiles+1 > f.MaxFiles {
+		return false
+	}
+	return true
+}
+
+// releaseSpace returns a reservation of n bytes for a file that wasn't saved.
+func (f *FileReceiveInspector) releaseSpace(n int64) {
+	f.mu.Lock()
+	f.usageBytes -= n
+	f.usageFiles--
+	f.mu.Unlock()
+}
+
+type inboxFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// inboxFiles lists regular files in the inbox, oldest first. In-progress
+// temp files (dot-prefixed) are skipped. With ByDate, files one level down
+// in the dated subdirectories are included.
+func (f *FileReceiveInspector) inboxFiles() []inboxFile {
+	files := f.listFiles(f.InboxDir, f.ByDate)
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	return files
+}
+
+func (f *FileReceiveInspector) listFiles(dir string, descend bool) []inboxFile {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		f.logger().Warn("file receive: failed to read inbox", "dir", dir, "error", err)
+		return nil
+	}
+	var files []inboxFile
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if e.IsDir() && descend {
+			files = append(files, f.listFiles(filepath.Join(dir, e.Name()), false)...)
+			continue
+		}
+		if !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, inboxFile{
+			path:    filepath.Join(dir, e.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	return files
+}
+
+// extensionAllowed reports whether the sanitized file name's extension is in
+// AllowedExtensions (case-insensitive). An empty list allows everything.
+func (f *FileReceiveInspector) extensionAllowed(fileName string) bool {
+	if len(f.AllowedExtensions) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(filepath.Base(fileName)))
+	for _, allowed := range f.AllowedExtensions {
+		if ext != "" && strings.EqualFold(ext, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// countSkipped increments the skipped-files counter if metrics are enabled.
+func (f *FileReceiveInspector) countSkipped(reason string) {
+	if f.SkippedTotal != nil {
+		f.SkippedTotal.WithLabelValues(reason).Inc()
+	}
+}
+
+// mimeFamilyMatches reports whether sniffed content (from
+// http.DetectContentType) is consistent with the declared MIME type. Only the
+// image, audio, video and text families are enforced: the sniffer can't
+// reliably identify most application/* formats (e.g. JSON sniffs as text).
+// An application/octet-stream result means the sniffer didn't recognise the
+// content (e.g. HEIC) and is never treated as a mismatch.
+func mimeFamilyMatches(declared, sniffed string) bool {
+	essence := func(m string) string {
+		m, _, _ = strings.Cut(strings.ToLower(m), ";")
+		return strings.TrimSpace(m)
+	}
+	d, s := essence(declared), essence(sniffed)
+	switch {
+	case s == "application/octet-stream":
+		return true
+	case d == "image/svg+xml" && (s == "text/xml" || s == "text/plain"):
+		return true // SVG is XML, sniffed as such
+	case isMP4(d) && isMP4(s):
+		return true // one container for audio (m4a) and video; sniffed as video/mp4
+	}
+	family := func(m string) string {
+		f, _, _ := strings.Cut(m, "/")
+		return f
+	}
+	switch df := family(d); df {
+	case "image", "audio", "video", "text":
+		return family(s) == df
+	default:
+		return true
+	}
+}
+
+// isMP4 reports whether m names the MP4 container, audio or video.
+func isMP4(m string) bool {
+	switch m {
+	case "video/mp4", "audio/mp4", "audio/x-m4a", "audio/m4a":
+		return true
+	}
+	return false
+}
+
+// sniffLen is the most content http.DetectContentType looks at.
+const sniffLen = 512
+
+// base64Prefix decodes at most n bytes from the start of s.
+func base64Prefix(s string, n int64) ([]byte, error) {
+	return io.ReadAll(io.LimitReader(base64.NewDecoder(base64.StdEncoding, strings.NewReader(s)), n))
+}
@@ -0,0 +1,428 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/cortexuvula/clawreachbridge/internal/logging"
+)
+
+// chatSendWithFile builds a chat.send request carrying one text file attachment.
+func chatSendWithFile(t *testing.T, name string, size int) []byte {
+	t.Helper()
+	return chatSendWithAttachment(t, name, "text/plain", []byte(strings.Repeat("x", size)))
+}
+
+// chatSendWithAttachment builds a chat.send request carrying one file
+// attachment with the given declared MIME type and content.
+func chatSendWithAttachment(t *testing.T, name, mimeType string, content []byte) []byte {
+	t.Helper()
+	msg := map[string]any{
+		"type":   "req",
+		"id":     "1",
+		"method": "chat.send",
+		"params": map[string]any{
+			"message": "see attached",
+			"attachments": []map[string]any{{
+				"type":     "file",
+				"fileName": name,
+				"mimeType": mimeType,
+				"content":  base64.StdEncoding.EncodeToString(content),
+			}},
+		},
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// parseSendResult returns the rewritten message text and whether the first
+// attachment still carries its content.
+func parseSendResult(t *testing.T, payload []byte) (string, bool) {
+	t.Helper()
+	var msg struct {
+		Params struct {
+			Message     string           `json:"message"`
+			Attachments []map[string]any `json:"attachments"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	_, hasContent := msg.Params.Attachments[0]["content"]
+	return msg.Params.Message, hasContent
+}
+
+func newTestFileReceiver(t *testing.T) *FileReceiveInspector {
+	t.Helper()
+	return &FileReceiveInspector{
+		InboxDir: t.TempDir(),
+	}
+}
+
+func TestFileReceiveSavesFile(t *testing.T) {
+	f := newTestFileReceiver(t)
+
+	out := f.InspectMessage(chatSendWithFile(t, "notes.txt", 10), websocket.MessageText)
+	text, hasContent := parseSendResult(t, out)
+
+	if !strings.Contains(text, "FILE_RECEIVED: "+filepath.Join(f.InboxDir, "notes.txt")) {
+		t.Errorf("message = %q, want FILE_RECEIVED marker", text)
+	}
+	if hasContent {
+		t.Error("attachment content should be stripped after saving")
+	}
+	if data, err := os.ReadFile(filepath.Join(f.InboxDir, "notes.txt")); err != nil || len(data) != 10 {
+		t.Errorf("saved file = %d bytes, %v; want 10 bytes", len(data), err)
+	}
+}
+
+func TestFileReceiveInboxLimitReject(t *testing.T) {
+	f := newTestFileReceiver(t)
+	f.MaxBytes = 25
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		f.InspectMessage(chatSendWithFile(t, name, 10), websocket.MessageText)
+	}
+
+	out := f.InspectMessage(chatSendWithFile(t, "c.txt", 10), websocket.MessageText)
+	text, hasContent := parseSendResult(t, out)
+
+	if !strings.Contains(text, "FILE_NOT_SAVED: c.txt") {
+		t.Errorf("message = %q, want FILE_NOT_SAVED note", text)
+	}
+	if !hasContent {
+		t.Error("rejected attachment should be forwarded intact")
+	}
+	if _, err := os.Stat(filepath.Join(f.InboxDir, "c.txt")); !os.IsNotExist(err) {
+		t.Errorf("c.txt should not be written, stat err = %v", err)
+	}
+}
+
+func TestFileReceiveConcurrentUploadsRespectLimit(t *testing.T) {
+	f := newTestFileReceiver(t)
+	f.MaxFiles = 3
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.InspectMessage(chatSendWithFile(t, fmt.Sprintf("f%d.txt", i), 10), websocket.MessageText)
+		}()
+	}
+	wg.Wait()
+
+	entries, _ := os.ReadDir(f.InboxDir)
+	if len(entries) != 3 {
+		t.Errorf("inbox has %d files, want exactly max_files (3)", len(entries))
+	}
+	if f.usageFiles != 3 || f.usageBytes != 30 {
+		t.Errorf("inbox usage = %d bytes / %d files, want 30 / 3", f.usageBytes, f.usageFiles)
+	}
+}
+
+func TestFileReceiveFailedWriteReleasesReservation(t *testing.T) {
+	f := newTestFileReceiver(t)
+	f.MaxFiles = 1
+
+	bad := strings.Replace(string(chatSendWithFile(t, "bad.txt", 300)), "eHh4", "e!h4", 1)
+	f.InspectMessage([]byte(bad), websocket.MessageText)
+
+	out := f.InspectMessage(chatSendWithFile(t, "good.txt", 10), websocket.MessageText)
+	if text, _ := parseSendResult(t, out); !strings.Contains(text, "FILE_RECEIVED:") {
+		t.Errorf("message = %q, want FILE_RECEIVED (failed write must not keep its slot)", text)
+	}
+	if f.usageFiles != 1 || f.usageBytes != 10 {
+		t.Errorf("inbox usage = %d bytes / %d files, want 10 / 1", f.usageBytes, f.usageFiles)
+	}
+}
+
+func TestFileReceiveBadBase64DoesNotEvict(t *testing.T) {
+	f := newTestFileReceiver(t)
+	f.MaxFiles = 1
+	f.EvictOldest = true
+
+	f.InspectMessage(chatSendWithFile(t, "keep.txt", 10), websocket.MessageText)
+	bad := strings.Replace(string(chatSendWithFile(t, "bad.txt", 300)), "eHh4", "e!h4", 1)
+	f.InspectMessage([]byte(bad), websocket.MessageText)
+
+	if _, err := os.Stat(filepath.Join(f.InboxDir, "keep.txt")); err != nil {
+		t.Errorf("keep.txt must not be evicted for an attachment that fails to decode: %v", err)
+	}
+	if f.usageFiles != 1 || f.usageBytes != 10 {
+		t.Errorf("inbox usage = %d bytes / %d files, want 10 / 1", f.usageBytes, f.usageFiles)
+	}
+}
+
+func TestFileReceiveInboxLimitEvictOldest(t *testing.T) {
+	f := newTestFileReceiver(t)
+	f.MaxFiles = 2
+	f.EvictOldest = true
+
+	// Stagger mtimes so eviction order is deterministic.
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"a.txt", "b.txt"} {
+		f.InspectMessage(chatSendWithFile(t, name, 10), websocket.MessageText)
+		mt := base.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(filepath.Join(f.InboxDir, name), mt, mt)
+	}
+
+	out := f.InspectMessage(chatSendWithFile(t, "c.txt", 10), websocket.MessageText)
+	text, _ := parseSendResult(t, out)
+	if !strings.Contains(text, "FILE_RECEIVED:") {
+		t.Errorf("message = %q, want FILE_RECEIVED marker", text)
+	}
+
+	if _, err := os.Stat(filepath.Join(f.InboxDir, "a.txt")); !os.IsNotExist(err) {
+		t.Error("oldest file a.txt should have been evicted")
+	}
+	for _, name := range []string{"b.txt", "c.txt"} {
+		if _, err := os.Stat(filepath.Join(f.InboxDir, name)); err != nil {
+			t.Errorf("%s should exist: %v", name, err)
+		}
+	}
+}
+
+func TestFileReceiveEvictCannotFitOversizedFile(t *testing.T) {
+	f := newTestFileReceiver(t)
+	f.MaxBytes = 5
+	f.EvictOldest = true
+
+	out := f.InspectMessage(chatSendWithFile(t, "big.txt", 10), websocket.MessageText)
+	text, hasContent := parseSendResult(t, out)
+	if !strings.Contains(text, "FILE_NOT_SAVED: big.txt") || !hasContent {
+		t.Errorf("message = %q, hasContent = %v; want file larger than cap rejected", text, hasContent)
+	}
+}
+
+func TestFileReceiveExtensionAllowlist(t *testing.T) {
+	f := newTestFileReceiver(t)
+	f.AllowedExtensions = []string{".txt", ".PDF"}
+	f.SkippedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_files_skipped"}, []string{"reason"})
+
+	out := f.InspectMessage(chatSendWithFile(t, "install.sh", 10), websocket.MessageText)
+	text, hasContent := parseSendResult(t, out)
+	if strings.Contains(text, "FILE_RECEIVED") || !hasContent {
+		t.Errorf("disallowed extension: message = %q, hasContent = %v; want attachment left intact", text, hasContent)
+	}
+	if _, err := os.Stat(filepath.Join(f.InboxDir, "install.sh")); !os.IsNotExist(err) {
+		t.Error("install.sh should not be written")
+	}
+	if got := testutil.ToFloat64(f.SkippedTotal.WithLabelValues("extension")); got != 1 {
+		t.Errorf("skipped{extension} = %v, want 1", got)
+	}
+
+	for _, name := range []string{"notes.txt", "report.pdf"} {
+		out = f.InspectMessage(chatSendWithFile(t, name, 10), websocket.MessageText)
+		if text, _ := parseSendResult(t, out); !strings.Contains(text, "FILE_RECEIVED") {
+			t.Errorf("%s: message = %q, want FILE_RECEIVED", name, text)
+		}
+		if _, err := os.Stat(filepath.Join(f.InboxDir, name)); err != nil {
+			t.Errorf("%s should be written: %v", name, err)
+		}
+	}
+}
+
+func TestFileReceiveVerifyMIME(t *testing.T) {
+	f := newTestFileReceiver(t)
+	f.VerifyMIME = true
+
+	// Declared as an image but the content is a zip archive.
+	zip := append([]byte("PK\x03\x04"), make([]byte, 64)...)
+	out := f.InspectMessage(chatSendWithAttachment(t, "photo.png", "image/png", zip), websocket.MessageText)
+	text, hasContent := parseSendResult(t, out)
+	if strings.Contains(text, "FILE_RECEIVED") || !hasContent {
+		t.Errorf("mismatched MIME: message = %q, hasContent = %v; want attachment left intact", text, hasContent)
+	}
+	if _, err := os.Stat(filepath.Join(f.InboxDir, "photo.png")); !os.IsNotExist(err) {
+		t.Error("photo.png should not be written")
+	}
+
+	// Real PNG header matches its declared type.
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+	out = f.InspectMessage(chatSendWithAttachment(t, "real.png", "image/png", png), websocket.MessageText)
+	if text, _ := parseSendResult(t, out); !strings.Contains(text, "FILE_RECEIVED") {
+		t.Errorf("matching MIME: message = %q, want FILE_RECEIVED", text)
+	}
+
+	// application/* is not enforced (JSON sniffs as text/plain).
+	out = f.InspectMessage(chatSendWithAttachment(t, "data.json", "application/json", []byte(`{"a":1}`)), websocket.MessageText)
+	if text, _ := parseSendResult(t, out); !strings.Contains(text, "FILE_RECEIVED") {
+		t.Errorf("application/json: message = %q, want FILE_RECEIVED", text)
+	}
+}
+
+func TestMimeFamilyMatches(t *testing.T) {
+	tests := []struct {
+		declared, sniffed string
+		want              bool
+	}{
+		{"image/png", "image/png", true},
+		{"image/jpeg", "image/png", true},
+		{"image/png", "application/zip", false},
+		{"image/png", "text/html; charset=utf-8", false},
+		{"text/csv", "text/plain; charset=utf-8", true},
+		{"text/plain", "application/pdf", false},
+		{"Video/MP4", "video/mp4", true},
+		{"application/pdf", "text/plain; charset=utf-8", true},
+		// Inconclusive sniff: HEIC and other unrecognised content.
+		{"image/heic", "application/octet-stream", true},
+		{"text/plain", "application/octet-stream", true},
+		// SVG sniffs as XML.
+		{"image/svg+xml", "text/xml; charset=utf-8", true},
+		{"image/svg+xml", "text/plain; charset=utf-8", true},
+		{"image/png", "text/xml; charset=utf-8", false},
+		// m4a shares the MP4 container.
+		{"audio/mp4", "video/mp4", true},
+		{"video/mp4", "audio/mp4", true},
+		{"audio/x-m4a", "video/mp4", true},
+		{"audio/mpeg", "video/mp4", false},
+	}
+	for _, tt := range tests {
+		if got := mimeFamilyMatches(tt.declared, tt.sniffed); got != tt.want {
+			t.Errorf("mimeFamilyMatches(%q, %q) = %v, want %v", tt.declared, tt.sniffed, got, tt.want)
+		}
+	}
+}
+
+func TestFileReceiveByDateLayout(t *testing.T) {
+	f := newTestFileReceiver(t)
+	f.ByDate = true
+
+	out := f.InspectMessage(chatSendWithFile(t, "notes.txt", 10), websocket.MessageText)
+	text, _ := parseSendResult(t, out)
+
+	want := filepath.Join(f.InboxDir, time.Now().Format("2006-01-02"), "notes.txt")
+	if !strings.Contains(text, "FILE_RECEIVED: "+want) {
+		t.Errorf("message = %q, want FILE_RECEIVED marker for %s", text, want)
+	}
+	if data, err := os.ReadFile(want); err != nil || len(data) != 10 {
+		t.Errorf("saved file = %d bytes, %v; want 10 bytes", len(data), err)
+	}
+	if _, err := os.Stat(filepath.Join(f.InboxDir, "notes.txt")); !os.IsNotExist(err) {
+		t.Errorf("file should not be written to the inbox root, stat err = %v", err)
+	}
+}
+
+func TestFileReceiveByDateEvictsAcrossDays(t *testing.T) {
+	f := newTestFileReceiver(t)
+	f.ByDate = true
+	f.MaxFiles = 1
+	f.EvictOldest = true
+
+	// A file left over from an earlier day counts towards the cap.
+	oldDir := filepath.Join(f.InboxDir, "2000-01-01")
+	if err := os.Mkdir(oldDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := filepath.Join(oldDir, "old.txt")
+	if err := os.WriteFile(old, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mt := time.Now().Add(-time.Hour)
+	os.Chtimes(old, mt, mt)
+
+	out := f.InspectMessage(chatSendWithFile(t, "new.txt", 10), websocket.MessageText)
+	text, _ := parseSendResult(t, out)
+	if !strings.Contains(text, "FILE_RECEIVED:") {
+		t.Errorf("message = %q, want FILE_RECEIVED marker", text)
+	}
+	if _, err := os.Stat(oldDir); !os.IsNotExist(err) {
+		t.Errorf("emptied dated directory should be removed, stat err = %v", err)
+	}
+}
+
+func TestFileReceiveStreamsLargeAttachment(t *testing.T) {
+	f := newTestFileReceiver(t)
+	f.MaxBytes = 4 << 20
+
+	// Pseudo-random content exercises every base64 alphabet character and a
+	// padded final quantum.
+	content := make([]byte, 3<<20+1)
+	for i := range content {
+		content[i] = byte(i*7 + i>>8)
+	}
+	out := f.InspectMessage(chatSendWithAttachment(t, "big.bin", "application/octet-stream", content), websocket.MessageText)
+	text, hasContent := parseSendResult(t, out)
+
+	path := filepath.Join(f.InboxDir, "big.bin")
+	if !strings.Contains(text, "FILE_RECEIVED: "+path+" (application/octet-stream, 3145729 bytes)") {
+		t.Errorf("message = %q, want FILE_RECEIVED marker with decoded size", text)
+	}
+	if hasContent {
+		t.Error("attachment content should be stripped after saving")
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("saved file differs from attachment (%d vs %d bytes)", len(got), len(content))
+	}
+	if used, files := f.usageBytes, f.usageFiles; used != int64(len(content)) || files != 1 {
+		t.Errorf("inbox usage = %d bytes / %d files, want %d / 1", used, files, len(content))
+	}
+}
+
+func TestFileReceiveBadBase64LeavesNoFile(t *testing.T) {
+	f := newTestFileReceiver(t)
+	msg := strings.Replace(string(chatSendWithFile(t, "notes.txt", 300)), "eHh4", "e!h4", 1)
+
+	out := f.InspectMessage([]byte(msg), websocket.MessageText)
+	if string(out) != msg {
+		t.Errorf("payload with corrupt base64 should be forwarded unchanged")
+	}
+	entries, _ := os.ReadDir(f.InboxDir)
+	if len(entries) != 0 {
+		t.Errorf("inbox has %d entries, want none (temp file should be removed)", len(entries))
+	}
+}
+
+func TestFileReceiveLogsFollowLoggingReload(t *testing.T) {
+	prev := slog.Default()
+	defer slog.SetDefault(prev)
+
+	dir := t.TempDir()
+	oldFile := filepath.Join(dir, "bridge.log")
+	handler, lj := logging.SetupHandler("info", "json", oldFile, 10, 1, 7, false)
+	slog.SetDefault(slog.New(handler))
+
+	f := newTestFileReceiver(t)
+	f.AllowedExtensions = []string{".pdf"}
+	f.InspectMessage(chatSendWithFile(t, "before.sh", 10), websocket.MessageText)
+
+	// Reload moves the log, as SIGHUP/SIGUSR1 do in main: the previous
+	// writer is closed and the default logger replaced.
+	newFile := filepath.Join(dir, "moved.log")
+	handler, lj, err := logging.ReopenLog(lj, "info", "text", newFile, 10, 1, 7, false)
+	if err != nil {
+		t.Fatalf("ReopenLog: %v", err)
+	}
+	defer lj.Close()
+	slog.SetDefault(slog.New(handler))
+	f.InspectMessage(chatSendWithFile(t, "after.sh", 10), websocket.MessageText)
+
+	moved, _ := os.ReadFile(newFile)
+	if !strings.Contains(string(moved), "after.sh") || !strings.Contains(string(moved), "component=file-receive") {
+		t.Errorf("reloaded log = %q, want the file-receive entry in text format", moved)
+	}
+	if old, _ := os.ReadFile(oldFile); strings.Contains(string(old), "after.sh") {
+		t.Error("file-receive logged to the closed log file after the reload")
+	}
+}
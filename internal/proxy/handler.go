@@ -2,15 +2,28 @@ package proxy
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
+	"mime"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/coder/websocket"
@@ -32,10 +45,13 @@ type Handler struct {
 	Metrics           *metrics.Metrics   // optional, nil if metrics disabled
 	MediaInjector     *media.Injector         // optional, nil if media injection disabled
 	ReactionInspector    *ReactionInspector    // optional, nil if reactions disabled
+	MethodInspector      *MethodInspector      // optional, nil if metrics or method_metrics disabled
 	FileReceiveInspector *FileReceiveInspector // optional, nil if file receive disabled
+	TransformInspector   *TransformInspector   // optional, nil if transform_command unset
 	CanvasTracker     *canvas.CanvasTracker   // optional, nil if canvas tracking disabled
-	SyncStore         *chatsync.MessageStore  // optional, nil if sync disabled
+	SyncStore         chatsync.Store          // optional, nil if sync disabled
 	SyncRegistry      *chatsync.ClientRegistry // optional, nil if sync disabled
+	AuthzWebhook      *security.AuthzWebhook   // optional, nil if no authz webhook configured
 	ShutdownCtx       context.Context         // cancelled on server shutdown
 
 	// httpProxy forwards non-WebSocket requests to the gateway.
@@ -46,6 +62,41 @@ type Handler struct {
 	drainCtx    context.Context
 	drainCancel context.CancelFunc
 
+	// conns tracks active connections by ID for ordered draining.
+	connsMu       sync.Mutex
+	conns         map[string]*activeConn
+	drainInterval time.Duration
+
+	// gatewayPool holds pre-dialed gateway connections; nil when
+	// bridge.gateway_pool_size is 0. Set by StartGatewayPool before serving.
+	gatewayPool *gatewayPool
+
+	// dialSlots bounds concurrent gateway dials to bridge.max_concurrent_dials;
+	// nil when unlimited.
+	dialSlots chan struct{}
+
+	// resolver caches gateway DNS lookups for bridge.gateway_resolve_ttl and
+	// applies bridge.gateway_resolve_override; nil when neither is set.
+	resolver *gatewayResolver
+
+	// thresholds tracks monitoring.connection_*_threshold crossings.
+	thresholds connectionThresholds
+
+	// resumes holds gateway sessions of dropped clients for bridge.resume.
+	resumes *resumeStore
+
+	// maintenance is set while a bridge.maintenance_schedule window is in
+	// effect; WebSocket upgrades are refused until it clears.
+	maintenance atomic.Bool
+
+	// mediaPaused suspends media injection at runtime without a config
+	// change. Checked per message, so it affects in-flight connections too.
+	mediaPaused atomic.Bool
+
+	// debugSeq counts per-message debug log candidates across all
+	// connections for logging.debug_sample_rate.
+	debugSeq atomic.Uint64
+
 	// mu protects Config during hot-reload
 	mu sync.RWMutex
 }
@@ -55,18 +106,32 @@ func NewHandler(cfg *config.Config, p *Proxy, rl *security.RateLimiter, shutdown
 	drainCtx, drainCancel := context.WithCancel(context.Background())
 
 	origin := cfg.Bridge.Origin
+	requestIDHeader := cfg.Bridge.RequestIDHeader
+	clientIPHeader := cfg.Bridge.ClientIPHeader
 	gatewayURL, _ := url.Parse(cfg.Bridge.GatewayURL)
+	_, gatewayAuth := stripUserinfo(cfg.Bridge.GatewayURL)
 	httpProxy := &httputil.ReverseProxy{
+		// Event streams and chunked responses are flushed on every write
+		// regardless; this covers streaming bodies with a Content-Length.
+		FlushInterval: cfg.Bridge.FlushInterval,
 		Rewrite: func(r *httputil.ProxyRequest) {
-			r.SetURL(gatewayURL)
+			r.SetURL(gatewayURL) // copies scheme, host and path; never the userinfo
 			r.Out.Host = gatewayURL.Host
-			r.Out.Header.Set("Origin", origin)
+			r.Out.Header.Set("Origin", origin.For(r.In.URL.Path))
+			if gatewayAuth != "" {
+				r.Out.Header.Set("Authorization", gatewayAuth)
+			}
+			if requestIDHeader != "" {
+				r.Out.Header.Set(requestIDHeader, requestID(r.In, requestIDHeader))
+			}
+			if clientIPHeader != "" {
+				if ip, _, err := net.SplitHostPort(r.In.RemoteAddr); err == nil {
+					r.Out.Header.Set(clientIPHeader, clientIPHeaderValue(r.In, clientIPHeader, ip))
+				}
+			}
 			// Do NOT call r.SetXForwarded() — the gateway treats
 			// X-Forwarded-For as a non-local request and rejects it.
-		},
-		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-			slog.Error("HTTP proxy error", "url", r.URL.Path, "error", err)
-			http.Error(w, "Bad Gateway", http.StatusBadGateway)
+			// bridge.client_ip_header opts in explicitly.
 		},
 	}
 
@@ -78,6 +143,24 @@ func NewHandler(cfg *config.Config, p *Proxy, rl *security.RateLimiter, shutdown
 		httpProxy:   httpProxy,
 		drainCtx:    drainCtx,
 		drainCancel: drainCancel,
+
+		conns:         make(map[string]*activeConn),
+		drainInterval: defaultDrainInterval,
+		resumes:       newResumeStore(),
+		resolver:      newGatewayResolver(cfg),
+	}
+	transport := newHTTPTransport(cfg.Bridge.HTTPTransport)
+	if h.resolver != nil {
+		transport.DialContext = h.resolver.DialContext
+	}
+	httpProxy.Transport = transport
+	httpProxy.ErrorHandler = h.proxyError
+	httpProxy.ModifyResponse = h.stripResponseHeaders
+	if cfg.Bridge.MaxConcurrentDials > 0 {
+		h.dialSlots = make(chan struct{}, cfg.Bridge.MaxConcurrentDials)
+	}
+	if shutdownCtx != nil {
+		context.AfterFunc(shutdownCtx, func() { h.resumes.closeAll("server shutting down") })
 	}
 
 	if cfg.Bridge.Media.Enabled {
@@ -92,26 +175,115 @@ func NewHandler(cfg *config.Config, p *Proxy, rl *security.RateLimiter, shutdown
 	return h
 }
 
-// StartDrain signals all active connections to begin graceful shutdown.
-// Each connection's drain watcher will send a WebSocket close frame.
-func (h *Handler) StartDrain() {
-	h.drainCancel()
+// newHTTPTransport returns the HTTP proxy's transport to the gateway: Go's
+// default transport with bridge.http_transport applied. Every proxied
+// request goes to the gateway, so the per-host idle limit is raised to the
+// overall one.
+func newHTTPTransport(c config.HTTPTransportConfig) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConns = c.MaxIdleConns
+	t.MaxIdleConnsPerHost = c.MaxIdleConns
+	if c.MaxIdleConns == 0 {
+		t.MaxIdleConnsPerHost = math.MaxInt // 0 here would mean the default of 2
+	}
+	t.IdleConnTimeout = c.IdleConnTimeout
+	t.TLSHandshakeTimeout = c.TLSHandshakeTimeout
+	return t
 }
 
-// GetConfig returns the current config (thread-safe for hot-reload).
+// GetConfig returns the current config (thread-safe for hot-reload). The
+// result is a shared snapshot and must be treated as read-only.
 func (h *Handler) GetConfig() *config.Config {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	return h.Config
 }
 
-// UpdateConfig swaps the config (called on SIGHUP).
+// UpdateConfig swaps the config (called on SIGHUP). It publishes a deep copy,
+// so callers may keep editing cfg without racing readers of the old or new
+// snapshot.
 func (h *Handler) UpdateConfig(cfg *config.Config) {
+	cfg = cfg.Clone()
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	h.Config = cfg
 }
 
+// SetMediaPaused pauses or resumes media injection for new and in-flight
+// connections. It has no effect when media injection is not configured.
+func (h *Handler) SetMediaPaused(paused bool) {
+	h.mediaPaused.Store(paused)
+}
+
+// ToggleMediaPaused flips the media injection pause flag and returns the new
+// paused state.
+func (h *Handler) ToggleMediaPaused() bool {
+	for {
+		old := h.mediaPaused.Load()
+		if h.mediaPaused.CompareAndSwap(old, !old) {
+			return !old
+		}
+	}
+}
+
+// MediaInjectionActive reports whether media injection is configured and
+// not paused.
+func (h *Handler) MediaInjectionActive() bool {
+	return h.GetConfig().Bridge.Media.Enabled && h.MediaInjector != nil && !h.mediaPaused.Load()
+}
+
+// setRateLimitHeaders adds Retry-After and X-RateLimit-* headers describing
+// the limiter state for key, so clients know how long to back off.
+func setRateLimitHeaders(w http.ResponseWriter, rl *security.RateLimiter, key string) {
+	remaining, wait := rl.Remaining(key)
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rl.Limit()))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+}
+
+// rejection is the body of a rejected request when security.json_rejections
+// is on and the client accepts JSON.
+type rejection struct {
+	Error      string `json:"error"`
+	Reason     string `json:"reason"`
+	RetryAfter int    `json:"retry_after,omitempty"`
+}
+
+// reject writes an error response for a refused request. Programmatic
+// clients (Accept: application/json, with security.json_rejections on) get a
+// rejection with a machine-readable reason and any Retry-After already set on
+// w; everyone else gets the plain-text message as before.
+func reject(w http.ResponseWriter, r *http.Request, cfg *config.Config, status int, message, reason string) {
+	if !cfg.Security.JSONRejections || !acceptsJSON(r) {
+		http.Error(w, message, status)
+		return
+	}
+	body := rejection{Error: message, Reason: reason}
+	body.RetryAfter, _ = strconv.Atoi(w.Header().Get("Retry-After"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// acceptsJSON reports whether the request's Accept header lists
+// application/json.
+func acceptsJSON(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(v, ",") {
+			mt, _, _ := strings.Cut(part, ";")
+			if strings.EqualFold(strings.TrimSpace(mt), "application/json") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // shouldInjectMedia reports whether the given request path matches any of
 // the configured media inject_paths prefixes. An empty inject_paths list
 // means inject on all paths (backward compatibility).
@@ -128,6 +300,33 @@ func (h *Handler) shouldInjectMedia(path string) bool {
 	return false
 }
 
+// stripPathPrefix returns r with prefix removed from its path, so routing,
+// auth exemptions and the gateway all see the path without it. Requests whose
+// path doesn't start with prefix (as a whole segment) are returned as is.
+func stripPathPrefix(r *http.Request, prefix string) *http.Request {
+	if prefix == "" {
+		return r
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, prefix)
+	if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+		return r
+	}
+	if rest == "" {
+		rest = "/"
+	}
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = rest
+	if rawRest, ok := strings.CutPrefix(r.URL.RawPath, prefix); ok && rawRest != "" {
+		r2.URL.RawPath = rawRest
+	} else {
+		r2.URL.RawPath = ""
+	}
+	return r2
+}
+
 // isPublicPath reports whether the given request path matches any of
 // the configured public_paths prefixes. Requests to public paths skip
 // auth token checks but still require Tailscale IP validation and rate limiting.
@@ -143,11 +342,12 @@ func (h *Handler) isPublicPath(path string) bool {
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	cfg := h.GetConfig()
+	r = stripPathPrefix(r, cfg.Bridge.StripPathPrefix)
 
 	// 1. Validate Tailscale IP
 	if cfg.Security.TailscaleOnly && !security.IsTailscaleIP(r.RemoteAddr) {
 		slog.Warn("rejected non-Tailscale connection", "remote_addr", r.RemoteAddr)
-		http.Error(w, "Unauthorized", http.StatusForbidden)
+		reject(w, r, cfg, http.StatusForbidden, "Unauthorized", "not_tailscale")
 		return
 	}
 
@@ -155,60 +355,96 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
 		slog.Error("failed to parse remote address", "remote_addr", r.RemoteAddr, "error", err)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		reject(w, r, cfg, http.StatusBadRequest, "Bad Request", "bad_remote_addr")
+		return
+	}
+	if !security.HostAllowed(r.Host, cfg.Security.AllowedHosts) {
+		slog.Warn("rejected request for disallowed host", "client_ip", clientIP, "host", r.Host)
+		reject(w, r, cfg, http.StatusForbidden, "Forbidden", "host_not_allowed")
 		return
 	}
 
-	// 3. Optional auth token check (header first, query param fallback)
+	// 3. Optional auth token check (header first, query param fallback unless
+	// security.allow_query_token is off).
 	// Public paths (e.g. A2UI static assets) bypass auth — WebViews can't pass tokens.
-	if cfg.Security.AuthToken != "" && !h.isPublicPath(r.URL.Path) {
-		token := security.ExtractBearerToken(r.Header.Get("Authorization"))
-		if token == "" {
+	token := security.ExtractBearerToken(r.Header.Get("Authorization"))
+	queryToken := false
+	if token == "" && r.URL.Query().Has("token") {
+		if cfg.Security.AllowQueryToken {
 			token = r.URL.Query().Get("token")
-			if token != "" {
-				slog.Warn("auth token provided via query parameter; use Authorization header instead", "client_ip", clientIP)
-			}
+			queryToken = token != ""
+		} else {
+			slog.Warn("ignoring auth token in query parameter (security.allow_query_token is false); use Authorization header", "client_ip", clientIP)
+		}
+	}
+	if cfg.Security.AuthToken != "" && !h.isPublicPath(r.URL.Path) {
+		if queryToken {
+			slog.Warn("auth token provided via query parameter; use Authorization header instead", "client_ip", clientIP)
 		}
 		if !security.TokenMatch(token, cfg.Security.AuthToken) {
 			slog.Warn("rejected invalid auth token", "client_ip", clientIP)
-			http.Error(w, "Forbidden", http.StatusForbidden)
+			reject(w, r, cfg, http.StatusForbidden, "Forbidden", "invalid_token")
 			return
 		}
 	}
 
-	// 4. Rate limit check
-	if cfg.Security.RateLimit.Enabled && h.RateLimiter != nil && !h.RateLimiter.Allow(clientIP) {
-		slog.Warn("rate limit exceeded", "client_ip", clientIP)
-		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+	// 4. Rate limit check, keyed by client cert subject instead of IP when
+	// configured (several clients may share one exit node IP).
+	certID := security.CertIdentity(r.TLS)
+	rateKey := clientIP
+	if cfg.Security.RateLimit.Key == "cert" && certID != "" {
+		rateKey = "cert:" + certID
+	}
+	if cfg.Security.RateLimit.Enabled && h.RateLimiter != nil && !h.RateLimiter.Allow(rateKey) {
+		slog.Warn("rate limit exceeded", "client_ip", clientIP, "client_cert", certID)
+		setRateLimitHeaders(w, h.RateLimiter, rateKey)
+		reject(w, r, cfg, http.StatusTooManyRequests, "Too Many Requests", "rate_limited")
 		return
 	}
 
 	// Route: plain HTTP requests go through the reverse proxy to the gateway.
 	// WebSocket upgrades continue through the WebSocket-specific path below.
+	// With the HTTP proxy disabled, only public paths (e.g. A2UI assets) pass.
 	if !isWebSocketUpgrade(r) {
+		if !cfg.Bridge.HTTPProxyEnabled && !h.isPublicPath(r.URL.Path) {
+			slog.Debug("HTTP proxy disabled, rejecting request", "client_ip", clientIP, "method", r.Method, "path", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
 		slog.Debug("proxying HTTP request", "client_ip", clientIP, "method", r.Method, "path", r.URL.Path)
 		h.httpProxy.ServeHTTP(w, r)
 		return
 	}
 
-	// 5. Connection limits (atomic check-and-increment to prevent TOCTOU race)
-	if reason := h.Proxy.TryIncrementConnections(clientIP, cfg.Security.MaxConnections, cfg.Security.MaxConnectionsPerIP); reason != "" {
-		if reason == "max_connections" {
-			slog.Warn("max connections reached", "current", h.Proxy.ConnectionCount(), "max", cfg.Security.MaxConnections)
-			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
-		} else {
-			slog.Warn("max connections per IP reached", "client_ip", clientIP, "current", h.Proxy.ConnectionCountForIP(clientIP))
-			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
-		}
+	if h.maintenance.Load() {
+		slog.Debug("rejected connection: maintenance window", "client_ip", clientIP)
+		reject(w, r, cfg, http.StatusServiceUnavailable, "Service Unavailable", "maintenance")
 		return
 	}
-	if h.Metrics != nil {
-		h.Metrics.ConnectionsTotal.Inc()
-		h.Metrics.ActiveConnections.Inc()
+
+	// External authorization webhook (after token/Tailscale checks, before
+	// any connection slots are taken).
+	if h.AuthzWebhook != nil {
+		headers := r.Header.Clone()
+		headers.Del("Authorization") // token is sent separately
+		allowed, err := h.AuthzWebhook.Authorize(r.Context(), security.AuthzRequest{
+			ClientIP: clientIP,
+			Token:    token,
+			Path:     r.URL.Path,
+			Headers:  headers,
+		})
+		if err != nil {
+			slog.Warn("authz webhook failed", "client_ip", clientIP, "path", r.URL.Path, "error", err, "allowed", allowed)
+		}
+		if !allowed {
+			slog.Warn("rejected by authz webhook", "client_ip", clientIP, "path", r.URL.Path)
+			reject(w, r, cfg, http.StatusForbidden, "Forbidden", "authz_denied")
+			return
+		}
 	}
 
-	// 6. Accept client WebSocket connection
-	// Forward subprotocols from client request to Gateway
+	// Forward subprotocols from client request to Gateway. Filtering happens
+	// before connection limits so the negotiated subprotocol can pick them.
 	subprotocols := r.Header.Values("Sec-WebSocket-Protocol")
 
 	// Filter subprotocols if an allowlist is configured
@@ -224,30 +460,138 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		if len(subprotocols) > 0 && len(filtered) == 0 {
-			h.Proxy.DecrementConnections(clientIP)
 			if h.Metrics != nil {
-				h.Metrics.ActiveConnections.Dec()
 				h.Metrics.ErrorsTotal.WithLabelValues("subprotocol_rejected").Inc()
 			}
 			slog.Warn("rejected connection: no allowed subprotocols", "client_ip", clientIP, "requested", subprotocols)
-			http.Error(w, "Forbidden", http.StatusForbidden)
+			reject(w, r, cfg, http.StatusForbidden, "Forbidden", "subprotocol_rejected")
 			return
 		}
 		subprotocols = filtered
 	}
+
+	// 5. Connection limits (atomic check-and-increment to prevent TOCTOU race).
+	// A security.max_connections_by_subprotocol entry for the subprotocol
+	// Accept will negotiate replaces the global limits for that class.
+	class := negotiatedSubprotocol(r, subprotocols)
+	maxConns, maxPerIP, classLimited := cfg.Security.ConnectionLimitsFor(class)
+	var reason string
+	if classLimited {
+		reason = h.Proxy.TryIncrementClassConnections(clientIP, class, maxConns, maxPerIP)
+	} else {
+		reason = h.Proxy.TryIncrementConnections(clientIP, maxConns, maxPerIP)
+	}
+	if reason != "" {
+		if h.Metrics != nil {
+			h.Metrics.ErrorsTotal.WithLabelValues(reason).Inc()
+		}
+		if reason == "max_connections" {
+			current := h.Proxy.ConnectionCount()
+			if classLimited {
+				current = h.Proxy.ConnectionCountForClass(class)
+			}
+			slog.Warn("max connections reached", "client_ip", clientIP, "current", current, "max", maxConns, "subprotocol", class)
+			reject(w, r, cfg, http.StatusServiceUnavailable, "Service Unavailable", "max_connections")
+		} else {
+			slog.Warn("max connections per IP reached", "client_ip", clientIP, "current", h.Proxy.ConnectionCountForIP(clientIP), "max", maxPerIP, "subprotocol", class)
+			reject(w, r, cfg, http.StatusTooManyRequests, "Too Many Requests", "max_connections_per_ip")
+		}
+		return
+	}
+	h.checkConnectionThresholds()
+	decrementConnections := func() {
+		if classLimited {
+			h.Proxy.DecrementClassConnections(clientIP, class)
+		} else {
+			h.Proxy.DecrementConnections(clientIP)
+		}
+		h.checkConnectionThresholds()
+	}
+	// Per-token quota (tokenless connections fall under the per-IP limit only)
+	var tokenKey string
+	if token != "" && cfg.Security.MaxConnectionsPerToken > 0 {
+		tokenKey = tokenHash(token)
+		if !h.Proxy.TryIncrementTokenConnections(tokenKey, cfg.Security.MaxConnectionsPerToken) {
+			decrementConnections()
+			if h.Metrics != nil {
+				h.Metrics.ErrorsTotal.WithLabelValues("max_connections_per_token").Inc()
+			}
+			slog.Warn("max connections per token reached", "client_ip", clientIP, "token_hash", tokenKey, "current", h.Proxy.ConnectionCountForToken(tokenKey))
+			reject(w, r, cfg, http.StatusTooManyRequests, "Too Many Requests", "max_connections_per_token")
+			return
+		}
+	}
+	releaseConnection := func() {
+		decrementConnections()
+		if tokenKey != "" {
+			h.Proxy.DecrementTokenConnections(tokenKey)
+		}
+	}
+
+	if h.Metrics != nil {
+		h.Metrics.ConnectionsTotal.Inc()
+		h.Metrics.ActiveConnections.Inc()
+	}
+
+	// 6. Accept client WebSocket connection
+	// Connection ID correlates every log line for this connection; the request
+	// ID (client-supplied or generated) is forwarded to the gateway.
+	connID := newConnID()
+	var reqID string
+	if cfg.Bridge.RequestIDHeader != "" {
+		reqID = requestID(r, cfg.Bridge.RequestIDHeader)
+	}
+	var forwardedIP string
+	if cfg.Bridge.ClientIPHeader != "" {
+		forwardedIP = clientIPHeaderValue(r, cfg.Bridge.ClientIPHeader, clientIP)
+	}
+
+	// A client reconnecting with the resume token of a dropped connection
+	// picks up its parked gateway session instead of dialing a new one.
+	var resumeTok string
+	var resumed *parkedSession
+	if cfg.Bridge.Resume.Enabled {
+		if resumeTok = resumeToken(r); resumeTok != "" {
+			if resumed = h.resumes.take(resumeTok, resumeOwner(token, clientIP)); resumed != nil {
+				w.Header().Set(resumedHeader, "true")
+			}
+		}
+	}
+
+	if dropped := filterExtensions(r, cfg.Bridge.AllowedExtensions); len(dropped) > 0 {
+		slog.Debug("declined WebSocket extensions not in allowed_extensions", "conn_id", connID, "client_ip", clientIP, "extensions", dropped)
+	}
 	clientConn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
-		Subprotocols: subprotocols,
+		Subprotocols:         subprotocols,
+		CompressionMode:      compressionMode(cfg.Bridge.Compression.Mode),
+		CompressionThreshold: cfg.Bridge.Compression.Threshold,
 	})
 	if err != nil {
-		h.Proxy.DecrementConnections(clientIP)
+		if resumed != nil {
+			resumed.pc.close()
+		}
+		releaseConnection()
 		if h.Metrics != nil {
 			h.Metrics.ActiveConnections.Dec()
 			h.Metrics.ErrorsTotal.WithLabelValues("accept_failure").Inc()
 		}
-		slog.Error("failed to accept client WebSocket", "error", err)
+		slog.Error("failed to accept client WebSocket", "conn_id", connID, "error", err)
 		return
 	}
-	clientConn.SetReadLimit(cfg.Bridge.MaxMessageSize)
+	// The read limit applies to the decompressed payload, so permessage-deflate
+	// cannot be used to slip a larger message past max_message_size.
+	clientConn.SetReadLimit(cfg.Bridge.UpstreamMessageLimit())
+
+	// A client that offers no subprotocol gets bridge.default_subprotocol on
+	// the gateway side only: RFC 6455 clients fail a handshake that names a
+	// subprotocol they did not offer, so it is not echoed back.
+	subprotocol := clientConn.Subprotocol()
+	gatewayOffer := subprotocols
+	if len(subprotocols) == 0 && cfg.Bridge.DefaultSubprotocol != "" {
+		subprotocol = cfg.Bridge.DefaultSubprotocol
+		gatewayOffer = []string{subprotocol}
+		slog.Debug("client offered no subprotocol, using default", "conn_id", connID, "subprotocol", subprotocol)
+	}
 
 	// 7. Dial Gateway with Origin header and matching subprotocols
 	// Use ShutdownCtx (not r.Context()) as the parent: when ServeHTTP returns,
@@ -256,27 +600,78 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	dialCtx, dialCancel := context.WithTimeout(h.ShutdownCtx, cfg.Bridge.DialTimeout)
 	defer dialCancel()
 
-	gatewayURL := httpToWS(cfg.Bridge.GatewayURL)
-	gatewayConn, _, err := websocket.Dial(dialCtx, gatewayURL, &websocket.DialOptions{
-		HTTPHeader:   http.Header{"Origin": {cfg.Bridge.Origin}},
-		Subprotocols: subprotocols,
-	})
+	gatewayURL := httpToWS(config.RedactURL(cfg.Bridge.GatewayURL))
+	var gatewayConn *websocket.Conn
+	var gatewaySrc messageSource
+	var pooled *pooledConn
+	// Pooled connections were dialed before the client was known, so they
+	// can't carry bridge.client_ip_header; dial fresh when it is set.
+	if resumed == nil && h.gatewayPool != nil && cfg.Bridge.ClientIPHeader == "" {
+		pooled = h.gatewayPool.get(dialCtx, r.URL.Path, cfg.Bridge.Origin.For(r.URL.Path), subprotocol, cfg.Bridge.GatewaySubprotocols)
+	}
+	if resumed != nil {
+		pooled = resumed.pc
+		gatewayConn, gatewaySrc = pooled.conn, &replaySource{buf: resumed.buf, src: pooled}
+		slog.Info("resumed parked gateway session", "conn_id", connID, "request_id", reqID, "replayed", len(resumed.buf))
+	} else if pooled != nil {
+		gatewayConn, gatewaySrc = pooled.conn, pooled
+		slog.Debug("adopted pooled gateway connection", "conn_id", connID, "request_id", reqID, "pool_request_id", pooled.reqID, "idle_for", time.Since(pooled.dialedAt).String())
+	} else {
+		// Only a real dial takes one of the bridge.max_concurrent_dials
+		// slots, waiting within dial_timeout so a reconnect storm is
+		// smoothed out; resumed and pooled sessions skip it.
+		releaseDial, slotErr := h.acquireDialSlot(dialCtx)
+		if slotErr != nil {
+			releaseConnection()
+			if h.Metrics != nil {
+				h.Metrics.ActiveConnections.Dec()
+				h.Metrics.ErrorsTotal.WithLabelValues("dial_capacity").Inc()
+			}
+			slog.Warn("closing connection: gateway dial capacity reached", "conn_id", connID, "client_ip", clientIP, "max_concurrent_dials", cfg.Bridge.MaxConcurrentDials)
+			clientConn.Close(websocket.StatusTryAgainLater, "gateway dial capacity reached")
+			return
+		}
+		gatewayConn, err = dialGateway(dialCtx, cfg, h.resolver.client(), r.URL.Path, reqID, forwardedIP, gatewayOffer)
+		releaseDial()
+		gatewaySrc = gatewayConn
+		if err == nil && h.Metrics != nil {
+			h.Metrics.GatewaySeen()
+		}
+	}
 	if err != nil {
-		slog.Error("failed to dial gateway", "url", gatewayURL, "error", err)
-		clientConn.Close(websocket.StatusBadGateway, "gateway unreachable")
-		h.Proxy.DecrementConnections(clientIP)
+		reason, errType := "gateway unreachable", "dial_failure"
+		var noUpgrade *gatewayNoUpgradeError
+		if errors.As(err, &noUpgrade) {
+			reason, errType = "gateway refused websocket upgrade", "gateway_no_upgrade"
+			slog.Error("gateway did not upgrade to WebSocket", "conn_id", connID, "request_id", reqID, "url", gatewayURL, "status", noUpgrade.StatusCode, "error", err)
+		} else {
+			slog.Error("failed to dial gateway", "conn_id", connID, "request_id", reqID, "url", gatewayURL, "error", err)
+		}
+		releaseConnection()
 		if h.Metrics != nil {
 			h.Metrics.ActiveConnections.Dec()
-			h.Metrics.ErrorsTotal.WithLabelValues("dial_failure").Inc()
+			h.Metrics.ErrorsTotal.WithLabelValues(errType).Inc()
 		}
+		clientConn.Close(websocket.StatusBadGateway, reason)
 		return
 	}
-	gatewayConn.SetReadLimit(cfg.Bridge.MaxMessageSize)
+	gatewayConn.SetReadLimit(cfg.Bridge.DownstreamMessageLimit())
+	if resumeTok != "" && pooled == nil {
+		// Read through a pump so the gateway connection survives the client
+		// dropping and can be parked for resume.
+		pooled = newPooledConn(gatewayConn, r.URL.Path, cfg.Bridge.Origin.For(r.URL.Path), reqID)
+		gatewaySrc = pooled
+	}
+	if sp := gatewayConn.Subprotocol(); sp != subprotocol {
+		// The gateway chose one of bridge.gateway_subprotocols. The client
+		// keeps the subprotocol it negotiated with the bridge.
+		slog.Debug("gateway selected bridge subprotocol", "conn_id", connID, "gateway_subprotocol", sp, "client_subprotocol", clientConn.Subprotocol())
+	}
 
 	// Replay canvas state for reconnecting clients (before forwarding starts)
 	if h.CanvasTracker != nil {
 		if err := h.CanvasTracker.ReplayMessages(dialCtx, clientConn); err != nil {
-			slog.Warn("canvas replay failed", "client_ip", clientIP, "error", err)
+			slog.Warn("canvas replay failed", "conn_id", connID, "client_ip", clientIP, "error", err)
 			// Non-fatal: continue with normal forwarding
 		}
 	}
@@ -284,10 +679,23 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Build inspector chains for each direction.
 	var upstream, downstream []MessageInspector
 
+	// External transform: runs next to the gateway in both directions, so it
+	// sees gateway messages before and client messages after everything else.
+	if h.TransformInspector != nil {
+		downstream = append(downstream, h.TransformInspector)
+	}
+
 	// Media injection: gateway→client text messages on matching paths.
 	injectMedia := cfg.Bridge.Media.Enabled && h.MediaInjector != nil && h.shouldInjectMedia(r.URL.Path)
 	if injectMedia {
-		downstream = append(downstream, &mediaInspectorAdapter{h.MediaInjector})
+		downstream = append(downstream, &mediaInspectorAdapter{
+			injector: h.MediaInjector,
+			paused:   &h.mediaPaused,
+			caps:     media.ParseClientCapabilities(r.Header.Get(media.ClientSupportsHeader)),
+		})
+		// Final chat messages on the inject path may carry embedded images;
+		// allow headroom so one large message doesn't tear down the session.
+		gatewayConn.SetReadLimit(injectReadLimit(cfg))
 	}
 
 	// Canvas inspector: gateway→client text messages.
@@ -297,6 +705,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		downstream = append(downstream, &canvasInspectorAdapter{
 			tracker: h.CanvasTracker,
 			a2uiURL: a2uiURL,
+			conn:    clientConn,
 		})
 	}
 
@@ -310,29 +719,86 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		upstream = append(upstream, h.ReactionInspector)
 	}
 
+	// Per-method request counter: client→gateway text messages.
+	if h.MethodInspector != nil {
+		upstream = append(upstream, h.MethodInspector)
+	}
+
 	// Sync inspectors: cross-device message sync.
 	var syncUpstream *SyncUpstreamInspector
 	if h.SyncStore != nil && h.SyncRegistry != nil {
 		clientID := fmt.Sprintf("c-%d", time.Now().UnixNano())
 		syncUpstream = NewSyncUpstreamInspector(h.ShutdownCtx, clientConn, h.SyncStore, h.SyncRegistry, clientID)
+		maxHistoryBytes := cfg.Bridge.Sync.MaxHistoryResponseSize
+		if maxHistoryBytes == 0 {
+			maxHistoryBytes = cfg.Bridge.DownstreamMessageLimit()
+		}
+		syncUpstream.SetMaxResponseBytes(maxHistoryBytes)
 		upstream = append(upstream, syncUpstream)
 		downstream = append(downstream, NewSyncDownstreamInspector(h.SyncStore, syncUpstream.SessionKey))
 	}
 
-	logAttrs := []any{"client_ip", clientIP, "gateway", gatewayURL, "path", r.URL.Path, "injectMedia", injectMedia}
+	// History routing: sessions.history requests the sync inspector didn't
+	// answer go to the secondary gateway.
+	var historyRouter *HistoryRouter
+	if cfg.Bridge.HistoryGatewayURL != "" {
+		var historySubprotocols []string
+		if subprotocol != "" {
+			historySubprotocols = []string{subprotocol}
+		}
+		path := r.URL.Path
+		historyRouter = NewHistoryRouter(h.ShutdownCtx, clientConn, connID, cfg.Bridge.WriteTimeoutFor(path), func(ctx context.Context) (*websocket.Conn, error) {
+			dialCtx, cancel := context.WithTimeout(ctx, cfg.Bridge.DialTimeout)
+			defer cancel()
+			release, err := h.acquireDialSlot(dialCtx)
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+			conn, err := dialGatewayURL(dialCtx, cfg, h.resolver.client(), cfg.Bridge.HistoryGatewayURL, path, reqID, forwardedIP, historySubprotocols)
+			if err != nil {
+				return nil, err
+			}
+			conn.SetReadLimit(cfg.Bridge.DownstreamMessageLimit())
+			return conn, nil
+		})
+		upstream = append(upstream, historyRouter)
+	}
+	if h.TransformInspector != nil {
+		upstream = append(upstream, h.TransformInspector)
+	}
+
+	logAttrs := []any{
+		"conn_id", connID,
+		"request_id", reqID,
+		"client_ip", clientIP,
+		"gateway", gatewayURL,
+		"path", r.URL.Path,
+		"subprotocol", clientConn.Subprotocol(),
+		"injectMedia", injectMedia,
+	}
+	if pooled != nil {
+		logAttrs = append(logAttrs, "pooled", true)
+	}
+	if certID != "" {
+		logAttrs = append(logAttrs, "client_cert", certID)
+	}
+	if historyRouter != nil {
+		logAttrs = append(logAttrs, "history_gateway", httpToWS(config.RedactURL(cfg.Bridge.HistoryGatewayURL)))
+	}
 	if a2uiURL != "" {
 		logAttrs = append(logAttrs, "a2ui_url", a2uiURL)
 	}
 	slog.Info("connection established", logAttrs...)
 
 	if cfg.Bridge.Media.Enabled && !injectMedia {
-		slog.Debug("media: injection skipped, path not in inject_paths", "path", r.URL.Path, "inject_paths", cfg.Bridge.Media.InjectPaths)
+		slog.Debug("media: injection skipped, path not in inject_paths", "conn_id", connID, "path", r.URL.Path, "inject_paths", cfg.Bridge.Media.InjectPaths)
 	}
 
 	// 8. Bidirectional forwarding with coordinated shutdown
 	// When either direction finishes, cancel context to tear down the other side.
 	// context.CancelFunc is safe to call multiple times.
-	proxyCtx, proxyCancel := context.WithCancel(h.ShutdownCtx)
+	proxyCtx, proxyCancel := context.WithCancel(withWriteTimeout(h.ShutdownCtx, cfg.Bridge.WriteTimeoutFor(r.URL.Path)))
 
 	// Start keepalive pings to detect dead connections.
 	// Ping must run concurrently with Reader per coder/websocket docs.
@@ -348,7 +814,23 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	closeClient := func(code websocket.StatusCode, reason string) {
 		closeClientOnce.Do(func() { clientConn.Close(code, reason) })
 	}
-	closeGateway := func() { closeGatewayOnce.Do(func() { gatewayConn.CloseNow() }) }
+	closeGateway := func() {
+		closeGatewayOnce.Do(func() {
+			if pooled != nil {
+				pooled.close()
+			} else {
+				gatewayConn.CloseNow()
+			}
+		})
+	}
+
+	h.trackConn(&activeConn{
+		id:          connID,
+		ip:          clientIP,
+		path:        r.URL.Path,
+		connectedAt: time.Now(),
+		close:       closeClient,
+	})
 
 	// Drain watcher: when the server starts draining, send a graceful close
 	// frame to the client. This causes Reader() in the forwarding goroutines
@@ -368,17 +850,61 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		msgLimiter = rate.NewLimiter(rate.Limit(cfg.Security.RateLimit.MessagesPerSecond), cfg.Security.RateLimit.MessagesPerSecond)
 	}
 
+	// clientDropped records that the client went away without a normal
+	// closure, seen by whichever direction stopped first, so the gateway
+	// session may be parked for resume. With a resume token, gateway→client
+	// messages pass through replay so one whose write failed isn't lost.
+	var clientDropped atomic.Bool
+	var sendBuf *sendBuffer
+	var replay *replaySink
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
 		defer proxyCancel()
-		h.forwardMessages(proxyCtx, clientConn, gatewayConn, "client→gateway", msgLimiter, upstream)
+		err := h.forwardMessages(proxyCtx, clientConn, gatewayConn, "client→gateway", connID, msgLimiter, upstream)
+		if proxyCtx.Err() == nil && websocket.CloseStatus(err) != websocket.StatusNormalClosure {
+			clientDropped.Store(true)
+		}
 	}()
 	go func() {
 		defer wg.Done()
 		defer proxyCancel()
-		h.forwardMessages(proxyCtx, gatewayConn, clientConn, "gateway→client", nil, downstream)
+		var sink messageSink = clientConn
+		if hw := cfg.Bridge.ClientBufferHighWater; hw > 0 {
+			sendBuf = newSendBuffer(proxyCtx, clientConn, hw, writeTimeoutFrom(proxyCtx, cfg.Bridge.WriteTimeout))
+			sink = sendBuf
+		}
+		if resumeTok != "" {
+			replay = &replaySink{dst: sink}
+			sink = replay
+		}
+		err := h.forwardMessages(proxyCtx, gatewaySrc, sink, "gateway→client", connID, nil, downstream)
+		if proxyCtx.Err() == nil && replay != nil && replay.err != nil && !errors.Is(replay.err, errSlowConsumer) {
+			clientDropped.Store(true) // the client went away mid-write
+		}
+		if errors.Is(err, errSlowConsumer) {
+			slog.Warn("closing slow consumer", "conn_id", connID, "client_ip", clientIP, "client_buffer_high_water", cfg.Bridge.ClientBufferHighWater)
+			if h.Metrics != nil {
+				h.Metrics.SlowConsumersShed.Inc()
+			}
+			// Tear down first: a client that isn't reading won't take the
+			// close frame, and the send buffer's pending write must not
+			// hold the connection open until write_timeout.
+			proxyCancel()
+			closeClient(websocket.StatusPolicyViolation, "slow consumer")
+			return
+		}
+		if sendBuf != nil {
+			sendBuf.close() // deliver what the gateway sent before it stopped
+		}
+		// Pass the gateway's close code and reason on to the client, remapped
+		// per bridge.close_code_map.
+		if code := websocket.CloseStatus(err); code != -1 {
+			var ce websocket.CloseError
+			errors.As(err, &ce)
+			closeClient(clientCloseCode(cfg, code), ce.Reason)
+		}
 	}()
 
 	// Cleanup: wait for both to finish, then close connections
@@ -386,40 +912,66 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		wg.Wait()
 		closeClient(websocket.StatusGoingAway, "")
-		closeGateway()
+		if resumeTok != "" && clientDropped.Load() && pooled.alive() && h.acceptingResumes() {
+			closeGatewayOnce.Do(func() {}) // the parked session owns the gateway connection now
+			var unsent []pumpedMessage
+			if sendBuf != nil {
+				unsent = append(unsent, sendBuf.unsent()...)
+			}
+			if replay.unsent != nil {
+				unsent = append(unsent, *replay.unsent)
+			}
+			h.resumes.park(resumeTok, resumeOwner(token, clientIP), pooled, unsent, cfg.Bridge.Resume.Window, cfg.Bridge.Resume.MaxBufferBytes, cfg.Security.MaxConnections)
+			slog.Info("client dropped, parked gateway session for resume", "conn_id", connID, "client_ip", clientIP, "window", cfg.Bridge.Resume.Window.String(), "undelivered", len(unsent))
+			if !h.acceptingResumes() {
+				// Drain or maintenance began while parking; don't leave it behind.
+				h.resumes.closeAll("server shutting down")
+			}
+		} else {
+			closeGateway()
+		}
 		if syncUpstream != nil {
 			syncUpstream.Cleanup()
 		}
-		h.Proxy.DecrementConnections(clientIP)
+		if historyRouter != nil {
+			historyRouter.Close()
+		}
+		h.untrackConn(connID)
+		releaseConnection()
 		if h.Metrics != nil {
 			h.Metrics.ActiveConnections.Dec()
 		}
-		slog.Info("connection closed", "client_ip", clientIP, "duration", time.Since(start).String())
+		slog.Info("connection closed", "conn_id", connID, "client_ip", clientIP, "duration", time.Since(start).String())
 	}()
 }
 
 // forwardMessages reads from src and writes to dst until the context is
-// cancelled or either side closes. This is the core proxy loop.
-// direction is "client→gateway" or "gateway→client" for logging.
+// cancelled or either side closes, and returns the error that stopped it.
+// This is the core proxy loop.
+// direction is "client→gateway" or "gateway→client" for logging; connID ties
+// the log lines to the connection's established/closed lines. The per-message
+// debug line is sampled per logging.debug_sample_rate; the lines logged when
+// forwarding stops are not.
 // msgLimiter is optional; if non-nil, messages are rate-limited.
 // inspectors is optional; if non-empty, text messages are read into memory
 // and passed through each inspector. Otherwise messages stream via io.Copy.
-func (h *Handler) forwardMessages(ctx context.Context, src, dst *websocket.Conn, direction string, msgLimiter *rate.Limiter, inspectors []MessageInspector) {
+func (h *Handler) forwardMessages(ctx context.Context, src messageSource, dst messageSink, direction, connID string, msgLimiter *rate.Limiter, inspectors []MessageInspector) error {
 	cfg := h.GetConfig()
+	writeTimeout := writeTimeoutFrom(ctx, cfg.Bridge.WriteTimeout)
 	for {
 		// Wait for the next message using only the proxy context (no timeout).
 		// Keepalive pings detect dead connections and cancel ctx via proxyCancel.
 		// A ReadTimeout here would kill idle-but-alive long-lived connections.
 		msgType, reader, err := src.Reader(ctx)
 		if err != nil {
-			slog.Debug("forward stopped", "direction", direction, "reason", err)
-			return
+			h.logForwardStop(ctx, connID, direction, "forward stopped", err)
+			return err
 		}
 
 		if msgLimiter != nil {
 			if err := msgLimiter.Wait(ctx); err != nil {
-				slog.Debug("message rate limit", "direction", direction, "reason", err)
-				return
+				slog.Debug("message rate limit", "conn_id", connID, "direction", direction, "reason", err)
+				return err
 			}
 		}
 
@@ -428,8 +980,8 @@ func (h *Handler) forwardMessages(ctx context.Context, src, dst *websocket.Conn,
 		if len(inspectors) > 0 && msgType == websocket.MessageText {
 			payload, err := io.ReadAll(reader)
 			if err != nil {
-				slog.Debug("read failed", "direction", direction, "reason", err)
-				return
+				h.logForwardStop(ctx, connID, direction, "read failed", err)
+				return err
 			}
 
 			for _, insp := range inspectors {
@@ -442,44 +994,47 @@ func (h *Handler) forwardMessages(ctx context.Context, src, dst *websocket.Conn,
 				continue // Inspector handled this message (e.g. sync history response)
 			}
 
-			writeCtx, writeCancel := context.WithTimeout(ctx, cfg.Bridge.WriteTimeout)
+			writeCtx, writeCancel := context.WithTimeout(ctx, writeTimeout)
 			writer, err := dst.Writer(writeCtx, msgType)
 			if err != nil {
 				writeCancel()
-				slog.Debug("write failed", "direction", direction, "reason", err)
-				return
+				h.logForwardStop(ctx, connID, direction, "write failed", err)
+				return err
 			}
 			if _, err := writer.Write(payload); err != nil {
 				writeCancel()
-				slog.Debug("write failed", "direction", direction, "reason", err)
-				return
+				h.logForwardStop(ctx, connID, direction, "write failed", err)
+				return err
 			}
 			if err := writer.Close(); err != nil {
 				writeCancel()
-				slog.Debug("flush failed", "direction", direction, "reason", err)
-				return
+				h.logForwardStop(ctx, connID, direction, "flush failed", err)
+				return err
 			}
 			writeCancel()
+			h.logForwarded(ctx, cfg, connID, direction, msgType, int64(len(payload)))
 		} else {
 			// Streaming pass-through path (zero overhead)
-			writeCtx, writeCancel := context.WithTimeout(ctx, cfg.Bridge.WriteTimeout)
+			writeCtx, writeCancel := context.WithTimeout(ctx, writeTimeout)
 			writer, err := dst.Writer(writeCtx, msgType)
 			if err != nil {
 				writeCancel()
-				slog.Debug("write failed", "direction", direction, "reason", err)
-				return
+				h.logForwardStop(ctx, connID, direction, "write failed", err)
+				return err
 			}
-			if _, err := io.Copy(writer, reader); err != nil {
+			n, err := io.Copy(writer, reader)
+			if err != nil {
 				writeCancel()
-				slog.Debug("copy failed", "direction", direction, "reason", err)
-				return
+				h.logForwardStop(ctx, connID, direction, "copy failed", err)
+				return err
 			}
 			if err := writer.Close(); err != nil {
 				writeCancel()
-				slog.Debug("flush failed", "direction", direction, "reason", err)
-				return
+				h.logForwardStop(ctx, connID, direction, "flush failed", err)
+				return err
 			}
 			writeCancel()
+			h.logForwarded(ctx, cfg, connID, direction, msgType, n)
 		}
 
 		h.Proxy.IncrementMessages()
@@ -493,8 +1048,327 @@ func (h *Handler) forwardMessages(ctx context.Context, src, dst *websocket.Conn,
 	}
 }
 
+// clientCloseCode returns the close code sent to the client when the gateway
+// closes with code. Codes that can't appear in a close frame (e.g. 1005 for
+// a close without a status) become StatusGoingAway.
+func clientCloseCode(cfg *config.Config, code websocket.StatusCode) websocket.StatusCode {
+	mapped := cfg.Bridge.CloseCodeFor(int(code))
+	if !config.ValidCloseCode(mapped) {
+		return websocket.StatusGoingAway
+	}
+	return websocket.StatusCode(mapped)
+}
+
+// logForwarded emits the per-message debug line for 1 in every
+// logging.debug_sample_rate forwarded messages, counted across connections.
+func (h *Handler) logForwarded(ctx context.Context, cfg *config.Config, connID, direction string, msgType websocket.MessageType, size int64) {
+	if !slog.Default().Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	if rate := uint64(cfg.Logging.DebugSampleRate); rate > 1 && h.debugSeq.Add(1)%rate != 0 {
+		return
+	}
+	slog.Debug("message forwarded", "conn_id", connID, "direction", direction,
+		"type", msgType.String(), "bytes", size, "sample_rate", cfg.Logging.DebugSampleRate)
+}
+
+// tokenHash returns a short, non-reversible key for per-token tracking so raw
+// tokens never appear in memory maps or logs.
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// newConnID returns a short random identifier for a proxied connection.
+func newConnID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%016x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// clientIPHeaderValue returns the bridge.client_ip_header value for a request
+// from clientIP. X-Forwarded-For gets clientIP appended to any list the client
+// sent, as proxies do; any other header carries clientIP alone, replacing a
+// client-supplied value.
+func clientIPHeaderValue(r *http.Request, header, clientIP string) string {
+	if http.CanonicalHeaderKey(header) == "X-Forwarded-For" {
+		if prior := r.Header.Values(header); len(prior) > 0 {
+			return strings.Join(prior, ", ") + ", " + clientIP
+		}
+	}
+	return clientIP
+}
+
+// requestID returns the correlation ID from the given header, generating one
+// if the client didn't send it.
+func requestID(r *http.Request, header string) string {
+	if id := r.Header.Get(header); id != "" {
+		return id
+	}
+	return newConnID()
+}
+
+// acquireDialSlot waits for one of the bridge.max_concurrent_dials slots and
+// returns a func that frees it; calling that func more than once is safe. It
+// fails once ctx is done. Without a limit it returns immediately.
+func (h *Handler) acquireDialSlot(ctx context.Context) (func(), error) {
+	if h.dialSlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case h.dialSlots <- struct{}{}:
+		return sync.OnceFunc(func() { <-h.dialSlots }), nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a gateway dial slot: %w", ctx.Err())
+	}
+}
+
+// dialGateway opens a WebSocket connection to the configured gateway with the
+// Origin header for the request path injected and the given subprotocols offered.
+// reqID, if non-empty, is sent in the configured request ID header, and
+// clientIP in bridge.client_ip_header. client, if non-nil, makes the dial
+// (see gatewayResolver); nil uses the default client.
+func dialGateway(ctx context.Context, cfg *config.Config, client *http.Client, path, reqID, clientIP string, subprotocols []string) (*websocket.Conn, error) {
+	return dialGatewayURL(ctx, cfg, client, cfg.Bridge.GatewayURL, path, reqID, clientIP, subprotocols)
+}
+
+// dialGatewayURL is dialGateway for an explicit gateway URL, such as
+// bridge.history_gateway_url.
+func dialGatewayURL(ctx context.Context, cfg *config.Config, client *http.Client, gatewayURL, path, reqID, clientIP string, subprotocols []string) (*websocket.Conn, error) {
+	header := http.Header{"Origin": {cfg.Bridge.Origin.For(path)}}
+	if cfg.Bridge.RequestIDHeader != "" && reqID != "" {
+		header.Set(cfg.Bridge.RequestIDHeader, reqID)
+	}
+	if cfg.Bridge.ClientIPHeader != "" && clientIP != "" {
+		header.Set(cfg.Bridge.ClientIPHeader, clientIP)
+	}
+	gatewayURL, auth := stripUserinfo(gatewayURL)
+	if auth != "" {
+		header.Set("Authorization", auth)
+	}
+	conn, resp, err := websocket.Dial(ctx, httpToWS(gatewayURL), &websocket.DialOptions{
+		HTTPClient:   client,
+		HTTPHeader:   header,
+		Subprotocols: gatewaySubprotocols(cfg, subprotocols),
+		// The gateway leg never negotiates extensions, so nothing outside
+		// bridge.allowed_extensions can reach the gateway.
+		CompressionMode: websocket.CompressionDisabled,
+	})
+	if err != nil && resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, &gatewayNoUpgradeError{StatusCode: resp.StatusCode, err: err}
+	}
+	return conn, err
+}
+
+// stripUserinfo removes user:password@ from a gateway URL and returns the
+// URL along with the Basic Authorization header value built from the
+// credentials ("" when there are none). The credentials then travel only in
+// the header, never in the request URL or log lines built from it.
+func stripUserinfo(gatewayURL string) (string, string) {
+	u, err := url.Parse(gatewayURL)
+	if err != nil || u.User == nil {
+		return gatewayURL, ""
+	}
+	username := u.User.Username()
+	password, _ := u.User.Password()
+	u.User = nil
+	return u.String(), "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// gatewaySubprotocols returns the subprotocols offered to the gateway: the
+// client's, in its order of preference, followed by any
+// bridge.gateway_subprotocols it didn't already offer.
+func gatewaySubprotocols(cfg *config.Config, client []string) []string {
+	if len(cfg.Bridge.GatewaySubprotocols) == 0 {
+		return client
+	}
+	offered := slices.Clone(client)
+	for _, sp := range cfg.Bridge.GatewaySubprotocols {
+		if !slices.Contains(offered, sp) {
+			offered = append(offered, sp)
+		}
+	}
+	return offered
+}
+
+// gatewayNoUpgradeError reports that the gateway answered the WebSocket
+// handshake with a plain HTTP response instead of 101 Switching Protocols,
+// e.g. a misrouted gateway_url or an HTTP-only upstream.
+type gatewayNoUpgradeError struct {
+	StatusCode int
+	err        error
+}
+
+func (e *gatewayNoUpgradeError) Error() string {
+	return fmt.Sprintf("gateway did not upgrade to WebSocket (HTTP %d): %v", e.StatusCode, e.err)
+}
+
+func (e *gatewayNoUpgradeError) Unwrap() error { return e.err }
+
+// logMessageTooBig logs and counts reads that failed because a message
+// exceeded the read limit. coder/websocket closes the connection with
+// StatusMessageTooBig in this case, so it is surfaced distinctly from
+// ordinary disconnects.
+func (h *Handler) logMessageTooBig(connID, direction string, err error) {
+	if !errors.Is(err, websocket.ErrMessageTooBig) {
+		return
+	}
+	limit := h.GetConfig().Bridge.DownstreamMessageLimit()
+	if direction == "client→gateway" {
+		limit = h.GetConfig().Bridge.UpstreamMessageLimit()
+	}
+	slog.Warn("message exceeded read limit, closing connection",
+		"conn_id", connID,
+		"direction", direction,
+		"max_message_size", limit,
+		"error", err,
+	)
+	if h.Metrics != nil {
+		h.Metrics.ErrorsTotal.WithLabelValues("message_too_big").Inc()
+	}
+}
+
+// logForwardStop logs why forwarding in one direction stopped. Teardown — the
+// proxy context cancelled, the bridge draining, or the peer sending a close
+// frame — is expected and logged at debug, as is shedding a slow consumer,
+// which the caller reports. Anything else is a real network error, logged at
+// warn and counted as forward_error. That includes a closed connection
+// outside teardown, which is how a write timeout on the opposite direction
+// shows up.
+func (h *Handler) logForwardStop(ctx context.Context, connID, direction, msg string, err error) {
+	switch {
+	case errors.Is(err, websocket.ErrMessageTooBig):
+		h.logMessageTooBig(connID, direction, err)
+	case ctx.Err() != nil, h.drainCtx.Err() != nil, websocket.CloseStatus(err) != -1, errors.Is(err, errSlowConsumer):
+		slog.Debug(msg, "conn_id", connID, "direction", direction, "reason", err)
+	default:
+		slog.Warn(msg, "conn_id", connID, "direction", direction, "error", err)
+		if h.Metrics != nil {
+			h.Metrics.ErrorsTotal.WithLabelValues("forward_error").Inc()
+		}
+	}
+}
+
+// proxyError answers a failed HTTP proxy request with a 502. If
+// bridge.bad_gateway_page is set the file is served as the body (read on each
+// error so edits apply without a reload); otherwise, or if it can't be read,
+// a plain "Bad Gateway" is returned.
+func (h *Handler) proxyError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.Error("HTTP proxy error", "url", r.URL.Path, "error", err)
+
+	if page := h.GetConfig().Bridge.BadGatewayPage; page != "" {
+		body, readErr := os.ReadFile(page)
+		if readErr == nil {
+			ct := mime.TypeByExtension(filepath.Ext(page))
+			if ct == "" {
+				ct = http.DetectContentType(body)
+			}
+			w.Header().Set("Content-Type", ct)
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write(body)
+			return
+		}
+		slog.Warn("failed to read bad_gateway_page, using plain text", "path", page, "error", readErr)
+	}
+	http.Error(w, "Bad Gateway", http.StatusBadGateway)
+}
+
+// stripResponseHeaders removes the bridge.strip_response_headers entries
+// (e.g. Server, X-Powered-By) from HTTP proxy responses before they reach
+// the client.
+func (h *Handler) stripResponseHeaders(resp *http.Response) error {
+	for _, name := range h.GetConfig().Bridge.StripResponseHeaders {
+		resp.Header.Del(name)
+	}
+	return nil
+}
+
+// filterExtensions removes every offer in r's Sec-WebSocket-Extensions header
+// whose extension isn't in allowed (case-insensitive), before the handshake
+// negotiates them, and returns the names of the dropped offers. Parameters of
+// kept offers are preserved. Extensions only ever apply to the client leg: the
+// gateway dial builds its own handshake and never carries the client's offers.
+func filterExtensions(r *http.Request, allowed []string) []string {
+	values := r.Header.Values("Sec-WebSocket-Extensions")
+	if len(values) == 0 {
+		return nil
+	}
+	var kept, dropped []string
+	for _, v := range values {
+		for _, offer := range strings.Split(v, ",") {
+			offer = strings.TrimSpace(offer)
+			if offer == "" {
+				continue
+			}
+			name, _, _ := strings.Cut(offer, ";")
+			name = strings.TrimSpace(name)
+			if slices.ContainsFunc(allowed, func(a string) bool { return strings.EqualFold(a, name) }) {
+				kept = append(kept, offer)
+			} else {
+				dropped = append(dropped, name)
+			}
+		}
+	}
+	if len(kept) == 0 {
+		r.Header.Del("Sec-WebSocket-Extensions")
+	} else {
+		r.Header.Set("Sec-WebSocket-Extensions", strings.Join(kept, ", "))
+	}
+	return dropped
+}
+
+// compressionMode maps a bridge.compression.mode value to the websocket
+// library's permessage-deflate mode. Unknown values disable compression.
+func compressionMode(mode string) websocket.CompressionMode {
+	switch mode {
+	case "context_takeover":
+		return websocket.CompressionContextTakeover
+	case "no_context_takeover":
+		return websocket.CompressionNoContextTakeover
+	default:
+		return websocket.CompressionDisabled
+	}
+}
+
+// writeTimeoutKey carries a connection's effective write timeout, chosen from
+// bridge.write_timeout_by_path at upgrade time, in its proxy context.
+type writeTimeoutKey struct{}
+
+// withWriteTimeout returns a copy of ctx carrying the write timeout d.
+func withWriteTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, writeTimeoutKey{}, d)
+}
+
+// writeTimeoutFrom returns the write timeout stored in ctx, or fallback if
+// none was set.
+func writeTimeoutFrom(ctx context.Context, fallback time.Duration) time.Duration {
+	if d, ok := ctx.Value(writeTimeoutKey{}).(time.Duration); ok && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// injectReadLimit returns the gateway read limit for connections on the media
+// inject path: the downstream message limit plus room for one base64-encoded
+// media file and envelope overhead, capped at the 64MB max_message_size ceiling.
+func injectReadLimit(cfg *config.Config) int64 {
+	const envelopeOverhead = 65536
+	limit := cfg.Bridge.DownstreamMessageLimit() + (cfg.Bridge.Media.MaxFileSize*4+2)/3 + envelopeOverhead
+	if limit > 67108864 {
+		limit = 67108864
+	}
+	return limit
+}
+
 // keepAlive sends periodic WebSocket pings to detect dead connections.
 // If a ping fails or times out, it sends a close frame and cancels the proxy context.
+//
+// The ping payload cannot be configured: coder/websocket's Conn.Ping always
+// sends its own per-connection counter as the payload and uses it to match
+// the pong, and exposes no way to write a ping frame with a custom payload.
 func (h *Handler) keepAlive(ctx context.Context, conn *websocket.Conn, interval, pongTimeout time.Duration, onFail context.CancelFunc) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
@@ -516,6 +1390,26 @@ func (h *Handler) keepAlive(ctx context.Context, conn *websocket.Conn, interval,
 	}
 }
 
+// negotiatedSubprotocol returns the subprotocol websocket.Accept will pick
+// when offered the given subprotocols: the first offered one the client listed
+// in Sec-WebSocket-Protocol, or "" if none.
+func negotiatedSubprotocol(r *http.Request, offered []string) string {
+	var requested []string
+	for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, t := range strings.Split(v, ",") {
+			requested = append(requested, strings.TrimSpace(t))
+		}
+	}
+	for _, sp := range offered {
+		for _, rp := range requested {
+			if strings.EqualFold(sp, rp) {
+				return rp
+			}
+		}
+	}
+	return ""
+}
+
 // isWebSocketUpgrade returns true if the request is a WebSocket upgrade per RFC 6455 §4.1.
 func isWebSocketUpgrade(r *http.Request) bool {
 	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
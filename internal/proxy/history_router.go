@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// HistoryRouter intercepts client->gateway sessions.history requests and sends
+// them to a secondary gateway (bridge.history_gateway_url) instead of the
+// primary. The secondary connection is dialed on the first history request;
+// the client's connect request, if one was seen, is replayed first so the
+// secondary has the same session. Only responses are relayed back to the
+// client (minus the reply to the replayed connect), so events pushed by the
+// secondary don't duplicate the primary's. If the secondary can't be reached
+// the request is forwarded to the primary as usual, and the secondary isn't
+// dialed again until a backoff (doubling per failure) has passed.
+type HistoryRouter struct {
+	ctx          context.Context
+	clientConn   *websocket.Conn
+	connID       string
+	writeTimeout time.Duration
+	dial         func(ctx context.Context) (*websocket.Conn, error)
+
+	mu        sync.Mutex
+	connect   []byte // client's connect request, replayed to the secondary
+	connectID string
+	conn      *websocket.Conn
+	closed    bool
+	retryAt   time.Time     // no redial before this after a failed dial
+	backoff   time.Duration // delay applied after the next failed dial
+}
+
+const (
+	historyRetryMin = time.Second
+	historyRetryMax = 30 * time.Second
+)
+
+// NewHistoryRouter creates a history router for a single client connection.
+// dial opens a connection to the secondary gateway.
+func NewHistoryRouter(
+	ctx context.Context,
+	clientConn *websocket.Conn,
+	connID string,
+	writeTimeout time.Duration,
+	dial func(ctx context.Context) (*websocket.Conn, error),
+) *HistoryRouter {
+	return &HistoryRouter{
+		ctx:          ctx,
+		clientConn:   clientConn,
+		connID:       connID,
+		writeTimeout: writeTimeout,
+		dial:         dial,
+	}
+}
+
+func (hr *HistoryRouter) InspectMessage(payload []byte, msgType websocket.MessageType) []byte {
+	if msgType != websocket.MessageText {
+		return payload
+	}
+
+	var env struct {
+		Type   string `json:"type"`
+		Method string `json:"method,omitempty"`
+		ID     string `json:"id,omitempty"`
+	}
+	if err := json.Unmarshal(payload, &env); err != nil || env.Type != "req" {
+		return payload
+	}
+
+	switch env.Method {
+	case "connect":
+		hr.mu.Lock()
+		if hr.connect == nil {
+			hr.connect = bytes.Clone(payload)
+			hr.connectID = env.ID
+		}
+		hr.mu.Unlock()
+	case "sessions.history":
+		conn, err := hr.secondary()
+		if err != nil {
+			slog.Warn("history gateway unavailable, sending sessions.history to primary", "conn_id", hr.connID, "error", err)
+			return payload
+		}
+		if err := hr.write(conn, payload); err != nil {
+			slog.Warn("history gateway write failed, sending sessions.history to primary", "conn_id", hr.connID, "error", err)
+			hr.drop(conn)
+			return payload
+		}
+		slog.Debug("routed sessions.history to history gateway", "conn_id", hr.connID, "id", env.ID)
+		return nil // Suppress forwarding to the primary gateway
+	}
+	return payload
+}
+
+// Close closes the secondary connection, if any. Later history requests go
+// to the primary.
+func (hr *HistoryRouter) Close() {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	hr.closed = true
+	if hr.conn != nil {
+		hr.conn.CloseNow()
+		hr.conn = nil
+	}
+}
+
+// secondary returns the open secondary connection, dialing it (and replaying
+// the client's connect request) if needed. The dial, which may wait for a
+// dial slot, runs without hr.mu held so Close and the relay aren't blocked
+// behind it. It is only called from the forwarding goroutine.
+func (hr *HistoryRouter) secondary() (*websocket.Conn, error) {
+	hr.mu.Lock()
+	if hr.closed {
+		hr.mu.Unlock()
+		return nil, errors.New("connection closing")
+	}
+	if hr.conn != nil {
+		conn := hr.conn
+		hr.mu.Unlock()
+		return conn, nil
+	}
+	if wait := time.Until(hr.retryAt); wait > 0 {
+		hr.mu.Unlock()
+		return nil, fmt.Errorf("backing off after failed dial, retrying in %s", wait.Round(time.Millisecond))
+	}
+	connect, connectID := hr.connect, hr.connectID
+	hr.mu.Unlock()
+
+	conn, err := hr.dial(hr.ctx)
+	if err == nil && connect != nil {
+		if err = hr.write(conn, connect); err != nil {
+			conn.CloseNow()
+		}
+	}
+
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	if err != nil {
+		hr.backoff = min(max(hr.backoff*2, historyRetryMin), historyRetryMax)
+		hr.retryAt = time.Now().Add(hr.backoff)
+		return nil, err
+	}
+	if hr.closed {
+		conn.CloseNow()
+		return nil, errors.New("connection closing")
+	}
+	hr.backoff, hr.retryAt = 0, time.Time{}
+	hr.conn = conn
+	go hr.relay(conn, connectID)
+	return conn, nil
+}
+
+func (hr *HistoryRouter) write(conn *websocket.Conn, payload []byte) error {
+	ctx, cancel := context.WithTimeout(hr.ctx, hr.writeTimeout)
+	defer cancel()
+	return conn.Write(ctx, websocket.MessageText, payload)
+}
+
+// relay copies responses from the secondary to the client until the secondary
+// connection closes.
+func (hr *HistoryRouter) relay(conn *websocket.Conn, connectID string) {
+	defer hr.drop(conn)
+	for {
+		msgType, data, err := conn.Read(hr.ctx)
+		if err != nil {
+			slog.Debug("history gateway relay stopped", "conn_id", hr.connID, "reason", err)
+			return
+		}
+		if msgType != websocket.MessageText {
+			continue
+		}
+		var env struct {
+			Type string `json:"type"`
+			ID   string `json:"id,omitempty"`
+		}
+		if err := json.Unmarshal(data, &env); err != nil || env.Type != "res" {
+			continue
+		}
+		if connectID != "" && env.ID == connectID {
+			continue // reply to the replayed connect; the client already has the primary's
+		}
+
+		ctx, cancel := context.WithTimeout(hr.ctx, hr.writeTimeout)
+		err = hr.clientConn.Write(ctx, websocket.MessageText, data)
+		cancel()
+		if err != nil {
+			slog.Debug("history gateway relay stopped", "conn_id", hr.connID, "reason", err)
+			return
+		}
+	}
+}
+
+// drop closes conn and forgets it if it is still the current secondary, so
+// the next history request redials.
+func (hr *HistoryRouter) drop(conn *websocket.Conn) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	if hr.conn == conn {
+		hr.conn = nil
+	}
+	conn.CloseNow()
+}
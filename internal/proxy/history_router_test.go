@@ -0,0 +1,219 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// rpcGateway is a fake gateway that records the method of every request it
+// receives and answers each with {"type":"res","id":...,"payload":{"from":name}}.
+// It also pushes a tick event after each response.
+type rpcGateway struct {
+	*httptest.Server
+	mu      sync.Mutex
+	methods []string
+}
+
+func newRPCGateway(t *testing.T, name string) *rpcGateway {
+	t.Helper()
+	g := &rpcGateway{}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+		if err != nil {
+			return
+		}
+		defer c.CloseNow()
+		for {
+			_, data, err := c.Read(r.Context())
+			if err != nil {
+				return
+			}
+			var req struct {
+				Method string `json:"method"`
+				ID     string `json:"id"`
+			}
+			json.Unmarshal(data, &req)
+			g.mu.Lock()
+			g.methods = append(g.methods, req.Method)
+			g.mu.Unlock()
+
+			res, _ := json.Marshal(map[string]any{"type": "res", "id": req.ID, "ok": true, "payload": map[string]string{"from": name}})
+			if err := c.Write(r.Context(), websocket.MessageText, res); err != nil {
+				return
+			}
+			if err := c.Write(r.Context(), websocket.MessageText, []byte(`{"type":"event","event":"tick"}`)); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+func (g *rpcGateway) received() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.methods...)
+}
+
+func TestHistoryGatewayRouting(t *testing.T) {
+	primary := newRPCGateway(t, "primary")
+	secondary := newRPCGateway(t, "secondary")
+
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = primary.URL
+	cfg.Bridge.HistoryGatewayURL = secondary.URL
+	cfg.Bridge.PingInterval = 0
+	handler := NewHandler(cfg, New(), nil, context.Background())
+	bridge := httptest.NewServer(handler)
+	defer bridge.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(bridge.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.CloseNow()
+
+	// call sends a request and returns which gateway answered it, skipping
+	// events and any other responses.
+	call := func(method, id string) string {
+		t.Helper()
+		req, _ := json.Marshal(map[string]any{"type": "req", "method": method, "id": id, "params": map[string]any{}})
+		if err := c.Write(ctx, websocket.MessageText, req); err != nil {
+			t.Fatalf("write %s: %v", method, err)
+		}
+		for {
+			_, data, err := c.Read(ctx)
+			if err != nil {
+				t.Fatalf("read %s response: %v", method, err)
+			}
+			var res struct {
+				Type    string `json:"type"`
+				ID      string `json:"id"`
+				Payload struct {
+					From string `json:"from"`
+				} `json:"payload"`
+			}
+			json.Unmarshal(data, &res)
+			if res.Type == "res" && res.ID == id {
+				return res.Payload.From
+			}
+		}
+	}
+
+	if from := call("connect", "1"); from != "primary" {
+		t.Errorf("connect answered by %q, want primary", from)
+	}
+	if from := call("sessions.history", "2"); from != "secondary" {
+		t.Errorf("sessions.history answered by %q, want secondary", from)
+	}
+	if from := call("chat.send", "3"); from != "primary" {
+		t.Errorf("chat.send answered by %q, want primary", from)
+	}
+	if from := call("sessions.history", "4"); from != "secondary" {
+		t.Errorf("second sessions.history answered by %q, want secondary", from)
+	}
+
+	if got, want := strings.Join(primary.received(), ","), "connect,chat.send"; got != want {
+		t.Errorf("primary received %s, want %s", got, want)
+	}
+	// The client's connect is replayed to the secondary before its first request.
+	if got, want := strings.Join(secondary.received(), ","), "connect,sessions.history,sessions.history"; got != want {
+		t.Errorf("secondary received %s, want %s", got, want)
+	}
+}
+
+func TestHistoryGatewayUnreachableFallsBackToPrimary(t *testing.T) {
+	primary := newRPCGateway(t, "primary")
+
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = primary.URL
+	cfg.Bridge.HistoryGatewayURL = "http://127.0.0.1:19999" // nothing listening
+	cfg.Bridge.PingInterval = 0
+	handler := NewHandler(cfg, New(), nil, context.Background())
+	bridge := httptest.NewServer(handler)
+	defer bridge.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(bridge.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.CloseNow()
+
+	if err := c.Write(ctx, websocket.MessageText, []byte(`{"type":"req","method":"sessions.history","id":"1","params":{}}`)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, data, err := c.Read(ctx)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !strings.Contains(string(data), `"from":"primary"`) {
+		t.Errorf("response = %s, want one from the primary", data)
+	}
+}
+
+func TestHistoryRouterBacksOffAfterFailedDial(t *testing.T) {
+	var dials int
+	hr := NewHistoryRouter(context.Background(), nil, "test", time.Second, func(ctx context.Context) (*websocket.Conn, error) {
+		dials++
+		return nil, errors.New("refused")
+	})
+	req := []byte(`{"type":"req","method":"sessions.history","id":"1","params":{}}`)
+
+	for i := 0; i < 3; i++ {
+		if out := hr.InspectMessage(req, websocket.MessageText); string(out) != string(req) {
+			t.Fatalf("request %d = %q, want it forwarded to the primary unchanged", i, out)
+		}
+	}
+	if dials != 1 {
+		t.Errorf("dials = %d, want 1 (later requests fall within the backoff)", dials)
+	}
+
+	hr.mu.Lock()
+	hr.retryAt = time.Now().Add(-time.Millisecond)
+	hr.mu.Unlock()
+	hr.InspectMessage(req, websocket.MessageText)
+	if dials != 2 {
+		t.Errorf("dials = %d, want 2 once the backoff has passed", dials)
+	}
+	if hr.backoff != 2*historyRetryMin {
+		t.Errorf("backoff = %s, want %s after a second failure", hr.backoff, 2*historyRetryMin)
+	}
+}
+
+func TestHistoryRouterCloseDoesNotWaitForDial(t *testing.T) {
+	dialing := make(chan struct{})
+	unblock := make(chan struct{})
+	hr := NewHistoryRouter(context.Background(), nil, "test", time.Second, func(ctx context.Context) (*websocket.Conn, error) {
+		close(dialing)
+		<-unblock
+		return nil, errors.New("refused")
+	})
+	defer close(unblock)
+	go hr.InspectMessage([]byte(`{"type":"req","method":"sessions.history","id":"1","params":{}}`), websocket.MessageText)
+	<-dialing
+
+	closed := make(chan struct{})
+	go func() {
+		hr.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close blocked behind an in-progress secondary dial")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log/slog"
 	"strings"
+	"sync/atomic"
 
 	"github.com/coder/websocket"
 	"github.com/cortexuvula/clawreachbridge/internal/canvas"
@@ -18,15 +19,21 @@ type MessageInspector interface {
 }
 
 // mediaInspectorAdapter wraps media.Injector to satisfy MessageInspector.
+// Messages pass through untouched while paused is set.
 type mediaInspectorAdapter struct {
 	injector *media.Injector
+	paused   *atomic.Bool             // nil = never paused
+	caps     media.ClientCapabilities // from the client's X-Client-Supports header; nil = everything
 }
 
 func (a *mediaInspectorAdapter) InspectMessage(payload []byte, msgType websocket.MessageType) []byte {
 	if msgType != websocket.MessageText {
 		return payload
 	}
-	return a.injector.ProcessMessage(payload)
+	if a.paused != nil && a.paused.Load() {
+		return payload
+	}
+	return a.injector.ProcessMessageFor(payload, a.caps)
 }
 
 // canvasInspectorAdapter wraps canvas.CanvasTracker to observe gateway→client
@@ -34,11 +41,13 @@ func (a *mediaInspectorAdapter) InspectMessage(payload []byte, msgType websocket
 // inject the configured URL before passing the payload to the tracker.
 type canvasInspectorAdapter struct {
 	tracker *canvas.CanvasTracker // nil if state_tracking disabled
-	a2uiURL string               // empty = no rewriting
+	a2uiURL string                // empty = no rewriting
+	conn    *websocket.Conn       // client connection, for replay deduplication
 }
 
-// canvasEnvelope extracts only the fields needed to identify canvas messages.
-type canvasEnvelope struct {
+// methodEnvelope extracts only the fields needed to identify a request by
+// method (canvas messages, per-method metrics).
+type methodEnvelope struct {
 	Type   string `json:"type"`
 	Method string `json:"method,omitempty"`
 }
@@ -48,7 +57,7 @@ func (a *canvasInspectorAdapter) InspectMessage(payload []byte, msgType websocke
 		return payload
 	}
 
-	var env canvasEnvelope
+	var env methodEnvelope
 	if err := json.Unmarshal(payload, &env); err != nil {
 		return payload
 	}
@@ -69,6 +78,10 @@ func (a *canvasInspectorAdapter) InspectMessage(payload []byte, msgType websocke
 	// Pass (potentially modified) payload to tracker
 	if a.tracker != nil {
 		a.tracker.HandleMessage(env.Method, payload)
+		if a.conn != nil && a.tracker.SuppressReplayed(a.conn, payload) {
+			slog.Debug("canvas inspector: dropped duplicate of replayed message", "method", env.Method)
+			return nil
+		}
 	}
 	slog.Debug("canvas inspector: observed", "method", env.Method)
 
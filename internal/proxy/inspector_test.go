@@ -1,7 +1,14 @@
 package proxy
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -38,6 +45,127 @@ func TestMediaInspectorAdapterProcessesText(t *testing.T) {
 	}
 }
 
+func TestMediaInspectorAdapterPaused(t *testing.T) {
+	dir := t.TempDir()
+	imgPath := filepath.Join(dir, "generated.png")
+	if err := os.WriteFile(imgPath, []byte("png-data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inj := media.NewInjector(config.MediaConfig{
+		Enabled:     true,
+		Directory:   t.TempDir(),
+		AllowedDirs: []string{dir},
+		MaxFileSize: 1024,
+		MaxAge:      time.Minute,
+		Extensions:  []string{".png"},
+	})
+
+	h := NewHandler(testConfig(), New(), nil, context.Background())
+	adapter := &mediaInspectorAdapter{injector: inj, paused: &h.mediaPaused}
+
+	final := func(runID string) []byte {
+		return []byte(`{"type":"event","event":"chat","payload":{"runId":"` + runID +
+			`","state":"final","message":{"role":"assistant","content":[{"type":"text","text":"MEDIA: ` + imgPath + `"}]}}}`)
+	}
+	injected := func(runID string) bool {
+		adapter.InspectMessage([]byte(`{"type":"event","event":"chat","payload":{"runId":"`+runID+`","state":"delta"}}`), websocket.MessageText)
+		in := final(runID)
+		return !bytes.Equal(adapter.InspectMessage(in, websocket.MessageText), in)
+	}
+
+	if !injected("run-1") {
+		t.Fatal("image should be injected while not paused")
+	}
+	if paused := h.ToggleMediaPaused(); !paused {
+		t.Fatal("ToggleMediaPaused() = false, want true")
+	}
+	if injected("run-2") {
+		t.Error("image should not be injected while paused")
+	}
+	if paused := h.ToggleMediaPaused(); paused {
+		t.Fatal("ToggleMediaPaused() = true, want false")
+	}
+	if !injected("run-3") {
+		t.Error("image should be injected again after resuming")
+	}
+}
+
+func TestHandlerMediaInjectionHonorsClientSupports(t *testing.T) {
+	dir := t.TempDir()
+	webpPath := filepath.Join(dir, "chart.webp")
+	os.WriteFile(webpPath, []byte("webp-data"), 0644)
+	os.WriteFile(filepath.Join(dir, "chart.png"), []byte("png-data"), 0644)
+
+	// The gateway sends one final with a MEDIA: marker as soon as a client connects.
+	final := `{"type":"event","event":"chat","payload":{"runId":"r1","state":"final","message":{"role":"assistant","content":[{"type":"text","text":"MEDIA: ` + webpPath + `"}]}}}`
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+		if err != nil {
+			return
+		}
+		defer c.CloseNow()
+		c.Write(r.Context(), websocket.MessageText, []byte(final))
+		c.Read(r.Context())
+	}))
+	defer gw.Close()
+
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = gw.URL
+	cfg.Bridge.PingInterval = 0
+	cfg.Bridge.Media = config.MediaConfig{
+		Enabled:     true,
+		Directory:   t.TempDir(),
+		AllowedDirs: []string{dir},
+		MaxFileSize: 1024,
+		MaxAge:      time.Minute,
+		Extensions:  []string{".webp", ".png"},
+	}
+	bridge := httptest.NewServer(NewHandler(cfg, New(), nil, context.Background()))
+	defer bridge.Close()
+
+	injectedFile := func(supports string) string {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		opts := &websocket.DialOptions{HTTPHeader: http.Header{}}
+		if supports != "" {
+			opts.HTTPHeader.Set(media.ClientSupportsHeader, supports)
+		}
+		c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(bridge.URL, "http"), opts)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.CloseNow()
+		_, msg, err := c.Read(ctx)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		var got struct {
+			Payload struct {
+				Message struct {
+					Content []struct {
+						FileName string `json:"fileName"`
+					} `json:"content"`
+				} `json:"message"`
+			} `json:"payload"`
+		}
+		if err := json.Unmarshal(msg, &got); err != nil {
+			t.Fatalf("decoding %s: %v", msg, err)
+		}
+		if n := len(got.Payload.Message.Content); n != 2 {
+			t.Fatalf("content items = %d, want text + image: %s", n, msg)
+		}
+		return got.Payload.Message.Content[1].FileName
+	}
+
+	if f := injectedFile(""); f != "chart.webp" {
+		t.Errorf("client without the header got %s, want chart.webp", f)
+	}
+	if f := injectedFile("image/png, image/jpeg"); f != "chart.png" {
+		t.Errorf("client without webp support got %s, want chart.png", f)
+	}
+}
+
 // noopInspector is a test inspector that records call count.
 type noopInspector struct {
 	calls int
@@ -339,3 +467,106 @@ func TestInjectA2UIURLFunction(t *testing.T) {
 		})
 	}
 }
+
+func TestCanvasReplayDedupSuppressesGatewayResend(t *testing.T) {
+	presentMsg := []byte(`{"type":"req","method":"canvas.present","params":{"url":"/__openclaw__/a2ui/"}}`)
+	// A gateway that re-sends the current canvas on every connection, followed
+	// by a marker so the client knows the re-send has been processed.
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+		if err != nil {
+			return
+		}
+		defer c.CloseNow()
+		c.Write(r.Context(), websocket.MessageText, presentMsg)
+		c.Write(r.Context(), websocket.MessageText, []byte(`{"type":"event","event":"marker"}`))
+		c.Read(r.Context())
+	}))
+	defer gw.Close()
+
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = gw.URL
+	cfg.Bridge.PingInterval = 0
+	cfg.Bridge.Canvas.StateTracking = true
+	handler := NewHandler(cfg, New(), nil, context.Background())
+	handler.CanvasTracker = canvas.NewTracker(cfg.Bridge.Canvas)
+	bridge := httptest.NewServer(handler)
+	defer bridge.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	wsURL := "ws" + strings.TrimPrefix(bridge.URL, "http")
+	readAll := func(c *websocket.Conn) []string {
+		t.Helper()
+		var got []string
+		for {
+			_, data, err := c.Read(ctx)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			got = append(got, string(data))
+			if strings.Contains(string(data), "marker") {
+				return got
+			}
+		}
+	}
+
+	// First connection: nothing to replay, the gateway's present goes through
+	// and becomes the tracked state.
+	first, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer first.CloseNow()
+	if got := readAll(first); len(got) != 2 || got[0] != string(presentMsg) {
+		t.Fatalf("first connection got %q, want present then marker", got)
+	}
+
+	// Reconnect: the bridge replays present and drops the gateway's copy.
+	second, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer second.CloseNow()
+	if got := readAll(second); len(got) != 2 || got[0] != string(presentMsg) {
+		t.Errorf("reconnect got %q, want one present (replayed) then marker", got)
+	}
+}
+
+func TestCanvasOversizedJSONLForwardedNotBuffered(t *testing.T) {
+	bridge, handler, _ := setupBridgeWithGateway(t)
+	canvasCfg := handler.GetConfig().Bridge.Canvas
+	canvasCfg.StateTracking = true
+	canvasCfg.MaxJSONLBytes = 1024
+	handler.CanvasTracker = canvas.NewTracker(canvasCfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(bridge.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.CloseNow()
+
+	// The echo gateway sends each message back, so it passes the canvas
+	// inspector on the way to the client.
+	present := `{"type":"req","method":"canvas.present","params":{"url":"/__openclaw__/a2ui/"}}`
+	big := `{"type":"req","method":"canvas.a2ui.pushJSONL","params":{"jsonl":"` + strings.Repeat("x", 4096) + `"}}`
+	for _, msg := range []string{present, big} {
+		if err := c.Write(ctx, websocket.MessageText, []byte(msg)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		_, got, err := c.Read(ctx)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if string(got) != msg {
+			t.Fatalf("client got %d bytes, want the %d byte message unchanged", len(got), len(msg))
+		}
+	}
+
+	state := handler.CanvasTracker.State()
+	if !state.Visible || state.JSONLBuffered != 0 {
+		t.Errorf("tracker state = %+v, want visible with no JSONL buffered", state)
+	}
+}
@@ -0,0 +1,37 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"github.com/cortexuvula/clawreachbridge/internal/config"
+	"github.com/cortexuvula/clawreachbridge/internal/security"
+)
+
+// Listen binds the proxy listener on bridge.listen_address, applying
+// bridge.reuse_port and bridge.listen_backlog. With bridge.tls.enabled the
+// listener terminates TLS (and mTLS when client_ca_file is set).
+func Listen(ctx context.Context, cfg *config.Config) (net.Listener, error) {
+	lc := listenConfig(cfg)
+	ln, err := lc.Listen(ctx, "tcp", cfg.Bridge.ListenAddress)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Bridge.ListenBacklog > 0 {
+		if err := setListenBacklog(ln, cfg.Bridge.ListenBacklog); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("bridge.listen_backlog: %w", err)
+		}
+	}
+	if cfg.Bridge.TLS.Enabled {
+		tlsCfg, err := security.ServerTLSConfig(cfg.Bridge.TLS)
+		if err != nil {
+			ln.Close()
+			return nil, err
+		}
+		ln = tls.NewListener(ln, tlsCfg)
+	}
+	return ln, nil
+}
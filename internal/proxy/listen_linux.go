@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/cortexuvula/clawreachbridge/internal/config"
+	"golang.org/x/sys/unix"
+)
+
+// listenConfig returns a ListenConfig whose Control sets SO_REUSEPORT on the
+// socket before bind when bridge.reuse_port is on.
+func listenConfig(cfg *config.Config) net.ListenConfig {
+	var lc net.ListenConfig
+	if cfg.Bridge.ReusePort {
+		lc.Control = func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+			})
+			if err != nil {
+				return err
+			}
+			if sockErr != nil {
+				return fmt.Errorf("set SO_REUSEPORT: %w", sockErr)
+			}
+			return nil
+		}
+	}
+	return lc
+}
+
+// setListenBacklog resizes the accept queue of a listening socket. The Go
+// runtime calls listen(2) itself after Control runs, using somaxconn, so the
+// backlog can't be set there; calling listen(2) again on the bound socket
+// updates it instead. The kernel still caps it at net.core.somaxconn.
+func setListenBacklog(ln net.Listener, backlog int) error {
+	tl, ok := ln.(*net.TCPListener)
+	if !ok {
+		return fmt.Errorf("unsupported listener type %T", ln)
+	}
+	rc, err := tl.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var listenErr error
+	if err := rc.Control(func(fd uintptr) {
+		listenErr = unix.Listen(int(fd), backlog)
+	}); err != nil {
+		return err
+	}
+	return listenErr
+}
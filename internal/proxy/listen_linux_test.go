@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func sockoptReusePort(t *testing.T, ln net.Listener) int {
+	t.Helper()
+	rc, err := ln.(*net.TCPListener).SyscallConn()
+	if err != nil {
+		t.Fatalf("SyscallConn: %v", err)
+	}
+	var v int
+	var sockErr error
+	rc.Control(func(fd uintptr) {
+		v, sockErr = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT)
+	})
+	if sockErr != nil {
+		t.Fatalf("getsockopt: %v", sockErr)
+	}
+	return v
+}
+
+func TestListenConfigReusePort(t *testing.T) {
+	cfg := testConfig()
+	cfg.Bridge.ListenAddress = "127.0.0.1:0"
+
+	if lc := listenConfig(cfg); lc.Control != nil {
+		t.Error("Control set with reuse_port off")
+	}
+
+	cfg.Bridge.ReusePort = true
+	first, err := Listen(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer first.Close()
+	if got := sockoptReusePort(t, first); got != 1 {
+		t.Errorf("SO_REUSEPORT = %d, want 1", got)
+	}
+
+	// A second process (here, listener) can bind the same port.
+	cfg.Bridge.ListenAddress = first.Addr().String()
+	second, err := Listen(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("second Listen on %s: %v", cfg.Bridge.ListenAddress, err)
+	}
+	second.Close()
+
+	// Without reuse_port the port is taken.
+	cfg.Bridge.ReusePort = false
+	if ln, err := Listen(context.Background(), cfg); err == nil {
+		ln.Close()
+		t.Error("second Listen without reuse_port succeeded, want address in use")
+	}
+}
+
+func TestListenBacklog(t *testing.T) {
+	cfg := testConfig()
+	cfg.Bridge.ListenAddress = "127.0.0.1:0"
+	cfg.Bridge.ListenBacklog = 16
+
+	ln, err := Listen(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+
+	// The listener still accepts after its backlog is resized.
+	go func() {
+		if c, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+			c.Close()
+		}
+	}()
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	c.Close()
+}
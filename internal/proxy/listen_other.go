@@ -0,0 +1,27 @@
+//go:build !linux
+
+package proxy
+
+import (
+	"errors"
+	"net"
+	"syscall"
+
+	"github.com/cortexuvula/clawreachbridge/internal/config"
+)
+
+// listenConfig returns a ListenConfig that refuses to bind when
+// bridge.reuse_port is on, since SO_REUSEPORT is only wired up on Linux.
+func listenConfig(cfg *config.Config) net.ListenConfig {
+	var lc net.ListenConfig
+	if cfg.Bridge.ReusePort {
+		lc.Control = func(network, address string, c syscall.RawConn) error {
+			return errors.New("bridge.reuse_port is only supported on Linux")
+		}
+	}
+	return lc
+}
+
+func setListenBacklog(ln net.Listener, backlog int) error {
+	return errors.New("only supported on Linux")
+}
@@ -0,0 +1,265 @@
+package proxy
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"golang.org/x/time/rate"
+
+	"github.com/cortexuvula/clawreachbridge/internal/config"
+	"github.com/cortexuvula/clawreachbridge/internal/security"
+)
+
+type listenTestCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// issueListenCert creates a certificate for cn valid for 127.0.0.1,
+// self-signed when parent is nil.
+func issueListenCert(t *testing.T, cn string, parent *listenTestCert, isCA bool, usage x509.ExtKeyUsage) *listenTestCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if isCA {
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return &listenTestCert{cert: cert, key: key, der: der}
+}
+
+func (c *listenTestCert) writePEM(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func (c *listenTestCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+// serveListen binds cfg's proxy listener through Listen and serves a
+// handler on it, returning the listener address.
+func serveListen(t *testing.T, cfg *config.Config, rl *security.RateLimiter) string {
+	t.Helper()
+	ln, err := Listen(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	srv := &http.Server{Handler: NewHandler(cfg, New(), rl, context.Background())}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	return ln.Addr().String()
+}
+
+func TestListenTLSClientCertEndToEnd(t *testing.T) {
+	gw := echoGateway(t)
+	defer gw.Close()
+
+	dir := t.TempDir()
+	ca := issueListenCert(t, "test CA", nil, true, 0)
+	caFile, _ := ca.writePEM(t, dir, "ca")
+	certFile, keyFile := issueListenCert(t, "bridge", ca, false, x509.ExtKeyUsageServerAuth).writePEM(t, dir, "server")
+	alice := issueListenCert(t, "alice", ca, false, x509.ExtKeyUsageClientAuth)
+	bob := issueListenCert(t, "bob", ca, false, x509.ExtKeyUsageClientAuth)
+
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = gw.URL
+	cfg.Bridge.PingInterval = 0
+	cfg.Bridge.TLS = config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, MinVersion: "1.2"}
+	cfg.Security.RateLimit.Enabled = true
+	cfg.Security.RateLimit.ConnectionsPerMinute = 1
+	cfg.Security.RateLimit.Key = "cert"
+	rl := security.NewRateLimiter(rate.Limit(1.0/60.0), 1)
+	defer rl.Stop()
+	addr := serveListen(t, cfg, rl)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	dial := func(client *listenTestCert) (*websocket.Conn, *http.Response, error) {
+		tlsCfg := &tls.Config{RootCAs: roots}
+		if client != nil {
+			tlsCfg.Certificates = []tls.Certificate{client.tlsCertificate()}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return websocket.Dial(ctx, "wss://"+addr+"/", &websocket.DialOptions{
+			HTTPClient: &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg}},
+		})
+	}
+
+	if c, _, err := dial(nil); err == nil {
+		c.CloseNow()
+		t.Fatal("dial without a client certificate succeeded")
+	}
+
+	c, _, err := dial(alice)
+	if err != nil {
+		t.Fatalf("dial as alice: %v", err)
+	}
+	defer c.CloseNow()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Write(ctx, websocket.MessageText, []byte("hello")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, got, err := c.Read(ctx); err != nil || string(got) != "hello" {
+		t.Fatalf("echo = %q, %v", got, err)
+	}
+
+	// Both clients share 127.0.0.1; the rate limit is keyed by certificate.
+	if c, _, err := dial(bob); err != nil {
+		t.Errorf("dial as bob: %v", err)
+	} else {
+		c.CloseNow()
+	}
+	if c, resp, err := dial(alice); err == nil {
+		c.CloseNow()
+		t.Error("second dial as alice was not rate limited")
+	} else if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("second dial as alice: %v, want 429", err)
+	}
+}
+
+func TestListenTLSVersionAndCipherSuites(t *testing.T) {
+	dir := t.TempDir()
+	server := issueListenCert(t, "bridge", nil, false, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := server.writePEM(t, dir, "server")
+	roots := x509.NewCertPool()
+	roots.AddCert(server.cert)
+
+	handshake := func(addr string, clientCfg *tls.Config) (tls.ConnectionState, error) {
+		clientCfg.RootCAs = roots
+		conn, err := tls.Dial("tcp", addr, clientCfg)
+		if err != nil {
+			return tls.ConnectionState{}, err
+		}
+		defer conn.Close()
+		return conn.ConnectionState(), nil
+	}
+
+	cfg := testConfig()
+	cfg.Bridge.TLS = config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"}
+	addr := serveListen(t, cfg, nil)
+	if _, err := handshake(addr, &tls.Config{MaxVersion: tls.VersionTLS12}); err == nil {
+		t.Error("TLS 1.2 handshake accepted with min_version 1.3")
+	}
+	if state, err := handshake(addr, &tls.Config{}); err != nil {
+		t.Errorf("TLS 1.3 handshake: %v", err)
+	} else if state.Version != tls.VersionTLS13 {
+		t.Errorf("negotiated version = %x, want TLS 1.3", state.Version)
+	}
+
+	cfg = testConfig()
+	cfg.Bridge.TLS = config.TLSConfig{
+		Enabled:      true,
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		MinVersion:   "1.2",
+		CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"},
+	}
+	addr = serveListen(t, cfg, nil)
+	state, err := handshake(addr, &tls.Config{MaxVersion: tls.VersionTLS12})
+	if err != nil {
+		t.Fatalf("TLS 1.2 handshake: %v", err)
+	}
+	if state.CipherSuite != tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384 {
+		t.Errorf("negotiated suite = %s, want TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", tls.CipherSuiteName(state.CipherSuite))
+	}
+	if _, err := handshake(addr, &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305},
+	}); err == nil {
+		t.Error("handshake accepted a cipher suite outside cipher_suites")
+	}
+}
+
+func TestListenTLSServesRenewedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueListenCert(t, "test CA", nil, true, 0)
+	certFile, keyFile := issueListenCert(t, "bridge-old", ca, false, x509.ExtKeyUsageServerAuth).writePEM(t, dir, "server")
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	cfg := testConfig()
+	cfg.Bridge.TLS = config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, MinVersion: "1.2"}
+	addr := serveListen(t, cfg, nil)
+
+	servedCN := func() string {
+		t.Helper()
+		conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: roots})
+		if err != nil {
+			t.Fatalf("handshake: %v", err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+	if cn := servedCN(); cn != "bridge-old" {
+		t.Fatalf("served CN = %q, want bridge-old", cn)
+	}
+
+	// Renew in place; bump the mtimes so the change is seen even within the
+	// filesystem's timestamp granularity.
+	issueListenCert(t, "bridge-new", ca, false, x509.ExtKeyUsageServerAuth).writePEM(t, dir, "server")
+	later := time.Now().Add(time.Minute)
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cn := servedCN(); cn != "bridge-new" {
+		t.Errorf("served CN after renewal = %q, want bridge-new", cn)
+	}
+}
@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// maintenanceCheckInterval is how often the scheduler compares the clock
+// with bridge.maintenance_schedule.
+const maintenanceCheckInterval = 15 * time.Second
+
+// InMaintenance reports whether a maintenance window is in effect.
+func (h *Handler) InMaintenance() bool {
+	return h.maintenance.Load()
+}
+
+// SetMaintenance enters or leaves maintenance mode and reports whether the
+// mode changed. Entering closes every active connection with 1001
+// "maintenance" along with any parked resume sessions; until it is left,
+// WebSocket upgrades get a 503.
+func (h *Handler) SetMaintenance(on bool) bool {
+	if !h.maintenance.CompareAndSwap(!on, on) {
+		return false
+	}
+	if on {
+		h.resumes.closeAll("maintenance")
+		// Close concurrently so clients that never answer the close frame
+		// don't stall the scheduler.
+		for _, c := range h.drainSequence(h.GetConfig().Bridge.DrainOrder) {
+			go c.close(websocket.StatusGoingAway, "maintenance")
+		}
+	}
+	return true
+}
+
+// RunMaintenanceSchedule enters maintenance mode while the clock is inside a
+// bridge.maintenance_schedule window and leaves it afterwards, until ctx is
+// cancelled. The schedule is re-read on every check, so reloads apply.
+func (h *Handler) RunMaintenanceSchedule(ctx context.Context) {
+	h.runMaintenanceSchedule(ctx, time.Now, maintenanceCheckInterval)
+}
+
+func (h *Handler) runMaintenanceSchedule(ctx context.Context, now func() time.Time, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	h.checkMaintenance(now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.checkMaintenance(now())
+		}
+	}
+}
+
+// checkMaintenance sets maintenance mode for the windows containing t and
+// logs transitions.
+func (h *Handler) checkMaintenance(t time.Time) {
+	windows, _ := h.GetConfig().Bridge.MaintenanceWindows() // validated on load
+	var active string
+	for _, w := range windows {
+		if w.Contains(t) {
+			active = w.String()
+			break
+		}
+	}
+	if !h.SetMaintenance(active != "") {
+		return
+	}
+	if active != "" {
+		slog.Warn("maintenance window started, draining connections", "window", active, "connections", h.Proxy.ConnectionCount())
+	} else {
+		slog.Info("maintenance window ended, accepting connections")
+	}
+}
@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+func TestMaintenanceScheduleEntersAndExits(t *testing.T) {
+	bridge, handler, _ := setupBridgeWithGateway(t)
+	cfg := *handler.GetConfig()
+	cfg.Bridge.MaintenanceSchedule = []string{"03:00-04:00"}
+	handler.UpdateConfig(&cfg)
+	wsURL := "ws" + strings.TrimPrefix(bridge.URL, "http")
+
+	var clock atomic.Int64
+	setClock := func(hhmm string) {
+		tm, _ := time.Parse("15:04", hhmm)
+		clock.Store(time.Date(2026, 3, 1, tm.Hour(), tm.Minute(), 0, 0, time.Local).UnixNano())
+	}
+	now := func() time.Time { return time.Unix(0, clock.Load()) }
+	setClock("02:59")
+
+	schedCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go handler.runMaintenanceSchedule(schedCtx, now, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial before window: %v", err)
+	}
+	defer c.CloseNow()
+
+	// Entering the window closes the active connection and refuses new ones.
+	setClock("03:00")
+	_, _, err = c.Read(ctx)
+	if got := websocket.CloseStatus(err); got != websocket.StatusGoingAway {
+		t.Fatalf("close status = %d (%v), want going away", got, err)
+	}
+	if !handler.InMaintenance() {
+		t.Error("InMaintenance() = false inside the window")
+	}
+	_, resp, err := websocket.Dial(ctx, wsURL, nil)
+	if err == nil {
+		t.Fatal("dial during maintenance succeeded")
+	}
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("dial during maintenance = %v, want 503", err)
+	}
+
+	// Leaving it accepts connections again.
+	setClock("04:00")
+	waitFor(t, "maintenance ended", func() bool { return !handler.InMaintenance() })
+	c2, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial after window: %v", err)
+	}
+	c2.Close(websocket.StatusNormalClosure, "")
+}
+
+func TestMaintenanceScheduleFollowsReload(t *testing.T) {
+	_, handler, _ := setupBridgeWithGateway(t)
+	noon := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+
+	handler.checkMaintenance(noon)
+	if handler.InMaintenance() {
+		t.Fatal("in maintenance with no schedule")
+	}
+
+	cfg := *handler.GetConfig()
+	cfg.Bridge.MaintenanceSchedule = []string{"Sun 11:00-13:00"}
+	handler.UpdateConfig(&cfg)
+	handler.checkMaintenance(noon)
+	if !handler.InMaintenance() {
+		t.Fatal("not in maintenance after a reload added a window covering now")
+	}
+
+	cfg.Bridge.MaintenanceSchedule = nil
+	handler.UpdateConfig(&cfg)
+	handler.checkMaintenance(noon)
+	if handler.InMaintenance() {
+		t.Error("still in maintenance after the window was removed")
+	}
+}
+
+func TestSetMaintenanceDoesNotWaitForUnresponsiveClients(t *testing.T) {
+	bridge, handler, _ := setupBridgeWithGateway(t)
+	wsURL := "ws" + strings.TrimPrefix(bridge.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// Neither client reads, so neither answers the close frame.
+	for i := 0; i < 2; i++ {
+		c, _, err := websocket.Dial(ctx, wsURL, nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.CloseNow()
+	}
+	waitFor(t, "connections tracked", func() bool { return len(handler.drainSequence("oldest_first")) == 2 })
+
+	start := time.Now()
+	if !handler.SetMaintenance(true) {
+		t.Fatal("SetMaintenance(true) = false")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("SetMaintenance took %v; it waited on the close handshakes", elapsed)
+	}
+}
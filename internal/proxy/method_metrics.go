@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"encoding/json"
+
+	"github.com/coder/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MethodInspector counts client→gateway requests by JSON-RPC method. Only
+// methods in the allowlist get their own label value; everything else is
+// counted as "other" to keep label cardinality bounded. The payload is
+// always returned unchanged.
+type MethodInspector struct {
+	counter *prometheus.CounterVec
+	allowed map[string]bool
+}
+
+// NewMethodInspector creates a MethodInspector that increments counter for
+// the given method names.
+func NewMethodInspector(counter *prometheus.CounterVec, methods []string) *MethodInspector {
+	allowed := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		allowed[m] = true
+	}
+	return &MethodInspector{counter: counter, allowed: allowed}
+}
+
+// InspectMessage increments the per-method counter for request messages.
+func (mi *MethodInspector) InspectMessage(payload []byte, msgType websocket.MessageType) []byte {
+	if msgType != websocket.MessageText {
+		return payload
+	}
+
+	var env methodEnvelope
+	if err := json.Unmarshal(payload, &env); err != nil || env.Type != "req" || env.Method == "" {
+		return payload
+	}
+
+	method := "other"
+	if mi.allowed[env.Method] {
+		method = env.Method
+	}
+	mi.counter.WithLabelValues(method).Inc()
+
+	return payload
+}
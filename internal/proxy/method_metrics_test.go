@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/coder/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMethodInspectorCountsPerMethod(t *testing.T) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "test_messages_by_method_total",
+		Help: "test",
+	}, []string{"method"})
+	mi := NewMethodInspector(counter, []string{"chat.send", "chat.history"})
+
+	msgs := []string{
+		`{"type":"req","method":"chat.send","params":{}}`,
+		`{"type":"req","method":"chat.send"}`,
+		`{"type":"req","method":"chat.history"}`,
+		`{"type":"req","method":"sessions.list"}`,
+		`{"type":"req","method":"device.pair"}`,
+		`{"type":"event","event":"chat"}`, // not a request
+		`{"type":"req"}`,                  // no method
+		`not json`,
+	}
+	for _, m := range msgs {
+		if got := mi.InspectMessage([]byte(m), websocket.MessageText); string(got) != m {
+			t.Errorf("payload should pass through unchanged: %s", m)
+		}
+	}
+	mi.InspectMessage([]byte(`{"type":"req","method":"chat.send"}`), websocket.MessageBinary)
+
+	want := map[string]float64{"chat.send": 2, "chat.history": 1, "other": 2}
+	for method, n := range want {
+		if got := testutil.ToFloat64(counter.WithLabelValues(method)); got != n {
+			t.Errorf("%s count = %v, want %v", method, got, n)
+		}
+	}
+	if got := testutil.CollectAndCount(counter); got != len(want) {
+		t.Errorf("label values = %d, want %d", got, len(want))
+	}
+}
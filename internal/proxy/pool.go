@@ -0,0 +1,299 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+const (
+	// poolPingTimeout bounds the liveness check on a pooled connection at
+	// handoff; a slow pong means the client is better off with a fresh dial.
+	poolPingTimeout = 2 * time.Second
+
+	// poolPumpBuffer is how many gateway messages a pooled connection may
+	// buffer before it is adopted (e.g. a greeting sent on connect).
+	poolPumpBuffer = 8
+
+	// poolPath is the request path pooled connections are dialed for.
+	poolPath = "/"
+
+	poolRetryMin = time.Second
+	poolRetryMax = 30 * time.Second
+)
+
+// messageSource is the read side of a gateway connection used by
+// forwardMessages. *websocket.Conn satisfies it directly; pooled connections
+// are read through their pump.
+type messageSource interface {
+	Reader(ctx context.Context) (websocket.MessageType, io.Reader, error)
+}
+
+type pumpedMessage struct {
+	typ  websocket.MessageType
+	data []byte
+}
+
+// pooledConn is a pre-dialed gateway connection. A pump goroutine reads from
+// it from the moment it is dialed so that pings are answered while it sits
+// idle in the pool; messages it reads are replayed to whoever adopts it.
+type pooledConn struct {
+	conn     *websocket.Conn
+	path     string // request path the connection was dialed for
+	origin   string // Origin header the connection was dialed with
+	reqID    string
+	dialedAt time.Time
+
+	msgs     chan pumpedMessage
+	done     chan struct{} // closed when the pump stops reading
+	err      error         // read error that stopped the pump; set before done is closed
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newPooledConn(conn *websocket.Conn, path, origin, reqID string) *pooledConn {
+	pc := &pooledConn{
+		conn:     conn,
+		path:     path,
+		origin:   origin,
+		reqID:    reqID,
+		dialedAt: time.Now(),
+		msgs:     make(chan pumpedMessage, poolPumpBuffer),
+		done:     make(chan struct{}),
+		stop:     make(chan struct{}),
+	}
+	go pc.pump()
+	return pc
+}
+
+func (pc *pooledConn) pump() {
+	for {
+		typ, data, err := pc.conn.Read(context.Background())
+		if err != nil {
+			pc.err = err
+			close(pc.done)
+			return
+		}
+		select {
+		case pc.msgs <- pumpedMessage{typ: typ, data: data}:
+		case <-pc.stop:
+			return
+		}
+	}
+}
+
+// Reader returns the next message read by the pump. Buffered messages are
+// delivered before the pump's terminal error.
+func (pc *pooledConn) Reader(ctx context.Context) (websocket.MessageType, io.Reader, error) {
+	select {
+	case m := <-pc.msgs:
+		return m.typ, bytes.NewReader(m.data), nil
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	case <-pc.done:
+		select {
+		case m := <-pc.msgs:
+			return m.typ, bytes.NewReader(m.data), nil
+		default:
+			return 0, nil, pc.err
+		}
+	}
+}
+
+// alive reports whether the pump is still reading.
+func (pc *pooledConn) alive() bool {
+	select {
+	case <-pc.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// close tears down the connection and stops the pump.
+func (pc *pooledConn) close() {
+	pc.stopOnce.Do(func() { close(pc.stop) })
+	pc.conn.CloseNow()
+}
+
+// gatewayPool keeps up to size idle gateway connections dialed ahead of
+// demand and refills in the background as they are adopted or die.
+type gatewayPool struct {
+	size   int
+	dial   func(ctx context.Context) (*pooledConn, error)
+	refill chan struct{}
+
+	mu      sync.Mutex
+	idle    []*pooledConn
+	closed  bool
+	dialing bool
+}
+
+func newGatewayPool(size int, dial func(ctx context.Context) (*pooledConn, error)) *gatewayPool {
+	return &gatewayPool{
+		size:   size,
+		dial:   dial,
+		refill: make(chan struct{}, 1),
+	}
+}
+
+// run keeps the pool filled until ctx is cancelled, then closes every idle
+// connection. Dial failures back off exponentially.
+func (gp *gatewayPool) run(ctx context.Context) {
+	defer gp.closeAll()
+
+	backoff := poolRetryMin
+	for {
+		for gp.needsConn() {
+			pc, err := gp.dial(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				slog.Debug("gateway pool: dial failed", "error", err, "retry_in", backoff)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				backoff = min(backoff*2, poolRetryMax)
+				continue
+			}
+			backoff = poolRetryMin
+			gp.add(pc)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-gp.refill:
+		}
+	}
+}
+
+// needsConn drops dead idle connections and reports whether the pool is
+// below its target size.
+func (gp *gatewayPool) needsConn() bool {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+	live := gp.idle[:0]
+	for _, pc := range gp.idle {
+		if pc.alive() {
+			live = append(live, pc)
+		} else {
+			pc.close()
+		}
+	}
+	clear(gp.idle[len(live):])
+	gp.idle = live
+	return !gp.closed && len(gp.idle) < gp.size
+}
+
+func (gp *gatewayPool) add(pc *pooledConn) {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+	if gp.closed {
+		pc.close()
+		return
+	}
+	gp.idle = append(gp.idle, pc)
+}
+
+// get hands out an idle connection dialed for the given path and origin
+// whose negotiated subprotocol matches, or is one of the bridge-only
+// bridgeSubprotocols, after confirming it still answers a ping.
+// It returns nil if none qualifies; the caller then dials as usual.
+func (gp *gatewayPool) get(ctx context.Context, path, origin, subprotocol string, bridgeSubprotocols []string) *pooledConn {
+	defer gp.signalRefill()
+	for {
+		pc := gp.take(path, origin, subprotocol, bridgeSubprotocols)
+		if pc == nil {
+			return nil
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, poolPingTimeout)
+		err := pc.conn.Ping(pingCtx)
+		cancel()
+		if err == nil {
+			return pc
+		}
+		slog.Debug("gateway pool: discarding connection that failed ping", "request_id", pc.reqID, "error", err)
+		pc.close()
+	}
+}
+
+func (gp *gatewayPool) take(path, origin, subprotocol string, bridgeSubprotocols []string) *pooledConn {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+	for i, pc := range gp.idle {
+		sp := pc.conn.Subprotocol()
+		if pc.path != path || pc.origin != origin || (sp != subprotocol && !slices.Contains(bridgeSubprotocols, sp)) || !pc.alive() {
+			continue
+		}
+		gp.idle = append(gp.idle[:i], gp.idle[i+1:]...)
+		return pc
+	}
+	return nil
+}
+
+func (gp *gatewayPool) signalRefill() {
+	select {
+	case gp.refill <- struct{}{}:
+	default:
+	}
+}
+
+// idleCount returns the number of idle pooled connections.
+func (gp *gatewayPool) idleCount() int {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+	return len(gp.idle)
+}
+
+func (gp *gatewayPool) closeAll() {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+	gp.closed = true
+	for _, pc := range gp.idle {
+		pc.close()
+	}
+	gp.idle = nil
+}
+
+// StartGatewayPool pre-dials up to size gateway connections and keeps the
+// pool topped up until ctx is cancelled. Pooled connections are dialed for
+// the root path, so only clients connecting to "/" adopt one, and only when
+// its Origin and negotiated subprotocol match what a fresh dial would use;
+// other clients dial as usual. Pooled connections are
+// dialed without any client context, so this only works with gateways that
+// accept anonymous WebSocket connections and authenticate later in-band.
+func (h *Handler) StartGatewayPool(ctx context.Context, size int) {
+	gp := newGatewayPool(size, func(ctx context.Context) (*pooledConn, error) {
+		cfg := h.GetConfig()
+		dialCtx, cancel := context.WithTimeout(ctx, cfg.Bridge.DialTimeout)
+		defer cancel()
+		origin := cfg.Bridge.Origin.For(poolPath)
+		reqID := "pool-" + newConnID()
+		release, err := h.acquireDialSlot(dialCtx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		conn, err := dialGateway(dialCtx, cfg, h.resolver.client(), poolPath, reqID, "", cfg.Bridge.AllowedSubprotocols)
+		if err != nil {
+			return nil, err
+		}
+		if h.Metrics != nil {
+			h.Metrics.GatewaySeen()
+		}
+		conn.SetReadLimit(cfg.Bridge.DownstreamMessageLimit())
+		return newPooledConn(conn, poolPath, origin, reqID), nil
+	})
+	h.gatewayPool = gp
+	go gp.run(ctx)
+	slog.Info("gateway connection pool enabled", "size", size)
+}
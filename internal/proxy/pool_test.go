@@ -0,0 +1,302 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// greetingGateway greets every connection with the request ID it was dialed
+// with, then echoes. dials counts completed handshakes.
+func greetingGateway(t *testing.T, dials *atomic.Int32) *httptest.Server {
+	t.Helper()
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+		if err != nil {
+			return
+		}
+		defer c.CloseNow()
+		dials.Add(1)
+		if err := c.Write(r.Context(), websocket.MessageText, []byte("hello "+r.Header.Get("X-Request-Id"))); err != nil {
+			return
+		}
+		for {
+			typ, data, err := c.Read(r.Context())
+			if err != nil {
+				return
+			}
+			if err := c.Write(r.Context(), typ, data); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(gw.Close)
+	return gw
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func setupPoolBridge(t *testing.T, poolSize int) (*httptest.Server, *Handler, *atomic.Int32) {
+	t.Helper()
+	var dials atomic.Int32
+	gw := greetingGateway(t, &dials)
+
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = gw.URL
+	cfg.Bridge.PingInterval = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	handler := NewHandler(cfg, New(), nil, ctx)
+	handler.StartGatewayPool(ctx, poolSize)
+
+	bridge := httptest.NewServer(handler)
+	t.Cleanup(bridge.Close)
+	return bridge, handler, &dials
+}
+
+func TestGatewayPoolConnectionIsAdopted(t *testing.T) {
+	bridge, handler, dials := setupPoolBridge(t, 1)
+	waitFor(t, "pool to fill", func() bool { return handler.gatewayPool.idleCount() == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(bridge.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.CloseNow()
+
+	// The greeting was sent while the connection sat in the pool and must
+	// still reach the client that adopts it.
+	_, greeting, err := c.Read(ctx)
+	if err != nil {
+		t.Fatalf("read greeting: %v", err)
+	}
+	if !strings.HasPrefix(string(greeting), "hello pool-") {
+		t.Errorf("greeting = %q, want one from a pooled connection", greeting)
+	}
+
+	if err := c.Write(ctx, websocket.MessageText, []byte("ping")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, echo, err := c.Read(ctx); err != nil || string(echo) != "ping" {
+		t.Fatalf("echo = %q, %v; want ping", echo, err)
+	}
+
+	// The adopted connection is replaced in the background.
+	waitFor(t, "pool to refill", func() bool { return handler.gatewayPool.idleCount() == 1 })
+	if n := dials.Load(); n != 2 {
+		t.Errorf("gateway handshakes = %d, want 2 (one adopted, one refill)", n)
+	}
+}
+
+func TestGatewayPoolAdoptionSkipsDialSlot(t *testing.T) {
+	var dials atomic.Int32
+	gw := greetingGateway(t, &dials)
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = gw.URL
+	cfg.Bridge.PingInterval = 0
+	cfg.Bridge.MaxConcurrentDials = 1
+	cfg.Bridge.DialTimeout = 300 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	handler := NewHandler(cfg, New(), nil, ctx)
+	handler.StartGatewayPool(ctx, 1)
+	bridge := httptest.NewServer(handler)
+	defer bridge.Close()
+	waitFor(t, "pool to fill", func() bool { return handler.gatewayPool.idleCount() == 1 })
+
+	// With every dial slot taken, a client can still adopt the pooled
+	// connection because it doesn't dial.
+	release, err := handler.acquireDialSlot(ctx)
+	if err != nil {
+		t.Fatalf("acquireDialSlot: %v", err)
+	}
+	defer release()
+
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(bridge.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.CloseNow()
+	_, greeting, err := c.Read(ctx)
+	if err != nil {
+		t.Fatalf("read greeting: %v", err)
+	}
+	if !strings.HasPrefix(string(greeting), "hello pool-") {
+		t.Errorf("greeting = %q, want one from the pooled connection", greeting)
+	}
+}
+
+func TestGatewayPoolSkipsMismatchedOrigin(t *testing.T) {
+	bridge, handler, _ := setupPoolBridge(t, 1)
+	cfg := *handler.GetConfig()
+	cfg.Bridge.Origin.Routes = map[string]string{"/operator": "https://operator.example"}
+	handler.UpdateConfig(&cfg)
+	waitFor(t, "pool to fill", func() bool { return handler.gatewayPool.idleCount() == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(bridge.URL, "http")+"/operator", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.CloseNow()
+
+	_, greeting, err := c.Read(ctx)
+	if err != nil {
+		t.Fatalf("read greeting: %v", err)
+	}
+	if strings.HasPrefix(string(greeting), "hello pool-") {
+		t.Error("a route with a different Origin must not adopt a pooled connection")
+	}
+	if n := handler.gatewayPool.idleCount(); n != 1 {
+		t.Errorf("idle pooled connections = %d, want 1 (untouched)", n)
+	}
+}
+
+func TestGatewayPoolSkipsNonRootPath(t *testing.T) {
+	bridge, handler, _ := setupPoolBridge(t, 1)
+	waitFor(t, "pool to fill", func() bool { return handler.gatewayPool.idleCount() == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(bridge.URL, "http")+"/chat", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.CloseNow()
+
+	_, greeting, err := c.Read(ctx)
+	if err != nil {
+		t.Fatalf("read greeting: %v", err)
+	}
+	if strings.HasPrefix(string(greeting), "hello pool-") {
+		t.Error("a client on a non-root path must not adopt a connection pooled for /")
+	}
+	if n := handler.gatewayPool.idleCount(); n != 1 {
+		t.Errorf("idle pooled connections = %d, want 1 (untouched)", n)
+	}
+}
+
+func TestGatewayPoolDropsDeadConnections(t *testing.T) {
+	var dials atomic.Int32
+	closeAll := make(chan struct{})
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+		if err != nil {
+			return
+		}
+		if dials.Add(1) == 1 {
+			<-closeAll
+			c.Close(websocket.StatusGoingAway, "bye")
+			return
+		}
+		defer c.CloseNow()
+		c.Read(r.Context())
+	}))
+	defer gw.Close()
+
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = gw.URL
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h := NewHandler(cfg, New(), nil, ctx)
+	h.StartGatewayPool(ctx, 1)
+	waitFor(t, "pool to fill", func() bool { return h.gatewayPool.idleCount() == 1 })
+
+	close(closeAll)
+	waitFor(t, "dead connection to be noticed", func() bool {
+		h.gatewayPool.mu.Lock()
+		defer h.gatewayPool.mu.Unlock()
+		return len(h.gatewayPool.idle) == 1 && !h.gatewayPool.idle[0].alive()
+	})
+	if pc := h.gatewayPool.get(ctx, "/", cfg.Bridge.Origin.Default, "", nil); pc != nil {
+		t.Fatal("get returned a closed connection")
+	}
+	waitFor(t, "pool to refill", func() bool {
+		return dials.Load() == 2 && h.gatewayPool.idleCount() == 1
+	})
+}
+
+func TestPooledConnReaderDrainsBufferBeforeError(t *testing.T) {
+	pc := &pooledConn{
+		msgs: make(chan pumpedMessage, 2),
+		done: make(chan struct{}),
+		stop: make(chan struct{}),
+	}
+	pc.msgs <- pumpedMessage{typ: websocket.MessageText, data: []byte("last words")}
+	pc.err = io.ErrUnexpectedEOF
+	close(pc.done)
+
+	_, r, err := pc.Reader(context.Background())
+	if err != nil {
+		t.Fatalf("Reader: %v", err)
+	}
+	if b, _ := io.ReadAll(r); string(b) != "last words" {
+		t.Errorf("message = %q", b)
+	}
+	if _, _, err := pc.Reader(context.Background()); err != io.ErrUnexpectedEOF {
+		t.Errorf("second Reader error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestGatewayPoolBypassedWithClientIPHeader(t *testing.T) {
+	echo := echoGateway(t)
+	defer echo.Close()
+	var forwarded atomic.Value
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("X-Request-Id"), "pool-") {
+			forwarded.Store(r.Header.Get("X-Forwarded-For"))
+		}
+		echo.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer gw.Close()
+
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = gw.URL
+	cfg.Bridge.PingInterval = 0
+	cfg.Bridge.ClientIPHeader = "X-Forwarded-For"
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := NewHandler(cfg, New(), nil, ctx)
+	handler.StartGatewayPool(ctx, 1)
+	bridge := httptest.NewServer(handler)
+	defer bridge.Close()
+	waitFor(t, "pool to fill", func() bool { return handler.gatewayPool.idleCount() == 1 })
+
+	dialCtx, dialCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer dialCancel()
+	c, _, err := websocket.Dial(dialCtx, "ws"+strings.TrimPrefix(bridge.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.CloseNow()
+
+	// The client is accepted before the gateway dial completes.
+	waitFor(t, "gateway dial", func() bool { return forwarded.Load() != nil })
+	if got := forwarded.Load().(string); got != "127.0.0.1" {
+		t.Errorf("gateway X-Forwarded-For = %q, want 127.0.0.1 from a fresh dial", got)
+	}
+	if n := handler.gatewayPool.idleCount(); n != 1 {
+		t.Errorf("idle pooled connections = %d, want 1 (not adopted)", n)
+	}
+}
@@ -14,12 +14,24 @@ type Proxy struct {
 	// Per-IP connection tracking
 	ipConnections map[string]int
 	ipMu          sync.Mutex
+
+	// Per-class connection tracking for subprotocol limit overrides, keyed by
+	// class and by class+"\x00"+IP. Guarded by ipMu.
+	classConnections   map[string]int
+	classIPConnections map[string]int
+
+	// Per-token connection tracking (keyed by token hash, never the raw token)
+	tokenConnections map[string]int
+	tokenMu          sync.Mutex
 }
 
 // New creates a new Proxy instance.
 func New() *Proxy {
 	return &Proxy{
-		ipConnections: make(map[string]int),
+		ipConnections:      make(map[string]int),
+		classConnections:   make(map[string]int),
+		classIPConnections: make(map[string]int),
+		tokenConnections:   make(map[string]int),
 	}
 }
 
@@ -70,6 +82,82 @@ func (p *Proxy) DecrementConnections(ip string) {
 	p.ipMu.Unlock()
 }
 
+// TryIncrementClassConnections is TryIncrementConnections for a connection
+// class with its own limits (e.g. a subprotocol override). maxClass and
+// maxClassPerIP are checked against the class's counts instead of the global
+// ones; the global and per-IP counters are still incremented. Release with
+// DecrementClassConnections.
+func (p *Proxy) TryIncrementClassConnections(ip, class string, maxClass, maxClassPerIP int) string {
+	p.ipMu.Lock()
+	defer p.ipMu.Unlock()
+
+	if p.classConnections[class] >= maxClass {
+		return "max_connections"
+	}
+	ipKey := class + "\x00" + ip
+	if p.classIPConnections[ipKey] >= maxClassPerIP {
+		return "max_connections_per_ip"
+	}
+
+	p.activeConnections.Add(1)
+	p.totalConnections.Add(1)
+	p.ipConnections[ip]++
+	p.classConnections[class]++
+	p.classIPConnections[ipKey]++
+	return ""
+}
+
+// DecrementClassConnections releases a connection taken with
+// TryIncrementClassConnections.
+func (p *Proxy) DecrementClassConnections(ip, class string) {
+	p.DecrementConnections(ip)
+	p.ipMu.Lock()
+	defer p.ipMu.Unlock()
+	if p.classConnections[class]--; p.classConnections[class] <= 0 {
+		delete(p.classConnections, class)
+	}
+	ipKey := class + "\x00" + ip
+	if p.classIPConnections[ipKey]--; p.classIPConnections[ipKey] <= 0 {
+		delete(p.classIPConnections, ipKey)
+	}
+}
+
+// ConnectionCountForClass returns the active connection count for a class.
+func (p *Proxy) ConnectionCountForClass(class string) int {
+	p.ipMu.Lock()
+	defer p.ipMu.Unlock()
+	return p.classConnections[class]
+}
+
+// TryIncrementTokenConnections atomically checks the per-token limit and
+// increments the token's counter. Returns false if the limit was hit.
+func (p *Proxy) TryIncrementTokenConnections(key string, maxPerToken int) bool {
+	p.tokenMu.Lock()
+	defer p.tokenMu.Unlock()
+	if p.tokenConnections[key] >= maxPerToken {
+		return false
+	}
+	p.tokenConnections[key]++
+	return true
+}
+
+// DecrementTokenConnections decrements the per-token connection counter.
+func (p *Proxy) DecrementTokenConnections(key string) {
+	p.tokenMu.Lock()
+	p.tokenConnections[key]--
+	if p.tokenConnections[key] <= 0 {
+		delete(p.tokenConnections, key)
+	}
+	p.tokenMu.Unlock()
+}
+
+// ConnectionCountForToken returns the active connection count for a token key.
+func (p *Proxy) ConnectionCountForToken(key string) int {
+	p.tokenMu.Lock()
+	defer p.tokenMu.Unlock()
+	return p.tokenConnections[key]
+}
+
 // IncrementMessages increments the total messages counter.
 func (p *Proxy) IncrementMessages() {
 	p.totalMessages.Add(1)
@@ -152,3 +152,26 @@ func TestTryIncrementConnections(t *testing.T) {
 		t.Errorf("TryIncrementConnections() = %q, want %q", reason, "max_connections")
 	}
 }
+
+func TestTryIncrementTokenConnections(t *testing.T) {
+	p := New()
+
+	if !p.TryIncrementTokenConnections("tok-a", 2) || !p.TryIncrementTokenConnections("tok-a", 2) {
+		t.Fatal("first two connections for tok-a should succeed")
+	}
+	if p.TryIncrementTokenConnections("tok-a", 2) {
+		t.Error("third connection for tok-a should be rejected")
+	}
+	if !p.TryIncrementTokenConnections("tok-b", 2) {
+		t.Error("tok-b should be tracked independently")
+	}
+
+	p.DecrementTokenConnections("tok-a")
+	if got := p.ConnectionCountForToken("tok-a"); got != 1 {
+		t.Errorf("tok-a count = %d, want 1", got)
+	}
+	p.DecrementTokenConnections("tok-a")
+	if got := p.ConnectionCountForToken("tok-a"); got != 0 {
+		t.Errorf("tok-a count = %d, want 0", got)
+	}
+}
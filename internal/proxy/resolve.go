@@ -0,0 +1,162 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/cortexuvula/clawreachbridge/internal/config"
+)
+
+// resolveRefreshTimeout bounds a background refresh of an expired entry.
+const resolveRefreshTimeout = 10 * time.Second
+
+// gatewayResolver resolves gateway hostnames for dials so that slow or
+// failing DNS doesn't fail connections. Addresses are cached for ttl; once an
+// entry expires, dials keep using it while a single background lookup
+// refreshes it, and a failed refresh leaves the old addresses in place. Only
+// a host that has never resolved waits on DNS. The gateway_url host can be
+// pinned to a static address, skipping DNS altogether.
+type gatewayResolver struct {
+	lookup   func(ctx context.Context, host string) ([]net.IPAddr, error)
+	ttl      time.Duration
+	override map[string]string // host → static IP
+	dialer   net.Dialer
+	now      func() time.Time
+	http     *http.Client // WebSocket dials go through this client
+
+	mu    sync.Mutex
+	cache map[string]*resolvedHost
+}
+
+type resolvedHost struct {
+	ips        []string
+	expires    time.Time
+	refreshing bool
+}
+
+// newGatewayResolver returns a resolver for cfg, or nil when neither
+// bridge.gateway_resolve_ttl nor bridge.gateway_resolve_override is set.
+func newGatewayResolver(cfg *config.Config) *gatewayResolver {
+	if cfg.Bridge.GatewayResolveTTL <= 0 && cfg.Bridge.GatewayResolveOverride == "" {
+		return nil
+	}
+	g := &gatewayResolver{
+		lookup:   net.DefaultResolver.LookupIPAddr,
+		ttl:      cfg.Bridge.GatewayResolveTTL,
+		override: make(map[string]string),
+		now:      time.Now,
+		cache:    make(map[string]*resolvedHost),
+	}
+	if cfg.Bridge.GatewayResolveOverride != "" {
+		if u, err := url.Parse(cfg.Bridge.GatewayURL); err == nil {
+			g.override[u.Hostname()] = cfg.Bridge.GatewayResolveOverride
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = g.DialContext
+	g.http = &http.Client{Transport: transport}
+	return g
+}
+
+// client returns the HTTP client for WebSocket dials through g; nil (the
+// default client) when g is nil.
+func (g *gatewayResolver) client() *http.Client {
+	if g == nil {
+		return nil
+	}
+	return g.http
+}
+
+// DialContext dials addr, resolving its host through the cache and trying
+// each address in turn.
+func (g *gatewayResolver) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return g.dialer.DialContext(ctx, network, addr)
+	}
+	ips, err := g.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var firstErr error
+	for _, ip := range ips {
+		conn, err := g.dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// resolve returns the addresses for host, from the override, a fresh or
+// stale cache entry, or a blocking lookup when host has never resolved.
+func (g *gatewayResolver) resolve(ctx context.Context, host string) ([]string, error) {
+	if ip, ok := g.override[host]; ok {
+		return []string{ip}, nil
+	}
+
+	g.mu.Lock()
+	if e := g.cache[host]; e != nil {
+		ips := e.ips
+		if !g.now().Before(e.expires) && !e.refreshing {
+			e.refreshing = true
+			go g.refresh(host)
+		}
+		g.mu.Unlock()
+		return ips, nil
+	}
+	g.mu.Unlock()
+
+	ips, err := g.lookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	g.store(host, ips)
+	return ips, nil
+}
+
+func (g *gatewayResolver) refresh(host string) {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveRefreshTimeout)
+	defer cancel()
+	ips, err := g.lookupHost(ctx, host)
+	if err != nil {
+		slog.Warn("gateway DNS refresh failed, keeping cached addresses", "host", host, "error", err)
+		g.mu.Lock()
+		g.cache[host].refreshing = false
+		g.mu.Unlock()
+		return
+	}
+	g.store(host, ips)
+}
+
+func (g *gatewayResolver) store(host string, ips []string) {
+	g.mu.Lock()
+	g.cache[host] = &resolvedHost{ips: ips, expires: g.now().Add(g.ttl)}
+	g.mu.Unlock()
+}
+
+func (g *gatewayResolver) lookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, err := g.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	ips := make([]string, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP.String()
+	}
+	return ips, nil
+}
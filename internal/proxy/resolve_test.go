@@ -0,0 +1,202 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// fakeLookup is a controllable stand-in for the system resolver.
+type fakeLookup struct {
+	mu    sync.Mutex
+	ip    string
+	err   error
+	block chan struct{} // when set, lookups wait for it to close
+	calls atomic.Int32
+}
+
+func (f *fakeLookup) set(ip string, err error) {
+	f.mu.Lock()
+	f.ip, f.err = ip, err
+	f.mu.Unlock()
+}
+
+func (f *fakeLookup) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	f.calls.Add(1)
+	f.mu.Lock()
+	block := f.block
+	f.mu.Unlock()
+	if block != nil {
+		select {
+		case <-block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []net.IPAddr{{IP: net.ParseIP(f.ip)}}, nil
+}
+
+// testResolver returns a resolver with a one-minute TTL backed by f and a
+// clock the test advances by hand.
+func testResolver(f *fakeLookup) (*gatewayResolver, func(time.Duration)) {
+	cfg := testConfig()
+	cfg.Bridge.GatewayResolveTTL = time.Minute
+	g := newGatewayResolver(cfg)
+	g.lookup = f.lookup
+	var clock atomic.Int64
+	clock.Store(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC).UnixNano())
+	g.now = func() time.Time { return time.Unix(0, clock.Load()) }
+	return g, func(d time.Duration) { clock.Add(int64(d)) }
+}
+
+func resolveOne(t *testing.T, g *gatewayResolver) string {
+	t.Helper()
+	ips, err := g.resolve(context.Background(), "gateway.test")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	return ips[0]
+}
+
+func TestGatewayResolverCachesAndRefreshes(t *testing.T) {
+	f := &fakeLookup{ip: "10.0.0.1"}
+	g, advance := testResolver(f)
+
+	resolveOne(t, g)
+	if got := resolveOne(t, g); got != "10.0.0.1" {
+		t.Fatalf("resolve = %s, want 10.0.0.1", got)
+	}
+	if n := f.calls.Load(); n != 1 {
+		t.Fatalf("lookups within TTL = %d, want 1", n)
+	}
+
+	// After expiry the old address is served while a refresh picks up the new one.
+	f.set("10.0.0.2", nil)
+	advance(2 * time.Minute)
+	if got := resolveOne(t, g); got != "10.0.0.1" {
+		t.Fatalf("resolve after expiry = %s, want stale 10.0.0.1", got)
+	}
+	waitFor(t, "refreshed address", func() bool { return resolveOne(t, g) == "10.0.0.2" })
+	if n := f.calls.Load(); n != 2 {
+		t.Errorf("lookups = %d, want 2", n)
+	}
+}
+
+func TestGatewayResolverKeepsStaleOnFailure(t *testing.T) {
+	f := &fakeLookup{ip: "10.0.0.1"}
+	g, advance := testResolver(f)
+	resolveOne(t, g)
+
+	f.set("", errors.New("SERVFAIL"))
+	advance(2 * time.Minute)
+	if got := resolveOne(t, g); got != "10.0.0.1" {
+		t.Fatalf("resolve = %s, want stale 10.0.0.1", got)
+	}
+	waitFor(t, "failed refresh", func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return f.calls.Load() == 2 && !g.cache["gateway.test"].refreshing
+	})
+
+	// The stale entry stays in use, and the next dial retries the refresh.
+	if got := resolveOne(t, g); got != "10.0.0.1" {
+		t.Fatalf("resolve after failed refresh = %s, want stale 10.0.0.1", got)
+	}
+	waitFor(t, "retried refresh", func() bool { return f.calls.Load() == 3 })
+}
+
+func TestGatewayResolverSlowRefreshDoesNotBlock(t *testing.T) {
+	f := &fakeLookup{ip: "10.0.0.1"}
+	g, advance := testResolver(f)
+	resolveOne(t, g)
+
+	block := make(chan struct{})
+	f.mu.Lock()
+	f.block = block
+	f.ip = "10.0.0.2"
+	f.mu.Unlock()
+	advance(2 * time.Minute)
+
+	start := time.Now()
+	for range 5 {
+		if got := resolveOne(t, g); got != "10.0.0.1" {
+			t.Fatalf("resolve during slow refresh = %s, want stale 10.0.0.1", got)
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("resolve waited %v on a slow refresh", d)
+	}
+	waitFor(t, "refresh started", func() bool { return f.calls.Load() == 2 })
+
+	close(block)
+	waitFor(t, "refreshed address", func() bool { return resolveOne(t, g) == "10.0.0.2" })
+	if n := f.calls.Load(); n != 2 {
+		t.Errorf("lookups = %d, want 2 (one refresh for all concurrent dials)", n)
+	}
+}
+
+func TestGatewayResolverFirstLookupFails(t *testing.T) {
+	f := &fakeLookup{err: errors.New("no such host")}
+	g, _ := testResolver(f)
+	if _, err := g.resolve(context.Background(), "gateway.test"); err == nil {
+		t.Fatal("resolve succeeded with nothing cached and DNS failing")
+	}
+	// Failures aren't cached.
+	f.set("10.0.0.1", nil)
+	if got := resolveOne(t, g); got != "10.0.0.1" {
+		t.Errorf("resolve after recovery = %s, want 10.0.0.1", got)
+	}
+}
+
+func TestGatewayResolverDisabled(t *testing.T) {
+	if g := newGatewayResolver(testConfig()); g != nil {
+		t.Error("resolver created with no TTL or override")
+	}
+}
+
+func TestHandlerGatewayResolveOverride(t *testing.T) {
+	gw := echoGateway(t)
+	defer gw.Close()
+	_, port, _ := net.SplitHostPort(gw.Listener.Addr().String())
+
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = "http://gateway.test:" + port // not resolvable
+	cfg.Bridge.GatewayResolveOverride = "127.0.0.1"
+	cfg.Bridge.PingInterval = 0
+	handler := NewHandler(cfg, New(), nil, context.Background())
+	f := &fakeLookup{err: errors.New("DNS should not be consulted")}
+	handler.resolver.lookup = f.lookup
+	bridge := httptest.NewServer(handler)
+	defer bridge.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(bridge.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.CloseNow()
+	if err := c.Write(ctx, websocket.MessageText, []byte("ping")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, msg, err := c.Read(ctx)
+	if err != nil || string(msg) != "ping" {
+		t.Fatalf("echo = %q, %v", msg, err)
+	}
+	if n := f.calls.Load(); n != 0 {
+		t.Errorf("DNS lookups = %d, want 0 with an override", n)
+	}
+}
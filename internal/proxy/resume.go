@@ -0,0 +1,255 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+const (
+	// resumeTokenHeader carries the client's resume token; browsers, which
+	// can't set WebSocket headers, use the resume_token query parameter.
+	resumeTokenHeader = "X-Resume-Token"
+
+	// resumedHeader is set on the upgrade response when the connection
+	// picked up a parked gateway session.
+	resumedHeader = "X-Resumed"
+
+	// minResumeTokenLen keeps guessable tokens from naming a session.
+	minResumeTokenLen = 16
+)
+
+// resumeToken returns the client's resume token, or "" if it sent none or
+// one too short to be used.
+func resumeToken(r *http.Request) string {
+	token := r.Header.Get(resumeTokenHeader)
+	if token == "" {
+		token = r.URL.Query().Get("resume_token")
+	}
+	if len(token) < minResumeTokenLen {
+		return ""
+	}
+	return token
+}
+
+// resumeOwner returns the key a parked session is bound to: the hash of the
+// client's auth token or, for clients without one, of its IP address, so a
+// leaked resume token alone can't pick up someone else's session.
+func resumeOwner(authToken, clientIP string) string {
+	if authToken != "" {
+		return tokenHash(authToken)
+	}
+	return tokenHash("ip:" + clientIP)
+}
+
+// acceptingResumes reports whether dropped sessions may be parked: not
+// once the bridge is draining, shutting down, or in maintenance.
+func (h *Handler) acceptingResumes() bool {
+	return h.drainCtx.Err() == nil && h.ShutdownCtx.Err() == nil && !h.maintenance.Load()
+}
+
+// parkedSession is the gateway side of a client connection that dropped.
+// Its gateway connection stays open and what the gateway sends is buffered
+// until the client resumes or the session expires.
+type parkedSession struct {
+	pc    *pooledConn
+	owner string // resumeOwner of the connection that parked it
+
+	stop  context.CancelFunc
+	done  chan struct{} // closed when the buffering goroutine exits
+	timer *time.Timer
+
+	// Written only by the buffering goroutine until done is closed.
+	buf   []pumpedMessage
+	bytes int64
+}
+
+// resumeStore holds parked sessions keyed by resume token.
+type resumeStore struct {
+	mu       sync.Mutex
+	sessions map[string]*parkedSession
+}
+
+func newResumeStore() *resumeStore {
+	return &resumeStore{sessions: make(map[string]*parkedSession)}
+}
+
+// park keeps pc open for window, buffering up to maxBytes of gateway
+// messages for a client that resumes with token. unsent holds messages the
+// dropped client never received; they are replayed first. The session is
+// closed when the window passes, the buffer overflows, or the gateway
+// closes. Sessions beyond maxSessions are not parked and pc is closed.
+func (rs *resumeStore) park(token, owner string, pc *pooledConn, unsent []pumpedMessage, window time.Duration, maxBytes int64, maxSessions int) {
+	ctx, stop := context.WithCancel(context.Background())
+	ps := &parkedSession{pc: pc, owner: owner, stop: stop, done: make(chan struct{}), buf: unsent}
+	for _, m := range unsent {
+		ps.bytes += int64(len(m.data))
+	}
+	if ps.bytes > maxBytes {
+		stop()
+		pc.close()
+		slog.Warn("resume: undelivered messages exceed the resume buffer, closing gateway connection", "request_id", pc.reqID, "bytes", ps.bytes)
+		return
+	}
+
+	rs.mu.Lock()
+	old := rs.sessions[token]
+	if old == nil && maxSessions > 0 && len(rs.sessions) >= maxSessions {
+		rs.mu.Unlock()
+		stop()
+		pc.close()
+		slog.Warn("resume: too many parked sessions, closing gateway connection", "request_id", pc.reqID, "parked", maxSessions)
+		return
+	}
+	rs.sessions[token] = ps
+	ps.timer = time.AfterFunc(window, func() { rs.discard(token, ps, "resume window expired") })
+	rs.mu.Unlock()
+	if old != nil {
+		old.close()
+	}
+
+	go func() {
+		defer close(ps.done)
+		for {
+			typ, r, err := pc.Reader(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					go rs.discard(token, ps, "gateway closed while parked")
+				}
+				return
+			}
+			data, _ := io.ReadAll(r)
+			if ps.bytes+int64(len(data)) > maxBytes {
+				go rs.discard(token, ps, "resume buffer full")
+				return
+			}
+			ps.buf = append(ps.buf, pumpedMessage{typ: typ, data: data})
+			ps.bytes += int64(len(data))
+		}
+	}()
+}
+
+// take removes and returns the session parked under token if owner opened
+// it, with its buffering stopped. It returns nil if there is none.
+func (rs *resumeStore) take(token, owner string) *parkedSession {
+	rs.mu.Lock()
+	ps := rs.sessions[token]
+	if ps == nil || ps.owner != owner {
+		rs.mu.Unlock()
+		return nil
+	}
+	delete(rs.sessions, token)
+	rs.mu.Unlock()
+
+	ps.timer.Stop()
+	ps.stop()
+	<-ps.done
+	if !ps.pc.alive() {
+		ps.pc.close()
+		return nil
+	}
+	return ps
+}
+
+// discard closes ps if it is still parked under token.
+func (rs *resumeStore) discard(token string, ps *parkedSession, reason string) {
+	rs.mu.Lock()
+	if rs.sessions[token] != ps {
+		rs.mu.Unlock()
+		return
+	}
+	delete(rs.sessions, token)
+	rs.mu.Unlock()
+	slog.Info("resume: discarding parked session", "request_id", ps.pc.reqID, "reason", reason)
+	ps.close()
+}
+
+// closeAll closes every parked session, e.g. when the bridge starts
+// draining and no client should resume onto it.
+func (rs *resumeStore) closeAll(reason string) {
+	rs.mu.Lock()
+	sessions := rs.sessions
+	rs.sessions = make(map[string]*parkedSession)
+	rs.mu.Unlock()
+	for _, ps := range sessions {
+		slog.Info("resume: discarding parked session", "request_id", ps.pc.reqID, "reason", reason)
+		ps.close()
+	}
+}
+
+func (ps *parkedSession) close() {
+	ps.timer.Stop()
+	ps.stop()
+	<-ps.done
+	ps.pc.close()
+}
+
+// count returns the number of parked sessions.
+func (rs *resumeStore) count() int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return len(rs.sessions)
+}
+
+// replaySource yields buffered messages before reading from src.
+type replaySource struct {
+	buf []pumpedMessage
+	src messageSource
+}
+
+func (rs *replaySource) Reader(ctx context.Context) (websocket.MessageType, io.Reader, error) {
+	if len(rs.buf) > 0 {
+		m := rs.buf[0]
+		rs.buf = rs.buf[1:]
+		return m.typ, bytes.NewReader(m.data), nil
+	}
+	return rs.src.Reader(ctx)
+}
+
+// replaySink writes each message to dst in one piece when it is closed. A
+// message whose write fails is kept, with the error, so it can be replayed
+// to the client if the session is parked; later writes fail with that error.
+type replaySink struct {
+	dst    messageSink
+	err    error
+	unsent *pumpedMessage
+}
+
+func (rs *replaySink) Writer(ctx context.Context, typ websocket.MessageType) (io.WriteCloser, error) {
+	if rs.err != nil {
+		return nil, rs.err
+	}
+	return &replayMessage{sink: rs, ctx: ctx, typ: typ}, nil
+}
+
+// replayMessage collects one message for a replaySink.
+type replayMessage struct {
+	sink *replaySink
+	ctx  context.Context
+	typ  websocket.MessageType
+	buf  bytes.Buffer
+}
+
+func (m *replayMessage) Write(p []byte) (int, error) {
+	return m.buf.Write(p)
+}
+
+func (m *replayMessage) Close() error {
+	w, err := m.sink.dst.Writer(m.ctx, m.typ)
+	if err == nil {
+		if _, err = w.Write(m.buf.Bytes()); err == nil {
+			err = w.Close()
+		}
+	}
+	if err != nil {
+		m.sink.err = err
+		m.sink.unsent = &pumpedMessage{typ: m.typ, data: m.buf.Bytes()}
+	}
+	return err
+}
@@ -0,0 +1,303 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+const testResumeToken = "resume-0123456789abcdef"
+
+// resumeGateway echoes messages, except "later", which it answers with two
+// messages after delay. It counts the connections it accepts.
+func resumeGateway(t *testing.T, delay time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var accepts atomic.Int32
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+		if err != nil {
+			return
+		}
+		accepts.Add(1)
+		defer c.CloseNow()
+		ctx := context.Background()
+		for {
+			typ, data, err := c.Read(ctx)
+			if err != nil {
+				return
+			}
+			if string(data) != "later" {
+				if err := c.Write(ctx, typ, data); err != nil {
+					return
+				}
+				continue
+			}
+			go func() {
+				time.Sleep(delay)
+				c.Write(ctx, websocket.MessageText, []byte("after-drop-1"))
+				c.Write(ctx, websocket.MessageText, []byte("after-drop-2"))
+			}()
+		}
+	}))
+	t.Cleanup(gw.Close)
+	return gw, &accepts
+}
+
+func setupResumeBridge(t *testing.T, gwURL string, window time.Duration) (*httptest.Server, *Handler) {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = gwURL
+	cfg.Bridge.Resume.Enabled = true
+	cfg.Bridge.Resume.Window = window
+	handler := NewHandler(cfg, New(), nil, context.Background())
+	bridge := httptest.NewServer(handler)
+	t.Cleanup(bridge.Close)
+	return bridge, handler
+}
+
+func dialResume(ctx context.Context, t *testing.T, bridgeURL string) (*websocket.Conn, *http.Response) {
+	t.Helper()
+	c, resp, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(bridgeURL, "http"), &websocket.DialOptions{
+		HTTPHeader: http.Header{resumeTokenHeader: {testResumeToken}},
+	})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	return c, resp
+}
+
+func TestResumeReplaysWithinWindow(t *testing.T) {
+	gw, accepts := resumeGateway(t, 200*time.Millisecond)
+	bridge, handler := setupResumeBridge(t, gw.URL, 10*time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, resp := dialResume(ctx, t, bridge.URL)
+	if resp.Header.Get(resumedHeader) != "" {
+		t.Errorf("first connection has %s set", resumedHeader)
+	}
+	if err := c.Write(ctx, websocket.MessageText, []byte("later")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	c.CloseNow() // drop without a close handshake, as a mobile client would
+
+	waitFor(t, "session parked", func() bool { return handler.resumes.count() == 1 })
+	time.Sleep(400 * time.Millisecond) // the gateway answers while the client is away
+
+	c, resp = dialResume(ctx, t, bridge.URL)
+	defer c.CloseNow()
+	if got := resp.Header.Get(resumedHeader); got != "true" {
+		t.Errorf("%s = %q, want true", resumedHeader, got)
+	}
+	for _, want := range []string{"after-drop-1", "after-drop-2"} {
+		_, data, err := c.Read(ctx)
+		if err != nil {
+			t.Fatalf("read replayed message: %v", err)
+		}
+		if string(data) != want {
+			t.Fatalf("replayed %q, want %q", data, want)
+		}
+	}
+	if err := c.Write(ctx, websocket.MessageText, []byte("still here")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, data, err := c.Read(ctx); err != nil || string(data) != "still here" {
+		t.Fatalf("echo after resume = %q, %v", data, err)
+	}
+	if got := accepts.Load(); got != 1 {
+		t.Errorf("gateway accepted %d connections, want 1", got)
+	}
+	if got := handler.resumes.count(); got != 0 {
+		t.Errorf("parked sessions = %d after resume, want 0", got)
+	}
+}
+
+func TestResumeExpiresAfterWindow(t *testing.T) {
+	gw, accepts := resumeGateway(t, 0)
+	bridge, handler := setupResumeBridge(t, gw.URL, 100*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, _ := dialResume(ctx, t, bridge.URL)
+	if err := c.Write(ctx, websocket.MessageText, []byte("hello")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, _, err := c.Read(ctx); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	c.CloseNow()
+
+	waitFor(t, "session parked", func() bool { return handler.resumes.count() == 1 })
+	waitFor(t, "session expired", func() bool { return handler.resumes.count() == 0 })
+
+	c, resp := dialResume(ctx, t, bridge.URL)
+	defer c.CloseNow()
+	if got := resp.Header.Get(resumedHeader); got != "" {
+		t.Errorf("%s = %q after the window expired, want unset", resumedHeader, got)
+	}
+	if err := c.Write(ctx, websocket.MessageText, []byte("hello")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, data, err := c.Read(ctx); err != nil || string(data) != "hello" {
+		t.Fatalf("echo = %q, %v", data, err)
+	}
+	if got := accepts.Load(); got != 2 {
+		t.Errorf("gateway accepted %d connections, want 2 (expired session redialed)", got)
+	}
+}
+
+func TestResumeRequiresSameAuthToken(t *testing.T) {
+	rs := newResumeStore()
+	gw, _ := resumeGateway(t, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(gw.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial gateway: %v", err)
+	}
+	rs.park(testResumeToken, tokenHash("alice"), newPooledConn(conn, "", "", ""), nil, time.Minute, 1024, 0)
+
+	if ps := rs.take(testResumeToken, tokenHash("mallory")); ps != nil {
+		t.Fatal("session taken with a different auth token")
+	}
+	ps := rs.take(testResumeToken, tokenHash("alice"))
+	if ps == nil {
+		t.Fatal("session not taken by its owner")
+	}
+	ps.pc.close()
+}
+
+func TestResumeBindsToClientIPWithoutAuthToken(t *testing.T) {
+	if resumeOwner("", "100.64.0.1") == resumeOwner("", "100.64.0.2") {
+		t.Error("tokenless clients on different IPs share a resume owner")
+	}
+	if resumeOwner("alice", "100.64.0.1") != resumeOwner("alice", "100.64.0.2") {
+		t.Error("an auth token's resume owner should not depend on the client IP")
+	}
+
+	rs := newResumeStore()
+	gw, _ := resumeGateway(t, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(gw.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial gateway: %v", err)
+	}
+	rs.park(testResumeToken, resumeOwner("", "100.64.0.1"), newPooledConn(conn, "", "", ""), nil, time.Minute, 1024, 0)
+	if ps := rs.take(testResumeToken, resumeOwner("", "100.64.0.2")); ps != nil {
+		t.Fatal("session taken from a different IP")
+	}
+	ps := rs.take(testResumeToken, resumeOwner("", "100.64.0.1"))
+	if ps == nil {
+		t.Fatal("session not taken from its own IP")
+	}
+	ps.pc.close()
+}
+
+func TestResumeParkedSessionsClosedOnDrainAndMaintenance(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		start func(h *Handler)
+	}{
+		{"drain", func(h *Handler) { h.StartDrain() }},
+		{"maintenance", func(h *Handler) { h.SetMaintenance(true) }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			gw, _ := resumeGateway(t, 0)
+			bridge, handler := setupResumeBridge(t, gw.URL, time.Minute)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			c, _ := dialResume(ctx, t, bridge.URL)
+			c.CloseNow()
+			waitFor(t, "session parked", func() bool { return handler.resumes.count() == 1 })
+
+			tt.start(handler)
+			if n := handler.resumes.count(); n != 0 {
+				t.Errorf("parked sessions = %d, want 0", n)
+			}
+		})
+	}
+}
+
+func TestResumeParkedSessionsClosedOnShutdown(t *testing.T) {
+	gw, _ := resumeGateway(t, 0)
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = gw.URL
+	cfg.Bridge.Resume.Enabled = true
+	cfg.Bridge.Resume.Window = time.Minute
+	shutdownCtx, shutdown := context.WithCancel(context.Background())
+	defer shutdown()
+	handler := NewHandler(cfg, New(), nil, shutdownCtx)
+	bridge := httptest.NewServer(handler)
+	defer bridge.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _ := dialResume(ctx, t, bridge.URL)
+	c.CloseNow()
+	waitFor(t, "session parked", func() bool { return handler.resumes.count() == 1 })
+
+	shutdown()
+	waitFor(t, "parked session closed", func() bool { return handler.resumes.count() == 0 })
+}
+
+// failingSink fails every write.
+type failingSink struct{}
+
+func (failingSink) Writer(context.Context, websocket.MessageType) (io.WriteCloser, error) {
+	return nil, errors.New("client gone")
+}
+
+func TestReplaySinkKeepsFailedMessage(t *testing.T) {
+	rs := &replaySink{dst: failingSink{}}
+	w, err := rs.Writer(context.Background(), websocket.MessageText)
+	if err != nil {
+		t.Fatalf("Writer: %v", err)
+	}
+	io.WriteString(w, "in flight")
+	if err := w.Close(); err == nil {
+		t.Fatal("Close succeeded on a failing sink")
+	}
+	if rs.unsent == nil || string(rs.unsent.data) != "in flight" {
+		t.Fatalf("unsent = %+v, want the in-flight message", rs.unsent)
+	}
+	if _, err := rs.Writer(context.Background(), websocket.MessageText); err == nil {
+		t.Error("Writer succeeded after a failed write")
+	}
+}
+
+func TestSendBufferKeepsUnsentMessages(t *testing.T) {
+	gw, _ := resumeGateway(t, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(gw.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	conn.CloseNow() // every write now fails
+
+	sb := newSendBuffer(ctx, conn, 1<<20, time.Second)
+	for _, msg := range []string{"one", "two"} {
+		w, err := sb.Writer(ctx, websocket.MessageText)
+		if err != nil {
+			break // the first write already failed
+		}
+		io.WriteString(w, msg)
+		w.Close()
+	}
+	sb.close()
+
+	unsent := sb.unsent()
+	if len(unsent) == 0 || string(unsent[0].data) != "one" {
+		t.Fatalf("unsent = %d messages, want the failed message \"one\" first", len(unsent))
+	}
+}
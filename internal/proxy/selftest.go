@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coder/websocket"
+	"github.com/cortexuvula/clawreachbridge/internal/config"
+)
+
+// SelfTest dials the gateway over WebSocket exactly as a proxied connection
+// would (same Origin header, allowed subprotocols offered) and round-trips a
+// ping. It catches Origin and subprotocol misconfigurations that a plain HTTP
+// health check cannot.
+func SelfTest(ctx context.Context, cfg *config.Config) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Bridge.DialTimeout)
+	defer cancel()
+
+	conn, err := dialGateway(ctx, cfg, newGatewayResolver(cfg).client(), "/", "selftest-"+newConnID(), "", cfg.Bridge.AllowedSubprotocols)
+	if err != nil {
+		return fmt.Errorf("dialing gateway %s: %w", httpToWS(config.RedactURL(cfg.Bridge.GatewayURL)), err)
+	}
+	defer conn.CloseNow()
+
+	// Ping needs a concurrent reader to receive the pong. The gateway may
+	// send events (e.g. a connect challenge) immediately, so discard them.
+	go func() {
+		for {
+			if _, _, err := conn.Read(ctx); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := conn.Ping(ctx); err != nil {
+		return fmt.Errorf("gateway ping: %w", err)
+	}
+	conn.Close(websocket.StatusNormalClosure, "selftest complete")
+	return nil
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coder/websocket"
+)
+
+func TestSelfTestWorkingGateway(t *testing.T) {
+	gw := echoGateway(t)
+	defer gw.Close()
+
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = gw.URL
+
+	if err := SelfTest(context.Background(), cfg); err != nil {
+		t.Fatalf("SelfTest() error = %v, want nil", err)
+	}
+}
+
+func TestSelfTestGatewayUnreachable(t *testing.T) {
+	cfg := testConfig() // points at 127.0.0.1:19999
+
+	if err := SelfTest(context.Background(), cfg); err == nil {
+		t.Fatal("SelfTest() error = nil, want dial failure")
+	}
+}
+
+func TestSelfTestOriginRejected(t *testing.T) {
+	// Gateway that only accepts a specific Origin, like OpenClaw does.
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "https://expected.example" {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		c, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+		if err != nil {
+			return
+		}
+		defer c.CloseNow()
+		c.Read(r.Context())
+	}))
+	defer gw.Close()
+
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = gw.URL
+	cfg.Bridge.Origin.Default = "https://wrong.example"
+
+	if err := SelfTest(context.Background(), cfg); err == nil {
+		t.Fatal("SelfTest() error = nil, want rejection for wrong Origin")
+	}
+
+	cfg.Bridge.Origin.Default = "https://expected.example"
+	if err := SelfTest(context.Background(), cfg); err != nil {
+		t.Fatalf("SelfTest() with correct Origin error = %v", err)
+	}
+}
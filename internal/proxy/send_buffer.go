@@ -0,0 +1,188 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// errSlowConsumer stops gateway→client forwarding once more than
+// bridge.client_buffer_high_water bytes are waiting for the client.
+var errSlowConsumer = errors.New("client send buffer over high-water mark")
+
+// messageSink is the write side used by forwardMessages. *websocket.Conn
+// satisfies it directly; clients with a send buffer are written through it.
+type messageSink interface {
+	Writer(ctx context.Context, typ websocket.MessageType) (io.WriteCloser, error)
+}
+
+// sendBuffer queues messages for a client and writes them from its own
+// goroutine, so a slow client holds bytes in a bounded queue instead of
+// stalling gateway reads. Bytes being queued, queued, or being written count
+// against highWater; going past it fails the write with errSlowConsumer.
+type sendBuffer struct {
+	conn         *websocket.Conn
+	highWater    int64
+	writeTimeout time.Duration
+
+	mu      sync.Mutex
+	queue   []pumpedMessage
+	pending int64
+	err     error // first write error or errSlowConsumer; the buffer is dead once set
+	closed  bool
+
+	wake chan struct{}
+	done chan struct{} // closed when the writer goroutine exits
+}
+
+func newSendBuffer(ctx context.Context, conn *websocket.Conn, highWater int64, writeTimeout time.Duration) *sendBuffer {
+	sb := &sendBuffer{
+		conn:         conn,
+		highWater:    highWater,
+		writeTimeout: writeTimeout,
+		wake:         make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+	go sb.run(ctx)
+	return sb
+}
+
+// Writer returns a writer whose bytes are queued as one message on Close.
+// ctx is unused: queueing never blocks.
+func (sb *sendBuffer) Writer(_ context.Context, typ websocket.MessageType) (io.WriteCloser, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	if sb.err != nil {
+		return nil, sb.err
+	}
+	return &bufferedMessage{sb: sb, typ: typ}, nil
+}
+
+func (sb *sendBuffer) reserve(n int64) error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	if sb.err != nil {
+		return sb.err
+	}
+	if sb.pending+n > sb.highWater {
+		sb.err = errSlowConsumer
+		sb.queue = nil
+		return sb.err
+	}
+	sb.pending += n
+	return nil
+}
+
+func (sb *sendBuffer) enqueue(m pumpedMessage) error {
+	sb.mu.Lock()
+	if sb.err != nil {
+		sb.mu.Unlock()
+		return sb.err
+	}
+	sb.queue = append(sb.queue, m)
+	sb.mu.Unlock()
+	sb.signal()
+	return nil
+}
+
+func (sb *sendBuffer) signal() {
+	select {
+	case sb.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run writes queued messages in order until the buffer fails, ctx ends, or
+// the buffer is closed and empty. Each write is bounded by writeTimeout.
+func (sb *sendBuffer) run(ctx context.Context) {
+	defer close(sb.done)
+	for {
+		sb.mu.Lock()
+		if sb.err != nil || (sb.closed && len(sb.queue) == 0) {
+			sb.mu.Unlock()
+			return
+		}
+		if len(sb.queue) == 0 {
+			sb.mu.Unlock()
+			select {
+			case <-sb.wake:
+				continue
+			case <-ctx.Done():
+				sb.fail(ctx.Err())
+				return
+			}
+		}
+		m := sb.queue[0]
+		sb.queue[0] = pumpedMessage{}
+		sb.queue = sb.queue[1:]
+		sb.mu.Unlock()
+
+		writeCtx, cancel := context.WithTimeout(ctx, sb.writeTimeout)
+		err := sb.conn.Write(writeCtx, m.typ, m.data)
+		cancel()
+
+		sb.mu.Lock()
+		sb.pending -= int64(len(m.data))
+		if err != nil {
+			// Keep the message for unsent: the client may not have got it.
+			sb.queue = append([]pumpedMessage{m}, sb.queue...)
+		}
+		sb.mu.Unlock()
+		if err != nil {
+			sb.fail(err)
+			return
+		}
+	}
+}
+
+// fail marks the buffer dead. Queued messages are kept for unsent.
+func (sb *sendBuffer) fail(err error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	if sb.err == nil {
+		sb.err = err
+	}
+}
+
+// unsent returns the messages that were queued but not written when the
+// buffer stopped, oldest first, for replay to a resuming client. It is only
+// meaningful once the writer goroutine has exited.
+func (sb *sendBuffer) unsent() []pumpedMessage {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.queue
+}
+
+// close stops accepting messages and waits until the queued ones have been
+// written, a write fails, or the context passed to newSendBuffer ends.
+func (sb *sendBuffer) close() {
+	sb.mu.Lock()
+	sb.closed = true
+	sb.mu.Unlock()
+	sb.signal()
+	<-sb.done
+}
+
+// bufferedMessage collects one message for a sendBuffer, reserving room for
+// its bytes as they are written.
+type bufferedMessage struct {
+	sb  *sendBuffer
+	typ websocket.MessageType
+	buf bytes.Buffer
+}
+
+func (m *bufferedMessage) Write(p []byte) (int, error) {
+	if err := m.sb.reserve(int64(len(p))); err != nil {
+		return 0, err
+	}
+	return m.buf.Write(p)
+}
+
+func (m *bufferedMessage) Close() error {
+	return m.sb.enqueue(pumpedMessage{typ: m.typ, data: m.buf.Bytes()})
+}
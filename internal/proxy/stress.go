@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cortexuvula/clawreachbridge/internal/config"
+)
+
+// StressReload hammers h's config swap for d: readers goroutines call
+// GetConfig and walk the snapshot (slices and maps included) through the
+// same helpers the request path uses, while one writer cycles UpdateConfig
+// through configs and keeps editing each config after publishing it, as the
+// web UI and SIGHUP paths may. configs are cloned first and never modified.
+// It is meant to be run under -race and returns how many reads and swaps ran.
+func StressReload(h *Handler, configs []*config.Config, readers int, d time.Duration) (reads, swaps int64) {
+	if len(configs) == 0 || readers < 1 {
+		return 0, 0
+	}
+	var nReads, nSwaps atomic.Int64
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				cfg := h.GetConfig()
+				_ = h.isPublicPath("/__openclaw__/a2ui/index.html")
+				_ = h.shouldInjectMedia("/ws")
+				_ = cfg.Bridge.Origin.For("/ws/operator")
+				_ = cfg.Bridge.WriteTimeoutFor("/ws/operator")
+				_, _, _ = cfg.Security.ConnectionLimitsFor("")
+				_ = gatewaySubprotocols(cfg, nil)
+				_ = slices.Contains(cfg.Bridge.AllowedSubprotocols, "stress")
+				_ = slices.Contains(cfg.Bridge.StripResponseHeaders, "X-Stress")
+				nReads.Add(1)
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		deadline := time.Now().Add(d)
+		for i := 0; time.Now().Before(deadline); i++ {
+			cfg := configs[i%len(configs)].Clone()
+			h.UpdateConfig(cfg)
+			nSwaps.Add(1)
+
+			// Edit the published config in place; readers must not see it.
+			key := "/stress/" + strconv.Itoa(i%8)
+			if cfg.Bridge.Origin.Routes == nil {
+				cfg.Bridge.Origin.Routes = map[string]string{}
+			}
+			cfg.Bridge.Origin.Routes[key] = "https://stress.invalid"
+			if cfg.Bridge.WriteTimeoutByPath == nil {
+				cfg.Bridge.WriteTimeoutByPath = map[string]time.Duration{}
+			}
+			cfg.Bridge.WriteTimeoutByPath[key] = time.Second
+			if cfg.Security.MaxConnectionsBySubprotocol == nil {
+				cfg.Security.MaxConnectionsBySubprotocol = map[string]config.ConnectionLimits{}
+			}
+			cfg.Security.MaxConnectionsBySubprotocol[""] = config.ConnectionLimits{MaxConnections: i%100 + 1}
+			for j := range cfg.Security.PublicPaths {
+				cfg.Security.PublicPaths[j] = key
+			}
+			for j := range cfg.Bridge.Media.InjectPaths {
+				cfg.Bridge.Media.InjectPaths[j] = key
+			}
+			for j := range cfg.Bridge.StripResponseHeaders {
+				cfg.Bridge.StripResponseHeaders[j] = "X-Stress"
+			}
+			for j := range cfg.Bridge.GatewaySubprotocols {
+				cfg.Bridge.GatewaySubprotocols[j] = "stress"
+			}
+			cfg.Security.MaxConnections = i
+		}
+		close(stop)
+	}()
+
+	wg.Wait()
+	return nReads.Load(), nSwaps.Load()
+}
@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cortexuvula/clawreachbridge/internal/config"
+)
+
+func TestStressReload(t *testing.T) {
+	a := testConfig()
+	a.Security.PublicPaths = []string{"/__openclaw__/a2ui/"}
+	a.Bridge.Media.InjectPaths = []string{"/ws"}
+	a.Bridge.StripResponseHeaders = []string{"Server"}
+	a.Bridge.GatewaySubprotocols = []string{"openclaw.v1"}
+	a.Bridge.Origin.Routes = map[string]string{"/ws/operator": "https://operator.example"}
+
+	b := a.Clone()
+	b.Security.PublicPaths = append(b.Security.PublicPaths, "/public/")
+	b.Bridge.WriteTimeoutByPath = map[string]time.Duration{"/ws/operator": time.Minute}
+	b.Security.MaxConnectionsBySubprotocol = map[string]config.ConnectionLimits{"": {MaxConnections: 5}}
+
+	h := NewHandler(a, New(), nil, context.Background())
+	reads, swaps := StressReload(h, []*config.Config{a, b}, 8, 200*time.Millisecond)
+	if reads == 0 || swaps == 0 {
+		t.Fatalf("reads = %d, swaps = %d, want both > 0", reads, swaps)
+	}
+
+	// The inputs are cloned, never edited.
+	if len(a.Security.PublicPaths) != 1 || a.Security.PublicPaths[0] != "/__openclaw__/a2ui/" {
+		t.Errorf("input config modified: public_paths = %v", a.Security.PublicPaths)
+	}
+	if len(a.Bridge.Origin.Routes) != 1 {
+		t.Errorf("input config modified: origin routes = %v", a.Bridge.Origin.Routes)
+	}
+}
+
+func TestUpdateConfigCopiesOnWrite(t *testing.T) {
+	cfg := testConfig()
+	cfg.Security.PublicPaths = []string{"/public/"}
+	h := NewHandler(testConfig(), New(), nil, context.Background())
+
+	h.UpdateConfig(cfg)
+	cfg.Security.PublicPaths[0] = "/edited/"
+	cfg.Security.MaxConnections = 1
+
+	got := h.GetConfig()
+	if got == cfg {
+		t.Fatal("UpdateConfig published the caller's pointer")
+	}
+	if got.Security.PublicPaths[0] != "/public/" || got.Security.MaxConnections == 1 {
+		t.Errorf("published config changed after UpdateConfig: public_paths = %v, max_connections = %d",
+			got.Security.PublicPaths, got.Security.MaxConnections)
+	}
+}
@@ -17,10 +17,12 @@ import (
 type SyncUpstreamInspector struct {
 	ctx        context.Context
 	clientConn *websocket.Conn
-	store      *chatsync.MessageStore
+	store      chatsync.Store
 	registry   *chatsync.ClientRegistry
 	clientID   string
 
+	maxResponseBytes int64 // 0 = unlimited
+
 	mu         sync.Mutex
 	sessionKey string
 }
@@ -29,7 +31,7 @@ type SyncUpstreamInspector struct {
 func NewSyncUpstreamInspector(
 	ctx context.Context,
 	clientConn *websocket.Conn,
-	store *chatsync.MessageStore,
+	store chatsync.Store,
 	registry *chatsync.ClientRegistry,
 	clientID string,
 ) *SyncUpstreamInspector {
@@ -42,6 +44,12 @@ func NewSyncUpstreamInspector(
 	}
 }
 
+// SetMaxResponseBytes caps the size of sessions.history responses written to
+// the client. Older messages are trimmed to fit. Call before use.
+func (s *SyncUpstreamInspector) SetMaxResponseBytes(n int64) {
+	s.maxResponseBytes = n
+}
+
 // SessionKey returns the discovered session key (empty if not yet known).
 func (s *SyncUpstreamInspector) SessionKey() string {
 	s.mu.Lock()
@@ -118,7 +126,7 @@ func (s *SyncUpstreamInspector) handleChatSend(payload []byte) []byte {
 	s.store.Append(sk, msg)
 
 	echo := buildUserEcho(req.Params.IdempotencyKey, req.Params.Message)
-	go s.registry.Broadcast(s.ctx, sk, s.clientID, echo)
+	s.registry.BroadcastOrdered(s.ctx, sk, s.clientID, echo)
 
 	slog.Debug("sync: stored + echoed user message", "session", sk, "client", s.clientID)
 
@@ -130,6 +138,11 @@ type historyRequest struct {
 	Params struct {
 		SessionKey string `json:"sessionKey"`
 		Limit      int    `json:"limit"`
+		Before     int64  `json:"before"`   // pagination cursor: return messages older than this timestamp (ms)
+		BeforeID   string `json:"beforeId"` // pagination cursor: return messages older than this message (nextCursorId)
+		// LastMessageID is the newest message the client already has; only
+		// newer ones are returned, including on later pages with before.
+		LastMessageID string `json:"lastMessageId"`
 	} `json:"params"`
 }
 
@@ -151,15 +164,25 @@ func (s *SyncUpstreamInspector) handleSessionsHistory(payload []byte, requestID
 		limit = 50
 	}
 
-	messages := s.store.GetHistory(sk, limit)
-	response := buildHistoryResponse(requestID, messages)
+	var messages []chatsync.StoredMessage
+	var more bool
+	paging := req.Params.Before > 0 || req.Params.BeforeID != ""
+	switch {
+	case req.Params.LastMessageID != "" && paging:
+		messages, more = chatsync.GetHistoryBetween(s.store, sk, req.Params.LastMessageID, req.Params.Before, req.Params.BeforeID, limit)
+	case req.Params.LastMessageID != "":
+		messages, more = s.store.GetHistoryAfter(sk, req.Params.LastMessageID, limit)
+	default:
+		messages, more = s.store.GetHistoryBefore(sk, req.Params.Before, req.Params.BeforeID, limit)
+	}
+	response := buildHistoryResponse(requestID, messages, more, s.maxResponseBytes)
 
 	if err := s.clientConn.Write(s.ctx, websocket.MessageText, response); err != nil {
 		slog.Warn("sync: failed to send history response", "error", err)
 		return payload // Fall back to forwarding if write fails
 	}
 
-	slog.Debug("sync: sent history response", "session", sk, "messages", len(messages))
+	slog.Debug("sync: sent history response", "session", sk, "messages", len(messages), "before", req.Params.Before, "last_message_id", req.Params.LastMessageID)
 
 	return nil // Suppress forwarding to gateway
 }
@@ -196,23 +219,67 @@ func buildUserEcho(idempotencyKey, text string) []byte {
 }
 
 // buildHistoryResponse creates a sessions.history response from stored messages.
-func buildHistoryResponse(requestID string, messages []chatsync.StoredMessage) []byte {
-	msgList := make([]map[string]interface{}, len(messages))
+// When more is set, the oldest message is returned as the cursor for the next
+// older page: nextCursor (its timestamp) and nextCursorId (its ID), which
+// clients pass back as params.before and params.beforeId. The ID is what keeps
+// messages sharing the boundary timestamp from being skipped.
+//
+// If maxBytes > 0 and the response would exceed it, the oldest messages are
+// dropped until it fits, "truncated" is set, and the cursor points at the
+// oldest message kept so the dropped ones can still be paged in. A single
+// message too large to fit on its own is skipped by pointing the cursor past it.
+func buildHistoryResponse(requestID string, messages []chatsync.StoredMessage, more bool, maxBytes int64) []byte {
+	items := make([]json.RawMessage, len(messages))
 	for i, m := range messages {
-		msgList[i] = map[string]interface{}{
+		items[i], _ = json.Marshal(map[string]interface{}{
 			"id":        m.ID,
 			"role":      m.Role,
 			"content":   m.Content,
 			"timestamp": m.Timestamp,
+		})
+	}
+
+	var cursor *chatsync.StoredMessage
+	if more && len(messages) > 0 {
+		cursor = &messages[0]
+	}
+	start := 0
+	truncated := false
+	for {
+		data := marshalHistoryResponse(requestID, items[start:], cursor, truncated)
+		if maxBytes <= 0 || int64(len(data)) <= maxBytes || start == len(items) {
+			return data
+		}
+		// Drop enough of the oldest messages to cover the excess, then re-check:
+		// the cursor and truncation flag change the envelope size slightly.
+		for excess := int64(len(data)) - maxBytes; excess > 0 && start < len(items); start++ {
+			excess -= int64(len(items[start])) + 1 // +1 for the separating comma
 		}
+		truncated = true
+		if start < len(items) {
+			cursor = &messages[start]
+		} else {
+			cursor = &messages[len(messages)-1]
+		}
+	}
+}
+
+func marshalHistoryResponse(requestID string, items []json.RawMessage, cursor *chatsync.StoredMessage, truncated bool) []byte {
+	payload := map[string]interface{}{
+		"messages": items,
+	}
+	if cursor != nil {
+		payload["nextCursor"] = cursor.Timestamp
+		payload["nextCursorId"] = cursor.ID
+	}
+	if truncated {
+		payload["truncated"] = true
 	}
 
 	resp := map[string]interface{}{
-		"type": "res",
-		"id":   requestID,
-		"payload": map[string]interface{}{
-			"messages": msgList,
-		},
+		"type":    "res",
+		"id":      requestID,
+		"payload": payload,
 	}
 	data, _ := json.Marshal(resp)
 	return data
@@ -221,12 +288,12 @@ func buildHistoryResponse(requestID string, messages []chatsync.StoredMessage) [
 // SyncDownstreamInspector observes gateway->client messages and stores
 // completed assistant responses for history retrieval.
 type SyncDownstreamInspector struct {
-	store      *chatsync.MessageStore
+	store      chatsync.Store
 	sessionKey func() string // lazy: session key discovered by upstream inspector
 }
 
 // NewSyncDownstreamInspector creates a downstream inspector that stores assistant messages.
-func NewSyncDownstreamInspector(store *chatsync.MessageStore, sessionKeyFn func() string) *SyncDownstreamInspector {
+func NewSyncDownstreamInspector(store chatsync.Store, sessionKeyFn func() string) *SyncDownstreamInspector {
 	return &SyncDownstreamInspector{
 		store:      store,
 		sessionKey: sessionKeyFn,
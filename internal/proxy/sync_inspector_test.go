@@ -3,8 +3,11 @@ package proxy
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -152,6 +155,199 @@ func TestSyncUpstreamSessionsHistoryReturnsNil(t *testing.T) {
 	}
 }
 
+func TestSyncUpstreamSessionsHistoryPagination(t *testing.T) {
+	client, server, cleanup := testWSPair(t)
+	defer cleanup()
+
+	store := chatsync.NewMessageStore(100)
+	registry := chatsync.NewClientRegistry()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Pairs of messages share a millisecond, so both page boundaries below
+	// fall between messages with equal timestamps.
+	for i := 1; i <= 5; i++ {
+		store.Append("sess-1", chatsync.StoredMessage{
+			ID: fmt.Sprintf("msg-%d", i), Role: "user",
+			Content:   []chatsync.ContentItem{{Type: "text", Text: "hi"}},
+			Timestamp: int64((i + 1) / 2 * 1000),
+		})
+	}
+
+	insp := NewSyncUpstreamInspector(ctx, server, store, registry, "test-client")
+	defer insp.Cleanup()
+
+	type historyPage struct {
+		Payload struct {
+			Messages []struct {
+				ID string `json:"id"`
+			} `json:"messages"`
+			NextCursor   int64  `json:"nextCursor"`
+			NextCursorID string `json:"nextCursorId"`
+		} `json:"payload"`
+	}
+	fetch := func(before int64, beforeID string) historyPage {
+		t.Helper()
+		payload := fmt.Sprintf(`{"type":"req","method":"sessions.history","id":"r","params":{"sessionKey":"sess-1","limit":2,"before":%d,"beforeId":%q}}`, before, beforeID)
+		if result := insp.InspectMessage([]byte(payload), websocket.MessageText); result != nil {
+			t.Fatalf("sessions.history should return nil, got %q", result)
+		}
+		_, msg, err := client.Read(ctx)
+		if err != nil {
+			t.Fatalf("read response: %v", err)
+		}
+		var page historyPage
+		if err := json.Unmarshal(msg, &page); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		return page
+	}
+
+	var ids []string
+	var before int64
+	var beforeID string
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+		page := fetch(before, beforeID)
+		for i := len(page.Payload.Messages) - 1; i >= 0; i-- {
+			ids = append(ids, page.Payload.Messages[i].ID)
+		}
+		if page.Payload.NextCursor == 0 {
+			break
+		}
+		before, beforeID = page.Payload.NextCursor, page.Payload.NextCursorID
+	}
+
+	want := "msg-5,msg-4,msg-3,msg-2,msg-1"
+	if got := strings.Join(ids, ","); got != want {
+		t.Errorf("paged IDs = %s, want %s", got, want)
+	}
+}
+
+func TestSyncUpstreamSessionsHistoryLastMessageID(t *testing.T) {
+	client, server, cleanup := testWSPair(t)
+	defer cleanup()
+
+	store := chatsync.NewMessageStore(100)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 1; i <= 5; i++ {
+		store.Append("sess-1", chatsync.StoredMessage{ID: fmt.Sprintf("msg-%d", i), Role: "user", Timestamp: int64(i * 1000)})
+	}
+
+	insp := NewSyncUpstreamInspector(ctx, server, store, chatsync.NewClientRegistry(), "test-client")
+	defer insp.Cleanup()
+
+	payload := `{"type":"req","method":"sessions.history","id":"r","params":{"sessionKey":"sess-1","lastMessageId":"msg-3"}}`
+	if result := insp.InspectMessage([]byte(payload), websocket.MessageText); result != nil {
+		t.Fatalf("sessions.history should return nil, got %q", result)
+	}
+	_, msg, err := client.Read(ctx)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	var resp struct {
+		Payload struct {
+			Messages []struct {
+				ID string `json:"id"`
+			} `json:"messages"`
+			NextCursor int64 `json:"nextCursor"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(msg, &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	var ids []string
+	for _, m := range resp.Payload.Messages {
+		ids = append(ids, m.ID)
+	}
+	if got := strings.Join(ids, ","); got != "msg-4,msg-5" {
+		t.Errorf("IDs after msg-3 = %s, want msg-4,msg-5", got)
+	}
+	if resp.Payload.NextCursor != 0 {
+		t.Errorf("nextCursor = %d, want 0 (nothing newer than msg-3 left out)", resp.Payload.NextCursor)
+	}
+}
+
+func TestSyncUpstreamSessionsHistoryLastMessageIDPaged(t *testing.T) {
+	client, server, cleanup := testWSPair(t)
+	defer cleanup()
+
+	store := chatsync.NewMessageStore(100)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 1; i <= 6; i++ {
+		store.Append("sess-1", chatsync.StoredMessage{ID: fmt.Sprintf("msg-%d", i), Role: "user", Timestamp: int64(i * 1000)})
+	}
+
+	insp := NewSyncUpstreamInspector(ctx, server, store, chatsync.NewClientRegistry(), "test-client")
+	defer insp.Cleanup()
+
+	fetch := func(params string) historyPage {
+		t.Helper()
+		payload := `{"type":"req","method":"sessions.history","id":"r","params":{"sessionKey":"sess-1","limit":2,"lastMessageId":"msg-2"` + params + `}}`
+		if result := insp.InspectMessage([]byte(payload), websocket.MessageText); result != nil {
+			t.Fatalf("sessions.history should return nil, got %q", result)
+		}
+		_, msg, err := client.Read(ctx)
+		if err != nil {
+			t.Fatalf("read response: %v", err)
+		}
+		var page historyPage
+		if err := json.Unmarshal(msg, &page); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		return page
+	}
+	pageIDs := func(page historyPage) string {
+		var ids []string
+		for _, m := range page.Payload.Messages {
+			ids = append(ids, m.ID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	first := fetch("")
+	if got := pageIDs(first); got != "msg-5,msg-6" {
+		t.Fatalf("first page = %s, want msg-5,msg-6", got)
+	}
+	if first.Payload.NextCursorID == "" {
+		t.Fatal("first page has no nextCursorId, want one (msg-3 and msg-4 remain)")
+	}
+
+	// Following the cursor with lastMessageId still set stops at msg-2.
+	second := fetch(fmt.Sprintf(`,"before":%d,"beforeId":%q`, first.Payload.NextCursor, first.Payload.NextCursorID))
+	if got := pageIDs(second); got != "msg-3,msg-4" {
+		t.Errorf("second page = %s, want msg-3,msg-4", got)
+	}
+	if second.Payload.NextCursor != 0 || second.Payload.NextCursorID != "" {
+		t.Errorf("second page cursor = %d/%q, want none (the client already has msg-2)", second.Payload.NextCursor, second.Payload.NextCursorID)
+	}
+}
+
+func TestBuildHistoryResponseNextCursor(t *testing.T) {
+	var parsed struct {
+		Payload map[string]json.RawMessage `json:"payload"`
+	}
+
+	msgs := []chatsync.StoredMessage{{ID: "m1", Timestamp: 1234}, {ID: "m2", Timestamp: 1234}}
+	json.Unmarshal(buildHistoryResponse("r", msgs, true, 0), &parsed)
+	if string(parsed.Payload["nextCursor"]) != "1234" || string(parsed.Payload["nextCursorId"]) != `"m1"` {
+		t.Errorf("cursor = %s/%s, want 1234/\"m1\"", parsed.Payload["nextCursor"], parsed.Payload["nextCursorId"])
+	}
+
+	parsed.Payload = nil
+	json.Unmarshal(buildHistoryResponse("r", msgs, false, 0), &parsed)
+	if _, ok := parsed.Payload["nextCursor"]; ok {
+		t.Error("nextCursor should be omitted when no older messages remain")
+	}
+	if _, ok := parsed.Payload["nextCursorId"]; ok {
+		t.Error("nextCursorId should be omitted when no older messages remain")
+	}
+}
+
 func TestSyncUpstreamIgnoresNonReq(t *testing.T) {
 	_, server, cleanup := testWSPair(t)
 	defer cleanup()
@@ -332,7 +528,7 @@ func TestBuildHistoryResponse(t *testing.T) {
 		{ID: "m2", Role: "assistant", Content: []chatsync.ContentItem{{Type: "text", Text: "hello"}}, Timestamp: 2000},
 	}
 
-	resp := buildHistoryResponse("req-99", msgs)
+	resp := buildHistoryResponse("req-99", msgs, false, 0)
 
 	var parsed struct {
 		Type    string `json:"type"`
@@ -362,7 +558,7 @@ func TestBuildHistoryResponse(t *testing.T) {
 }
 
 func TestBuildHistoryResponseEmpty(t *testing.T) {
-	resp := buildHistoryResponse("req-1", nil)
+	resp := buildHistoryResponse("req-1", nil, false, 0)
 
 	var parsed struct {
 		Payload struct {
@@ -381,3 +577,188 @@ func TestBuildHistoryResponseEmpty(t *testing.T) {
 		t.Log("messages is null (nil input), acceptable")
 	}
 }
+
+// mockStore is a chatsync.Store that records appends and serves a fixed history.
+type mockStore struct {
+	mu       sync.Mutex
+	appended []chatsync.StoredMessage
+	history  []chatsync.StoredMessage
+}
+
+func (m *mockStore) Append(_ string, msg chatsync.StoredMessage) {
+	m.mu.Lock()
+	m.appended = append(m.appended, msg)
+	m.mu.Unlock()
+}
+
+func (m *mockStore) GetHistory(string, int) []chatsync.StoredMessage { return m.history }
+
+func (m *mockStore) GetHistoryBefore(string, int64, string, int) ([]chatsync.StoredMessage, bool) {
+	return m.history, false
+}
+
+func (m *mockStore) GetHistoryAfter(string, string, int) ([]chatsync.StoredMessage, bool) {
+	return m.history, false
+}
+
+func (m *mockStore) Count(string) int { return len(m.history) }
+
+func (m *mockStore) appendedCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.appended)
+}
+
+func TestHandlerSyncWithCustomStore(t *testing.T) {
+	bridge, handler, _ := setupBridgeWithGateway(t)
+	store := &mockStore{history: []chatsync.StoredMessage{{
+		ID: "remote-1", Role: "assistant",
+		Content:   []chatsync.ContentItem{{Type: "text", Text: "from another bridge"}},
+		Timestamp: 1000,
+	}}}
+	handler.SyncStore = store
+	handler.SyncRegistry = chatsync.NewClientRegistry()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(bridge.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.CloseNow()
+
+	send := `{"type":"req","method":"chat.send","id":"r1","params":{"sessionKey":"sess-1","message":"hi","idempotencyKey":"k1"}}`
+	if err := c.Write(ctx, websocket.MessageText, []byte(send)); err != nil {
+		t.Fatalf("write chat.send: %v", err)
+	}
+	// Echo gateway returns chat.send; by then the store has seen it.
+	if _, _, err := c.Read(ctx); err != nil {
+		t.Fatalf("read echo: %v", err)
+	}
+	if got := store.appendedCount(); got != 1 {
+		t.Errorf("store appends = %d, want 1", got)
+	}
+
+	hist := `{"type":"req","method":"sessions.history","id":"h1","params":{"sessionKey":"sess-1","limit":50}}`
+	if err := c.Write(ctx, websocket.MessageText, []byte(hist)); err != nil {
+		t.Fatalf("write sessions.history: %v", err)
+	}
+	_, msg, err := c.Read(ctx)
+	if err != nil {
+		t.Fatalf("read history: %v", err)
+	}
+	if !strings.Contains(string(msg), "from another bridge") {
+		t.Errorf("history response = %s, want message from custom store", msg)
+	}
+}
+
+// historyPage is the subset of a sessions.history response the paging and
+// size-cap tests check.
+type historyPage struct {
+	Payload struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+		NextCursor   int64  `json:"nextCursor"`
+		NextCursorID string `json:"nextCursorId"`
+		Truncated    bool   `json:"truncated"`
+	} `json:"payload"`
+}
+
+func bigHistoryMessages(n, textSize int) []chatsync.StoredMessage {
+	msgs := make([]chatsync.StoredMessage, n)
+	for i := range msgs {
+		msgs[i] = chatsync.StoredMessage{
+			ID:        fmt.Sprintf("m%d", i),
+			Role:      "assistant",
+			Content:   []chatsync.ContentItem{{Type: "text", Text: strings.Repeat("x", textSize)}},
+			Timestamp: int64(1000 * (i + 1)),
+		}
+	}
+	return msgs
+}
+
+func TestBuildHistoryResponseTrimsToBudget(t *testing.T) {
+	msgs := bigHistoryMessages(10, 1000)
+	const budget = 3500
+
+	resp := buildHistoryResponse("req-1", msgs, false, budget)
+	if len(resp) > budget {
+		t.Fatalf("response = %d bytes, want <= %d", len(resp), budget)
+	}
+
+	var page historyPage
+	if err := json.Unmarshal(resp, &page); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !page.Payload.Truncated {
+		t.Error("truncated should be set")
+	}
+	got := page.Payload.Messages
+	if len(got) != 3 || got[0].ID != "m7" || got[2].ID != "m9" {
+		t.Errorf("kept messages = %+v, want the newest three (m7..m9)", got)
+	}
+	if page.Payload.NextCursor != msgs[7].Timestamp || page.Payload.NextCursorID != "m7" {
+		t.Errorf("cursor = %d/%q, want %d/\"m7\" (oldest kept)", page.Payload.NextCursor, page.Payload.NextCursorID, msgs[7].Timestamp)
+	}
+}
+
+func TestBuildHistoryResponseFitsUntouched(t *testing.T) {
+	msgs := bigHistoryMessages(3, 100)
+	full := buildHistoryResponse("req-1", msgs, false, 0)
+	if got := buildHistoryResponse("req-1", msgs, false, int64(len(full))); string(got) != string(full) {
+		t.Errorf("response within budget was modified:\n%s\n%s", got, full)
+	}
+}
+
+func TestBuildHistoryResponseSkipsMessageLargerThanBudget(t *testing.T) {
+	msgs := bigHistoryMessages(2, 5000)
+
+	resp := buildHistoryResponse("req-1", msgs, false, 1000)
+	if len(resp) > 1000 {
+		t.Fatalf("response = %d bytes, want <= 1000", len(resp))
+	}
+	var page historyPage
+	if err := json.Unmarshal(resp, &page); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(page.Payload.Messages) != 0 || !page.Payload.Truncated {
+		t.Errorf("page = %+v, want empty and truncated", page.Payload)
+	}
+	if page.Payload.NextCursor != msgs[1].Timestamp {
+		t.Errorf("nextCursor = %d, want %d (past the oversized message)", page.Payload.NextCursor, msgs[1].Timestamp)
+	}
+}
+
+func TestSyncUpstreamHistoryRespectsMaxResponseBytes(t *testing.T) {
+	client, server, cleanup := testWSPair(t)
+	defer cleanup()
+
+	store := chatsync.NewMessageStore(100)
+	for _, m := range bigHistoryMessages(20, 2000) {
+		store.Append("s1", m)
+	}
+	insp := NewSyncUpstreamInspector(context.Background(), server, store, chatsync.NewClientRegistry(), "c1")
+	insp.SetMaxResponseBytes(8192)
+	defer insp.Cleanup()
+
+	go insp.InspectMessage([]byte(`{"type":"req","method":"sessions.history","id":"h1","params":{"sessionKey":"s1"}}`), websocket.MessageText)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, resp, err := client.Read(ctx)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(resp) > 8192 {
+		t.Errorf("history response = %d bytes, want <= 8192", len(resp))
+	}
+	var page historyPage
+	if err := json.Unmarshal(resp, &page); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !page.Payload.Truncated || len(page.Payload.Messages) == 0 || page.Payload.Messages[len(page.Payload.Messages)-1].ID != "m19" {
+		t.Errorf("page = %+v, want truncated with the newest message last", page.Payload)
+	}
+}
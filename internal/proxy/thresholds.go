@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// Levels reported as the state of connection_threshold events.
+const (
+	thresholdOK = iota
+	thresholdWarn
+	thresholdCritical
+)
+
+var thresholdLevelNames = [...]string{"ok", "warn", "critical"}
+
+// connectionThresholds logs a "connection_threshold" event each time the
+// active connection count crosses monitoring.connection_warn_threshold or
+// monitoring.connection_critical_threshold, for alerting off the log stream
+// without a metrics scraper. A level is entered when the count reaches its
+// threshold and left only once the count drops thresholdClearMargin below
+// it, so a count hovering at the line produces one event, not one per
+// connect/disconnect.
+type connectionThresholds struct {
+	mu    sync.Mutex
+	level int
+}
+
+// thresholdClearMargin is how far below threshold the count must fall for
+// its level to clear: 10% of the threshold, at least one connection.
+func thresholdClearMargin(threshold int) int {
+	return max(1, threshold/10)
+}
+
+// observe updates the level for the current count and logs an event when it
+// changes. Thresholds of 0 are disabled.
+func (t *connectionThresholds) observe(active, warn, critical int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// A level is held while the count stays above its clear point.
+	held := thresholdOK
+	switch {
+	case critical > 0 && active > critical-thresholdClearMargin(critical):
+		held = thresholdCritical
+	case warn > 0 && active > warn-thresholdClearMargin(warn):
+		held = thresholdWarn
+	}
+	reached := thresholdOK
+	switch {
+	case critical > 0 && active >= critical:
+		reached = thresholdCritical
+	case warn > 0 && active >= warn:
+		reached = thresholdWarn
+	}
+
+	level := t.level
+	if reached > level {
+		level = reached
+	} else if held < level {
+		level = held
+	}
+	if level == t.level {
+		return
+	}
+	prev := t.level
+	t.level = level
+
+	// Report the threshold that was crossed: the higher of the two levels.
+	threshold := warn
+	if max(level, prev) == thresholdCritical {
+		threshold = critical
+	}
+	slog.Warn("active connections crossed threshold",
+		"event", "connection_threshold",
+		"state", thresholdLevelNames[level],
+		"previous_state", thresholdLevelNames[prev],
+		"active_connections", active,
+		"threshold", threshold,
+	)
+}
+
+// checkConnectionThresholds feeds the current connection count to the
+// threshold tracker. Called whenever the count changes.
+func (h *Handler) checkConnectionThresholds() {
+	m := h.GetConfig().Monitoring
+	h.thresholds.observe(h.Proxy.ConnectionCount(), m.ConnectionWarnThreshold, m.ConnectionCriticalThreshold)
+}
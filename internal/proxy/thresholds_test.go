@@ -0,0 +1,141 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// captureThresholdEvents routes slog to a JSON buffer for the test and
+// returns a func that decodes the connection_threshold events logged so far.
+func captureThresholdEvents(t *testing.T) func() []map[string]any {
+	t.Helper()
+	var logs lockedBuffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return func() []map[string]any {
+		var events []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var rec map[string]any
+			if json.Unmarshal([]byte(line), &rec) == nil && rec["event"] == "connection_threshold" {
+				events = append(events, rec)
+			}
+		}
+		return events
+	}
+}
+
+func TestConnectionThresholdsOneEventPerCrossing(t *testing.T) {
+	events := captureThresholdEvents(t)
+	var th connectionThresholds
+	drive := func(counts ...int) {
+		for _, n := range counts {
+			th.observe(n, 20, 40)
+		}
+	}
+
+	// Up to and past warn, then hovering at the line: clears only at 18
+	// (10% below 20), so the 19/20 flapping logs nothing.
+	drive(1, 5, 19, 20, 21, 19, 20, 19, 20, 21)
+	if got := len(events()); got != 1 {
+		t.Fatalf("events after reaching warn = %d, want 1", got)
+	}
+	drive(40, 39, 37, 40, 45)
+	drive(36)
+	drive(18)
+	drive(0, 0)
+
+	got := events()
+	want := []struct {
+		state, prev string
+		active      float64
+		threshold   float64
+	}{
+		{"warn", "ok", 20, 20},
+		{"critical", "warn", 40, 40},
+		{"warn", "critical", 36, 40},
+		{"ok", "warn", 18, 20},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("events = %d, want %d: %v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		e := got[i]
+		if e["state"] != w.state || e["previous_state"] != w.prev || e["active_connections"] != w.active || e["threshold"] != w.threshold {
+			t.Errorf("event %d = %v, want state=%s previous=%s active=%v threshold=%v", i, e, w.state, w.prev, w.active, w.threshold)
+		}
+		if e["level"] != "WARN" {
+			t.Errorf("event %d logged at %v, want WARN", i, e["level"])
+		}
+	}
+}
+
+func TestConnectionThresholdsJumpAndDisable(t *testing.T) {
+	events := captureThresholdEvents(t)
+	var th connectionThresholds
+
+	// A burst straight past both thresholds is one event, as is a
+	// mass disconnect straight back to zero.
+	th.observe(50, 20, 40)
+	th.observe(0, 20, 40)
+	// Threshold of 1: one connection is enough, and the state clears at 0.
+	th.observe(1, 1, 0)
+	th.observe(1, 1, 0)
+	// Disabling the thresholds (e.g. by reload) clears an active state.
+	th.observe(1, 0, 0)
+	th.observe(100, 0, 0)
+
+	var transitions []string
+	for _, e := range events() {
+		transitions = append(transitions, e["previous_state"].(string)+"→"+e["state"].(string))
+	}
+	want := "ok→critical critical→ok ok→warn warn→ok"
+	if got := strings.Join(transitions, " "); got != want {
+		t.Errorf("transitions = %q, want %q", got, want)
+	}
+}
+
+func TestHandlerConnectionThresholdEvents(t *testing.T) {
+	events := captureThresholdEvents(t)
+	gw := echoGateway(t)
+	defer gw.Close()
+
+	cfg := testConfig()
+	cfg.Bridge.GatewayURL = gw.URL
+	cfg.Bridge.PingInterval = 0
+	cfg.Monitoring.ConnectionWarnThreshold = 2
+	handler := NewHandler(cfg, New(), nil, context.Background())
+	bridge := httptest.NewServer(handler)
+	defer bridge.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dial := func() *websocket.Conn {
+		c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(bridge.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		return c
+	}
+
+	c1 := dial()
+	defer c1.CloseNow()
+	if n := len(events()); n != 0 {
+		t.Fatalf("events with 1 connection = %d, want 0", n)
+	}
+	c2 := dial()
+	waitFor(t, "warn event", func() bool { return len(events()) == 1 })
+
+	c2.Close(websocket.StatusNormalClosure, "")
+	waitFor(t, "recovery event", func() bool { return len(events()) == 2 })
+	if e := events()[1]; e["state"] != "ok" || e["active_connections"] != float64(1) {
+		t.Errorf("recovery event = %v, want state ok at 1 connection", e)
+	}
+}
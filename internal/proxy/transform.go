@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// transformStderrLimit caps how much of a failing command's stderr is logged.
+const transformStderrLimit = 1024
+
+// errTransformOutputTooLarge aborts a command whose stdout exceeds MaxOutput.
+var errTransformOutputTooLarge = errors.New("output exceeds limit")
+
+// TransformInspector pipes each text message through an external command
+// (bridge.transform_command): the message is written to the command's stdin
+// and whatever it writes to stdout is forwarded instead. A command that fails,
+// exceeds Timeout, writes nothing, or writes more than MaxOutput leaves the
+// message unchanged (fail open), so a broken transform can't stall or drop
+// traffic. The command runs once per message without a shell.
+type TransformInspector struct {
+	Command   []string
+	Timeout   time.Duration
+	MaxOutput int64              // 0 = unlimited
+	Failures  prometheus.Counter // optional
+}
+
+// InspectMessage returns the command's output for text messages and the
+// original payload when the transform fails.
+func (t *TransformInspector) InspectMessage(payload []byte, msgType websocket.MessageType) []byte {
+	if msgType != websocket.MessageText {
+		return payload
+	}
+	out, err := t.run(payload)
+	if err != nil {
+		slog.Warn("transform command failed, forwarding message unchanged", "command", t.Command[0], "error", err)
+		if t.Failures != nil {
+			t.Failures.Inc()
+		}
+		return payload
+	}
+	if len(out) == 0 {
+		return payload
+	}
+	return out
+}
+
+func (t *TransformInspector) run(payload []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.Timeout)
+	defer cancel()
+
+	var stdout limitedBuffer
+	stdout.max = t.MaxOutput
+	var stderr limitedBuffer
+	stderr.max = transformStderrLimit
+	stderr.truncate = true
+
+	cmd := exec.CommandContext(ctx, t.Command[0], t.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = t.Timeout // don't wait on pipes held open by orphaned children
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if stdout.overflow {
+		return nil, errTransformOutputTooLarge
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.buf.String()); msg != "" {
+			return nil, errors.New(err.Error() + ": " + msg)
+		}
+		return nil, err
+	}
+	return stdout.buf.Bytes(), nil
+}
+
+// limitedBuffer collects up to max bytes (0 = unlimited). Past that it
+// either fails the write, stopping the command, or with truncate set
+// silently drops the rest.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	max      int64
+	truncate bool
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.max > 0 && int64(b.buf.Len()+len(p)) > b.max {
+		b.overflow = true
+		if !b.truncate {
+			return 0, errTransformOutputTooLarge
+		}
+		b.buf.Write(p[:b.max-int64(b.buf.Len())])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
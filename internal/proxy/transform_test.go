@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// shellTransform runs script under /bin/sh so tests can use ordinary
+// commands; the bridge itself never involves a shell.
+func shellTransform(t *testing.T, script string, timeout time.Duration) (*TransformInspector, prometheus.Counter) {
+	t.Helper()
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh in PATH")
+	}
+	failures := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_transform_failures"})
+	return &TransformInspector{
+		Command:  []string{sh, "-c", script},
+		Timeout:  timeout,
+		Failures: failures,
+	}, failures
+}
+
+func TestTransformInspector(t *testing.T) {
+	msg := []byte(`{"type":"event","event":"chat","payload":{"text":"hello"}}`)
+
+	tests := []struct {
+		name       string
+		script     string
+		maxOutput  int64
+		want       string
+		wantFailed bool
+	}{
+		{name: "cat passes through", script: "cat", want: string(msg)},
+		{name: "rewrite", script: "sed s/hello/goodbye/", want: strings.Replace(string(msg), "hello", "goodbye", 1)},
+		{name: "exit status fails open", script: "echo oops >&2; exit 3", want: string(msg), wantFailed: true},
+		{name: "missing executable fails open", script: "exec /nonexistent/transform", want: string(msg), wantFailed: true},
+		{name: "empty output forwards original", script: "cat >/dev/null", want: string(msg)},
+		{name: "oversized output fails open", script: "cat; cat /dev/zero", maxOutput: 1024, want: string(msg), wantFailed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ti, failures := shellTransform(t, tt.script, 5*time.Second)
+			ti.MaxOutput = tt.maxOutput
+			got := ti.InspectMessage(msg, websocket.MessageText)
+			if string(got) != tt.want {
+				t.Errorf("InspectMessage = %q, want %q", got, tt.want)
+			}
+			if failed := testutil.ToFloat64(failures) > 0; failed != tt.wantFailed {
+				t.Errorf("failure counted = %v, want %v", failed, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestTransformInspectorTimeoutFailsOpen(t *testing.T) {
+	ti, failures := shellTransform(t, "sleep 10", 100*time.Millisecond)
+	start := time.Now()
+	got := ti.InspectMessage([]byte("hello"), websocket.MessageText)
+	if string(got) != "hello" {
+		t.Errorf("InspectMessage = %q, want original", got)
+	}
+	if d := time.Since(start); d > 3*time.Second {
+		t.Errorf("timed-out transform took %v", d)
+	}
+	if testutil.ToFloat64(failures) != 1 {
+		t.Error("timeout not counted as a failure")
+	}
+}
+
+func TestTransformInspectorSkipsBinary(t *testing.T) {
+	ti, _ := shellTransform(t, "echo replaced", time.Second)
+	if got := ti.InspectMessage([]byte{0, 1, 2}, websocket.MessageBinary); string(got) != "\x00\x01\x02" {
+		t.Errorf("binary message transformed to %q", got)
+	}
+}
+
+func TestHandlerTransformCommand(t *testing.T) {
+	bridge, handler, _ := setupBridgeWithGateway(t)
+	// Prefixes client→gateway messages and upper-cases the gateway's echo,
+	// so the reply shows both directions ran.
+	ti, _ := shellTransform(t, `read -r line; case "$line" in ECHO:*) printf '%s' "$line" | tr a-z A-Z;; *) printf 'ECHO:%s' "$line";; esac`, 5*time.Second)
+	handler.TransformInspector = ti
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(bridge.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.CloseNow()
+	if err := c.Write(ctx, websocket.MessageText, []byte("hello")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, got, err := c.Read(ctx)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(got) != "ECHO:HELLO" {
+		t.Errorf("echo = %q, want ECHO:HELLO", got)
+	}
+}
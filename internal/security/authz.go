@@ -0,0 +1,125 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AuthzRequest is the JSON body POSTed to the authorization webhook for each
+// WebSocket upgrade.
+type AuthzRequest struct {
+	ClientIP string              `json:"client_ip"`
+	Token    string              `json:"token"`
+	Path     string              `json:"path"`
+	Headers  map[string][]string `json:"headers"`
+}
+
+type authzDecision struct {
+	allowed bool
+	expires time.Time
+}
+
+// AuthzWebhook asks an external service whether a connection may proceed.
+// A 200 response allows it; any other status denies it. Decisions are cached
+// per token+path for the configured TTL. Transport errors are not cached and
+// resolve to the configured fail mode.
+type AuthzWebhook struct {
+	url      string
+	client   *http.Client
+	ttl      time.Duration
+	failOpen bool
+
+	mu         sync.Mutex
+	cache      map[string]authzDecision
+	maxEntries int // sweep expired entries once the cache grows this large
+}
+
+// NewAuthzWebhook creates a webhook authorizer. A cacheTTL of 0 disables
+// caching. failOpen allows connections when the webhook cannot be reached.
+func NewAuthzWebhook(url string, timeout, cacheTTL time.Duration, failOpen bool) *AuthzWebhook {
+	return &AuthzWebhook{
+		url:        url,
+		client:     &http.Client{Timeout: timeout},
+		ttl:        cacheTTL,
+		failOpen:   failOpen,
+		cache:      make(map[string]authzDecision),
+		maxEntries: 10000,
+	}
+}
+
+// Authorize reports whether the request is allowed. A non-nil error means the
+// webhook could not be consulted and the returned decision is the fail mode.
+func (a *AuthzWebhook) Authorize(ctx context.Context, req AuthzRequest) (bool, error) {
+	key := authzCacheKey(req.Token, req.Path)
+	if allowed, ok := a.cached(key); ok {
+		return allowed, nil
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return a.failOpen, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return a.failOpen, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(httpReq)
+	if err != nil {
+		return a.failOpen, fmt.Errorf("authz webhook: %w", err)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024)) // allow connection reuse
+	resp.Body.Close()
+
+	allowed := resp.StatusCode == http.StatusOK
+	a.store(key, allowed)
+	return allowed, nil
+}
+
+func (a *AuthzWebhook) cached(key string) (bool, bool) {
+	if a.ttl <= 0 {
+		return false, false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	d, ok := a.cache[key]
+	if !ok || time.Now().After(d.expires) {
+		return false, false
+	}
+	return d.allowed, true
+}
+
+func (a *AuthzWebhook) store(key string, allowed bool) {
+	if a.ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.cache) >= a.maxEntries {
+		for k, d := range a.cache {
+			if now.After(d.expires) {
+				delete(a.cache, k)
+			}
+		}
+		if len(a.cache) >= a.maxEntries {
+			return // still full of live entries; skip caching rather than grow
+		}
+	}
+	a.cache[key] = authzDecision{allowed: allowed, expires: now.Add(a.ttl)}
+}
+
+// authzCacheKey hashes the token so raw credentials are not kept as map keys.
+func authzCacheKey(token, path string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:]) + "\x00" + path
+}
@@ -0,0 +1,88 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAuthzWebhookAllowAndDeny(t *testing.T) {
+	var got AuthzRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if got.Token != "good" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	a := NewAuthzWebhook(srv.URL, time.Second, 0, false)
+
+	allowed, err := a.Authorize(context.Background(), AuthzRequest{
+		ClientIP: "100.64.0.1",
+		Token:    "good",
+		Path:     "/ws",
+		Headers:  map[string][]string{"X-Device": {"phone"}},
+	})
+	if err != nil || !allowed {
+		t.Fatalf("Authorize(good) = %v, %v; want true, nil", allowed, err)
+	}
+	if got.ClientIP != "100.64.0.1" || got.Path != "/ws" || got.Headers["X-Device"][0] != "phone" {
+		t.Errorf("webhook received %+v", got)
+	}
+
+	allowed, err = a.Authorize(context.Background(), AuthzRequest{Token: "bad", Path: "/ws"})
+	if err != nil || allowed {
+		t.Fatalf("Authorize(bad) = %v, %v; want false, nil", allowed, err)
+	}
+}
+
+func TestAuthzWebhookCachesDecisions(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	a := NewAuthzWebhook(srv.URL, time.Second, time.Minute, false)
+	req := AuthzRequest{Token: "tok", Path: "/ws"}
+	for i := 0; i < 3; i++ {
+		if allowed, err := a.Authorize(context.Background(), req); err != nil || !allowed {
+			t.Fatalf("Authorize = %v, %v", allowed, err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("webhook called %d times, want 1 (cached)", n)
+	}
+
+	// A different path is a different cache key.
+	a.Authorize(context.Background(), AuthzRequest{Token: "tok", Path: "/other"})
+	if n := calls.Load(); n != 2 {
+		t.Errorf("webhook called %d times, want 2", n)
+	}
+}
+
+func TestAuthzWebhookFailMode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close() // unreachable from here on
+
+	closed := NewAuthzWebhook(url, time.Second, time.Minute, false)
+	if allowed, err := closed.Authorize(context.Background(), AuthzRequest{Token: "t"}); err == nil || allowed {
+		t.Errorf("fail closed: Authorize = %v, %v; want false with error", allowed, err)
+	}
+
+	open := NewAuthzWebhook(url, time.Second, time.Minute, true)
+	if allowed, err := open.Authorize(context.Background(), AuthzRequest{Token: "t"}); err == nil || !allowed {
+		t.Errorf("fail open: Authorize = %v, %v; want true with error", allowed, err)
+	}
+	if len(open.cache) != 0 {
+		t.Errorf("transport errors should not be cached, cache has %d entries", len(open.cache))
+	}
+}
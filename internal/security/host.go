@@ -0,0 +1,42 @@
+package security
+
+import (
+	"net"
+	"strings"
+)
+
+// HostAllowed reports whether host, a request's Host header, matches an
+// entry of security.allowed_hosts. Entries are hostnames or IPs compared
+// case-insensitively with the port stripped from host; an entry that
+// includes a port ("bridge.example.ts.net:8080") must match host:port
+// exactly. An empty list allows every host.
+func HostAllowed(host string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	name := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		name = h
+	}
+	name = normalizeHost(name)
+	for _, entry := range allowed {
+		entry = strings.ToLower(entry)
+		if _, _, err := net.SplitHostPort(entry); err == nil {
+			if entry == host {
+				return true
+			}
+			continue
+		}
+		if normalizeHost(entry) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeHost drops IPv6 brackets and a trailing root-zone dot.
+func normalizeHost(h string) string {
+	h = strings.TrimSuffix(strings.TrimPrefix(h, "["), "]")
+	return strings.TrimSuffix(h, ".")
+}
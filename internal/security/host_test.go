@@ -0,0 +1,39 @@
+package security
+
+import "testing"
+
+func TestHostAllowed(t *testing.T) {
+	allowed := []string{"bridge.tail1234.ts.net", "100.64.0.1", "fd7a:115c:a1e0::1", "localhost:8080"}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"bridge.tail1234.ts.net", true},
+		{"bridge.tail1234.ts.net:8080", true},
+		{"Bridge.Tail1234.TS.net", true},
+		{"bridge.tail1234.ts.net.", true},
+		{"100.64.0.1:8080", true},
+		{"[fd7a:115c:a1e0::1]:8080", true},
+		{"localhost:8080", true},
+		{"localhost:9090", false}, // entry with a port matches only that port
+		{"localhost", false},
+		{"attacker.example", false},
+		{"attacker.example:8080", false},
+		{"bridge.tail1234.ts.net.attacker.example", false},
+		{"100.64.0.2:8080", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := HostAllowed(tt.host, allowed); got != tt.want {
+			t.Errorf("HostAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestHostAllowedEmptyList(t *testing.T) {
+	for _, host := range []string{"anything.example", "", "100.64.0.1:8080"} {
+		if !HostAllowed(host, nil) {
+			t.Errorf("HostAllowed(%q, nil) = false, want true (check disabled)", host)
+		}
+	}
+}
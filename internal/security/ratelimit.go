@@ -22,25 +22,70 @@ type RateLimiter struct {
 	burst      int
 	ttl        time.Duration // evict entries not seen within this window
 	maxEntries int           // cap on number of tracked IPs
+
+	gcMu       sync.Mutex // guards the cleanup goroutine's lifecycle
+	gcInterval time.Duration
 	cancel     context.CancelFunc
+	done       chan struct{} // closed when the cleanup goroutine exits
 }
 
 // NewRateLimiter creates a new per-IP rate limiter.
 // r is the rate (events per second), burst is the maximum burst size.
+// Idle entries are evicted every minute once unseen for 10 minutes; see
+// SetCleanup.
 func NewRateLimiter(r rate.Limit, burst int) *RateLimiter {
-	ctx, cancel := context.WithCancel(context.Background())
 	rl := &RateLimiter{
 		limiters:   make(map[string]*ipLimiter),
 		r:          r,
 		burst:      burst,
 		ttl:        10 * time.Minute,
 		maxEntries: 10000,
-		cancel:     cancel,
+		gcInterval: time.Minute,
 	}
-	go rl.cleanup(ctx) // background goroutine to evict stale entries
+	rl.startCleanup()
 	return rl
 }
 
+// SetCleanup changes how often idle entries are evicted and how long an
+// entry must go unseen to be evicted, restarting the cleanup goroutine if
+// the interval changed.
+func (rl *RateLimiter) SetCleanup(interval, idleTTL time.Duration) {
+	rl.mu.Lock()
+	rl.ttl = idleTTL
+	rl.mu.Unlock()
+
+	rl.gcMu.Lock()
+	defer rl.gcMu.Unlock()
+	if interval == rl.gcInterval && rl.cancel != nil {
+		return
+	}
+	rl.stopCleanupLocked()
+	rl.gcInterval = interval
+	rl.startCleanupLocked()
+}
+
+func (rl *RateLimiter) startCleanup() {
+	rl.gcMu.Lock()
+	defer rl.gcMu.Unlock()
+	rl.startCleanupLocked()
+}
+
+func (rl *RateLimiter) startCleanupLocked() {
+	ctx, cancel := context.WithCancel(context.Background())
+	rl.cancel = cancel
+	rl.done = make(chan struct{})
+	go rl.cleanup(ctx, rl.gcInterval, rl.done) // background goroutine to evict stale entries
+}
+
+func (rl *RateLimiter) stopCleanupLocked() {
+	if rl.cancel == nil {
+		return
+	}
+	rl.cancel()
+	<-rl.done
+	rl.cancel = nil
+}
+
 // Allow checks whether the given IP is allowed to proceed.
 func (rl *RateLimiter) Allow(ip string) bool {
 	rl.mu.Lock()
@@ -59,9 +104,43 @@ func (rl *RateLimiter) Allow(ip string) bool {
 	return entry.limiter.Allow()
 }
 
-// Stop shuts down the cleanup goroutine.
+// Limit returns the configured burst size, i.e. how many requests a key may
+// make back to back before it is throttled.
+func (rl *RateLimiter) Limit() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.burst
+}
+
+// Remaining returns the number of whole tokens left for the given key and,
+// when none are left, how long until the next one becomes available. Keys
+// that have not been seen yet have the full burst available.
+func (rl *RateLimiter) Remaining(key string) (int, time.Duration) {
+	rl.mu.Lock()
+	entry, exists := rl.limiters[key]
+	r, burst := rl.r, rl.burst
+	rl.mu.Unlock()
+	if !exists {
+		return burst, 0
+	}
+
+	tokens := entry.limiter.Tokens()
+	if tokens >= 1 {
+		return int(tokens), 0
+	}
+	if r <= 0 {
+		return 0, 0 // never refills
+	}
+	wait := time.Duration((1 - tokens) / float64(r) * float64(time.Second))
+	return 0, wait
+}
+
+// Stop shuts down the cleanup goroutine and waits for it to exit. It is safe
+// to call more than once.
 func (rl *RateLimiter) Stop() {
-	rl.cancel()
+	rl.gcMu.Lock()
+	defer rl.gcMu.Unlock()
+	rl.stopCleanupLocked()
 }
 
 // UpdateRate changes the rate limit parameters. Existing per-IP limiters
@@ -75,8 +154,9 @@ func (rl *RateLimiter) UpdateRate(r rate.Limit, burst int) {
 	rl.limiters = make(map[string]*ipLimiter)
 }
 
-func (rl *RateLimiter) cleanup(ctx context.Context) {
-	ticker := time.NewTicker(1 * time.Minute)
+func (rl *RateLimiter) cleanup(ctx context.Context, interval time.Duration, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for {
 		select {
@@ -3,6 +3,7 @@ package security
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"golang.org/x/time/rate"
 )
@@ -95,5 +96,94 @@ func TestRateLimiterMaxEntries(t *testing.T) {
 
 func TestRateLimiterStop(t *testing.T) {
 	rl := NewRateLimiter(rate.Limit(1), 1)
+	done := rl.done
 	rl.Stop() // Should not panic or deadlock
+	select {
+	case <-done:
+	default:
+		t.Error("cleanup goroutine still running after Stop")
+	}
+	rl.Stop() // Second call is a no-op
+}
+
+func TestRateLimiterEvictsIdleEntries(t *testing.T) {
+	rl := NewRateLimiter(rate.Limit(1), 10)
+	defer rl.Stop()
+	rl.SetCleanup(10*time.Millisecond, 50*time.Millisecond)
+
+	rl.Allow("100.64.0.1")
+	rl.Allow("100.64.0.2")
+
+	// Keep one IP active; the other goes idle past the TTL.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		rl.Allow("100.64.0.2")
+		rl.mu.Lock()
+		_, idle := rl.limiters["100.64.0.1"]
+		_, active := rl.limiters["100.64.0.2"]
+		rl.mu.Unlock()
+		if !active {
+			t.Fatal("active IP was evicted")
+		}
+		if !idle {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("idle IP not evicted after its TTL")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRateLimiterSetCleanupRestartsGoroutine(t *testing.T) {
+	rl := NewRateLimiter(rate.Limit(1), 1)
+	defer rl.Stop()
+
+	old := rl.done
+	rl.SetCleanup(time.Second, time.Minute)
+	select {
+	case <-old:
+	default:
+		t.Error("previous cleanup goroutine still running after the interval changed")
+	}
+	if rl.done == old {
+		t.Error("no new cleanup goroutine started")
+	}
+
+	// Same interval: only the TTL changes.
+	current := rl.done
+	rl.SetCleanup(time.Second, 2*time.Minute)
+	if rl.done != current {
+		t.Error("cleanup goroutine restarted although the interval was unchanged")
+	}
+	if rl.ttl != 2*time.Minute {
+		t.Errorf("ttl = %v, want 2m", rl.ttl)
+	}
+}
+
+func TestRateLimiterRemaining(t *testing.T) {
+	// 1 per minute, burst of 2
+	rl := NewRateLimiter(rate.Limit(1.0/60.0), 2)
+	defer rl.Stop()
+
+	if got := rl.Limit(); got != 2 {
+		t.Errorf("Limit() = %d, want 2", got)
+	}
+	if n, wait := rl.Remaining("100.64.0.1"); n != 2 || wait != 0 {
+		t.Errorf("Remaining(unseen) = %d, %v; want 2, 0", n, wait)
+	}
+
+	rl.Allow("100.64.0.1")
+	if n, _ := rl.Remaining("100.64.0.1"); n != 1 {
+		t.Errorf("Remaining after one request = %d, want 1", n)
+	}
+
+	rl.Allow("100.64.0.1")
+	n, wait := rl.Remaining("100.64.0.1")
+	if n != 0 {
+		t.Errorf("Remaining after burst = %d, want 0", n)
+	}
+	if wait <= 55*time.Second || wait > time.Minute {
+		t.Errorf("wait after burst = %v, want just under 1m", wait)
+	}
 }
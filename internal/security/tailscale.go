@@ -1,6 +1,11 @@
 package security
 
-import "net"
+import (
+	"fmt"
+	"net"
+
+	"github.com/cortexuvula/clawreachbridge/internal/config"
+)
 
 // Package-level vars — parsed once at init, not per-request
 var (
@@ -31,3 +36,18 @@ func IsTailscaleIP(addr string) bool {
 
 	return tailscaleIPv4.Contains(ip) || tailscaleIPv6.Contains(ip)
 }
+
+// CheckTailscaleListen returns an error when security.tailscale_only is set
+// but bridge.listen_address binds an IP outside the Tailscale ranges (a LAN
+// or loopback address). Clients then arrive from non-Tailscale addresses, so
+// every connection is rejected. Hostnames aren't resolved and pass.
+func CheckTailscaleListen(cfg *config.Config) error {
+	if !cfg.Security.TailscaleOnly {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(cfg.Bridge.ListenAddress)
+	if err != nil || net.ParseIP(host) == nil || IsTailscaleIP(cfg.Bridge.ListenAddress) {
+		return nil
+	}
+	return fmt.Errorf("bridge.listen_address %s is not a Tailscale IP but security.tailscale_only is true; every client connection will be rejected", host)
+}
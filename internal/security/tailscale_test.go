@@ -1,6 +1,11 @@
 package security
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/cortexuvula/clawreachbridge/internal/config"
+)
 
 func TestIsTailscaleIP(t *testing.T) {
 	tests := []struct {
@@ -51,3 +56,30 @@ func TestIsTailscaleIP(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckTailscaleListen(t *testing.T) {
+	tests := []struct {
+		listen        string
+		tailscaleOnly bool
+		wantErr       bool
+	}{
+		{"100.64.0.1:8080", true, false},
+		{"[fd7a:115c:a1e0::1]:8080", true, false},
+		{"192.168.1.20:8080", true, true},
+		{"127.0.0.1:8080", true, true},
+		{"192.168.1.20:8080", false, false},
+		{"bridge.tailnet.ts.net:8080", true, false}, // hostnames aren't resolved
+	}
+	for _, tt := range tests {
+		cfg := config.DefaultConfig()
+		cfg.Bridge.ListenAddress = tt.listen
+		cfg.Security.TailscaleOnly = tt.tailscaleOnly
+		err := CheckTailscaleListen(cfg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("listen=%s tailscale_only=%v: err = %v, wantErr %v", tt.listen, tt.tailscaleOnly, err, tt.wantErr)
+		}
+		if err != nil && !strings.Contains(err.Error(), "every client connection will be rejected") {
+			t.Errorf("listen=%s: error %q doesn't explain the consequence", tt.listen, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package security
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cortexuvula/clawreachbridge/internal/config"
+)
+
+// ServerTLSConfig builds the proxy listener's tls.Config: certificate, minimum
+// version and cipher suites from t, and, when t.ClientCAFile is set, required
+// client certificates signed by one of the CAs in that PEM bundle (mTLS).
+// The certificate is served through a CertReloader, so renewed cert/key files
+// take effect on the next handshake without a restart.
+func ServerTLSConfig(t config.TLSConfig) (*tls.Config, error) {
+	minVersion, err := t.MinTLSVersion()
+	if err != nil {
+		return nil, err
+	}
+	suites, err := t.CipherSuiteIDs()
+	if err != nil {
+		return nil, err
+	}
+	reloader, err := NewCertReloader(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg := &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     minVersion,
+		CipherSuites:   suites,
+	}
+	if clientCAFile := t.ClientCAFile; clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client CA file %s contains no PEM certificates", clientCAFile)
+		}
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsCfg, nil
+}
+
+// CertIdentity returns the verified client certificate's subject common
+// name, or the full subject if the CN is empty. Returns "" when the
+// connection is not TLS or no client certificate was presented.
+func CertIdentity(state *tls.ConnectionState) string {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return ""
+	}
+	subject := state.PeerCertificates[0].Subject
+	if subject.CommonName != "" {
+		return subject.CommonName
+	}
+	return subject.String()
+}
+
+// CertReloader serves a certificate/key pair from disk, reloading it when
+// either file's modification time changes. If a reload fails (e.g. the cert
+// was replaced but the key not yet), the previous pair keeps being served.
+type CertReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+// NewCertReloader loads the initial certificate pair, failing if it is invalid.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS certificate: %w", err)
+	}
+	if err := r.load(certMod, keyMod); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		slog.Warn("TLS certificate stat failed, serving cached certificate", "error", err)
+		return r.cert, nil
+	}
+	if !certMod.Equal(r.certMod) || !keyMod.Equal(r.keyMod) {
+		if err := r.load(certMod, keyMod); err != nil {
+			slog.Warn("TLS certificate reload failed, serving previous certificate", "cert_file", r.certFile, "error", err)
+		} else {
+			slog.Info("TLS certificate reloaded", "cert_file", r.certFile)
+		}
+	}
+	return r.cert, nil
+}
+
+func (r *CertReloader) modTimes() (certMod, keyMod time.Time, err error) {
+	ci, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	ki, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return ci.ModTime(), ki.ModTime(), nil
+}
+
+// load reads the pair and records the mod times it was loaded at. On failure
+// the mod times are still recorded so a broken pair isn't re-parsed on every
+// handshake; the next change to either file retries.
+func (r *CertReloader) load(certMod, keyMod time.Time) error {
+	r.certMod, r.keyMod = certMod, keyMod
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading TLS certificate: %w", err)
+	}
+	r.cert = &cert
+	return nil
+}
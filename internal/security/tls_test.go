@@ -0,0 +1,358 @@
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cortexuvula/clawreachbridge/internal/config"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// issueCert creates a certificate for subject, self-signed when parent is nil.
+func issueCert(t *testing.T, subject pkix.Name, parent *testCert, isCA bool, usage x509.ExtKeyUsage) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if isCA {
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) writePEM(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// serveTLS serves h over a TLS listener wrapped the same way main does
+// (httptest.StartTLS would inject its own certificate) and returns the URL.
+func serveTLS(t *testing.T, tlsCfg *tls.Config, h http.Handler) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := &http.Server{Handler: h, ErrorLog: log.New(io.Discard, "", 0)}
+	go srv.Serve(tls.NewListener(ln, tlsCfg))
+	t.Cleanup(func() { srv.Close() })
+	return "https://" + ln.Addr().String()
+}
+
+func tlsSettings(certFile, keyFile, clientCAFile string) config.TLSConfig {
+	return config.TLSConfig{
+		Enabled:      true,
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		ClientCAFile: clientCAFile,
+		MinVersion:   "1.2",
+	}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+func TestServerTLSConfigClientCertIdentity(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCert(t, pkix.Name{CommonName: "test-ca"}, nil, true, 0)
+	server := issueCert(t, pkix.Name{CommonName: "bridge"}, ca, false, x509.ExtKeyUsageServerAuth)
+	client := issueCert(t, pkix.Name{CommonName: "alice-phone"}, ca, false, x509.ExtKeyUsageClientAuth)
+
+	caFile, _ := ca.writePEM(t, dir, "ca")
+	certFile, keyFile := server.writePEM(t, dir, "server")
+
+	tlsCfg, err := ServerTLSConfig(tlsSettings(certFile, keyFile, caFile))
+	if err != nil {
+		t.Fatalf("ServerTLSConfig: %v", err)
+	}
+	if tlsCfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("ClientAuth = %v, want RequireAndVerifyClientCert", tlsCfg.ClientAuth)
+	}
+
+	srvURL := serveTLS(t, tlsCfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, CertIdentity(r.TLS))
+	}))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: certs,
+		}}}
+	}
+
+	resp, err := newClient(client.tlsCertificate()).Get(srvURL)
+	if err != nil {
+		t.Fatalf("GET with client cert: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "alice-phone" {
+		t.Errorf("identity = %q, want %q", body, "alice-phone")
+	}
+
+	if resp, err := newClient().Get(srvURL); err == nil {
+		resp.Body.Close()
+		t.Error("expected handshake failure without a client certificate")
+	}
+}
+
+func TestServerTLSConfigWithoutClientCA(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCert(t, pkix.Name{CommonName: "test-ca"}, nil, true, 0)
+	server := issueCert(t, pkix.Name{CommonName: "bridge"}, ca, false, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := server.writePEM(t, dir, "server")
+
+	tlsCfg, err := ServerTLSConfig(tlsSettings(certFile, keyFile, ""))
+	if err != nil {
+		t.Fatalf("ServerTLSConfig: %v", err)
+	}
+	if tlsCfg.ClientAuth != tls.NoClientCert || tlsCfg.ClientCAs != nil {
+		t.Error("expected no client auth without a client CA file")
+	}
+}
+
+func TestServerTLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCert(t, pkix.Name{CommonName: "test-ca"}, nil, true, 0)
+	certFile, keyFile := ca.writePEM(t, dir, "ca")
+
+	if _, err := ServerTLSConfig(tlsSettings(filepath.Join(dir, "missing.crt"), keyFile, "")); err == nil {
+		t.Error("expected error for missing certificate")
+	}
+	if _, err := ServerTLSConfig(tlsSettings(certFile, keyFile, filepath.Join(dir, "missing-ca.pem"))); err == nil {
+		t.Error("expected error for missing client CA file")
+	}
+	if _, err := ServerTLSConfig(tlsSettings(certFile, keyFile, keyFile)); err == nil {
+		t.Error("expected error for client CA file without certificates")
+	}
+}
+
+func TestCertIdentity(t *testing.T) {
+	if got := CertIdentity(nil); got != "" {
+		t.Errorf("CertIdentity(nil) = %q, want empty", got)
+	}
+	if got := CertIdentity(&tls.ConnectionState{}); got != "" {
+		t.Errorf("CertIdentity(no certs) = %q, want empty", got)
+	}
+
+	withCN := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+		{Subject: pkix.Name{CommonName: "alice", Organization: []string{"Home"}}},
+	}}
+	if got := CertIdentity(withCN); got != "alice" {
+		t.Errorf("CertIdentity = %q, want %q", got, "alice")
+	}
+
+	noCN := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+		{Subject: pkix.Name{Organization: []string{"Home"}, OrganizationalUnit: []string{"Phones"}}},
+	}}
+	if got := CertIdentity(noCN); got != "OU=Phones,O=Home" {
+		t.Errorf("CertIdentity without CN = %q, want full subject", got)
+	}
+}
+
+func TestServerTLSConfigVersionAndCiphers(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCert(t, pkix.Name{CommonName: "test-ca"}, nil, true, 0)
+	server := issueCert(t, pkix.Name{CommonName: "bridge"}, ca, false, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := server.writePEM(t, dir, "server")
+
+	tests := []struct {
+		name        string
+		minVersion  string
+		suites      []string
+		wantVersion uint16
+		wantSuites  []uint16
+		wantErr     bool
+	}{
+		{name: "default 1.2", minVersion: "1.2", wantVersion: tls.VersionTLS12},
+		{name: "1.3", minVersion: "1.3", wantVersion: tls.VersionTLS13},
+		{
+			name:        "restricted suites",
+			minVersion:  "1.2",
+			suites:      []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"},
+			wantVersion: tls.VersionTLS12,
+			wantSuites:  []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256},
+		},
+		{name: "old version", minVersion: "1.0", wantErr: true},
+		{name: "empty version", minVersion: "", wantErr: true},
+		{name: "unknown suite", minVersion: "1.2", suites: []string{"TLS_MADE_UP"}, wantErr: true},
+		{name: "insecure suite", minVersion: "1.2", suites: []string{"TLS_RSA_WITH_RC4_128_SHA"}, wantErr: true},
+		{name: "TLS 1.3 suite", minVersion: "1.2", suites: []string{"TLS_AES_128_GCM_SHA256"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := tlsSettings(certFile, keyFile, "")
+			settings.MinVersion = tt.minVersion
+			settings.CipherSuites = tt.suites
+
+			tlsCfg, err := ServerTLSConfig(settings)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ServerTLSConfig: %v", err)
+			}
+			if tlsCfg.MinVersion != tt.wantVersion {
+				t.Errorf("MinVersion = %x, want %x", tlsCfg.MinVersion, tt.wantVersion)
+			}
+			if !slices.Equal(tlsCfg.CipherSuites, tt.wantSuites) {
+				t.Errorf("CipherSuites = %v, want %v", tlsCfg.CipherSuites, tt.wantSuites)
+			}
+		})
+	}
+}
+
+func TestServerTLSConfigRejectsOldClients(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCert(t, pkix.Name{CommonName: "test-ca"}, nil, true, 0)
+	server := issueCert(t, pkix.Name{CommonName: "bridge"}, ca, false, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := server.writePEM(t, dir, "server")
+
+	settings := tlsSettings(certFile, keyFile, "")
+	settings.MinVersion = "1.3"
+	tlsCfg, err := ServerTLSConfig(settings)
+	if err != nil {
+		t.Fatalf("ServerTLSConfig: %v", err)
+	}
+
+	srvURL := serveTLS(t, tlsCfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:    roots,
+		MaxVersion: tls.VersionTLS12,
+	}}}
+	if resp, err := client.Get(srvURL); err == nil {
+		resp.Body.Close()
+		t.Error("expected TLS 1.2 client to be rejected with min_version 1.3")
+	}
+}
+
+func TestCertReloaderPicksUpChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCert(t, pkix.Name{CommonName: "test-ca"}, nil, true, 0)
+	first := issueCert(t, pkix.Name{CommonName: "bridge-2025"}, ca, false, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := first.writePEM(t, dir, "server")
+
+	r, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewCertReloader: %v", err)
+	}
+	leafCN := func() string {
+		t.Helper()
+		c, err := r.GetCertificate(nil)
+		if err != nil || c == nil {
+			t.Fatalf("GetCertificate: %v", err)
+		}
+		leaf, err := x509.ParseCertificate(c.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.Subject.CommonName
+	}
+	// bumpMtime pushes both files' mtimes forward so the change is seen even
+	// on filesystems with coarse timestamps.
+	bump := time.Now()
+	bumpMtime := func() {
+		bump = bump.Add(time.Minute)
+		os.Chtimes(certFile, bump, bump)
+		os.Chtimes(keyFile, bump, bump)
+	}
+
+	if cn := leafCN(); cn != "bridge-2025" {
+		t.Fatalf("initial CN = %q, want bridge-2025", cn)
+	}
+
+	renewed := issueCert(t, pkix.Name{CommonName: "bridge-2026"}, ca, false, x509.ExtKeyUsageServerAuth)
+	renewed.writePEM(t, dir, "server")
+	bumpMtime()
+	if cn := leafCN(); cn != "bridge-2026" {
+		t.Errorf("CN after renewal = %q, want bridge-2026", cn)
+	}
+
+	// A half-written renewal (key no longer matches) keeps the last good pair.
+	other := issueCert(t, pkix.Name{CommonName: "mismatched"}, ca, false, x509.ExtKeyUsageServerAuth)
+	_, otherKey := other.writePEM(t, dir, "other")
+	data, _ := os.ReadFile(otherKey)
+	if err := os.WriteFile(keyFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	bumpMtime()
+	if cn := leafCN(); cn != "bridge-2026" {
+		t.Errorf("CN after broken renewal = %q, want previous bridge-2026", cn)
+	}
+}
+
+func TestNewCertReloaderInvalidPair(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCert(t, pkix.Name{CommonName: "test-ca"}, nil, true, 0)
+	certFile, _ := ca.writePEM(t, dir, "ca")
+	if _, err := NewCertReloader(certFile, certFile); err == nil {
+		t.Error("expected error when key file holds no key")
+	}
+}
@@ -0,0 +1,28 @@
+package security
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+// Bounds and default for GenerateToken's entropy, in bytes.
+const (
+	DefaultTokenBytes = 32
+	MinTokenBytes     = 16
+	MaxTokenBytes     = 1024
+)
+
+// GenerateToken returns n cryptographically random bytes encoded as unpadded
+// base64url, suitable for security.auth_token: it needs no quoting in YAML,
+// headers or the ?token= query parameter.
+func GenerateToken(n int) (string, error) {
+	if n < MinTokenBytes || n > MaxTokenBytes {
+		return "", fmt.Errorf("token size must be between %d and %d bytes", MinTokenBytes, MaxTokenBytes)
+	}
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("reading random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
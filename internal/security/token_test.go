@@ -0,0 +1,49 @@
+package security
+
+import (
+	"encoding/base64"
+	"regexp"
+	"testing"
+)
+
+var base64URLChars = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func TestGenerateToken(t *testing.T) {
+	for _, n := range []int{MinTokenBytes, DefaultTokenBytes, 33, MaxTokenBytes} {
+		tok, err := GenerateToken(n)
+		if err != nil {
+			t.Fatalf("GenerateToken(%d): %v", n, err)
+		}
+		if want := base64.RawURLEncoding.EncodedLen(n); len(tok) != want {
+			t.Errorf("GenerateToken(%d) length = %d, want %d", n, len(tok), want)
+		}
+		if !base64URLChars.MatchString(tok) {
+			t.Errorf("GenerateToken(%d) = %q, want only base64url characters", n, tok)
+		}
+		if b, err := base64.RawURLEncoding.DecodeString(tok); err != nil || len(b) != n {
+			t.Errorf("GenerateToken(%d) decodes to %d bytes, %v", n, len(b), err)
+		}
+	}
+}
+
+func TestGenerateTokenUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 1000 {
+		tok, err := GenerateToken(DefaultTokenBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[tok] {
+			t.Fatalf("duplicate token %q", tok)
+		}
+		seen[tok] = true
+	}
+}
+
+func TestGenerateTokenSizeBounds(t *testing.T) {
+	for _, n := range []int{0, -1, MinTokenBytes - 1, MaxTokenBytes + 1} {
+		if _, err := GenerateToken(n); err == nil {
+			t.Errorf("GenerateToken(%d) succeeded, want error", n)
+		}
+	}
+}
@@ -26,13 +26,33 @@ const (
 	defaultGatewayURL = "http://localhost:18800"
 	defaultListenPort = "8080"
 	defaultHealthPort = "8081"
+
+	// defaultNetworkTimeout bounds the gateway reachability check when
+	// WizardOptions.NetworkTimeout is unset.
+	defaultNetworkTimeout = 3 * time.Second
+
+	// defaultServiceStartTimeout bounds how long the wizard waits for the
+	// service to become active when WizardOptions.ServiceStartTimeout is unset.
+	defaultServiceStartTimeout = 30 * time.Second
+
+	// The service state is polled with exponential backoff between these.
+	serviceStartInitialPoll = 250 * time.Millisecond
+	serviceStartMaxPoll     = 2 * time.Second
 )
 
 // WizardOptions configures the setup wizard.
 type WizardOptions struct {
-	ConfigPath       string            // Override default config path
-	DetectTailscale  func() string     // Override Tailscale IP detection (for testing)
-	CheckGateway     func(io.Writer, string) // Override gateway check (for testing)
+	ConfigPath          string                                            // Override default config path
+	DetectTailscale     func() string                                     // Override Tailscale IP detection (for testing)
+	CheckGateway        func(io.Writer, string)                           // Override gateway check (for testing)
+	NetworkTimeout      time.Duration                                     // Gateway reachability check timeout (0 = 3s)
+	ServiceStartTimeout time.Duration                                     // How long to wait for the service to become active (0 = 30s)
+	RunCommand          func(name string, args ...string) ([]byte, error) // Override systemctl calls that start the service (for testing)
+}
+
+// runCommand runs name with args and returns its stdout.
+func runCommand(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).Output()
 }
 
 // RunWizard runs the interactive setup wizard.
@@ -93,7 +113,11 @@ func RunWizard(in io.Reader, out io.Writer, opts WizardOptions) error {
 	}
 
 	// Check gateway reachability (warning only)
-	gwCheck := checkGateway
+	timeout := opts.NetworkTimeout
+	if timeout <= 0 {
+		timeout = defaultNetworkTimeout
+	}
+	gwCheck := func(out io.Writer, gatewayURL string) { checkGateway(out, gatewayURL, timeout) }
 	if opts.CheckGateway != nil {
 		gwCheck = opts.CheckGateway
 	}
@@ -134,7 +158,17 @@ func RunWizard(in io.Reader, out io.Writer, opts WizardOptions) error {
 
 	// Step 6: Auth token (optional)
 	authToken := prompt(scanner, out,
-		"Auth token (leave empty for none): ", "")
+		"Auth token (leave empty for none, \"generate\" for a random one): ", "")
+	if authToken == "generate" {
+		token, err := security.GenerateToken(security.DefaultTokenBytes)
+		if err != nil {
+			return fmt.Errorf("generating auth token: %w", err)
+		}
+		authToken = token
+		fmt.Fprintf(out, "  Generated auth token: %s\n", authToken)
+		fmt.Fprintln(out, "  (Clients must send it as \"Authorization: Bearer <token>\"; keep a copy.)")
+		fmt.Fprintln(out)
+	}
 
 	// Step 7: Media injection (optional)
 	fmt.Fprintln(out)
@@ -192,7 +226,15 @@ func RunWizard(in io.Reader, out io.Writer, opts WizardOptions) error {
 		startService := prompt(scanner, out,
 			"Start clawreachbridge service now? [Y/n]: ", "y")
 		if strings.HasPrefix(strings.ToLower(startService), "y") || startService == "" {
-			if err := startSystemdService(out); err != nil {
+			run := runCommand
+			if opts.RunCommand != nil {
+				run = opts.RunCommand
+			}
+			startTimeout := opts.ServiceStartTimeout
+			if startTimeout <= 0 {
+				startTimeout = defaultServiceStartTimeout
+			}
+			if err := startSystemdService(out, run, startTimeout); err != nil {
 				fmt.Fprintf(out, "  WARNING: Failed to start service: %v\n", err)
 				fmt.Fprintln(out, "  You can start it manually: sudo systemctl start clawreachbridge")
 			}
@@ -273,10 +315,11 @@ func detectTailscaleIP() string {
 	return ""
 }
 
-// checkGateway performs a quick HTTP check against the gateway URL.
-func checkGateway(out io.Writer, gatewayURL string) {
+// checkGateway performs a quick HTTP check against the gateway URL, giving up
+// after timeout.
+func checkGateway(out io.Writer, gatewayURL string, timeout time.Duration) {
 	client := &http.Client{
-		Timeout: 3 * time.Second,
+		Timeout: timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
@@ -312,33 +355,48 @@ func isSystemdAvailable() bool {
 	return err == nil
 }
 
-// startSystemdService starts (or restarts) the clawreachbridge service.
-func startSystemdService(out io.Writer) error {
+// startSystemdService starts (or restarts) the clawreachbridge service, then
+// polls `systemctl is-active` with backoff until it reports active, reports
+// failed, or timeout passes. Slow hosts can take a while to bring the service
+// up, so transitional states ("activating", or "inactive" between restart
+// attempts) are polled through rather than treated as failure.
+func startSystemdService(out io.Writer, run func(string, ...string) ([]byte, error), timeout time.Duration) error {
 	// Reload in case service file changed
-	if err := exec.Command("systemctl", "daemon-reload").Run(); err != nil {
+	if _, err := run("systemctl", "daemon-reload"); err != nil {
 		return fmt.Errorf("daemon-reload: %w", err)
 	}
 
 	// Try restart first (handles already-running case), fall back to start
-	if err := exec.Command("systemctl", "restart", "clawreachbridge").Run(); err != nil {
-		if err := exec.Command("systemctl", "start", "clawreachbridge").Run(); err != nil {
+	if _, err := run("systemctl", "restart", "clawreachbridge"); err != nil {
+		if _, err := run("systemctl", "start", "clawreachbridge"); err != nil {
 			return err
 		}
 	}
 
-	// Brief wait then check status
-	time.Sleep(2 * time.Second)
-	output, err := exec.Command("systemctl", "is-active", "clawreachbridge").Output()
-	if err != nil {
-		return fmt.Errorf("service did not start (status: %s)", strings.TrimSpace(string(output)))
-	}
-	status := strings.TrimSpace(string(output))
-	if status == "active" {
-		fmt.Fprintln(out, "  Service started successfully.")
-	} else {
-		fmt.Fprintf(out, "  Service status: %s\n", status)
+	deadline := time.Now().Add(timeout)
+	wait := serviceStartInitialPoll
+	var status string
+	for {
+		// is-active exits non-zero for every state but active; the state
+		// itself is on stdout either way.
+		output, _ := run("systemctl", "is-active", "clawreachbridge")
+		status = strings.TrimSpace(string(output))
+		if status == "" {
+			status = "unknown"
+		}
+		switch status {
+		case "active":
+			fmt.Fprintln(out, "  Service started successfully.")
+			return nil
+		case "failed":
+			return fmt.Errorf("service failed to start (status: failed); see: journalctl -u clawreachbridge")
+		}
+		if time.Until(deadline) <= 0 {
+			return fmt.Errorf("service not active after %s (status: %s)", timeout, status)
+		}
+		time.Sleep(min(wait, time.Until(deadline)))
+		wait = min(wait*2, serviceStartMaxPoll)
 	}
-	return nil
 }
 
 // yamlEscapeString escapes a string for use inside YAML double quotes.
@@ -458,6 +516,9 @@ User=%s
 Group=%s
 ProtectHome=false
 ReadOnlyPaths=%s
+# To keep the auth token out of config.yaml, set
+# security.auth_token_credential: "auth_token" and uncomment:
+#LoadCredential=auth_token:/etc/clawreachbridge/auth_token
 `, currentUser, currentUser, mediaDir)
 
 	if err := os.WriteFile(overridePath, []byte(content), 0644); err != nil {
@@ -468,7 +529,7 @@ ReadOnlyPaths=%s
 	fmt.Fprintf(out, "  (Service will run as %s to read media files)\n", currentUser)
 
 	// Reload systemd to pick up the override
-	if err := exec.Command("systemctl", "daemon-reload").Run(); err != nil {
+	if _, err := runCommand("systemctl", "daemon-reload"); err != nil {
 		return fmt.Errorf("daemon-reload: %w", err)
 	}
 
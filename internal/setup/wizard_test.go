@@ -3,11 +3,16 @@ package setup
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 )
 
 // noopGatewayCheck skips the HTTP check in tests.
@@ -142,6 +147,48 @@ func TestRunWizard_AllDefaults_WithTailscale(t *testing.T) {
 	}
 }
 
+func TestRunWizard_UnresponsiveGatewayTimesOut(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+
+	// A gateway that accepts the connection but never answers.
+	release := make(chan struct{})
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer gw.Close()
+	defer close(release)
+
+	input := strings.Join([]string{
+		gw.URL, // gateway URL
+		"",     // listen port (accept default)
+		"",     // health port (accept default)
+		"",     // auth token (none)
+		"",     // media dir (disabled)
+	}, "\n") + "\n"
+
+	opts := testOpts(configPath, "100.64.1.1")
+	opts.CheckGateway = nil
+	opts.NetworkTimeout = 50 * time.Millisecond
+
+	var out bytes.Buffer
+	start := time.Now()
+	if err := RunWizard(strings.NewReader(input), &out, opts); err != nil {
+		t.Fatalf("RunWizard() error: %v", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("wizard took %v with a 50ms network timeout", d)
+	}
+
+	output := out.String()
+	if !strings.Contains(output, "WARNING: Gateway at "+gw.URL+" is not reachable") {
+		t.Errorf("wizard should warn about the unresponsive gateway, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Setup complete!") {
+		t.Error("wizard should finish despite the unreachable gateway")
+	}
+}
+
 func TestRunWizard_NoTailscale_ManualIP(t *testing.T) {
 	dir := t.TempDir()
 	configPath := filepath.Join(dir, "config.yaml")
@@ -210,6 +257,36 @@ func TestRunWizard_CustomValues(t *testing.T) {
 	}
 }
 
+func TestRunWizard_GeneratedAuthToken(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+
+	input := strings.Join([]string{
+		"",         // gateway URL (accept default)
+		"",         // listen port (accept default)
+		"",         // health port (accept default)
+		"generate", // auth token
+		"",         // media dir (disabled)
+	}, "\n") + "\n"
+
+	var out bytes.Buffer
+	if err := RunWizard(strings.NewReader(input), &out, testOpts(configPath, "100.64.1.1")); err != nil {
+		t.Fatalf("RunWizard() error: %v", err)
+	}
+
+	m := regexp.MustCompile(`Generated auth token: ([A-Za-z0-9_-]{43})\n`).FindStringSubmatch(out.String())
+	if m == nil {
+		t.Fatalf("wizard should print a 32-byte base64url token, got:\n%s", out.String())
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("reading config: %v", err)
+	}
+	if !strings.Contains(string(data), `auth_token: "`+m[1]+`"`) {
+		t.Error("config should contain the generated auth token")
+	}
+}
+
 func TestRunWizard_ExistingConfig_NoOverwrite(t *testing.T) {
 	dir := t.TempDir()
 	configPath := filepath.Join(dir, "config.yaml")
@@ -532,3 +609,68 @@ func TestPromptPort_InvalidThenEOF(t *testing.T) {
 		t.Error("promptPort should warn about invalid port before falling back to default")
 	}
 }
+
+// fakeSystemctl records systemctl invocations and answers is-active from
+// states in order, repeating the last one.
+type fakeSystemctl struct {
+	states []string
+	polls  int
+	calls  []string
+}
+
+func (f *fakeSystemctl) run(name string, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, strings.Join(append([]string{name}, args...), " "))
+	if len(args) == 0 || args[0] != "is-active" {
+		return nil, nil
+	}
+	state := f.states[min(f.polls, len(f.states)-1)]
+	f.polls++
+	if state != "active" {
+		return []byte(state + "\n"), errors.New("exit status 3")
+	}
+	return []byte(state + "\n"), nil
+}
+
+func TestStartSystemdService_BecomesActiveAfterPolls(t *testing.T) {
+	f := &fakeSystemctl{states: []string{"activating", "activating", "active"}}
+	var out bytes.Buffer
+	if err := startSystemdService(&out, f.run, 10*time.Second); err != nil {
+		t.Fatalf("startSystemdService() error: %v", err)
+	}
+	if f.polls != 3 {
+		t.Errorf("is-active polled %d times, want 3", f.polls)
+	}
+	if !strings.Contains(out.String(), "Service started successfully.") {
+		t.Errorf("output = %q, want success message", out.String())
+	}
+	want := []string{"systemctl daemon-reload", "systemctl restart clawreachbridge"}
+	if len(f.calls) < 2 || f.calls[0] != want[0] || f.calls[1] != want[1] {
+		t.Errorf("calls = %v, want %v first", f.calls, want)
+	}
+}
+
+func TestStartSystemdService_Failed(t *testing.T) {
+	f := &fakeSystemctl{states: []string{"activating", "failed"}}
+	err := startSystemdService(io.Discard, f.run, 10*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "status: failed") {
+		t.Fatalf("error = %v, want failed status", err)
+	}
+	if f.polls != 2 {
+		t.Errorf("is-active polled %d times, want 2 (stop at failed)", f.polls)
+	}
+}
+
+func TestStartSystemdService_Timeout(t *testing.T) {
+	f := &fakeSystemctl{states: []string{"activating"}}
+	start := time.Now()
+	err := startSystemdService(io.Discard, f.run, 600*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "not active after 600ms (status: activating)") {
+		t.Fatalf("error = %v, want timeout with last status", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("gave up after %v with a 600ms timeout", d)
+	}
+	if f.polls < 2 {
+		t.Errorf("is-active polled %d times, want retries before giving up", f.polls)
+	}
+}
@@ -2,13 +2,24 @@ package webui
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/cortexuvula/clawreachbridge/internal/audit"
+	"github.com/cortexuvula/clawreachbridge/internal/chatsync"
+	"github.com/cortexuvula/clawreachbridge/internal/config"
+	"github.com/cortexuvula/clawreachbridge/internal/logring"
+	"github.com/cortexuvula/clawreachbridge/internal/proxy"
+	"github.com/cortexuvula/clawreachbridge/internal/security"
 )
 
 // statusResponse is the JSON body for GET /api/v1/status.
@@ -24,6 +35,9 @@ type statusResponse struct {
 	Version           string  `json:"version"`
 	BuildTime         string  `json:"build_time"`
 	GitCommit         string  `json:"git_commit"`
+
+	MediaInjectionActive bool   `json:"media_injection_active"`
+	LogEntriesDropped    uint64 `json:"log_entries_dropped"`
 }
 
 func (ui *WebUI) handleStatus(w http.ResponseWriter, r *http.Request) {
@@ -49,9 +63,33 @@ func (ui *WebUI) handleStatus(w http.ResponseWriter, r *http.Request) {
 		Version:           ui.deps.Version,
 		BuildTime:         ui.deps.BuildTime,
 		GitCommit:         ui.deps.GitCommit,
+
+		MediaInjectionActive: ui.deps.Handler.MediaInjectionActive(),
+		LogEntriesDropped:    ui.deps.RingBuffer.DroppedCount(),
 	}
 
-	writeJSON(w, http.StatusOK, resp)
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// infoResponse is the JSON body for GET /api/v1/info.
+type infoResponse struct {
+	Version   string `json:"version"`
+	BuildTime string `json:"build_time"`
+	GitCommit string `json:"git_commit"`
+	ReadOnly  bool   `json:"read_only"`
+}
+
+func (ui *WebUI) handleInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, infoResponse{
+		Version:   ui.deps.Version,
+		BuildTime: ui.deps.BuildTime,
+		GitCommit: ui.deps.GitCommit,
+		ReadOnly:  ui.deps.ReadOnly,
+	})
 }
 
 // connectionEntry represents a per-IP connection entry.
@@ -75,13 +113,225 @@ func (ui *WebUI) handleConnections(w http.ResponseWriter, r *http.Request) {
 		return entries[i].Count > entries[j].Count
 	})
 
-	writeJSON(w, http.StatusOK, entries)
+	writeJSON(w, r, http.StatusOK, entries)
+}
+
+// closeConnectionsRequest is the JSON body for POST /api/v1/connections/close.
+type closeConnectionsRequest struct {
+	IP         string `json:"ip"`
+	PathPrefix string `json:"path_prefix"`
+	OlderThan  string `json:"older_than"` // Go duration, e.g. "10m"
+	Reason     string `json:"reason"`
+}
+
+// handleConnectionsClose closes every active connection matching the filter.
+// At least one of ip, path_prefix, or older_than is required so a malformed
+// request can't evict everyone.
+func (ui *WebUI) handleConnectionsClose(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireJSON(w, r) {
+		return
+	}
+
+	var req closeConnectionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+
+	filter := proxy.ConnFilter{IP: req.IP, PathPrefix: req.PathPrefix}
+	if req.OlderThan != "" {
+		d, err := time.ParseDuration(req.OlderThan)
+		if err != nil || d <= 0 {
+			writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "older_than must be a positive duration"})
+			return
+		}
+		filter.OlderThan = d
+	}
+	if filter.IsEmpty() {
+		writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "at least one of ip, path_prefix, or older_than is required"})
+		return
+	}
+	reason := req.Reason
+	if reason == "" {
+		reason = "closed by administrator"
+	}
+	if len(reason) > 123 {
+		writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "reason must be at most 123 bytes"})
+		return
+	}
+
+	closed := ui.deps.Handler.CloseConnections(filter, reason)
+	ui.recordAuditEntry(audit.Entry{Action: "close_connections", Details: map[string]any{
+		"ip":          filter.IP,
+		"path_prefix": filter.PathPrefix,
+		"older_than":  req.OlderThan,
+		"reason":      reason,
+		"closed":      closed,
+	}})
+	slog.Info("connections closed via web UI",
+		"ip", filter.IP,
+		"path_prefix", filter.PathPrefix,
+		"older_than", req.OlderThan,
+		"closed", closed,
+	)
+
+	writeJSON(w, r, http.StatusOK, map[string]int{"closed": closed})
+}
+
+// maxSyncExportBytes bounds the encoded size of a sync export and of an
+// import request body.
+const maxSyncExportBytes = 32 << 20 // 32MB
+
+// syncExport is the JSON body of GET /api/v1/sync/export and
+// POST /api/v1/sync/import: every session's history, oldest first.
+type syncExport struct {
+	ExportedAt string                              `json:"exported_at,omitempty"`
+	Sessions   map[string][]chatsync.StoredMessage `json:"sessions"`
+}
+
+// syncExporter returns the sync store if sync is enabled and the store
+// supports export, or nil.
+func (ui *WebUI) syncExporter() chatsync.Exporter {
+	if ui.deps.Handler == nil || ui.deps.Handler.SyncStore == nil {
+		return nil
+	}
+	e, _ := ui.deps.Handler.SyncStore.(chatsync.Exporter)
+	return e
+}
+
+// requireSyncToken enforces security.auth_token as a bearer token on the
+// sync export/import endpoints, which expose every client's chat history.
+// It writes a 401 and returns false when the token is missing or wrong;
+// with no auth_token configured the endpoints stay open, like the metrics
+// endpoint without metrics_auth_token.
+func (ui *WebUI) requireSyncToken(w http.ResponseWriter, r *http.Request) bool {
+	if ui.deps.GetConfig == nil {
+		return true
+	}
+	token := ui.deps.GetConfig().Security.AuthToken
+	if token == "" || security.TokenMatch(security.ExtractBearerToken(r.Header.Get("Authorization")), token) {
+		return true
+	}
+	w.Header().Set("WWW-Authenticate", `Bearer realm="sync"`)
+	writeJSON(w, r, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	return false
+}
+
+// handleSyncExport downloads the whole chat sync store as JSON.
+func (ui *WebUI) handleSyncExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !ui.requireSyncToken(w, r) {
+		return
+	}
+	store := ui.syncExporter()
+	if store == nil {
+		writeJSON(w, r, http.StatusNotFound, map[string]string{"error": "sync is disabled or its store does not support export"})
+		return
+	}
+
+	now := time.Now().UTC()
+	data, err := json.Marshal(syncExport{ExportedAt: now.Format(time.RFC3339), Sessions: store.Export()})
+	if err != nil {
+		writeJSON(w, r, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if len(data) > maxSyncExportBytes {
+		writeJSON(w, r, http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("export is %d bytes, over the %d byte limit", len(data), maxSyncExportBytes)})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="clawreachbridge-sync-%s.json"`, now.Format("20060102T150405Z")))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// handleSyncImport loads an export into the chat sync store, replacing the
+// history of every session it contains.
+func (ui *WebUI) handleSyncImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !ui.requireSyncToken(w, r) {
+		return
+	}
+	if !requireJSON(w, r) {
+		return
+	}
+	store := ui.syncExporter()
+	if store == nil {
+		writeJSON(w, r, http.StatusNotFound, map[string]string{"error": "sync is disabled or its store does not support import"})
+		return
+	}
+
+	var req syncExport
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSyncExportBytes)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, r, http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("import exceeds the %d byte limit", maxSyncExportBytes)})
+			return
+		}
+		writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	for key, msgs := range req.Sessions {
+		if key == "" {
+			writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "session keys must be non-empty"})
+			return
+		}
+		for _, m := range msgs {
+			if m.ID == "" {
+				writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("session %q has a message without an id", key)})
+				return
+			}
+		}
+	}
+
+	kept := store.Import(req.Sessions)
+	ui.recordAudit("sync_import", nil, nil)
+	slog.Info("chat sync history imported via web UI", "sessions", len(req.Sessions), "messages", kept)
+
+	writeJSON(w, r, http.StatusOK, map[string]int{"sessions": len(req.Sessions), "messages": kept})
+}
+
+// handleMediaToggle pauses or resumes media injection without touching the
+// config. The flag is runtime-only and resets on restart.
+func (ui *WebUI) handleMediaToggle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireJSON(w, r) {
+		return
+	}
+
+	paused := ui.deps.Handler.ToggleMediaPaused()
+	action := "media_resume"
+	if paused {
+		action = "media_pause"
+	}
+	ui.recordAudit(action, nil, nil)
+	slog.Info("media injection toggled via web UI", "paused", paused)
+
+	writeJSON(w, r, http.StatusOK, map[string]bool{
+		"paused":                 paused,
+		"media_injection_active": ui.deps.Handler.MediaInjectionActive(),
+	})
 }
 
 // configResponse is the JSON body for GET /api/v1/config.
 type configResponse struct {
-	Reloadable configReloadable `json:"reloadable"`
-	ReadOnly   configReadOnly   `json:"read_only"`
+	Reloadable   configReloadable `json:"reloadable"`
+	ReadOnly     configReadOnly   `json:"read_only"`
+	LockedFields []string         `json:"locked_fields,omitempty"` // reloadable fields PUT may not change
 }
 
 type configReloadable struct {
@@ -96,12 +346,13 @@ type configReloadable struct {
 }
 
 type configReadOnly struct {
-	ListenAddress string `json:"listen_address"`
-	GatewayURL    string `json:"gateway_url"`
-	Origin        string `json:"origin"`
-	HealthAddress string `json:"health_address"`
-	TailscaleOnly bool   `json:"tailscale_only"`
-	TLSEnabled    bool   `json:"tls_enabled"`
+	ListenAddress string            `json:"listen_address"`
+	GatewayURL    string            `json:"gateway_url"`
+	Origin        string            `json:"origin"`
+	OriginRoutes  map[string]string `json:"origin_routes,omitempty"`
+	HealthAddress string            `json:"health_address"`
+	TailscaleOnly bool              `json:"tailscale_only"`
+	TLSEnabled    bool              `json:"tls_enabled"`
 }
 
 func (ui *WebUI) handleConfig(w http.ResponseWriter, r *http.Request) {
@@ -115,7 +366,7 @@ func (ui *WebUI) handleConfig(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (ui *WebUI) handleConfigGet(w http.ResponseWriter, _ *http.Request) {
+func (ui *WebUI) handleConfigGet(w http.ResponseWriter, r *http.Request) {
 	cfg := ui.deps.GetConfig()
 
 	resp := configResponse{
@@ -131,15 +382,17 @@ func (ui *WebUI) handleConfigGet(w http.ResponseWriter, _ *http.Request) {
 		},
 		ReadOnly: configReadOnly{
 			ListenAddress: cfg.Bridge.ListenAddress,
-			GatewayURL:    cfg.Bridge.GatewayURL,
-			Origin:        cfg.Bridge.Origin,
+			GatewayURL:    config.RedactURL(cfg.Bridge.GatewayURL),
+			Origin:        cfg.Bridge.Origin.Default,
+			OriginRoutes:  cfg.Bridge.Origin.Routes,
 			HealthAddress: cfg.Health.ListenAddress,
 			TailscaleOnly: cfg.Security.TailscaleOnly,
 			TLSEnabled:    cfg.Bridge.TLS.Enabled,
 		},
+		LockedFields: cfg.WebUI.LockedFields,
 	}
 
-	writeJSON(w, http.StatusOK, resp)
+	writeJSON(w, r, http.StatusOK, resp)
 }
 
 // configUpdateRequest is the JSON body for PUT /api/v1/config.
@@ -160,7 +413,7 @@ func (ui *WebUI) handleConfigPut(w http.ResponseWriter, r *http.Request) {
 
 	var req configUpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
 		return
 	}
 
@@ -169,49 +422,81 @@ func (ui *WebUI) handleConfigPut(w http.ResponseWriter, r *http.Request) {
 	// Apply updates to a copy
 	updated := *cfg
 
+	// Fields listed in webui.locked_fields can't be changed from here. The
+	// config form submits every field, so resubmitting the current value of a
+	// locked field is allowed.
+	locked := func(field string, changed bool) bool {
+		if changed && slices.Contains(cfg.WebUI.LockedFields, field) {
+			writeJSON(w, r, http.StatusForbidden, map[string]string{"error": field + " is locked by webui.locked_fields"})
+			return true
+		}
+		return false
+	}
+
 	if req.LogLevel != nil {
+		if locked("log_level", *req.LogLevel != cfg.Logging.Level) {
+			return
+		}
 		switch *req.LogLevel {
 		case "debug", "info", "warn", "error":
 			updated.Logging.Level = *req.LogLevel
 		default:
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "log_level must be debug, info, warn, or error"})
+			writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "log_level must be debug, info, warn, or error"})
 			return
 		}
 	}
 	if req.MaxConnections != nil {
+		if locked("max_connections", *req.MaxConnections != cfg.Security.MaxConnections) {
+			return
+		}
 		if *req.MaxConnections <= 0 || *req.MaxConnections > 65535 {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "max_connections must be 1-65535"})
+			writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "max_connections must be 1-65535"})
 			return
 		}
 		updated.Security.MaxConnections = *req.MaxConnections
 	}
 	if req.MaxConnectionsPerIP != nil {
+		if locked("max_connections_per_ip", *req.MaxConnectionsPerIP != cfg.Security.MaxConnectionsPerIP) {
+			return
+		}
 		if *req.MaxConnectionsPerIP <= 0 {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "max_connections_per_ip must be positive"})
+			writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "max_connections_per_ip must be positive"})
 			return
 		}
 		updated.Security.MaxConnectionsPerIP = *req.MaxConnectionsPerIP
 	}
 	if req.MaxMessageSize != nil {
+		if locked("max_message_size", *req.MaxMessageSize != cfg.Bridge.MaxMessageSize) {
+			return
+		}
 		if *req.MaxMessageSize <= 0 || *req.MaxMessageSize > 67108864 {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "max_message_size must be 1 to 67108864"})
+			writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "max_message_size must be 1 to 67108864"})
 			return
 		}
 		updated.Bridge.MaxMessageSize = *req.MaxMessageSize
 	}
 	if req.RateLimitEnabled != nil {
+		if locked("rate_limit_enabled", *req.RateLimitEnabled != cfg.Security.RateLimit.Enabled) {
+			return
+		}
 		updated.Security.RateLimit.Enabled = *req.RateLimitEnabled
 	}
 	if req.ConnectionsPerMin != nil {
+		if locked("connections_per_minute", *req.ConnectionsPerMin != cfg.Security.RateLimit.ConnectionsPerMinute) {
+			return
+		}
 		if *req.ConnectionsPerMin <= 0 {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "connections_per_minute must be positive"})
+			writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "connections_per_minute must be positive"})
 			return
 		}
 		updated.Security.RateLimit.ConnectionsPerMinute = *req.ConnectionsPerMin
 	}
 	if req.MessagesPerSecond != nil {
+		if locked("messages_per_second", *req.MessagesPerSecond != cfg.Security.RateLimit.MessagesPerSecond) {
+			return
+		}
 		if *req.MessagesPerSecond <= 0 {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "messages_per_second must be positive"})
+			writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "messages_per_second must be positive"})
 			return
 		}
 		updated.Security.RateLimit.MessagesPerSecond = *req.MessagesPerSecond
@@ -219,17 +504,18 @@ func (ui *WebUI) handleConfigPut(w http.ResponseWriter, r *http.Request) {
 
 	// Validate cross-field constraint
 	if updated.Security.MaxConnectionsPerIP > updated.Security.MaxConnections {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "max_connections_per_ip must not exceed max_connections"})
+		writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "max_connections_per_ip must not exceed max_connections"})
 		return
 	}
 
 	ui.deps.Handler.UpdateConfig(&updated)
+	ui.recordAudit("config_update", reloadableChanges(cfg, &updated), nil)
 	slog.Info("config updated via web UI",
 		"log_level", updated.Logging.Level,
 		"max_connections", updated.Security.MaxConnections,
 	)
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+	writeJSON(w, r, http.StatusOK, map[string]string{"status": "updated"})
 }
 
 // logEntry mirrors logring.LogEntry for JSON serialization.
@@ -253,26 +539,7 @@ func (ui *WebUI) handleLogs(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	minLevel := slog.LevelDebug
-	if v := r.URL.Query().Get("level"); v != "" {
-		switch v {
-		case "debug":
-			minLevel = slog.LevelDebug
-		case "info":
-			minLevel = slog.LevelInfo
-		case "warn":
-			minLevel = slog.LevelWarn
-		case "error":
-			minLevel = slog.LevelError
-		}
-	}
-
-	var since time.Time
-	if v := r.URL.Query().Get("since"); v != "" {
-		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
-			since = t
-		}
-	}
+	minLevel, since := parseLogFilters(r)
 
 	entries := ui.deps.RingBuffer.Entries(limit, minLevel, since)
 	resp := make([]logEntryResponse, len(entries))
@@ -285,7 +552,88 @@ func (ui *WebUI) handleLogs(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	writeJSON(w, http.StatusOK, resp)
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// handleLogsExport returns the full ring buffer as a downloadable file, oldest
+// entry first. The body is NDJSON unless ?format=text is given or, absent the
+// parameter, logging.format is "text". Supports the same level/since filters
+// as /api/v1/logs.
+func (ui *WebUI) handleLogsExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = ui.deps.GetConfig().Logging.Format
+	}
+
+	minLevel, since := parseLogFilters(r)
+	entries := ui.deps.RingBuffer.Entries(ui.deps.RingBuffer.Cap(), minLevel, since)
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	if format == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="clawreachbridge-logs-%s.log"`, stamp))
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="clawreachbridge-logs-%s.ndjson"`, stamp))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if format == "text" {
+			fmt.Fprintln(w, formatLogLine(e))
+			continue
+		}
+		enc.Encode(logEntryResponse{
+			Time:    e.Time.Format(time.RFC3339Nano),
+			Level:   e.Level.String(),
+			Message: e.Message,
+			Attrs:   e.Attrs,
+		})
+	}
+}
+
+// parseLogFilters reads the level and since query parameters shared by the
+// log endpoints. Unknown or malformed values fall back to no filtering.
+func parseLogFilters(r *http.Request) (slog.Level, time.Time) {
+	minLevel := slog.LevelDebug
+	switch r.URL.Query().Get("level") {
+	case "info":
+		minLevel = slog.LevelInfo
+	case "warn":
+		minLevel = slog.LevelWarn
+	case "error":
+		minLevel = slog.LevelError
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			since = t
+		}
+	}
+	return minLevel, since
+}
+
+// formatLogLine renders an entry in slog's text style with attrs sorted by key.
+func formatLogLine(e logring.LogEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "time=%s level=%s msg=%q", e.Time.Format(time.RFC3339Nano), e.Level, e.Message)
+	keys := make([]string, 0, len(e.Attrs))
+	for k := range e.Attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, e.Attrs[k])
+	}
+	return b.String()
 }
 
 func (ui *WebUI) handleReload(w http.ResponseWriter, r *http.Request) {
@@ -298,16 +646,18 @@ func (ui *WebUI) handleReload(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if ui.deps.ReloadFunc == nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "reload not available"})
+		writeJSON(w, r, http.StatusInternalServerError, map[string]string{"error": "reload not available"})
 		return
 	}
 
-	if err := ui.deps.ReloadFunc(); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	err := ui.deps.ReloadFunc()
+	ui.recordAudit("reload", nil, err)
+	if err != nil {
+		writeJSON(w, r, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "reloaded"})
+	writeJSON(w, r, http.StatusOK, map[string]string{"status": "reloaded"})
 }
 
 func (ui *WebUI) handleRestart(w http.ResponseWriter, r *http.Request) {
@@ -320,7 +670,8 @@ func (ui *WebUI) handleRestart(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slog.Warn("restart requested via web UI")
-	writeJSON(w, http.StatusOK, map[string]string{"status": "restarting"})
+	ui.recordAudit("restart", nil, nil)
+	writeJSON(w, r, http.StatusOK, map[string]string{"status": "restarting"})
 
 	// Flush response before exiting
 	if f, ok := w.(http.Flusher); ok {
@@ -334,6 +685,89 @@ func (ui *WebUI) handleRestart(w http.ResponseWriter, r *http.Request) {
 	}()
 }
 
+// auditEntryResponse is a single entry in the GET /api/v1/audit response.
+type auditEntryResponse struct {
+	Time    string                  `json:"time"`
+	Action  string                  `json:"action"`
+	Source  string                  `json:"source"`
+	Changes map[string]audit.Change `json:"changes,omitempty"`
+	Error   string                  `json:"error,omitempty"`
+}
+
+func (ui *WebUI) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 1000 {
+			limit = n
+		}
+	}
+
+	resp := []auditEntryResponse{}
+	if ui.deps.AuditLog != nil {
+		for _, e := range ui.deps.AuditLog.Entries(limit) {
+			resp = append(resp, auditEntryResponse{
+				Time:    e.Time.Format(time.RFC3339Nano),
+				Action:  e.Action,
+				Source:  e.Source,
+				Changes: e.Changes,
+				Error:   e.Error,
+			})
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// recordAudit appends a web UI action to the audit log, if configured.
+func (ui *WebUI) recordAudit(action string, changes map[string]audit.Change, actionErr error) {
+	e := audit.Entry{Action: action, Changes: changes}
+	if actionErr != nil {
+		e.Error = actionErr.Error()
+	}
+	ui.recordAuditEntry(e)
+}
+
+// recordAuditEntry appends e, attributed to the web UI, to the audit log.
+func (ui *WebUI) recordAuditEntry(e audit.Entry) {
+	if ui.deps.AuditLog == nil {
+		return
+	}
+	e.Source = "webui"
+	if err := ui.deps.AuditLog.Record(e); err != nil {
+		slog.Warn("failed to write audit entry", "action", e.Action, "error", err)
+	}
+}
+
+// reloadableChanges returns the reloadable fields that differ between old and
+// updated, keyed by their config API name.
+func reloadableChanges(old, updated *config.Config) map[string]audit.Change {
+	fields := []struct {
+		name          string
+		before, after any
+	}{
+		{"log_level", old.Logging.Level, updated.Logging.Level},
+		{"max_connections", old.Security.MaxConnections, updated.Security.MaxConnections},
+		{"max_connections_per_ip", old.Security.MaxConnectionsPerIP, updated.Security.MaxConnectionsPerIP},
+		{"max_message_size", old.Bridge.MaxMessageSize, updated.Bridge.MaxMessageSize},
+		{"rate_limit_enabled", old.Security.RateLimit.Enabled, updated.Security.RateLimit.Enabled},
+		{"connections_per_minute", old.Security.RateLimit.ConnectionsPerMinute, updated.Security.RateLimit.ConnectionsPerMinute},
+		{"messages_per_second", old.Security.RateLimit.MessagesPerSecond, updated.Security.RateLimit.MessagesPerSecond},
+	}
+
+	changes := make(map[string]audit.Change)
+	for _, f := range fields {
+		if f.before != f.after {
+			changes[f.name] = audit.Change{Before: f.before, After: f.after}
+		}
+	}
+	return changes
+}
+
 // checkGatewayReachable does a quick HTTP check against the gateway.
 var gatewayClient = &http.Client{
 	Timeout: 3 * time.Second,
@@ -351,11 +785,23 @@ func checkGatewayReachable(gatewayURL string) bool {
 	return true
 }
 
-// writeJSON writes a JSON response with the given status code.
-func writeJSON(w http.ResponseWriter, code int, v any) {
+// writeJSON writes a JSON response with the given status code. Output is
+// compact unless the request asks for ?pretty=1 (handy with curl).
+func writeJSON(w http.ResponseWriter, r *http.Request, code int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(v)
+	enc := json.NewEncoder(w)
+	if wantPretty(r) {
+		enc.SetIndent("", "  ")
+	}
+	enc.Encode(v)
+}
+
+// wantPretty reports whether the pretty query parameter is set to a true
+// value (1, true, ...).
+func wantPretty(r *http.Request) bool {
+	pretty, _ := strconv.ParseBool(r.URL.Query().Get("pretty"))
+	return pretty
 }
 
 // requireJSON checks that the Content-Type header is application/json.
@@ -363,7 +809,7 @@ func writeJSON(w http.ResponseWriter, code int, v any) {
 func requireJSON(w http.ResponseWriter, r *http.Request) bool {
 	ct := r.Header.Get("Content-Type")
 	if ct != "application/json" {
-		writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "Content-Type must be application/json"})
+		writeJSON(w, r, http.StatusUnsupportedMediaType, map[string]string{"error": "Content-Type must be application/json"})
 		return false
 	}
 	return true
@@ -387,6 +387,7 @@
             setText('total-msgs', formatNumber(d.total_messages));
             setText('memory', d.memory_mb.toFixed(1) + ' MB');
             setText('goroutines', formatNumber(d.goroutines));
+            setText('log-dropped', formatNumber(d.log_entries_dropped));
             setText('version', 'v' + d.version);
 
             var gs = document.getElementById('gateway-status');
@@ -398,6 +399,8 @@
                 gs.className = 'card-value status-degraded';
             }
 
+            setText('media-state', d.media_injection_active ? 'Active' : 'Paused or disabled');
+
             var bi = document.getElementById('build-info');
             var commitShort = d.git_commit.substring(0, 8);
             bi.textContent = commitShort + '\n' + d.build_time;
@@ -509,6 +512,23 @@
             document.getElementById('cfg-conns-min').value = rl.connections_per_minute;
             document.getElementById('cfg-msgs-sec').value = rl.messages_per_second;
 
+            // Grey out fields locked by webui.locked_fields
+            var lockedInputs = {
+                log_level: 'cfg-log-level',
+                max_connections: 'cfg-max-conns',
+                max_connections_per_ip: 'cfg-max-conns-ip',
+                max_message_size: 'cfg-max-msg',
+                rate_limit_enabled: 'cfg-rate-enabled',
+                connections_per_minute: 'cfg-conns-min',
+                messages_per_second: 'cfg-msgs-sec'
+            };
+            var locked = d.locked_fields || [];
+            Object.keys(lockedInputs).forEach(function(field) {
+                var el = document.getElementById(lockedInputs[field]);
+                el.disabled = locked.indexOf(field) !== -1;
+                el.title = el.disabled ? 'Locked by webui.locked_fields' : '';
+            });
+
             var ro = d.read_only;
             var roEl = document.getElementById('config-readonly');
             while (roEl.firstChild) roEl.removeChild(roEl.firstChild);
@@ -698,6 +718,27 @@
         });
     });
 
+    document.getElementById('btn-media-toggle').addEventListener('click', function() {
+        var btn = this;
+        btn.disabled = true;
+        fetch(API + '/media/toggle', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' }
+        }).then(function(r) { return r.json().then(function(d) { return { ok: r.ok, data: d }; }); })
+        .then(function(res) {
+            if (res.ok) {
+                setText('media-state', res.data.media_injection_active ? 'Active' : 'Paused or disabled');
+                Toast.show(res.data.paused ? 'Media injection paused' : 'Media injection resumed', 'success');
+            } else {
+                Toast.show(res.data.error || 'Toggle failed', 'error');
+            }
+            btn.disabled = false;
+        }).catch(function() {
+            btn.disabled = false;
+            Toast.show('Network error', 'error');
+        });
+    });
+
     document.getElementById('btn-restart').addEventListener('click', function() {
         if (!confirm('Are you sure you want to restart the service?')) return;
         var btn = this;
@@ -712,9 +753,22 @@
         });
     });
 
+    // ─── Read-only mode ──────────────────────────────────────────────
+    function applyReadOnly() {
+        fetch(API + '/info').then(function(r) { return r.json(); }).then(function(d) {
+            if (!d.read_only) return;
+            var buttons = document.querySelectorAll('#config-form button, #tab-controls button');
+            for (var i = 0; i < buttons.length; i++) {
+                buttons[i].disabled = true;
+                buttons[i].title = 'Web UI is in read-only mode';
+            }
+        }).catch(function() {});
+    }
+
     // ─── Init ────────────────────────────────────────────────────────
     Theme.init();
     Keyboard.init();
     ConnMonitor.start();
+    applyReadOnly();
     startTimers('dashboard');
 })();
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/cortexuvula/clawreachbridge/internal/audit"
 	"github.com/cortexuvula/clawreachbridge/internal/config"
 	"github.com/cortexuvula/clawreachbridge/internal/logring"
 	"github.com/cortexuvula/clawreachbridge/internal/proxy"
@@ -21,6 +22,7 @@ type Dependencies struct {
 	Handler     *proxy.Handler
 	RateLimiter *security.RateLimiter
 	RingBuffer  *logring.RingBuffer
+	AuditLog    *audit.Log // optional, nil disables audit recording
 	Version     string
 	BuildTime   string
 	GitCommit   string
@@ -28,6 +30,7 @@ type Dependencies struct {
 	StartTime   time.Time
 	ReloadFunc  func() error
 	GetConfig   func() *config.Config
+	ReadOnly    bool // reject mutating requests with 403
 }
 
 // WebUI provides HTTP handlers for the admin interface.
@@ -52,13 +55,36 @@ func (ui *WebUI) APIHandler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/v1/status", ui.handleStatus)
 	mux.HandleFunc("/api/v1/connections", ui.handleConnections)
+	mux.HandleFunc("/api/v1/connections/close", ui.handleConnectionsClose)
+	mux.HandleFunc("/api/v1/sync/export", ui.handleSyncExport)
+	mux.HandleFunc("/api/v1/sync/import", ui.handleSyncImport)
+	mux.HandleFunc("/api/v1/media/toggle", ui.handleMediaToggle)
 	mux.HandleFunc("/api/v1/config", ui.handleConfig)
 	mux.HandleFunc("/api/v1/logs", ui.handleLogs)
+	mux.HandleFunc("/api/v1/logs/export", ui.handleLogsExport)
 	mux.HandleFunc("/api/v1/reload", ui.handleReload)
 	mux.HandleFunc("/api/v1/restart", ui.handleRestart)
+	mux.HandleFunc("/api/v1/audit", ui.handleAudit)
+	mux.HandleFunc("/api/v1/info", ui.handleInfo)
+	if ui.deps.ReadOnly {
+		return readOnly(mux)
+	}
 	return mux
 }
 
+// readOnly rejects every request that isn't a GET or HEAD, so config
+// changes, reloads, restarts and connection closes are unavailable on a
+// shared dashboard.
+func readOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeJSON(w, r, http.StatusForbidden, map[string]string{"error": "web UI is in read-only mode"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func securityHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("X-Content-Type-Options", "nosniff")
@@ -2,13 +2,18 @@ package webui
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/cortexuvula/clawreachbridge/internal/audit"
+	"github.com/cortexuvula/clawreachbridge/internal/chatsync"
 	"github.com/cortexuvula/clawreachbridge/internal/config"
 	"github.com/cortexuvula/clawreachbridge/internal/logring"
 	"github.com/cortexuvula/clawreachbridge/internal/proxy"
@@ -57,6 +62,25 @@ func TestStatusEndpoint(t *testing.T) {
 	}
 }
 
+func TestStatusLogEntriesDropped(t *testing.T) {
+	deps := testDeps()
+	for i := 0; i < deps.RingBuffer.Cap()+7; i++ {
+		deps.RingBuffer.Add(logring.LogEntry{Message: "spam", Level: slog.LevelInfo, Time: time.Now()})
+	}
+	mux := New(deps).APIHandler()
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/status", nil))
+
+	var resp statusResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if resp.LogEntriesDropped != 7 {
+		t.Errorf("log_entries_dropped = %d, want 7", resp.LogEntriesDropped)
+	}
+}
+
 func TestStatusMethodNotAllowed(t *testing.T) {
 	ui := New(testDeps())
 	mux := ui.APIHandler()
@@ -70,6 +94,33 @@ func TestStatusMethodNotAllowed(t *testing.T) {
 	}
 }
 
+func TestPrettyJSON(t *testing.T) {
+	mux := New(testDeps()).APIHandler()
+
+	get := func(target string) string {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status code = %d, want %d", target, w.Code, http.StatusOK)
+		}
+		return w.Body.String()
+	}
+
+	compact := get("/api/v1/config")
+	if strings.Count(compact, "\n") != 1 {
+		t.Errorf("default output should be a single line, got:\n%s", compact)
+	}
+
+	pretty := get("/api/v1/config?pretty=1")
+	if !strings.Contains(pretty, "{\n  \"reloadable\": {\n    \"log_level\"") {
+		t.Errorf("pretty=1 output should be indented, got:\n%s", pretty)
+	}
+	var resp configResponse
+	if err := json.Unmarshal([]byte(pretty), &resp); err != nil {
+		t.Errorf("pretty output is not valid JSON: %v", err)
+	}
+}
+
 func TestConnectionsEndpoint(t *testing.T) {
 	deps := testDeps()
 	deps.Proxy.TryIncrementConnections("10.0.0.1", 1000, 100)
@@ -148,6 +199,167 @@ func TestConfigPutEndpoint(t *testing.T) {
 	}
 }
 
+func TestConfigPutRecordsAudit(t *testing.T) {
+	deps := testDeps()
+	deps.AuditLog, _ = audit.NewLog(10, "")
+	ui := New(deps)
+	mux := ui.APIHandler()
+
+	body := `{"log_level":"debug","max_connections":500,"max_connections_per_ip":10}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/config", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want %d; body: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/audit", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	var entries []auditEntryResponse
+	if err := json.NewDecoder(w.Body).Decode(&entries); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %d, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Action != "config_update" || e.Source != "webui" {
+		t.Errorf("entry = %+v, want action=config_update source=webui", e)
+	}
+	if len(e.Changes) != 2 {
+		t.Errorf("changes = %v, want exactly log_level and max_connections", e.Changes)
+	}
+	if c := e.Changes["log_level"]; c.Before != "info" || c.After != "debug" {
+		t.Errorf("log_level change = %+v, want info→debug", c)
+	}
+	// JSON numbers decode as float64
+	if c := e.Changes["max_connections"]; c.Before != float64(1000) || c.After != float64(500) {
+		t.Errorf("max_connections change = %+v, want 1000→500", c)
+	}
+}
+
+func TestReloadRecordsAuditError(t *testing.T) {
+	deps := testDeps()
+	deps.AuditLog, _ = audit.NewLog(10, "")
+	deps.ReloadFunc = func() error { return errors.New("bad yaml") }
+	ui := New(deps)
+	mux := ui.APIHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/reload", nil)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	entries := deps.AuditLog.Entries(0)
+	if len(entries) != 1 || entries[0].Action != "reload" || entries[0].Error != "bad yaml" {
+		t.Errorf("audit entries = %+v, want one failed reload", entries)
+	}
+}
+
+func TestMediaToggle(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Bridge.Media.Enabled = true
+	cfg.Bridge.Media.Directory = t.TempDir()
+	deps := testDeps()
+	deps.Handler = proxy.NewHandler(cfg, deps.Proxy, nil, nil)
+	deps.AuditLog, _ = audit.NewLog(10, "")
+	mux := New(deps).APIHandler()
+
+	mediaActive := func() bool {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/status", nil))
+		var resp statusResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode status: %v", err)
+		}
+		return resp.MediaInjectionActive
+	}
+	toggle := func() map[string]bool {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/media/toggle", nil)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status code = %d, want %d", w.Code, http.StatusOK)
+		}
+		var resp map[string]bool
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp
+	}
+
+	if !mediaActive() {
+		t.Fatal("media injection should start active")
+	}
+	if resp := toggle(); !resp["paused"] || resp["media_injection_active"] {
+		t.Errorf("first toggle = %v, want paused", resp)
+	}
+	if mediaActive() {
+		t.Error("status should report media injection paused")
+	}
+	if resp := toggle(); resp["paused"] || !resp["media_injection_active"] {
+		t.Errorf("second toggle = %v, want resumed", resp)
+	}
+
+	entries := deps.AuditLog.Entries(0)
+	if len(entries) != 2 || entries[0].Action != "media_resume" || entries[1].Action != "media_pause" {
+		t.Errorf("audit entries = %+v, want media_resume after media_pause", entries)
+	}
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	deps := testDeps()
+	deps.ReadOnly = true
+	reloadCalled := false
+	deps.ReloadFunc = func() error {
+		reloadCalled = true
+		return nil
+	}
+	mux := New(deps).APIHandler()
+
+	for _, tc := range []struct{ method, path, body string }{
+		{http.MethodPut, "/api/v1/config", `{"log_level":"debug"}`},
+		{http.MethodPost, "/api/v1/reload", ``},
+		{http.MethodPost, "/api/v1/restart", ``},
+		{http.MethodPost, "/api/v1/connections/close", `{"ip":"10.0.0.1"}`},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s: status = %d, want %d", tc.method, tc.path, w.Code, http.StatusForbidden)
+		}
+	}
+	if reloadCalled {
+		t.Error("reload should not run in read-only mode")
+	}
+	if got := deps.GetConfig().Logging.Level; got != "info" {
+		t.Errorf("log level changed to %q in read-only mode", got)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/config", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /api/v1/config: status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/info", nil))
+	var info infoResponse
+	if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+		t.Fatalf("decode info: %v", err)
+	}
+	if !info.ReadOnly || info.Version != "1.0.0-test" {
+		t.Errorf("info = %+v, want read_only true", info)
+	}
+}
+
 func TestConfigPutBadContentType(t *testing.T) {
 	ui := New(testDeps())
 	mux := ui.APIHandler()
@@ -177,6 +389,63 @@ func TestConfigPutValidation(t *testing.T) {
 	}
 }
 
+func TestConfigPutLockedFields(t *testing.T) {
+	deps := testDeps()
+	cfg := config.DefaultConfig()
+	cfg.WebUI.LockedFields = []string{"max_connections", "rate_limit_enabled"}
+	deps.Handler.UpdateConfig(cfg)
+	ui := New(deps)
+	mux := ui.APIHandler()
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/config", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	// Changing a locked field is refused and leaves everything untouched.
+	w := put(`{"log_level":"debug","max_connections":500}`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("changing locked max_connections: status = %d, want 403; body: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "max_connections is locked") {
+		t.Errorf("error body = %s, want it to name the locked field", w.Body.String())
+	}
+	if w := put(`{"rate_limit_enabled":false}`); w.Code != http.StatusForbidden {
+		t.Errorf("changing locked rate_limit_enabled: status = %d, want 403", w.Code)
+	}
+	got := ui.deps.GetConfig()
+	if got.Logging.Level != cfg.Logging.Level || got.Security.MaxConnections != cfg.Security.MaxConnections || !got.Security.RateLimit.Enabled {
+		t.Errorf("rejected PUT changed config: level=%q max_connections=%d rate_limit=%v",
+			got.Logging.Level, got.Security.MaxConnections, got.Security.RateLimit.Enabled)
+	}
+
+	// Other fields still change, even alongside the locked fields'
+	// current values (the config form submits every field).
+	body := fmt.Sprintf(`{"log_level":"debug","max_connections":%d,"rate_limit_enabled":true,"messages_per_second":7}`, cfg.Security.MaxConnections)
+	if w := put(body); w.Code != http.StatusOK {
+		t.Fatalf("changing unlocked fields: status = %d, want 200; body: %s", w.Code, w.Body.String())
+	}
+	got = ui.deps.GetConfig()
+	if got.Logging.Level != "debug" || got.Security.RateLimit.MessagesPerSecond != 7 {
+		t.Errorf("unlocked fields not applied: level=%q messages_per_second=%d", got.Logging.Level, got.Security.RateLimit.MessagesPerSecond)
+	}
+
+	// GET reports the locked fields so the UI can disable their inputs.
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/config", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	var resp configResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if strings.Join(resp.LockedFields, ",") != "max_connections,rate_limit_enabled" {
+		t.Errorf("locked_fields = %v", resp.LockedFields)
+	}
+}
+
 func TestLogsEndpoint(t *testing.T) {
 	deps := testDeps()
 	deps.RingBuffer.Add(logring.LogEntry{
@@ -208,6 +477,62 @@ func TestLogsEndpoint(t *testing.T) {
 	}
 }
 
+func TestLogsExport(t *testing.T) {
+	deps := testDeps()
+	deps.RingBuffer.Add(logring.LogEntry{Time: time.Now(), Level: slog.LevelDebug, Message: "noise"})
+	deps.RingBuffer.Add(logring.LogEntry{Time: time.Now(), Level: slog.LevelInfo, Message: "first"})
+	deps.RingBuffer.Add(logring.LogEntry{Time: time.Now(), Level: slog.LevelWarn, Message: "second", Attrs: map[string]any{"k": "v"}})
+
+	mux := New(deps).APIHandler()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/logs/export?level=info&format=json", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want %d", w.Code, http.StatusOK)
+	}
+	cd := w.Header().Get("Content-Disposition")
+	if !strings.HasPrefix(cd, "attachment;") || !strings.Contains(cd, ".ndjson") {
+		t.Errorf("Content-Disposition = %q, want ndjson attachment", cd)
+	}
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %d, want 2: %q", len(lines), w.Body.String())
+	}
+	var first, second logEntryResponse
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("unmarshal line 0: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatalf("unmarshal line 1: %v", err)
+	}
+	if first.Message != "first" || second.Message != "second" {
+		t.Errorf("messages = %q, %q, want oldest first", first.Message, second.Message)
+	}
+	if second.Attrs["k"] != "v" {
+		t.Errorf("attrs = %v, want k=v", second.Attrs)
+	}
+}
+
+func TestLogsExportText(t *testing.T) {
+	deps := testDeps()
+	deps.RingBuffer.Add(logring.LogEntry{Time: time.Now(), Level: slog.LevelInfo, Message: "hello", Attrs: map[string]any{"client_ip": "100.64.0.5"}})
+
+	mux := New(deps).APIHandler()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/logs/export?format=text", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, ".log") {
+		t.Errorf("Content-Disposition = %q, want .log attachment", cd)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `msg="hello"`) || !strings.Contains(body, "client_ip=100.64.0.5") {
+		t.Errorf("body = %q, want text log line", body)
+	}
+}
+
 func TestLogsSinceFilter(t *testing.T) {
 	deps := testDeps()
 	deps.RingBuffer.Add(logring.LogEntry{
@@ -325,3 +650,217 @@ func TestRequireJSON(t *testing.T) {
 		t.Fatalf("status code = %d, want %d", w.Code, http.StatusUnsupportedMediaType)
 	}
 }
+
+func TestConnectionsCloseEndpoint(t *testing.T) {
+	deps := testDeps()
+	deps.AuditLog, _ = audit.NewLog(10, "")
+	ui := New(deps)
+	mux := ui.APIHandler()
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"no filter", `{"reason":"bye"}`, http.StatusBadRequest},
+		{"bad older_than", `{"older_than":"soon"}`, http.StatusBadRequest},
+		{"negative older_than", `{"older_than":"-5m"}`, http.StatusBadRequest},
+		{"reason too long", `{"ip":"10.0.0.1","reason":"` + strings.Repeat("x", 124) + `"}`, http.StatusBadRequest},
+		{"invalid JSON", `{`, http.StatusBadRequest},
+		{"ip filter", `{"ip":"10.0.0.1"}`, http.StatusOK},
+		{"combined filter", `{"path_prefix":"/ws/","older_than":"10m","reason":"maintenance"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/connections/close", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status code = %d, want %d (body %s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var resp map[string]int
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if n, ok := resp["closed"]; !ok || n != 0 {
+				t.Errorf("response = %v, want closed=0 with no active connections", resp)
+			}
+		})
+	}
+
+	entries := deps.AuditLog.Entries(0)
+	if len(entries) != 2 || entries[0].Action != "close_connections" {
+		t.Fatalf("audit entries = %+v, want two close_connections entries", entries)
+	}
+	// Newest first: the combined filter, then the ip filter.
+	want := []map[string]any{
+		{"ip": "", "path_prefix": "/ws/", "older_than": "10m", "reason": "maintenance", "closed": 0},
+		{"ip": "10.0.0.1", "path_prefix": "", "older_than": "", "reason": "closed by administrator", "closed": 0},
+	}
+	for i, e := range entries {
+		if !reflect.DeepEqual(e.Details, want[i]) {
+			t.Errorf("audit entry %d details = %v, want %v", i, e.Details, want[i])
+		}
+	}
+}
+
+func TestConnectionsCloseMethodAndContentType(t *testing.T) {
+	mux := New(testDeps()).APIHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/connections/close", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/connections/close", strings.NewReader(`{"ip":"10.0.0.1"}`))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("no content type status = %d, want %d", w.Code, http.StatusUnsupportedMediaType)
+	}
+}
+
+func TestSyncExportImportRoundTrip(t *testing.T) {
+	srcDeps := testDeps()
+	src := chatsync.NewMessageStore(100)
+	src.Append("agent:main:main", chatsync.StoredMessage{ID: "m1", Role: "user", Content: []chatsync.ContentItem{{Type: "text", Text: "hello"}}, Timestamp: 1000})
+	src.Append("agent:main:main", chatsync.StoredMessage{ID: "m2", Role: "assistant", Content: []chatsync.ContentItem{{Type: "text", Text: "hi there"}}, Timestamp: 2000})
+	src.Append("agent:ops:main", chatsync.StoredMessage{ID: "o1", Role: "user", Content: []chatsync.ContentItem{{Type: "text", Text: "status?"}}, Timestamp: 1500})
+	srcDeps.Handler.SyncStore = src
+
+	w := httptest.NewRecorder()
+	New(srcDeps).APIHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/sync/export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("export status = %d: %s", w.Code, w.Body.String())
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "clawreachbridge-sync-") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	exported := w.Body.String()
+
+	dstDeps := testDeps()
+	dstDeps.AuditLog, _ = audit.NewLog(10, "")
+	dst := chatsync.NewMessageStore(100)
+	dst.Append("agent:main:main", chatsync.StoredMessage{ID: "stale", Timestamp: 1})
+	dstDeps.Handler.SyncStore = dst
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sync/import", strings.NewReader(exported))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	New(dstDeps).APIHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("import status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]int
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp["sessions"] != 2 || resp["messages"] != 3 {
+		t.Errorf("import response = %v, want 2 sessions, 3 messages", resp)
+	}
+
+	for _, key := range []string{"agent:main:main", "agent:ops:main"} {
+		want, got := src.GetHistory(key, 0), dst.GetHistory(key, 0)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s after import = %+v, want %+v", key, got, want)
+		}
+	}
+	if entries := dstDeps.AuditLog.Entries(0); len(entries) != 1 || entries[0].Action != "sync_import" {
+		t.Errorf("audit entries = %+v, want one sync_import", entries)
+	}
+}
+
+func TestSyncImportRejects(t *testing.T) {
+	deps := testDeps()
+	deps.Handler.SyncStore = chatsync.NewMessageStore(100)
+	mux := New(deps).APIHandler()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    int
+	}{
+		{"wrong content type", "text/plain", `{"sessions":{}}`, http.StatusUnsupportedMediaType},
+		{"invalid JSON", "application/json", `{`, http.StatusBadRequest},
+		{"empty session key", "application/json", `{"sessions":{"":[{"id":"m1"}]}}`, http.StatusBadRequest},
+		{"message without id", "application/json", `{"sessions":{"s":[{"role":"user"}]}}`, http.StatusBadRequest},
+		{"too large", "application/json", `{"sessions":{"s":[{"id":"` + strings.Repeat("x", maxSyncExportBytes) + `"}]}}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/sync/import", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSyncExportImportRequireAuthToken(t *testing.T) {
+	deps := testDeps()
+	cfg := config.DefaultConfig()
+	cfg.Security.AuthToken = "s3cret"
+	deps.Handler.UpdateConfig(cfg)
+	deps.Handler.SyncStore = chatsync.NewMessageStore(100)
+	mux := New(deps).APIHandler()
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		auth     string
+		wantCode int
+	}{
+		{"export without token", http.MethodGet, "/api/v1/sync/export", "", http.StatusUnauthorized},
+		{"export wrong token", http.MethodGet, "/api/v1/sync/export", "Bearer nope", http.StatusUnauthorized},
+		{"export with token", http.MethodGet, "/api/v1/sync/export", "Bearer s3cret", http.StatusOK},
+		{"import without token", http.MethodPost, "/api/v1/sync/import", "", http.StatusUnauthorized},
+		{"import with token", http.MethodPost, "/api/v1/sync/import", "Bearer s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"sessions":{}}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantCode == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestSyncExportDisabled(t *testing.T) {
+	mux := New(testDeps()).APIHandler()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/sync/export", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("export with sync disabled = %d, want 404", w.Code)
+	}
+
+	deps := testDeps()
+	deps.ReadOnly = true
+	deps.Handler.SyncStore = chatsync.NewMessageStore(100)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sync/import", strings.NewReader(`{"sessions":{}}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	New(deps).APIHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("import in read-only mode = %d, want 403", w.Code)
+	}
+}